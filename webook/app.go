@@ -2,6 +2,9 @@ package main
 
 import (
 	"basic-go/webook/internal/events"
+	"basic-go/webook/internal/job"
+	"basic-go/webook/internal/service"
+	"basic-go/webook/pkg/fulltext"
 
 	"github.com/gin-gonic/gin"
 )
@@ -9,4 +12,14 @@ import (
 type App struct {
 	server    *gin.Engine
 	consumers []events.Consumer
+	// 定时发表文章的后台任务
+	publisher *service.ScheduledPublisher
+	// 定时计算热榜的后台任务
+	rankingJob *job.RankingJob
+	// 写回模式下把计数的增量写入数据库的后台任务
+	flusher *service.InteractiveFlusher
+	// 分布式的定时任务调度器
+	scheduler *job.Scheduler
+	// 搜索用的全文索引，退出的时候要关掉，把内存里面的索引落盘
+	index *fulltext.Index
 }
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var errUnknownCommand = errors.New("未知的命令")
+
+// runCommand 执行运维用的子命令，不启动 Web 服务，比如：
+//
+//	webook --config=./config/config.yaml rebuild-interactive article 123
+func runCommand(args []string) error {
+	switch args[0] {
+	case "rebuild-interactive":
+		return rebuildInteractive(args[1:])
+	default:
+		return fmt.Errorf("%w %s", errUnknownCommand, args[0])
+	}
+}
+
+// rebuildInteractive 用数据库里面的计数强制重建一个资源的缓存
+func rebuildInteractive(args []string) error {
+	if len(args) != 2 {
+		return errors.New("用法：rebuild-interactive <biz> <bizId>")
+	}
+	bizId, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("bizId 不对：%w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	intr, err := InitInteractiveReconciler().Rebuild(ctx, args[0], bizId)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("重建完成 %s:%d read_cnt=%d like_cnt=%d collect_cnt=%d\n",
+		intr.Biz, intr.BizId, intr.ReadCnt, intr.LikeCnt, intr.CollectCnt)
+	return nil
+}
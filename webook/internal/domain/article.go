@@ -1,6 +1,10 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"basic-go/webook/pkg/markdown"
+)
 
 type Article struct {
 	Id      int64
@@ -8,12 +12,23 @@ type Article struct {
 	Content string
 	Author  Author
 	Status  ArticleStatus
-	Ctime   time.Time
-	Utime   time.Time
+	// Category 作者自己给文章选的分类
+	Category string
+	// Tags 文章的标签，一篇文章可以有多个标签
+	Tags []string
+	// PublishAt 定时发表的时间，零值代表立刻发表
+	PublishAt time.Time
+	Ctime     time.Time
+	Utime     time.Time
+	// DeletedAt 放进回收站的时间，零值代表没有删除
+	DeletedAt time.Time
+	// Rendered Content 渲染之后的结果，只有线上库的文章才会渲染
+	Rendered RenderedContent
 }
 
+// Abstract 去掉 Markdown 和 HTML 标记之后的前 128 个字
 func (a Article) Abstract() string {
-	str := []rune(a.Content)
+	str := []rune(markdown.PlainText(a.Content))
 	// 只取部分作为摘要
 	if len(str) > 128 {
 		str = str[:128]
@@ -21,6 +36,22 @@ func (a Article) Abstract() string {
 	return string(str)
 }
 
+// RenderedContent 文章内容渲染成 HTML 之后的结果
+type RenderedContent struct {
+	// HTML 已经按照白名单过滤过，可以直接渲染
+	HTML string
+	TOC  []TOCItem
+	// ReadingTime 预计阅读时间
+	ReadingTime time.Duration
+}
+
+// TOCItem 目录里面的一项，Anchor 是 HTML 里面对应标题的 id
+type TOCItem struct {
+	Level  int
+	Title  string
+	Anchor string
+}
+
 type ArticleStatus uint8
 
 func (s ArticleStatus) ToUint8() uint8 {
@@ -36,8 +67,20 @@ const (
 	ArticleStatusPublished
 	// ArticleStatusPrivate 仅自己可见
 	ArticleStatusPrivate
+	// ArticleStatusScheduled 等待定时发表
+	ArticleStatusScheduled
+	// ArticleStatusPendingReview 机器审核没通过，等待人工审核
+	ArticleStatusPendingReview
+	// ArticleStatusRejected 人工审核没通过
+	ArticleStatusRejected
 )
 
+// TagCount 标签以及打了这个标签的已发表文章数量，用来做标签云
+type TagCount struct {
+	Name string
+	Cnt  int64
+}
+
 type Author struct {
 	Id   int64
 	Name string
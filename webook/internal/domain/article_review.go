@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// ArticleReview 一次人工审核。机器审核没通过的文章会生成一条待审核的记录，
+// 记录的是提交审核的时候的内容，审核员看到的就是作者发表的版本
+type ArticleReview struct {
+	Id        int64
+	ArticleId int64
+	Author    Author
+	Title     string
+	Content   string
+	// Violations 机器审核没通过的原因
+	Violations []string
+	Status     ReviewStatus
+	// ReviewerId 处理这条记录的审核员
+	ReviewerId int64
+	// Reason 审核员给出的理由，驳回的时候必须有
+	Reason string
+	Ctime  time.Time
+	Utime  time.Time
+}
+
+type ReviewStatus uint8
+
+func (s ReviewStatus) ToUint8() uint8 {
+	return uint8(s)
+}
+
+const (
+	ReviewStatusUnknown ReviewStatus = iota
+	// ReviewStatusPending 等待审核
+	ReviewStatusPending
+	ReviewStatusApproved
+	ReviewStatusRejected
+	// ReviewStatusOutdated 审核之前作者又修改了文章，这条记录作废
+	ReviewStatusOutdated
+)
@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// ArticleRevision 文章的一个历史版本
+// 每一次保存或者发表，都会留下一个快照
+type ArticleRevision struct {
+	Id        int64
+	ArticleId int64
+	// 同一篇文章内从 1 开始递增
+	Version int64
+	Title   string
+	Content string
+	Author  Author
+	// 快照时文章的状态，用来区分是草稿还是发表的版本
+	Status ArticleStatus
+	Ctime  time.Time
+}
+
+// Article 用这个版本的内容恢复出一篇草稿
+func (r ArticleRevision) Article() Article {
+	return Article{
+		Id:      r.ArticleId,
+		Title:   r.Title,
+		Content: r.Content,
+		Author:  r.Author,
+	}
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// Collection 收藏夹。Id 为 0 的是每个用户都有的默认收藏夹，不需要创建
+type Collection struct {
+	Id          int64
+	Uid         int64
+	Name        string
+	Description string
+	// ItemCnt 收藏夹里面有多少条内容
+	ItemCnt int64
+	Ctime   time.Time
+	Utime   time.Time
+}
+
+// CollectionItem 收藏夹里面的一条内容
+type CollectionItem struct {
+	Id    int64
+	Cid   int64
+	Biz   string
+	BizId int64
+	// Utime 收藏或者移动到这个收藏夹的时间
+	Utime time.Time
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// Comment 评论，可以挂在任何业务上面，目前只有文章。
+// 根评论的 RootId 和 ParentId 都是 0；回复的 RootId 是它所在的根评论，
+// ParentId 是它直接回复的那条评论（可能是根评论，也可能是另外一条回复）
+type Comment struct {
+	Id int64
+	// Commentator 评论的人，Name 是昵称
+	Commentator Author
+	Biz         string
+	BizId       int64
+	RootId      int64
+	ParentId    int64
+	Content     string
+	// LikeCnt 点赞数，和文章一样放在 Interactive 里面
+	LikeCnt int64
+	// ReplyCnt 根评论下面有多少条回复，回复本身这个字段没有意义
+	ReplyCnt int64
+	Ctime    time.Time
+	Utime    time.Time
+}
+
+func (c Comment) IsRoot() bool {
+	return c.RootId == 0
+}
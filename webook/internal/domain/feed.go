@@ -0,0 +1,10 @@
+package domain
+
+import "time"
+
+// FeedItem 首页时间线上的一条，Ctime 是文章第一次发表的时间，
+// 编辑之后重新发表不会改变它在时间线上的位置
+type FeedItem struct {
+	Article Article
+	Ctime   time.Time
+}
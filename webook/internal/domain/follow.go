@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// FollowRelation Follower 关注了 Followee，Utime 是关注的时间
+type FollowRelation struct {
+	Id       int64
+	Follower Author
+	Followee Author
+	Utime    time.Time
+}
+
+// FollowStatistics 用户的粉丝数和关注数
+type FollowStatistics struct {
+	Uid       int64
+	Followers int64
+	Followees int64
+}
@@ -1,7 +1,14 @@
 package domain
 
+import "time"
+
 type HistoryRecord struct {
+	Id    int64
 	BizId int64
 	Biz   string
 	Uid   int64
+	// LastReadTime 最近一次阅读的时间
+	LastReadTime time.Time
+	// Title 查询的时候补充的标题，内容已经删除或者撤回了就是空的
+	Title string
 }
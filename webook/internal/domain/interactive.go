@@ -1,9 +1,57 @@
 package domain
 
+import (
+	"strconv"
+	"time"
+)
+
 type Interactive struct {
+	Biz   string
+	BizId int64
+	// ReadCnt 累计阅读数，同一个读者在去重窗口里面重复打开只算一次
 	ReadCnt    int64
 	LikeCnt    int64
 	CollectCnt int64
 	Liked      bool
 	Collected  bool
+	// PV 今天的浏览量，不去重；UV 今天的独立读者数
+	PV int64
+	UV int64
+}
+
+// Like 一条点赞记录，取消了的不算
+type Like struct {
+	Id    int64
+	Biz   string
+	BizId int64
+	Liker Liker
+	// Utime 点赞的时间，取消之后重新点赞会更新
+	Utime time.Time
+}
+
+// Liker 点赞的人，列表里面展示昵称和头像
+type Liker struct {
+	Id     int64
+	Name   string
+	Avatar string
+}
+
+// Read 一次阅读。登录用户按照 Uid 区分读者，没有登录的按照 IP
+type Read struct {
+	Biz   string
+	BizId int64
+	Uid   int64
+	IP    string
+	Time  time.Time
+}
+
+// Reader 读者标识，Uid 和 IP 都没有的时候返回空字符串，这种阅读没法去重
+func (r Read) Reader() string {
+	if r.Uid > 0 {
+		return "u:" + strconv.FormatInt(r.Uid, 10)
+	}
+	if r.IP != "" {
+		return "ip:" + r.IP
+	}
+	return ""
 }
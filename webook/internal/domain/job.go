@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// cronParser 支持标准的 5 段表达式，也支持带秒的 6 段表达式和 @every 1m 这种写法
+var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour |
+	cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+type Job struct {
+	Id   int64
+	Name string
+	// Expression cron 表达式
+	Expression string
+	// Executor 用哪个执行器执行，比如 local 或者 http
+	Executor string
+	// Cfg 交给执行器的配置，比如 HTTP 回调的地址
+	Cfg string
+	// Version 每次被抢占都会加一，续约和释放都要带上
+	Version  int64
+	NextTime time.Time
+}
+
+// Next 计算 t 之后的下一次执行时间
+func (j Job) Next(t time.Time) (time.Time, error) {
+	s, err := cronParser.Parse(j.Expression)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return s.Next(t), nil
+}
@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// ArticleSearchHit 搜索命中的一篇文章，
+// Title 和 Snippet 里面命中的词已经用 <em> 标记出来了，其余部分做过 HTML 转义
+type ArticleSearchHit struct {
+	Id      int64
+	Title   string
+	Snippet string
+	Author  Author
+	Utime   time.Time
+	Score   float64
+}
@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// Upload 用户在编辑器里面上传的图片或者附件。
+// 同样内容的文件只存一份，对象的名字由内容的哈希决定，所以 URL 是稳定的
+type Upload struct {
+	Id  int64
+	Uid int64
+	// Hash 内容的 SHA-256，十六进制
+	Hash string
+	// Name 用户上传时候的文件名
+	Name        string
+	ContentType string
+	Size        int64
+	// HasThumbnail 有没有单独生成缩略图，图片本身就不大的话不需要
+	HasThumbnail bool
+	URL          string
+	// ThumbnailURL 只有图片才有，没有单独的缩略图的话和 URL 一样
+	ThumbnailURL string
+	Ctime        time.Time
+}
+
+// UploadUsage 用户已经用掉的上传空间
+type UploadUsage struct {
+	Used  int64
+	Quota int64
+}
@@ -8,6 +8,8 @@ type User struct {
 	Password string
 
 	Nickname string
+	// Avatar 头像的 URL，一般是用户上传的图片
+	Avatar string
 	// YYYY-MM-DD
 	Birthday time.Time
 	AboutMe  string
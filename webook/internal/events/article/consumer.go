@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"basic-go/webook/internal/domain"
 	"basic-go/webook/internal/repository"
 	"basic-go/webook/pkg/logger"
 	"basic-go/webook/pkg/samarax"
@@ -56,17 +57,15 @@ func (i *InteractiveReadEventConsumer) StartV1() error {
 }
 func (i *InteractiveReadEventConsumer) BatchConsume(msgs []*sarama.ConsumerMessage,
 	events []ReadEvent) error {
-	bizs := make([]string, 0, len(events))
-	bizIds := make([]int64, 0, len(events))
+	reads := make([]domain.Read, 0, len(events))
 	for _, evt := range events {
 		fmt.Println("consume: ", evt.Aid, "len(events): ", len(events))
-		bizs = append(bizs, "article")
-		bizIds = append(bizIds, evt.Aid)
+		reads = append(reads, toRead(evt))
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 	fmt.Println("收到批量消息")
-	return i.repo.BatchIncrReadCnt(ctx, bizs, bizIds)
+	return i.repo.RecordReads(ctx, reads)
 }
 
 func (i *InteractiveReadEventConsumer) Consume(msg *sarama.ConsumerMessage,
@@ -74,5 +73,20 @@ func (i *InteractiveReadEventConsumer) Consume(msg *sarama.ConsumerMessage,
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 	fmt.Println("收到消息")
-	return i.repo.IncrReadCnt(ctx, "article", event.Aid)
+	return i.repo.RecordReads(ctx, []domain.Read{toRead(event)})
+}
+
+func toRead(evt ReadEvent) domain.Read {
+	readTime := time.UnixMilli(evt.Ctime)
+	// 老版本的事件里面没有时间
+	if evt.Ctime == 0 {
+		readTime = time.Now()
+	}
+	return domain.Read{
+		Biz:   "article",
+		BizId: evt.Aid,
+		Uid:   evt.Uid,
+		IP:    evt.IP,
+		Time:  readTime,
+	}
 }
@@ -12,14 +12,21 @@ import (
 	"github.com/IBM/sarama"
 )
 
+// HistoryRecordConsumer 根据阅读事件记录阅读历史
 type HistoryRecordConsumer struct {
 	repo   repository.HistoryRecordRepository
 	client sarama.Client
 	l      logger.LoggerV1
 }
 
+func NewHistoryRecordConsumer(repo repository.HistoryRecordRepository,
+	client sarama.Client, l logger.LoggerV1) *HistoryRecordConsumer {
+	return &HistoryRecordConsumer{repo: repo, client: client, l: l}
+}
+
 func (i *HistoryRecordConsumer) Start() error {
-	cg, err := sarama.NewConsumerGroupFromClient("interactive", i.client)
+	// 用自己的消费者组，和阅读计数各自消费一份完整的阅读事件
+	cg, err := sarama.NewConsumerGroupFromClient("history", i.client)
 	if err != nil {
 		return err
 	}
@@ -38,9 +45,15 @@ func (i *HistoryRecordConsumer) Consume(msg *sarama.ConsumerMessage,
 	event ReadEvent) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
+	// 阅读事件里面没有时间，用消息的时间戳，也就是阅读发生的时间
+	readTime := msg.Timestamp
+	if readTime.IsZero() {
+		readTime = time.Now()
+	}
 	return i.repo.AddRecord(ctx, domain.HistoryRecord{
-		BizId: event.Aid,
-		Biz:   "article",
-		Uid:   event.Uid,
+		BizId:        event.Aid,
+		Biz:          "article",
+		Uid:          event.Uid,
+		LastReadTime: readTime,
 	})
 }
@@ -2,19 +2,30 @@ package article
 
 import (
 	"encoding/json"
+	"strconv"
 
 	"github.com/IBM/sarama"
 )
 
-const TopicReadEvent = "article_read"
+const (
+	TopicReadEvent    = "article_read"
+	TopicPublishEvent = "article_publish"
+	TopicReviewEvent  = "article_review"
+)
 
 type Producer interface {
 	ProduceReadEvent(evt ReadEvent) error
+	ProducePublishEvent(evt PublishEvent) error
+	ProduceReviewEvent(evt ReviewEvent) error
 }
 
 type ReadEvent struct {
 	Aid int64
 	Uid int64
+	// IP 没有登录的读者按照 IP 去重
+	IP string
+	// Ctime 阅读的时间，毫秒数
+	Ctime int64
 }
 
 type BatchReadEvent struct {
@@ -22,6 +33,39 @@ type BatchReadEvent struct {
 	Uids []int64
 }
 
+const (
+	PublishTypePublish  = "publish"
+	PublishTypeWithdraw = "withdraw"
+)
+
+// PublishEvent 文章发表或者撤回的时候发出来，
+// 撤回的时候只有 Aid、Uid 和 Type 是有意义的
+type PublishEvent struct {
+	Aid     int64
+	Uid     int64
+	Title   string
+	Content string
+	Type    string
+	Utime   int64
+}
+
+const (
+	ReviewResultApproved = "approved"
+	ReviewResultRejected = "rejected"
+)
+
+// ReviewEvent 人工审核的结果，通知作者之类的下游可以订阅它
+type ReviewEvent struct {
+	ReviewId   int64
+	Aid        int64
+	Uid        int64
+	ReviewerId int64
+	Result     string
+	// Reason 审核员给出的理由
+	Reason string
+	Utime  int64
+}
+
 type SaramaSyncProducer struct {
 	producer sarama.SyncProducer
 }
@@ -41,3 +85,30 @@ func (s *SaramaSyncProducer) ProduceReadEvent(evt ReadEvent) error {
 	})
 	return err
 }
+
+func (s *SaramaSyncProducer) ProducePublishEvent(evt PublishEvent) error {
+	val, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	_, _, err = s.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: TopicPublishEvent,
+		// 同一篇文章的发表和撤回要落在同一个分区，保证顺序
+		Key:   sarama.StringEncoder(strconv.FormatInt(evt.Aid, 10)),
+		Value: sarama.StringEncoder(val),
+	})
+	return err
+}
+
+func (s *SaramaSyncProducer) ProduceReviewEvent(evt ReviewEvent) error {
+	val, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	_, _, err = s.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: TopicReviewEvent,
+		Key:   sarama.StringEncoder(strconv.FormatInt(evt.Aid, 10)),
+		Value: sarama.StringEncoder(val),
+	})
+	return err
+}
@@ -0,0 +1,71 @@
+package article
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository"
+	"basic-go/webook/pkg/logger"
+	"basic-go/webook/pkg/samarax"
+
+	"github.com/IBM/sarama"
+)
+
+// SearchIndexConsumer 根据发表和撤回事件维护搜索索引
+type SearchIndexConsumer struct {
+	repo   repository.SearchRepository
+	client sarama.Client
+	l      logger.LoggerV1
+}
+
+func NewSearchIndexConsumer(repo repository.SearchRepository,
+	client sarama.Client, l logger.LoggerV1) *SearchIndexConsumer {
+	return &SearchIndexConsumer{repo: repo, client: client, l: l}
+}
+
+func (s *SearchIndexConsumer) Start() error {
+	// 索引在每个实例的进程内，所以每个实例都要收到全部的消息，
+	// 也就是每个实例用自己的消费者组
+	host, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	cg, err := sarama.NewConsumerGroupFromClient("search_"+host, s.client)
+	if err != nil {
+		return err
+	}
+	go func() {
+		er := cg.Consume(context.Background(),
+			[]string{TopicPublishEvent},
+			samarax.NewHandler[PublishEvent](s.l, s.Consume))
+		if er != nil {
+			s.l.Error("退出消费", logger.Error(er))
+		}
+	}()
+	return err
+}
+
+func (s *SearchIndexConsumer) Consume(msg *sarama.ConsumerMessage,
+	evt PublishEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	switch evt.Type {
+	case PublishTypePublish:
+		return s.repo.InputArticle(ctx, domain.Article{
+			Id:      evt.Aid,
+			Title:   evt.Title,
+			Content: evt.Content,
+			Author:  domain.Author{Id: evt.Uid},
+			Utime:   time.UnixMilli(evt.Utime),
+		})
+	case PublishTypeWithdraw:
+		return s.repo.DeleteArticle(ctx, evt.Aid)
+	default:
+		s.l.Warn("未知的发表事件类型",
+			logger.Int64("aid", evt.Aid),
+			logger.String("type", evt.Type))
+		return nil
+	}
+}
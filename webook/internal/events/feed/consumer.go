@@ -0,0 +1,70 @@
+package feed
+
+import (
+	"context"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/events/article"
+	"basic-go/webook/internal/service"
+	"basic-go/webook/pkg/logger"
+	"basic-go/webook/pkg/samarax"
+
+	"github.com/IBM/sarama"
+)
+
+// pushTimeout 推送给上千个粉丝需要分批写入，给多一点时间
+const pushTimeout = time.Second * 10
+
+// ArticlePublishConsumer 根据文章的发表和撤回事件维护时间线。
+// 它要调用 FeedService，而 service 又依赖 article 包里面的 Producer，
+// 所以不能放在 article 包里面
+type ArticlePublishConsumer struct {
+	svc    service.FeedService
+	client sarama.Client
+	l      logger.LoggerV1
+}
+
+func NewArticlePublishConsumer(svc service.FeedService,
+	client sarama.Client, l logger.LoggerV1) *ArticlePublishConsumer {
+	return &ArticlePublishConsumer{svc: svc, client: client, l: l}
+}
+
+func (a *ArticlePublishConsumer) Start() error {
+	cg, err := sarama.NewConsumerGroupFromClient("feed", a.client)
+	if err != nil {
+		return err
+	}
+	go func() {
+		er := cg.Consume(context.Background(),
+			[]string{article.TopicPublishEvent},
+			samarax.NewHandler[article.PublishEvent](a.l, a.Consume))
+		if er != nil {
+			a.l.Error("退出消费", logger.Error(er))
+		}
+	}()
+	return err
+}
+
+func (a *ArticlePublishConsumer) Consume(msg *sarama.ConsumerMessage,
+	evt article.PublishEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+	defer cancel()
+	switch evt.Type {
+	case article.PublishTypePublish:
+		return a.svc.PushArticle(ctx, domain.FeedItem{
+			Article: domain.Article{
+				Id:     evt.Aid,
+				Author: domain.Author{Id: evt.Uid},
+			},
+			Ctime: time.UnixMilli(evt.Utime),
+		})
+	case article.PublishTypeWithdraw:
+		return a.svc.RemoveArticle(ctx, evt.Aid)
+	default:
+		a.l.Warn("未知的发表事件类型",
+			logger.Int64("aid", evt.Aid),
+			logger.String("type", evt.Type))
+		return nil
+	}
+}
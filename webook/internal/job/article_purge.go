@@ -0,0 +1,34 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/service"
+	"basic-go/webook/pkg/logger"
+)
+
+const ArticlePurgeJobName = "article_purge"
+
+// ArticlePurgeJob 清空回收站里面过了保留期的文章，注册到 LocalFuncExecutor 上，由 Scheduler 调度
+type ArticlePurgeJob struct {
+	purger *service.ArticlePurger
+	l      logger.LoggerV1
+	// retention 文章在回收站里面保留多久
+	retention time.Duration
+}
+
+func NewArticlePurgeJob(purger *service.ArticlePurger, l logger.LoggerV1, retention time.Duration) *ArticlePurgeJob {
+	return &ArticlePurgeJob{
+		purger:    purger,
+		l:         l,
+		retention: retention,
+	}
+}
+
+func (a *ArticlePurgeJob) Run(ctx context.Context, j domain.Job) error {
+	cnt, err := a.purger.Purge(ctx, time.Now().Add(-a.retention))
+	a.l.Debug("清理回收站里面的文章", logger.Int("cnt", cnt))
+	return err
+}
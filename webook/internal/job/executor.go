@@ -0,0 +1,98 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"basic-go/webook/internal/domain"
+)
+
+// Executor 负责真正执行任务，调度器根据 domain.Job 的 Executor 字段挑选
+type Executor interface {
+	Name() string
+	// Exec ctx 被取消的时候要尽快返回，比如任务被别的节点抢走了，或者调度器停止了
+	Exec(ctx context.Context, j domain.Job) error
+}
+
+// LocalFuncExecutor 执行本地注册的 Go 方法，按照任务的名字查找
+type LocalFuncExecutor struct {
+	mu    sync.RWMutex
+	funcs map[string]func(ctx context.Context, j domain.Job) error
+}
+
+func NewLocalFuncExecutor() *LocalFuncExecutor {
+	return &LocalFuncExecutor{
+		funcs: make(map[string]func(ctx context.Context, j domain.Job) error),
+	}
+}
+
+func (l *LocalFuncExecutor) Name() string {
+	return "local"
+}
+
+func (l *LocalFuncExecutor) RegisterFunc(name string, fn func(ctx context.Context, j domain.Job) error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.funcs[name] = fn
+}
+
+func (l *LocalFuncExecutor) Exec(ctx context.Context, j domain.Job) error {
+	l.mu.RLock()
+	fn, ok := l.funcs[j.Name]
+	l.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("未注册本地方法 %s", j.Name)
+	}
+	return fn(ctx, j)
+}
+
+// HttpExecutor 回调 Cfg 里面配置的地址，返回 2xx 就认为执行成功
+type HttpExecutor struct {
+	client *http.Client
+}
+
+func NewHttpExecutor(client *http.Client) *HttpExecutor {
+	return &HttpExecutor{
+		client: client,
+	}
+}
+
+func (h *HttpExecutor) Name() string {
+	return "http"
+}
+
+type httpCallback struct {
+	Id          int64  `json:"id"`
+	Name        string `json:"name"`
+	ScheduledAt string `json:"scheduledAt"`
+}
+
+func (h *HttpExecutor) Exec(ctx context.Context, j domain.Job) error {
+	body, err := json.Marshal(httpCallback{
+		Id:          j.Id,
+		Name:        j.Name,
+		ScheduledAt: j.NextTime.Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.Cfg, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("回调 %s 返回了 %d", j.Cfg, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package job
+
+import (
+	"context"
+	"expvar"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/service"
+	"basic-go/webook/pkg/logger"
+)
+
+const InteractiveReconcileJobName = "interactive_reconcile"
+
+var (
+	// reconcileStats 累计检查了多少个计数缓存，有多少个和数据库对不上，
+	// 通过 expvar 的 /debug/vars 暴露出去
+	reconcileStats = expvar.NewMap("interactive_reconcile")
+	// reconcileDriftRate 最近一次检查的不一致比例
+	reconcileDriftRate = new(expvar.Float)
+)
+
+func init() {
+	reconcileStats.Set("last_drift_rate", reconcileDriftRate)
+}
+
+// InteractiveReconcileJob 定期检查计数缓存和数据库，注册到 LocalFuncExecutor 上，由 Scheduler 调度
+type InteractiveReconcileJob struct {
+	reconciler *service.InteractiveReconciler
+	l          logger.LoggerV1
+	// maxKeys 一次最多检查多少个 key，剩下的下次继续
+	maxKeys int
+}
+
+func NewInteractiveReconcileJob(reconciler *service.InteractiveReconciler,
+	l logger.LoggerV1, maxKeys int) *InteractiveReconcileJob {
+	return &InteractiveReconcileJob{
+		reconciler: reconciler,
+		l:          l,
+		maxKeys:    maxKeys,
+	}
+}
+
+func (i *InteractiveReconcileJob) Run(ctx context.Context, j domain.Job) error {
+	res, err := i.reconciler.Reconcile(ctx, i.maxKeys)
+	reconcileStats.Add("checked", int64(res.Checked))
+	reconcileStats.Add("drifted", int64(res.Drifted))
+	if res.Checked > 0 {
+		reconcileDriftRate.Set(float64(res.Drifted) / float64(res.Checked))
+	}
+	if res.Drifted > 0 {
+		i.l.Warn("计数缓存和数据库不一致，已经删除了对应的缓存",
+			logger.Int("checked", res.Checked),
+			logger.Int("drifted", res.Drifted))
+	}
+	return err
+}
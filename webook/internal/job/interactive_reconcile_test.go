@@ -0,0 +1,135 @@
+package job
+
+import (
+	"context"
+	"expvar"
+	"sort"
+	"testing"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository"
+	"basic-go/webook/internal/repository/cache"
+	"basic-go/webook/internal/repository/dao"
+	"basic-go/webook/internal/service"
+	"basic-go/webook/pkg/logger"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bwmarrin/snowflake"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// pagedCache miniredis 的 SCAN 一次返回全部的 key，游标不是 0 的时候什么都不返回，
+// 这里按照 key 排好序，每次返回 pageSize 个，游标就是下标，模拟真的 Redis 分批遍历
+type pagedCache struct {
+	cache.InteractiveCache
+	pageSize int
+}
+
+func (c *pagedCache) Scan(ctx context.Context, cursor uint64, count int64) ([]domain.Interactive, uint64, error) {
+	all, _, err := c.InteractiveCache.Scan(ctx, 0, count)
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Biz != all[j].Biz {
+			return all[i].Biz < all[j].Biz
+		}
+		return all[i].BizId < all[j].BizId
+	})
+	start := min(int(cursor), len(all))
+	end := min(start+c.pageSize, len(all))
+	var next uint64
+	if end < len(all) {
+		next = uint64(end)
+	}
+	return all[start:end], next, nil
+}
+
+func statValue(name string) int64 {
+	v, ok := reconcileStats.Get(name).(*expvar.Int)
+	if !ok {
+		return 0
+	}
+	return v.Value()
+}
+
+func TestInteractiveReconcileJob_Run(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	// 内存里的 sqlite 每个连接都是一个独立的库
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	require.NoError(t, db.AutoMigrate(&dao.Interactive{}, &dao.UserLikeBiz{}))
+	node, err := snowflake.NewNode(1)
+	require.NoError(t, err)
+	l := logger.NewZapLogger(zap.NewNop())
+	intrCache := cache.NewInteractiveRedisCache(client)
+	// 每次都重新创建，模拟任务换了实例或者重启
+	newJob := func() (*InteractiveReconcileJob, repository.InteractiveRepository) {
+		repo := repository.NewCachedInteractiveRepository(dao.NewGORMInteractiveDAO(db), l,
+			&pagedCache{InteractiveCache: intrCache, pageSize: 2},
+			cache.NewReadRedisCache(client, 0),
+			cache.NewInteractiveDeltaRedisCache(client, node),
+			cache.NewInteractiveStateRedisCache(client), nil)
+		return NewInteractiveReconcileJob(service.NewInteractiveReconciler(repo), l, 2), repo
+	}
+	ctx := context.Background()
+
+	// 1 到 5 都在缓存里面，2 和 5 和数据库对不上
+	for id := int64(1); id <= 5; id++ {
+		require.NoError(t, db.Create(&dao.Interactive{Biz: "article", BizId: id, ReadCnt: id, LikeCnt: 1}).Error)
+		intr := domain.Interactive{Biz: "article", BizId: id, ReadCnt: id, LikeCnt: 1}
+		if id == 2 || id == 5 {
+			intr.LikeCnt = 100
+		}
+		require.NoError(t, intrCache.Set(ctx, "article", id, intr))
+	}
+	j, repo := newJob()
+	// 点赞状态的集合、对账的游标和计数放在同一个 Redis 里面，不能被当成计数检查
+	_, err = repo.Liked(ctx, "article", 1, 10)
+	require.NoError(t, err)
+
+	checked, drifted := statValue("checked"), statValue("drifted")
+	require.NoError(t, j.Run(ctx, domain.Job{}))
+	assert.Equal(t, checked+2, statValue("checked"))
+	assert.Equal(t, drifted+1, statValue("drifted"))
+	assert.Equal(t, 0.5, reconcileDriftRate.Value())
+	// 对不上的删掉了缓存，再读的时候是数据库里面的
+	_, err = intrCache.Get(ctx, "article", 2)
+	assert.Equal(t, cache.ErrKeyNotExist, err)
+	intr, err := repo.Get(ctx, "article", 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), intr.LikeCnt)
+	cursor, err := repo.ReconcileCursor(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), cursor)
+
+	// 重启之后接着上次的位置检查，不会从头开始
+	j, repo = newJob()
+	require.NoError(t, j.Run(ctx, domain.Job{}))
+	assert.Equal(t, checked+4, statValue("checked"))
+	assert.Equal(t, drifted+1, statValue("drifted"))
+	assert.Equal(t, 0.0, reconcileDriftRate.Value())
+	_, err = intrCache.Get(ctx, "article", 5)
+	require.NoError(t, err)
+
+	require.NoError(t, j.Run(ctx, domain.Job{}))
+	assert.Equal(t, checked+5, statValue("checked"))
+	assert.Equal(t, drifted+2, statValue("drifted"))
+	_, err = intrCache.Get(ctx, "article", 5)
+	assert.Equal(t, cache.ErrKeyNotExist, err)
+	// 遍历完了，下次从头开始
+	cursor, err = repo.ReconcileCursor(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), cursor)
+}
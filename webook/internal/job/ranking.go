@@ -0,0 +1,108 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"basic-go/webook/internal/service"
+	"basic-go/webook/pkg/logger"
+	"basic-go/webook/pkg/rlock"
+)
+
+const rankingLockKey = "lock:job:ranking"
+
+// RankingJob 定时重新计算热榜。
+// 部署多个实例的时候，用 Redis 的分布式锁保证同一时刻只有一个实例在算
+type RankingJob struct {
+	svc      service.RankingService
+	lock     *rlock.Client
+	l        logger.LoggerV1
+	interval time.Duration
+	// timeout 一次计算最多允许的时间，也是锁的过期时间
+	timeout time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewRankingJob(svc service.RankingService, lock *rlock.Client,
+	l logger.LoggerV1, interval time.Duration, timeout time.Duration) *RankingJob {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &RankingJob{
+		svc:      svc,
+		lock:     lock,
+		l:        l,
+		interval: interval,
+		timeout:  timeout,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+func (r *RankingJob) Name() string {
+	return "ranking"
+}
+
+// Start 开启后台循环，启动的时候先算一次，之后每隔 interval 算一次
+func (r *RankingJob) Start() {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			if err := r.Run(); err != nil {
+				r.l.Error("计算热榜失败", logger.Error(err))
+			}
+			select {
+			case <-ticker.C:
+			case <-r.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止后台循环，正在算的话等它算完并释放锁。
+// ctx 到期了还没结束就直接返回，锁过期之后别的实例还能接着算
+func (r *RankingJob) Stop(ctx context.Context) error {
+	r.cancel()
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run 拿到锁就计算一次，拿不到说明别的实例在算，直接返回
+func (r *RankingJob) Run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	lock, err := r.lock.TryLock(ctx, rankingLockKey, r.timeout)
+	cancel()
+	if errors.Is(err, rlock.ErrFailedToPreemptLock) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		err := lock.Unlock(ctx)
+		if err != nil {
+			r.l.Error("释放热榜的分布式锁失败", logger.Error(err))
+		}
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	return r.svc.TopN(ctx)
+}
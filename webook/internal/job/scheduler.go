@@ -0,0 +1,189 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository"
+	"basic-go/webook/internal/service"
+	"basic-go/webook/pkg/logger"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// Scheduler 抢占式的分布式调度器。
+// 每个节点都在不停地抢占到期的任务，抢到之后一边执行一边更新心跳，
+// 节点崩溃之后心跳不再更新，任务超时之后就会被别的节点抢走
+type Scheduler struct {
+	svc       service.CronJobService
+	executors map[string]Executor
+	l         logger.LoggerV1
+
+	// limiter 限制一个节点同时执行的任务数量
+	limiter *semaphore.Weighted
+	// dbTimeout 每一次数据库操作的超时时间
+	dbTimeout time.Duration
+	// heartbeatInterval 必须明显小于 CronJobService 的 reclaimAfter
+	heartbeatInterval time.Duration
+	// idleInterval 没有任务可以抢的时候，等多久再抢
+	idleInterval time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewScheduler(svc service.CronJobService, l logger.LoggerV1,
+	maxConcurrency int64, heartbeatInterval time.Duration) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		svc:               svc,
+		executors:         make(map[string]Executor),
+		l:                 l,
+		limiter:           semaphore.NewWeighted(maxConcurrency),
+		dbTimeout:         time.Second,
+		heartbeatInterval: heartbeatInterval,
+		idleInterval:      time.Second,
+		ctx:               ctx,
+		cancel:            cancel,
+	}
+}
+
+// RegisterExecutor 要在 Start 之前调用
+func (s *Scheduler) RegisterExecutor(exec Executor) {
+	s.executors[exec.Name()] = exec
+}
+
+// Start 开启后台调度
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.schedule()
+	}()
+}
+
+// Stop 停止抢占新的任务，并且取消正在执行的任务，等待它们释放。
+// ctx 到期了还没结束就直接返回，那些任务会在心跳超时之后被别的节点抢走
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.cancel()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) schedule() {
+	for {
+		// 先拿到执行的名额再去抢占，免得抢到了任务却没法执行
+		if err := s.limiter.Acquire(s.ctx, 1); err != nil {
+			return
+		}
+		ctx, cancel := context.WithTimeout(s.ctx, s.dbTimeout)
+		j, err := s.svc.Preempt(ctx)
+		cancel()
+		switch {
+		case err == nil:
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				defer s.limiter.Release(1)
+				s.run(j)
+			}()
+			continue
+		case errors.Is(err, repository.ErrNoJobToPreempt):
+		case s.ctx.Err() != nil:
+			s.limiter.Release(1)
+			return
+		default:
+			s.l.Error("抢占任务失败", logger.Error(err))
+		}
+		s.limiter.Release(1)
+		select {
+		case <-time.After(s.idleInterval):
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Scheduler) run(j domain.Job) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		s.heartbeat(ctx, cancel, j)
+	}()
+
+	err := s.exec(ctx, j)
+	if err != nil {
+		s.l.Error("执行任务失败",
+			logger.Int64("jid", j.Id),
+			logger.String("name", j.Name),
+			logger.Error(err))
+	}
+	cancel()
+	<-heartbeatDone
+
+	// 调度器停止了也要释放，不然别的节点要等心跳超时才能接手
+	rctx, rcancel := context.WithTimeout(context.Background(), s.dbTimeout)
+	defer rcancel()
+	err = s.svc.Release(rctx, j)
+	if err != nil && !errors.Is(err, repository.ErrJobNotHold) {
+		s.l.Error("释放任务失败",
+			logger.Int64("jid", j.Id),
+			logger.String("name", j.Name),
+			logger.Error(err))
+	}
+}
+
+func (s *Scheduler) exec(ctx context.Context, j domain.Job) error {
+	exec, ok := s.executors[j.Executor]
+	if !ok {
+		return fmt.Errorf("未知的执行器 %s", j.Executor)
+	}
+	return exec.Exec(ctx, j)
+}
+
+// heartbeat 定时续约，任务被别的节点抢走了就取消执行
+func (s *Scheduler) heartbeat(ctx context.Context, cancel context.CancelFunc, j domain.Job) {
+	ticker := time.NewTicker(s.heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			hctx, hcancel := context.WithTimeout(ctx, s.dbTimeout)
+			err := s.svc.Heartbeat(hctx, j)
+			hcancel()
+			switch {
+			case err == nil:
+			case errors.Is(err, repository.ErrJobNotHold):
+				s.l.Warn("任务已经被别的节点抢走，停止执行",
+					logger.Int64("jid", j.Id),
+					logger.String("name", j.Name))
+				cancel()
+				return
+			default:
+				// 偶发的失败不要紧，只要在 reclaimAfter 之内续约成功就可以
+				s.l.Error("任务续约失败",
+					logger.Int64("jid", j.Id),
+					logger.String("name", j.Name),
+					logger.Error(err))
+			}
+		}
+	}
+}
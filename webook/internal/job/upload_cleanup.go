@@ -0,0 +1,34 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/service"
+	"basic-go/webook/pkg/logger"
+)
+
+const UploadCleanupJobName = "upload_cleanup"
+
+// UploadCleanupJob 清理没有被任何文章引用的上传文件，注册到 LocalFuncExecutor 上，由 Scheduler 调度
+type UploadCleanupJob struct {
+	svc service.UploadService
+	l   logger.LoggerV1
+	// orphanAfter 刚上传的文件可能还没来得及保存到文章里面，超过这么久还没有引用才清理
+	orphanAfter time.Duration
+}
+
+func NewUploadCleanupJob(svc service.UploadService, l logger.LoggerV1, orphanAfter time.Duration) *UploadCleanupJob {
+	return &UploadCleanupJob{
+		svc:         svc,
+		l:           l,
+		orphanAfter: orphanAfter,
+	}
+}
+
+func (u *UploadCleanupJob) Run(ctx context.Context, j domain.Job) error {
+	cnt, err := u.svc.CleanOrphans(ctx, time.Now().Add(-u.orphanAfter))
+	u.l.Debug("清理没有引用的上传文件", logger.Int("cnt", cnt))
+	return err
+}
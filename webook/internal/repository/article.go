@@ -21,11 +21,54 @@ type ArticleRepository interface {
 	GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error)
 	GetById(ctx context.Context, id int64) (domain.Article, error)
 	GetPubById(ctx context.Context, id int64) (domain.Article, error)
+	// CachePub 覆盖线上库文章的缓存，比如补上渲染之后的内容
+	CachePub(ctx context.Context, art domain.Article) error
+
+	GetScheduledByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error)
+	Reschedule(ctx context.Context, uid int64, id int64, publishAt time.Time) error
+	CancelSchedule(ctx context.Context, uid int64, id int64) error
+	PreemptScheduled(ctx context.Context) (domain.Article, error)
+
+	// ListPubByTag 按照标签分页查询已发表的文章
+	ListPubByTag(ctx context.Context, tag string, offset int, limit int) ([]domain.Article, error)
+	// TagCloud 返回文章最多的 limit 个标签
+	TagCloud(ctx context.Context, limit int) ([]domain.TagCount, error)
+	GetCategories(ctx context.Context, uid int64) ([]string, error)
+
+	// ListPub 分批遍历 start 之后第一次发表的文章，不包含作者昵称和标签
+	ListPub(ctx context.Context, start time.Time, offset int, limit int) ([]domain.Article, error)
+	// GetPubByAuthor 和 ListNewestPub 按照 (utime, id) 游标分页，maxId 为 0 代表第一页，
+	// 同样不包含作者昵称和标签
+	GetPubByAuthor(ctx context.Context, uid int64, utime time.Time, maxId int64, limit int) ([]domain.Article, error)
+	ListNewestPub(ctx context.Context, utime time.Time, maxId int64, limit int) ([]domain.Article, error)
+
+	// Delete 放进回收站，Restore 从回收站恢复，不存在或者状态不对的返回 ErrArticleNotFound
+	Delete(ctx context.Context, uid int64, id int64) error
+	Restore(ctx context.Context, uid int64, id int64) error
+	GetDeletedByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error)
+	ListDeletedBefore(ctx context.Context, before time.Time, limit int) ([]domain.Article, error)
+	// ClaimPurge 准备彻底删除回收站里面的文章，之后作者就不能恢复了，已经恢复了的返回 ErrArticleNotFound
+	ClaimPurge(ctx context.Context, id int64) error
+	// Purge 彻底删除 ClaimPurge 过的文章以及它的标签
+	Purge(ctx context.Context, id int64) error
 }
 
+const (
+	// tagFirstPageSize 标签列表第一页缓存的文章数量
+	tagFirstPageSize = 50
+	// tagCloudSize 标签云缓存的标签数量
+	tagCloudSize = 100
+)
+
+var (
+	ErrArticleNotFound          = dao.ErrRecordNotFound
+	ErrScheduledArticleNotFound = dao.ErrScheduledArticleNotFound
+)
+
 type CachedArticleRepository struct {
-	dao   dao.ArticleDAO
-	cache cache.ArticleCache
+	dao    dao.ArticleDAO
+	tagDAO dao.ArticleTagDAO
+	cache  cache.ArticleCache
 	// 因为如果你直接访问 UserDAO，你就绕开了 repository，
 	// repository 一般都有一些缓存机制
 	userRepo UserRepository
@@ -35,10 +78,12 @@ type CachedArticleRepository struct {
 }
 
 func NewCachedArticleRepository(dao dao.ArticleDAO,
+	tagDAO dao.ArticleTagDAO,
 	userRepo UserRepository,
 	cache cache.ArticleCache) ArticleRepository {
 	return &CachedArticleRepository{
 		dao:      dao,
+		tagDAO:   tagDAO,
 		cache:    cache,
 		userRepo: userRepo,
 	}
@@ -62,6 +107,10 @@ func (c *CachedArticleRepository) GetPubById(ctx context.Context, id int64) (dom
 		//return res, nil
 	}
 	res.Author.Name = author.Nickname
+	res.Tags, err = c.tagDAO.GetTagsByArticle(ctx, id, false)
+	if err != nil {
+		return domain.Article{}, err
+	}
 	go func() {
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 		defer cancel()
@@ -73,6 +122,10 @@ func (c *CachedArticleRepository) GetPubById(ctx context.Context, id int64) (dom
 	return res, nil
 }
 
+func (c *CachedArticleRepository) CachePub(ctx context.Context, art domain.Article) error {
+	return c.cache.SetPub(ctx, art)
+}
+
 func (c *CachedArticleRepository) GetById(ctx context.Context, id int64) (domain.Article, error) {
 	res, err := c.cache.Get(ctx, id)
 	if err == nil {
@@ -83,6 +136,10 @@ func (c *CachedArticleRepository) GetById(ctx context.Context, id int64) (domain
 		return domain.Article{}, err
 	}
 	res = c.toDomain(art)
+	res.Tags, err = c.tagDAO.GetTagsByArticle(ctx, id, true)
+	if err != nil {
+		return domain.Article{}, err
+	}
 	go func() {
 		er := c.cache.Set(ctx, res)
 		if er != nil {
@@ -141,6 +198,15 @@ func (c *CachedArticleRepository) SyncStatus(ctx context.Context, uid int64, id
 		if er != nil {
 			// 也要记录日志
 		}
+		c.delCache(ctx, id)
+		// 撤回之后，这篇文章就不能出现在标签列表里面了
+		tags, er := c.tagDAO.GetTagsByArticle(ctx, id, false)
+		if er != nil {
+			zap.L().Error("查询文章标签失败，标签列表缓存可能不一致",
+				zap.Int64("aid", id), zap.Error(er))
+		} else {
+			c.delTagsCache(ctx, tags)
+		}
 	}
 	return err
 }
@@ -152,6 +218,12 @@ func (c *CachedArticleRepository) Sync(ctx context.Context, art domain.Article)
 		if er != nil {
 			// 也要记录日志
 		}
+		c.delCache(ctx, id)
+		art.Id = id
+		err = c.saveDraftTags(ctx, id, art.Tags)
+		if err == nil {
+			err = c.savePubTags(ctx, id, art.Tags)
+		}
 	}
 	// 在这里尝试，设置缓存，开启协程缓存设置操作通常是 I/O 操作，耗时较长。通过将缓存设置放在协程中，主线程可以继续执行其他任务，而不需要等待缓存设置完成。
 	// 协程中的错误处理不会影响主线程的执行。即使缓存设置失败，也不会影响主业务逻辑的正常返回。
@@ -183,6 +255,8 @@ func (c *CachedArticleRepository) Update(ctx context.Context, art domain.Article
 		if er != nil {
 			// 也要记录日志
 		}
+		c.delCache(ctx, art.Id)
+		err = c.saveDraftTags(ctx, art.Id, art.Tags)
 	}
 	return err
 }
@@ -194,22 +268,285 @@ func (c *CachedArticleRepository) Create(ctx context.Context, art domain.Article
 		if er != nil {
 			// 也要记录日志
 		}
+		err = c.saveDraftTags(ctx, id, art.Tags)
 	}
 	return id, err
 }
 
+func (c *CachedArticleRepository) ListPubByTag(ctx context.Context, tag string, offset int, limit int) ([]domain.Article, error) {
+	// 第一页是访问最多的，只缓存第一页
+	if offset == 0 && limit <= tagFirstPageSize {
+		res, err := c.cache.GetTagFirstPage(ctx, tag)
+		if err == nil {
+			return firstN(res, limit), nil
+		}
+		res, err = c.listPubByTag(ctx, tag, 0, tagFirstPageSize)
+		if err != nil {
+			return nil, err
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			er := c.cache.SetTagFirstPage(ctx, tag, res)
+			if er != nil {
+				zap.L().Error("回写标签列表缓存失败", zap.String("tag", tag), zap.Error(er))
+			}
+		}()
+		return firstN(res, limit), nil
+	}
+	return c.listPubByTag(ctx, tag, offset, limit)
+}
+
+func (c *CachedArticleRepository) listPubByTag(ctx context.Context, tag string, offset int, limit int) ([]domain.Article, error) {
+	arts, err := c.tagDAO.GetPubByTag(ctx, tag, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.PublishedArticle, domain.Article](arts, func(idx int, src dao.PublishedArticle) domain.Article {
+		return c.toDomain(dao.Article(src))
+	}), nil
+}
+
+func (c *CachedArticleRepository) TagCloud(ctx context.Context, limit int) ([]domain.TagCount, error) {
+	if limit <= tagCloudSize {
+		res, err := c.cache.GetTagCloud(ctx)
+		if err == nil {
+			return firstN(res, limit), nil
+		}
+	}
+	tcs, err := c.tagDAO.CountPubByTag(ctx, max(limit, tagCloudSize))
+	if err != nil {
+		return nil, err
+	}
+	res := slice.Map[dao.TagCount, domain.TagCount](tcs, func(idx int, src dao.TagCount) domain.TagCount {
+		return domain.TagCount{Name: src.Name, Cnt: src.Cnt}
+	})
+	if limit <= tagCloudSize {
+		er := c.cache.SetTagCloud(ctx, res)
+		if er != nil {
+			zap.L().Error("回写标签云缓存失败", zap.Error(er))
+		}
+	}
+	return firstN(res, limit), nil
+}
+
+func (c *CachedArticleRepository) GetCategories(ctx context.Context, uid int64) ([]string, error) {
+	return c.dao.GetCategories(ctx, uid)
+}
+
+func (c *CachedArticleRepository) ListPub(ctx context.Context, start time.Time,
+	offset int, limit int) ([]domain.Article, error) {
+	arts, err := c.dao.ListPub(ctx, start.UnixMilli(), offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.PublishedArticle, domain.Article](arts, func(idx int, src dao.PublishedArticle) domain.Article {
+		return c.toDomain(dao.Article(src))
+	}), nil
+}
+
+func (c *CachedArticleRepository) GetPubByAuthor(ctx context.Context, uid int64,
+	utime time.Time, maxId int64, limit int) ([]domain.Article, error) {
+	arts, err := c.dao.GetPubByAuthor(ctx, uid, utime.UnixMilli(), maxId, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.PublishedArticle, domain.Article](arts, func(idx int, src dao.PublishedArticle) domain.Article {
+		return c.toDomain(dao.Article(src))
+	}), nil
+}
+
+func (c *CachedArticleRepository) ListNewestPub(ctx context.Context,
+	utime time.Time, maxId int64, limit int) ([]domain.Article, error) {
+	arts, err := c.dao.ListNewestPub(ctx, utime.UnixMilli(), maxId, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.PublishedArticle, domain.Article](arts, func(idx int, src dao.PublishedArticle) domain.Article {
+		return c.toDomain(dao.Article(src))
+	}), nil
+}
+
+func (c *CachedArticleRepository) Delete(ctx context.Context, uid int64, id int64) error {
+	err := c.dao.Delete(ctx, uid, id)
+	if err == nil {
+		c.delTrashCache(ctx, uid, id)
+	}
+	return err
+}
+
+func (c *CachedArticleRepository) Restore(ctx context.Context, uid int64, id int64) error {
+	err := c.dao.Restore(ctx, uid, id)
+	if err == nil {
+		c.delTrashCache(ctx, uid, id)
+	}
+	return err
+}
+
+// delTrashCache 删除和恢复之后，作者的第一页、文章详情、线上详情以及标签列表都变了
+func (c *CachedArticleRepository) delTrashCache(ctx context.Context, uid int64, id int64) {
+	er := c.cache.DelFirstPage(ctx, uid)
+	if er != nil {
+		zap.L().Error("删除第一页缓存失败", zap.Int64("uid", uid), zap.Error(er))
+	}
+	c.delCache(ctx, id)
+	er = c.cache.DelPub(ctx, id)
+	if er != nil {
+		zap.L().Error("删除线上文章缓存失败", zap.Int64("aid", id), zap.Error(er))
+	}
+	tags, er := c.tagDAO.GetTagsByArticle(ctx, id, false)
+	if er != nil {
+		zap.L().Error("查询文章标签失败，标签列表缓存可能不一致",
+			zap.Int64("aid", id), zap.Error(er))
+		return
+	}
+	c.delTagsCache(ctx, tags)
+}
+
+func (c *CachedArticleRepository) GetDeletedByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error) {
+	arts, err := c.dao.GetDeletedByAuthor(ctx, uid, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.Article, domain.Article](arts, func(idx int, src dao.Article) domain.Article {
+		return c.toDomain(src)
+	}), nil
+}
+
+func (c *CachedArticleRepository) ListDeletedBefore(ctx context.Context, before time.Time, limit int) ([]domain.Article, error) {
+	arts, err := c.dao.ListDeletedBefore(ctx, before.UnixMilli(), limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.Article, domain.Article](arts, func(idx int, src dao.Article) domain.Article {
+		return c.toDomain(src)
+	}), nil
+}
+
+func (c *CachedArticleRepository) ClaimPurge(ctx context.Context, id int64) error {
+	return c.dao.ClaimPurge(ctx, id)
+}
+
+func (c *CachedArticleRepository) Purge(ctx context.Context, id int64) error {
+	err := c.dao.Purge(ctx, id)
+	if err != nil {
+		return err
+	}
+	c.delCache(ctx, id)
+	er := c.cache.DelPub(ctx, id)
+	if er != nil {
+		zap.L().Error("删除线上文章缓存失败", zap.Int64("aid", id), zap.Error(er))
+	}
+	// 标签关系没有别的地方会删了
+	err = c.saveDraftTags(ctx, id, nil)
+	if err != nil {
+		return err
+	}
+	return c.savePubTags(ctx, id, nil)
+}
+
+// saveDraftTags 替换草稿的标签，草稿的标签不会出现在标签列表和标签云里面
+func (c *CachedArticleRepository) saveDraftTags(ctx context.Context, aid int64, tags []string) error {
+	return c.tagDAO.ReplaceTags(ctx, aid, true, tags)
+}
+
+// savePubTags 替换线上文章的标签，新旧标签的列表缓存以及标签云缓存都要失效
+func (c *CachedArticleRepository) savePubTags(ctx context.Context, aid int64, tags []string) error {
+	old, err := c.tagDAO.GetTagsByArticle(ctx, aid, false)
+	if err != nil {
+		return err
+	}
+	err = c.tagDAO.ReplaceTags(ctx, aid, false, tags)
+	if err != nil {
+		return err
+	}
+	c.delTagsCache(ctx, append(old, tags...))
+	return nil
+}
+
+func (c *CachedArticleRepository) delCache(ctx context.Context, id int64) {
+	er := c.cache.Del(ctx, id)
+	if er != nil {
+		zap.L().Error("删除文章缓存失败", zap.Int64("aid", id), zap.Error(er))
+	}
+}
+
+func (c *CachedArticleRepository) delTagsCache(ctx context.Context, tags []string) {
+	er := c.cache.DelTags(ctx, tags...)
+	if er != nil {
+		zap.L().Error("删除标签缓存失败", zap.Strings("tags", tags), zap.Error(er))
+	}
+}
+
+func firstN[T any](src []T, n int) []T {
+	if len(src) > n {
+		return src[:n]
+	}
+	return src
+}
+
+func (c *CachedArticleRepository) GetScheduledByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error) {
+	arts, err := c.dao.GetScheduledByAuthor(ctx, uid, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.Article, domain.Article](arts, func(idx int, src dao.Article) domain.Article {
+		return c.toDomain(src)
+	}), nil
+}
+
+func (c *CachedArticleRepository) Reschedule(ctx context.Context, uid int64, id int64, publishAt time.Time) error {
+	return c.dao.Reschedule(ctx, uid, id, publishAt.UnixMilli())
+}
+
+func (c *CachedArticleRepository) CancelSchedule(ctx context.Context, uid int64, id int64) error {
+	err := c.dao.CancelSchedule(ctx, uid, id)
+	if err == nil {
+		er := c.cache.DelFirstPage(ctx, uid)
+		if er != nil {
+			// 也要记录日志
+		}
+		c.delCache(ctx, id)
+	}
+	return err
+}
+
+func (c *CachedArticleRepository) PreemptScheduled(ctx context.Context) (domain.Article, error) {
+	art, err := c.dao.PreemptScheduled(ctx)
+	if err != nil {
+		return domain.Article{}, err
+	}
+	res := c.toDomain(art)
+	// 发表的时候会用 Tags 覆盖标签，所以这里要带上
+	res.Tags, err = c.tagDAO.GetTagsByArticle(ctx, art.Id, true)
+	return res, err
+}
+
 func (c *CachedArticleRepository) toEntity(art domain.Article) dao.Article {
+	var publishAt int64
+	if !art.PublishAt.IsZero() {
+		publishAt = art.PublishAt.UnixMilli()
+	}
 	return dao.Article{
 		Id:       art.Id,
 		Title:    art.Title,
 		Content:  art.Content,
 		AuthorId: art.Author.Id,
+		Category: art.Category,
 		//Status:   uint8(art.Status),
-		Status: art.Status.ToUint8(),
+		Status:    art.Status.ToUint8(),
+		PublishAt: publishAt,
 	}
 }
 
 func (c *CachedArticleRepository) toDomain(art dao.Article) domain.Article {
+	var publishAt, deletedAt time.Time
+	if art.PublishAt > 0 {
+		publishAt = time.UnixMilli(art.PublishAt)
+	}
+	if art.DeletedAt > 0 {
+		deletedAt = time.UnixMilli(art.DeletedAt)
+	}
 	return domain.Article{
 		Id:      art.Id,
 		Title:   art.Title,
@@ -218,9 +555,12 @@ func (c *CachedArticleRepository) toDomain(art dao.Article) domain.Article {
 			// 这里有一个错误
 			Id: art.AuthorId,
 		},
-		Ctime:  time.UnixMilli(art.Ctime),
-		Utime:  time.UnixMilli(art.Utime),
-		Status: domain.ArticleStatus(art.Status),
+		Category:  art.Category,
+		Ctime:     time.UnixMilli(art.Ctime),
+		Utime:     time.UnixMilli(art.Utime),
+		Status:    domain.ArticleStatus(art.Status),
+		PublishAt: publishAt,
+		DeletedAt: deletedAt,
 	}
 }
 func (c *CachedArticleRepository) toDomain1(art dao.PublishedArticle) domain.Article {
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository/dao"
+
+	"github.com/ecodeclub/ekit/slice"
+)
+
+var (
+	ErrReviewNotFound = dao.ErrRecordNotFound
+	ErrReviewResolved = dao.ErrReviewResolved
+)
+
+type ArticleReviewRepository interface {
+	Submit(ctx context.Context, r domain.ArticleReview) (int64, error)
+	GetById(ctx context.Context, id int64) (domain.ArticleReview, error)
+	ListPending(ctx context.Context, offset int, limit int) ([]domain.ArticleReview, error)
+	Resolve(ctx context.Context, r domain.ArticleReview) error
+}
+
+type articleReviewRepository struct {
+	dao dao.ArticleReviewDAO
+}
+
+func NewArticleReviewRepository(dao dao.ArticleReviewDAO) ArticleReviewRepository {
+	return &articleReviewRepository{
+		dao: dao,
+	}
+}
+
+func (a *articleReviewRepository) Submit(ctx context.Context, r domain.ArticleReview) (int64, error) {
+	return a.dao.Submit(ctx, dao.ArticleReview{
+		ArticleId:  r.ArticleId,
+		AuthorId:   r.Author.Id,
+		Title:      r.Title,
+		Content:    r.Content,
+		Violations: strings.Join(r.Violations, "\n"),
+	})
+}
+
+func (a *articleReviewRepository) GetById(ctx context.Context, id int64) (domain.ArticleReview, error) {
+	r, err := a.dao.GetById(ctx, id)
+	if err != nil {
+		return domain.ArticleReview{}, err
+	}
+	return a.toDomain(r), nil
+}
+
+func (a *articleReviewRepository) ListPending(ctx context.Context, offset int, limit int) ([]domain.ArticleReview, error) {
+	rs, err := a.dao.ListPending(ctx, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.ArticleReview, domain.ArticleReview](rs,
+		func(idx int, src dao.ArticleReview) domain.ArticleReview {
+			return a.toDomain(src)
+		}), nil
+}
+
+func (a *articleReviewRepository) Resolve(ctx context.Context, r domain.ArticleReview) error {
+	return a.dao.Resolve(ctx, r.Id, r.Status.ToUint8(), r.ReviewerId, r.Reason)
+}
+
+func (a *articleReviewRepository) toDomain(r dao.ArticleReview) domain.ArticleReview {
+	var violations []string
+	if r.Violations != "" {
+		violations = strings.Split(r.Violations, "\n")
+	}
+	return domain.ArticleReview{
+		Id:         r.Id,
+		ArticleId:  r.ArticleId,
+		Author:     domain.Author{Id: r.AuthorId},
+		Title:      r.Title,
+		Content:    r.Content,
+		Violations: violations,
+		Status:     domain.ReviewStatus(r.Status),
+		ReviewerId: r.ReviewerId,
+		Reason:     r.Reason,
+		Ctime:      time.UnixMilli(r.Ctime),
+		Utime:      time.UnixMilli(r.Utime),
+	}
+}
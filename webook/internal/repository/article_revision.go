@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository/dao"
+
+	"github.com/ecodeclub/ekit/slice"
+)
+
+var ErrArticleRevisionNotFound = dao.ErrRecordNotFound
+
+type ArticleRevisionRepository interface {
+	// Add 给文章的当前内容拍一个快照
+	Add(ctx context.Context, art domain.Article) (int64, error)
+	GetByArticle(ctx context.Context, aid int64, offset int, limit int) ([]domain.ArticleRevision, error)
+	GetById(ctx context.Context, id int64) (domain.ArticleRevision, error)
+	DeleteByArticle(ctx context.Context, aid int64) error
+}
+
+type articleRevisionRepository struct {
+	dao dao.ArticleRevisionDAO
+}
+
+func NewArticleRevisionRepository(dao dao.ArticleRevisionDAO) ArticleRevisionRepository {
+	return &articleRevisionRepository{
+		dao: dao,
+	}
+}
+
+func (a *articleRevisionRepository) Add(ctx context.Context, art domain.Article) (int64, error) {
+	return a.dao.Insert(ctx, dao.ArticleRevision{
+		ArticleId: art.Id,
+		AuthorId:  art.Author.Id,
+		Title:     art.Title,
+		Content:   art.Content,
+		Status:    art.Status.ToUint8(),
+	})
+}
+
+func (a *articleRevisionRepository) GetByArticle(ctx context.Context, aid int64, offset int, limit int) ([]domain.ArticleRevision, error) {
+	revs, err := a.dao.GetByArticle(ctx, aid, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.ArticleRevision, domain.ArticleRevision](revs,
+		func(idx int, src dao.ArticleRevision) domain.ArticleRevision {
+			return a.toDomain(src)
+		}), nil
+}
+
+func (a *articleRevisionRepository) GetById(ctx context.Context, id int64) (domain.ArticleRevision, error) {
+	rev, err := a.dao.GetById(ctx, id)
+	if err != nil {
+		return domain.ArticleRevision{}, err
+	}
+	return a.toDomain(rev), nil
+}
+
+func (a *articleRevisionRepository) DeleteByArticle(ctx context.Context, aid int64) error {
+	return a.dao.DeleteByArticle(ctx, aid)
+}
+
+func (a *articleRevisionRepository) toDomain(rev dao.ArticleRevision) domain.ArticleRevision {
+	return domain.ArticleRevision{
+		Id:        rev.Id,
+		ArticleId: rev.ArticleId,
+		Version:   rev.Version,
+		Title:     rev.Title,
+		Content:   rev.Content,
+		Author: domain.Author{
+			Id: rev.AuthorId,
+		},
+		Status: domain.ArticleStatus(rev.Status),
+		Ctime:  time.UnixMilli(rev.Ctime),
+	}
+}
@@ -17,8 +17,18 @@ type ArticleCache interface {
 	DelFirstPage(ctx context.Context, uid int64) error
 	Get(ctx context.Context, id int64) (domain.Article, error)
 	Set(ctx context.Context, art domain.Article) error
+	// Del 删除制作库文章的缓存，文章改了之后要调用，不然审核之类的地方会读到旧的状态
+	Del(ctx context.Context, id int64) error
 	GetPub(ctx context.Context, id int64) (domain.Article, error)
 	SetPub(ctx context.Context, res domain.Article) error
+	DelPub(ctx context.Context, id int64) error
+
+	GetTagFirstPage(ctx context.Context, tag string) ([]domain.Article, error)
+	SetTagFirstPage(ctx context.Context, tag string, arts []domain.Article) error
+	GetTagCloud(ctx context.Context) ([]domain.TagCount, error)
+	SetTagCloud(ctx context.Context, tcs []domain.TagCount) error
+	// DelTags 删除这些标签的第一页缓存，以及标签云的缓存
+	DelTags(ctx context.Context, tags ...string) error
 }
 
 type ArticleRedisCache struct {
@@ -47,6 +57,10 @@ func (a *ArticleRedisCache) Set(ctx context.Context, art domain.Article) error {
 	return a.client.Set(ctx, a.key(art.Id), val, time.Minute*10).Err()
 }
 
+func (a *ArticleRedisCache) Del(ctx context.Context, id int64) error {
+	return a.client.Del(ctx, a.key(id)).Err()
+}
+
 func (a *ArticleRedisCache) GetPub(ctx context.Context, id int64) (domain.Article, error) {
 	val, err := a.client.Get(ctx, a.pubKey(id)).Bytes()
 	if err != nil {
@@ -65,6 +79,10 @@ func (a *ArticleRedisCache) SetPub(ctx context.Context, art domain.Article) erro
 	return a.client.Set(ctx, a.pubKey(art.Id), val, time.Minute*10).Err()
 }
 
+func (a *ArticleRedisCache) DelPub(ctx context.Context, id int64) error {
+	return a.client.Del(ctx, a.pubKey(id)).Err()
+}
+
 func NewArticleRedisCache(client redis.Cmdable) ArticleCache {
 	return &ArticleRedisCache{
 		client: client,
@@ -96,6 +114,65 @@ func (a *ArticleRedisCache) SetFirstPage(ctx context.Context, uid int64, arts []
 	return a.client.Set(ctx, key, val, time.Minute*10).Err()
 }
 
+func (a *ArticleRedisCache) GetTagFirstPage(ctx context.Context, tag string) ([]domain.Article, error) {
+	val, err := a.client.Get(ctx, a.tagFirstKey(tag)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var res []domain.Article
+	err = json.Unmarshal(val, &res)
+	return res, err
+}
+
+func (a *ArticleRedisCache) SetTagFirstPage(ctx context.Context, tag string, arts []domain.Article) error {
+	// 列表页只需要摘要，复制一份，不要修改调用者的数据
+	abstracts := make([]domain.Article, len(arts))
+	for i := 0; i < len(arts); i++ {
+		abstracts[i] = arts[i]
+		abstracts[i].Content = arts[i].Abstract()
+	}
+	val, err := json.Marshal(abstracts)
+	if err != nil {
+		return err
+	}
+	return a.client.Set(ctx, a.tagFirstKey(tag), val, time.Minute*10).Err()
+}
+
+func (a *ArticleRedisCache) GetTagCloud(ctx context.Context) ([]domain.TagCount, error) {
+	val, err := a.client.Get(ctx, a.tagCloudKey()).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var res []domain.TagCount
+	err = json.Unmarshal(val, &res)
+	return res, err
+}
+
+func (a *ArticleRedisCache) SetTagCloud(ctx context.Context, tcs []domain.TagCount) error {
+	val, err := json.Marshal(tcs)
+	if err != nil {
+		return err
+	}
+	return a.client.Set(ctx, a.tagCloudKey(), val, time.Minute*10).Err()
+}
+
+func (a *ArticleRedisCache) DelTags(ctx context.Context, tags ...string) error {
+	keys := make([]string, 0, len(tags)+1)
+	keys = append(keys, a.tagCloudKey())
+	for _, tag := range tags {
+		keys = append(keys, a.tagFirstKey(tag))
+	}
+	return a.client.Del(ctx, keys...).Err()
+}
+
+func (a *ArticleRedisCache) tagFirstKey(tag string) string {
+	return fmt.Sprintf("article:tag:first_page:%s", tag)
+}
+
+func (a *ArticleRedisCache) tagCloudKey() string {
+	return "article:tag:cloud"
+}
+
 func (a *ArticleRedisCache) pubKey(id int64) string {
 	return fmt.Sprintf("article:pub:detail:%d", id)
 }
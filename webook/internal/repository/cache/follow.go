@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"basic-go/webook/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	fieldFollowerCnt = "follower_cnt"
+	fieldFolloweeCnt = "followee_cnt"
+)
+
+type FollowCache interface {
+	// Follow follower 的关注数和 followee 的粉丝数加一，缓存不存在就什么都不做
+	Follow(ctx context.Context, follower int64, followee int64) error
+	CancelFollow(ctx context.Context, follower int64, followee int64) error
+	GetStatistics(ctx context.Context, uid int64) (domain.FollowStatistics, error)
+	SetStatistics(ctx context.Context, stat domain.FollowStatistics) error
+}
+
+type FollowRedisCache struct {
+	client redis.Cmdable
+}
+
+func NewFollowRedisCache(client redis.Cmdable) FollowCache {
+	return &FollowRedisCache{
+		client: client,
+	}
+}
+
+func (f *FollowRedisCache) Follow(ctx context.Context, follower int64, followee int64) error {
+	return f.incr(ctx, follower, followee, 1)
+}
+
+func (f *FollowRedisCache) CancelFollow(ctx context.Context, follower int64, followee int64) error {
+	return f.incr(ctx, follower, followee, -1)
+}
+
+func (f *FollowRedisCache) incr(ctx context.Context, follower int64, followee int64, delta int) error {
+	err := f.client.Eval(ctx, luaIncrCnt, []string{f.key(follower)}, fieldFolloweeCnt, delta).Err()
+	if err != nil {
+		return err
+	}
+	return f.client.Eval(ctx, luaIncrCnt, []string{f.key(followee)}, fieldFollowerCnt, delta).Err()
+}
+
+func (f *FollowRedisCache) GetStatistics(ctx context.Context, uid int64) (domain.FollowStatistics, error) {
+	res, err := f.client.HGetAll(ctx, f.key(uid)).Result()
+	if err != nil {
+		return domain.FollowStatistics{}, err
+	}
+	if len(res) == 0 {
+		return domain.FollowStatistics{}, ErrKeyNotExist
+	}
+	stat := domain.FollowStatistics{Uid: uid}
+	// 这边是可以忽略错误的
+	stat.Followers, _ = strconv.ParseInt(res[fieldFollowerCnt], 10, 64)
+	stat.Followees, _ = strconv.ParseInt(res[fieldFolloweeCnt], 10, 64)
+	return stat, nil
+}
+
+func (f *FollowRedisCache) SetStatistics(ctx context.Context, stat domain.FollowStatistics) error {
+	key := f.key(stat.Uid)
+	err := f.client.HSet(ctx, key, fieldFollowerCnt, stat.Followers,
+		fieldFolloweeCnt, stat.Followees).Err()
+	if err != nil {
+		return err
+	}
+	return f.client.Expire(ctx, key, time.Minute*15).Err()
+}
+
+func (f *FollowRedisCache) key(uid int64) string {
+	return fmt.Sprintf("follow:statistics:%d", uid)
+}
@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"basic-go/webook/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type HistoryRecordCache interface {
+	// GetFirstPage 最近阅读的若干条记录，缓存不存在返回 redis.Nil
+	GetFirstPage(ctx context.Context, uid int64) ([]domain.HistoryRecord, error)
+	SetFirstPage(ctx context.Context, uid int64, records []domain.HistoryRecord) error
+	DelFirstPage(ctx context.Context, uid int64) error
+}
+
+type HistoryRecordRedisCache struct {
+	client redis.Cmdable
+}
+
+func NewHistoryRecordRedisCache(client redis.Cmdable) HistoryRecordCache {
+	return &HistoryRecordRedisCache{
+		client: client,
+	}
+}
+
+func (h *HistoryRecordRedisCache) GetFirstPage(ctx context.Context, uid int64) ([]domain.HistoryRecord, error) {
+	val, err := h.client.Get(ctx, h.firstKey(uid)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var res []domain.HistoryRecord
+	err = json.Unmarshal(val, &res)
+	return res, err
+}
+
+func (h *HistoryRecordRedisCache) SetFirstPage(ctx context.Context, uid int64, records []domain.HistoryRecord) error {
+	val, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	return h.client.Set(ctx, h.firstKey(uid), val, time.Minute*10).Err()
+}
+
+func (h *HistoryRecordRedisCache) DelFirstPage(ctx context.Context, uid int64) error {
+	return h.client.Del(ctx, h.firstKey(uid)).Err()
+}
+
+func (h *HistoryRecordRedisCache) firstKey(uid int64) string {
+	return fmt.Sprintf("history:first_page:%d", uid)
+}
@@ -5,6 +5,7 @@ import (
 	_ "embed"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"basic-go/webook/internal/domain"
@@ -17,6 +18,21 @@ var (
 	luaIncrCnt string
 )
 
+// 互动相关的 key 都在 Redis 的同一个库里面。Scan 用 keyPrefixInteractive+"*" 遍历计数的 hash 对账，
+// 所以 keyPrefixInteractive 下面只能放计数，别的 key 都不能以 "interactive:" 开头，
+// 不然会被当成计数检查，和数据库对不上还会被删掉
+const (
+	keyPrefixInteractive = "interactive:"
+	// keyPrefixDelta 写回模式下的增量日志
+	keyPrefixDelta = "interactive_delta:"
+	// keyPrefixState 用户点赞、收藏状态的集合
+	keyPrefixState = "interactive_state:"
+	// keyReconcileCursor 对账停下来的位置
+	keyReconcileCursor = "interactive_reconcile:cursor"
+	// keyPrefixRead 阅读去重以及每天的 PV、UV
+	keyPrefixRead = "read:"
+)
+
 const fieldReadCnt = "read_cnt"
 const fieldLikeCnt = "like_cnt"
 const fieldCollectCnt = "collect_cnt"
@@ -26,8 +42,20 @@ type InteractiveCache interface {
 	IncrLikeCntIfPresent(ctx context.Context, biz string, id int64) error
 	DecrLikeCntIfPresent(ctx context.Context, biz string, id int64) error
 	IncrCollectCntIfPresent(ctx context.Context, biz string, id int64) error
+	DecrCollectCntIfPresent(ctx context.Context, biz string, id int64) error
 	Get(ctx context.Context, biz string, id int64) (domain.Interactive, error)
 	Set(ctx context.Context, biz string, bizId int64, res domain.Interactive) error
+	// GetByIds 用 pipeline 一次查询多个，只返回缓存里面有的
+	GetByIds(ctx context.Context, biz string, ids []int64) (map[int64]domain.Interactive, error)
+	// SetAll 用 pipeline 一次写入多个
+	SetAll(ctx context.Context, intrs []domain.Interactive) error
+	Del(ctx context.Context, biz string, bizId int64) error
+	// Scan 遍历缓存里面的计数，cursor 是 Redis SCAN 的游标，从 0 开始，返回 0 代表遍历完了。
+	// count 只是给 Redis 的提示，每次返回的数量不一定正好是 count
+	Scan(ctx context.Context, cursor uint64, count int64) ([]domain.Interactive, uint64, error)
+	// GetReconcileCursor 上一次对账停下来的 Scan 游标，没有的话返回 0
+	GetReconcileCursor(ctx context.Context) (uint64, error)
+	SetReconcileCursor(ctx context.Context, cursor uint64) error
 }
 
 type InteractiveRedisCache struct {
@@ -63,12 +91,54 @@ func (i *InteractiveRedisCache) Get(ctx context.Context, biz string, id int64) (
 	if len(res) == 0 {
 		return domain.Interactive{}, ErrKeyNotExist
 	}
-	var intr domain.Interactive
+	return i.toDomain(biz, id, res), nil
+}
+
+func (i *InteractiveRedisCache) GetByIds(ctx context.Context, biz string,
+	ids []int64) (map[int64]domain.Interactive, error) {
+	pipe := i.client.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, 0, len(ids))
+	for _, id := range ids {
+		cmds = append(cmds, pipe.HGetAll(ctx, i.key(biz, id)))
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[int64]domain.Interactive, len(ids))
+	for idx, cmd := range cmds {
+		val := cmd.Val()
+		if len(val) == 0 {
+			continue
+		}
+		res[ids[idx]] = i.toDomain(biz, ids[idx], val)
+	}
+	return res, nil
+}
+
+func (i *InteractiveRedisCache) SetAll(ctx context.Context, intrs []domain.Interactive) error {
+	pipe := i.client.Pipeline()
+	for _, intr := range intrs {
+		key := i.key(intr.Biz, intr.BizId)
+		pipe.HSet(ctx, key, fieldCollectCnt, intr.CollectCnt,
+			fieldReadCnt, intr.ReadCnt,
+			fieldLikeCnt, intr.LikeCnt)
+		pipe.Expire(ctx, key, time.Minute*15)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (i *InteractiveRedisCache) toDomain(biz string, id int64, res map[string]string) domain.Interactive {
+	intr := domain.Interactive{
+		Biz:   biz,
+		BizId: id,
+	}
 	// 这边是可以忽略错误的
 	intr.CollectCnt, _ = strconv.ParseInt(res[fieldCollectCnt], 10, 64)
 	intr.LikeCnt, _ = strconv.ParseInt(res[fieldLikeCnt], 10, 64)
 	intr.ReadCnt, _ = strconv.ParseInt(res[fieldReadCnt], 10, 64)
-	return intr, nil
+	return intr
 }
 
 func (i *InteractiveRedisCache) IncrCollectCntIfPresent(ctx context.Context,
@@ -77,6 +147,12 @@ func (i *InteractiveRedisCache) IncrCollectCntIfPresent(ctx context.Context,
 	return i.client.Eval(ctx, luaIncrCnt, []string{key}, fieldCollectCnt, 1).Err()
 }
 
+func (i *InteractiveRedisCache) DecrCollectCntIfPresent(ctx context.Context,
+	biz string, id int64) error {
+	key := i.key(biz, id)
+	return i.client.Eval(ctx, luaIncrCnt, []string{key}, fieldCollectCnt, -1).Err()
+}
+
 func (i *InteractiveRedisCache) IncrLikeCntIfPresent(ctx context.Context,
 	biz string, bizId int64) error {
 	key := i.key(biz, bizId)
@@ -97,6 +173,68 @@ func (i *InteractiveRedisCache) IncrReadCntIfPresent(ctx context.Context,
 	return i.client.Eval(ctx, luaIncrCnt, []string{key}, fieldReadCnt, 1).Err()
 }
 
+func (i *InteractiveRedisCache) Del(ctx context.Context, biz string, bizId int64) error {
+	return i.client.Del(ctx, i.key(biz, bizId)).Err()
+}
+
+func (i *InteractiveRedisCache) GetReconcileCursor(ctx context.Context) (uint64, error) {
+	cursor, err := i.client.Get(ctx, keyReconcileCursor).Uint64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return cursor, err
+}
+
+func (i *InteractiveRedisCache) SetReconcileCursor(ctx context.Context, cursor uint64) error {
+	return i.client.Set(ctx, keyReconcileCursor, cursor, 0).Err()
+}
+
+func (i *InteractiveRedisCache) Scan(ctx context.Context, cursor uint64,
+	count int64) ([]domain.Interactive, uint64, error) {
+	keys, next, err := i.client.Scan(ctx, cursor, keyPrefixInteractive+"*", count).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	type bizKey struct {
+		biz   string
+		bizId int64
+	}
+	bks := make([]bizKey, 0, len(keys))
+	for _, key := range keys {
+		// biz 里面也可能有冒号，所以从后面找
+		rest := strings.TrimPrefix(key, keyPrefixInteractive)
+		idx := strings.LastIndex(rest, ":")
+		if idx < 0 {
+			continue
+		}
+		bizId, er := strconv.ParseInt(rest[idx+1:], 10, 64)
+		if er != nil {
+			continue
+		}
+		bks = append(bks, bizKey{biz: rest[:idx], bizId: bizId})
+	}
+	pipe := i.client.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, 0, len(bks))
+	for _, bk := range bks {
+		cmds = append(cmds, pipe.HGetAll(ctx, i.key(bk.biz, bk.bizId)))
+	}
+	if len(cmds) > 0 {
+		_, err = pipe.Exec(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	res := make([]domain.Interactive, 0, len(bks))
+	for idx, cmd := range cmds {
+		// SCAN 和 HGETALL 之间过期了
+		if len(cmd.Val()) == 0 {
+			continue
+		}
+		res = append(res, i.toDomain(bks[idx].biz, bks[idx].bizId, cmd.Val()))
+	}
+	return res, next, nil
+}
+
 func (i *InteractiveRedisCache) key(biz string, bizId int64) string {
-	return fmt.Sprintf("interactive:%s:%d", biz, bizId)
+	return fmt.Sprintf("%s%s:%d", keyPrefixInteractive, biz, bizId)
 }
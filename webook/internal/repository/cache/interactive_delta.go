@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"basic-go/webook/internal/domain"
+
+	"github.com/bwmarrin/snowflake"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	//go:embed lua/drain_delta.lua
+	luaDrainDelta string
+	//go:embed lua/ack_delta.lua
+	luaAckDelta string
+)
+
+// fieldBatch 正在写入的那一批增量的批次，和增量放在同一个 hash 里面
+const fieldBatch = "_batch"
+
+// InteractiveDeltaCache 写回模式下计数的增量日志。
+// 增量先记在 Redis 里面，再由后台任务合并写入数据库，
+// Redis 开了 AOF 的话进程崩溃、重启都不会丢增量。
+// 同一个 biz 的几个 key 用 {biz} 做 hash tag，Redis 集群下也能在 lua 脚本里面一起操作
+type InteractiveDeltaCache interface {
+	// Add 记录一批增量，Biz 和 BizId 指定资源，计数字段是增量。
+	// 缓存里面有这个资源的计数的话一起更新，保证读到的计数是最新的
+	Add(ctx context.Context, deltas []domain.Interactive) error
+	// Pending 还没有写入数据库的增量，包括正在写入的那一批，没有增量的 id 不会出现在结果里面
+	Pending(ctx context.Context, biz string, ids []int64) (map[int64]domain.Interactive, error)
+	// Drain 取出一批增量准备写入数据库，batch 是这一批的标识。
+	// 上一批还没有确认的话返回的还是上一批，没有增量的时候 deltas 为空
+	Drain(ctx context.Context, biz string) (batch string, deltas []domain.Interactive, err error)
+	// Ack 确认 batch 已经写入数据库
+	Ack(ctx context.Context, biz string, batch string) error
+}
+
+type InteractiveDeltaRedisCache struct {
+	client redis.Cmdable
+	// node 生成批次。数据库用批次判断这一批是不是已经写过了，
+	// 所以批次不能依赖 Redis 里面的序号，Redis 清空或者主从切换之后序号会重复
+	node *snowflake.Node
+}
+
+func NewInteractiveDeltaRedisCache(client redis.Cmdable, node *snowflake.Node) InteractiveDeltaCache {
+	return &InteractiveDeltaRedisCache{
+		client: client,
+		node:   node,
+	}
+}
+
+func (i *InteractiveDeltaRedisCache) Add(ctx context.Context, deltas []domain.Interactive) error {
+	pipe := i.client.Pipeline()
+	for _, d := range deltas {
+		journal := i.journalKey(d.Biz)
+		key := fmt.Sprintf("%s%s:%d", keyPrefixInteractive, d.Biz, d.BizId)
+		for _, f := range []struct {
+			name  string
+			delta int64
+		}{{fieldReadCnt, d.ReadCnt}, {fieldLikeCnt, d.LikeCnt}} {
+			if f.delta == 0 {
+				continue
+			}
+			pipe.HIncrBy(ctx, journal, i.field(d.BizId, f.name), f.delta)
+			pipe.Eval(ctx, luaIncrCnt, []string{key}, f.name, f.delta)
+		}
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (i *InteractiveDeltaRedisCache) Pending(ctx context.Context, biz string,
+	ids []int64) (map[int64]domain.Interactive, error) {
+	fields := make([]string, 0, len(ids)*2)
+	for _, id := range ids {
+		fields = append(fields, i.field(id, fieldReadCnt), i.field(id, fieldLikeCnt))
+	}
+	pipe := i.client.Pipeline()
+	cmds := []*redis.SliceCmd{
+		pipe.HMGet(ctx, i.journalKey(biz), fields...),
+		pipe.HMGet(ctx, i.flushingKey(biz), fields...),
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[int64]domain.Interactive)
+	for _, cmd := range cmds {
+		for idx, val := range cmd.Val() {
+			str, ok := val.(string)
+			if !ok {
+				continue
+			}
+			delta, er := strconv.ParseInt(str, 10, 64)
+			if er != nil || delta == 0 {
+				continue
+			}
+			id := ids[idx/2]
+			intr := res[id]
+			intr.Biz, intr.BizId = biz, id
+			if idx%2 == 0 {
+				intr.ReadCnt += delta
+			} else {
+				intr.LikeCnt += delta
+			}
+			res[id] = intr
+		}
+	}
+	return res, nil
+}
+
+func (i *InteractiveDeltaRedisCache) Drain(ctx context.Context,
+	biz string) (string, []domain.Interactive, error) {
+	// 上一批还没有确认的话，这个批次用不上
+	vals, err := i.client.Eval(ctx, luaDrainDelta,
+		[]string{i.journalKey(biz), i.flushingKey(biz)}, i.node.Generate().String()).StringSlice()
+	if err != nil {
+		return "", nil, err
+	}
+	var batch string
+	merged := make(map[int64]*domain.Interactive)
+	ids := make([]int64, 0, len(vals)/4)
+	// HGETALL 返回的是 field, value 交替的列表
+	for idx := 0; idx+1 < len(vals); idx += 2 {
+		field, val := vals[idx], vals[idx+1]
+		if field == fieldBatch {
+			batch = val
+			continue
+		}
+		id, name, ok := strings.Cut(field, ":")
+		if !ok {
+			continue
+		}
+		bizId, er := strconv.ParseInt(id, 10, 64)
+		if er != nil {
+			continue
+		}
+		delta, er := strconv.ParseInt(val, 10, 64)
+		if er != nil {
+			continue
+		}
+		intr, ok := merged[bizId]
+		if !ok {
+			intr = &domain.Interactive{Biz: biz, BizId: bizId}
+			merged[bizId] = intr
+			ids = append(ids, bizId)
+		}
+		switch name {
+		case fieldReadCnt:
+			intr.ReadCnt += delta
+		case fieldLikeCnt:
+			intr.LikeCnt += delta
+		}
+	}
+	res := make([]domain.Interactive, 0, len(ids))
+	for _, id := range ids {
+		res = append(res, *merged[id])
+	}
+	return batch, res, nil
+}
+
+func (i *InteractiveDeltaRedisCache) Ack(ctx context.Context, biz string, batch string) error {
+	return i.client.Eval(ctx, luaAckDelta, []string{i.flushingKey(biz)}, batch).Err()
+}
+
+// field 增量日志里面的字段，比如 12:like_cnt
+func (i *InteractiveDeltaRedisCache) field(bizId int64, name string) string {
+	return fmt.Sprintf("%d:%s", bizId, name)
+}
+
+func (i *InteractiveDeltaRedisCache) journalKey(biz string) string {
+	return fmt.Sprintf("%s{%s}", keyPrefixDelta, biz)
+}
+
+func (i *InteractiveDeltaRedisCache) flushingKey(biz string) string {
+	return i.journalKey(biz) + ":flushing"
+}
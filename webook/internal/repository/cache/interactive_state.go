@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	//go:embed lua/update_state.lua
+	luaUpdateState string
+	//go:embed lua/init_state.lua
+	luaInitState string
+)
+
+const (
+	// StateLiked 和 StateCollected 是 InteractiveStateCache 里面的两种状态
+	StateLiked     = "liked"
+	StateCollected = "collected"
+	// statePlaceholder 集合里面的占位，这样什么都没点过赞的用户也能缓存
+	statePlaceholder = 0
+)
+
+// InteractiveStateCache 用户点过赞、收藏过哪些资源，每个用户每个 biz 每种状态一个 set。
+// 集合要么不在缓存里面，要么是完整的，这样不在集合里面就能确定没有点过赞，不需要再查数据库
+type InteractiveStateCache interface {
+	// Contains 返回 ids 里面在集合里面的，集合不在缓存里面的时候返回 ErrKeyNotExist
+	Contains(ctx context.Context, state string, biz string, uid int64, ids []int64) ([]int64, error)
+	// BeginInit 在查数据库之前调用，返回的 token 交给 Init。
+	// 查数据库到 Init 之间有别的修改的话，Init 什么都不做，免得用旧的数据建出一个不完整的集合
+	BeginInit(ctx context.Context, state string, biz string, uid int64) (token string, err error)
+	// Init 用数据库里面的全部记录初始化集合，集合已经在缓存里面了也什么都不做
+	Init(ctx context.Context, state string, biz string, uid int64, token string, ids []int64) error
+	// AddIfPresent 和 RemoveIfPresent 只在集合已经在缓存里面的时候才修改
+	AddIfPresent(ctx context.Context, state string, biz string, uid int64, id int64) error
+	RemoveIfPresent(ctx context.Context, state string, biz string, uid int64, id int64) error
+}
+
+type InteractiveStateRedisCache struct {
+	client     redis.Cmdable
+	expiration time.Duration
+	// loadingExpiration 查数据库最多用这么久，过期了 Init 就什么都不做
+	loadingExpiration time.Duration
+}
+
+func NewInteractiveStateRedisCache(client redis.Cmdable) InteractiveStateCache {
+	return &InteractiveStateRedisCache{
+		client:            client,
+		expiration:        time.Minute * 15,
+		loadingExpiration: time.Second * 10,
+	}
+}
+
+func (i *InteractiveStateRedisCache) Contains(ctx context.Context, state string,
+	biz string, uid int64, ids []int64) ([]int64, error) {
+	key := i.key(state, biz, uid)
+	members := make([]any, 0, len(ids))
+	for _, id := range ids {
+		members = append(members, id)
+	}
+	pipe := i.client.Pipeline()
+	exists := pipe.Exists(ctx, key)
+	cmd := pipe.SMIsMember(ctx, key, members...)
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if exists.Val() == 0 {
+		return nil, ErrKeyNotExist
+	}
+	res := make([]int64, 0, len(ids))
+	for idx, ok := range cmd.Val() {
+		if ok {
+			res = append(res, ids[idx])
+		}
+	}
+	return res, nil
+}
+
+// BeginInit 同时有几个在加载的话，只有最后一个的 Init 会生效
+func (i *InteractiveStateRedisCache) BeginInit(ctx context.Context, state string,
+	biz string, uid int64) (string, error) {
+	token := uuid.New().String()
+	err := i.client.Set(ctx, i.loadingKey(state, biz, uid), token, i.loadingExpiration).Err()
+	return token, err
+}
+
+func (i *InteractiveStateRedisCache) Init(ctx context.Context, state string,
+	biz string, uid int64, token string, ids []int64) error {
+	args := make([]any, 0, len(ids)+3)
+	args = append(args, token, int64(i.expiration/time.Second), statePlaceholder)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return i.client.Eval(ctx, luaInitState,
+		[]string{i.key(state, biz, uid), i.loadingKey(state, biz, uid)}, args...).Err()
+}
+
+func (i *InteractiveStateRedisCache) AddIfPresent(ctx context.Context, state string,
+	biz string, uid int64, id int64) error {
+	return i.client.Eval(ctx, luaUpdateState,
+		[]string{i.key(state, biz, uid), i.loadingKey(state, biz, uid)}, 1, id).Err()
+}
+
+func (i *InteractiveStateRedisCache) RemoveIfPresent(ctx context.Context, state string,
+	biz string, uid int64, id int64) error {
+	return i.client.Eval(ctx, luaUpdateState,
+		[]string{i.key(state, biz, uid), i.loadingKey(state, biz, uid)}, 0, id).Err()
+}
+
+// key 和 loadingKey 用 hash tag 放在同一个 slot，Redis 集群下也能在 lua 脚本里面一起操作
+func (i *InteractiveStateRedisCache) key(state string, biz string, uid int64) string {
+	return fmt.Sprintf("%s{%s:%s:%d}", keyPrefixState, state, biz, uid)
+}
+
+func (i *InteractiveStateRedisCache) loadingKey(state string, biz string, uid int64) string {
+	return i.key(state, biz, uid) + ":loading"
+}
@@ -0,0 +1,15 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 别的 key 以 keyPrefixInteractive 开头的话，对账的时候会被当成计数删掉
+func TestKeyPrefixes(t *testing.T) {
+	for _, key := range []string{keyPrefixDelta, keyPrefixState, keyReconcileCursor, keyPrefixRead} {
+		assert.False(t, strings.HasPrefix(key, keyPrefixInteractive), key)
+	}
+}
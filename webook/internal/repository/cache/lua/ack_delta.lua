@@ -0,0 +1,7 @@
+-- 正在写入数据库的那一批
+local flushing = KEYS[1]
+-- 批次不对说明别的实例已经确认过了，现在这一批是新取出来的，不能删
+if redis.call("HGET", flushing, "_batch") == ARGV[1] then
+    return redis.call("DEL", flushing)
+end
+return 0
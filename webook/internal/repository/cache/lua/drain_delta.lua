@@ -0,0 +1,17 @@
+-- 增量日志
+local journal = KEYS[1]
+-- 正在写入数据库的那一批
+local flushing = KEYS[2]
+
+-- 上一批还没有确认，说明写数据库的时候失败或者崩溃了，继续写上一批
+local batch = redis.call("HGET", flushing, "_batch")
+if not batch then
+    if redis.call("EXISTS", journal) == 0 then
+        return {}
+    end
+    redis.call("RENAME", journal, flushing)
+    -- 新的一批用调用方生成的全局唯一的批次
+    batch = ARGV[1]
+    redis.call("HSET", flushing, "_batch", batch)
+end
+return redis.call("HGETALL", flushing)
@@ -0,0 +1,19 @@
+-- 用户点过赞（收藏过）的集合
+local key = KEYS[1]
+-- 加载标记，值是开始加载的时候生成的 token
+local loading = KEYS[2]
+local token = ARGV[1]
+local expiration = tonumber(ARGV[2])
+
+-- 别的请求已经建好了集合
+if redis.call("EXISTS", key) == 1 then
+    return 0
+end
+-- 加载的时候有修改，或者后面又有别的请求开始加载了，这次查到的数据不能用
+if redis.call("GET", loading) ~= token then
+    return 0
+end
+redis.call("DEL", loading)
+redis.call("SADD", key, unpack(ARGV, 3))
+redis.call("EXPIRE", key, expiration)
+return 1
@@ -0,0 +1,20 @@
+-- 用户点过赞（收藏过）的集合
+local key = KEYS[1]
+-- 加载标记
+local loading = KEYS[2]
+-- 1 是加入，0 是移除
+local add = ARGV[1]
+local member = ARGV[2]
+
+-- 集合不在缓存里面的话什么都不做，下次查询的时候从数据库完整加载。
+-- 正在加载的话，加载用的数据可能已经旧了，删掉标记让那次加载放弃
+if redis.call("EXISTS", key) == 0 then
+    redis.call("DEL", loading)
+    return 0
+end
+if add == "1" then
+    redis.call("SADD", key, member)
+else
+    redis.call("SREM", key, member)
+end
+return 1
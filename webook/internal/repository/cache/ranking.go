@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"basic-go/webook/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrLocalCacheExpired = errors.New("本地缓存已经过期")
+
+type RankingCache interface {
+	Set(ctx context.Context, arts []domain.Article) error
+	Get(ctx context.Context) ([]domain.Article, error)
+}
+
+type RankingRedisCache struct {
+	client redis.Cmdable
+	key    string
+	// expiration 要比计算榜单的间隔长，保证新的榜单算出来之前旧的还在
+	expiration time.Duration
+}
+
+func NewRankingRedisCache(client redis.Cmdable) *RankingRedisCache {
+	return &RankingRedisCache{
+		client:     client,
+		key:        "ranking:top_n",
+		expiration: time.Minute * 10,
+	}
+}
+
+func (r *RankingRedisCache) Set(ctx context.Context, arts []domain.Article) error {
+	val, err := json.Marshal(arts)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.key, val, r.expiration).Err()
+}
+
+func (r *RankingRedisCache) Get(ctx context.Context) ([]domain.Article, error) {
+	val, err := r.client.Get(ctx, r.key).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var res []domain.Article
+	err = json.Unmarshal(val, &res)
+	return res, err
+}
+
+// RankingLocalCache 本地缓存，Redis 出问题的时候兜底
+type RankingLocalCache struct {
+	mu         sync.RWMutex
+	arts       []domain.Article
+	ddl        time.Time
+	expiration time.Duration
+}
+
+func NewRankingLocalCache() *RankingLocalCache {
+	return &RankingLocalCache{
+		expiration: time.Minute * 3,
+	}
+}
+
+func (r *RankingLocalCache) Set(ctx context.Context, arts []domain.Article) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.arts = arts
+	r.ddl = time.Now().Add(r.expiration)
+	return nil
+}
+
+func (r *RankingLocalCache) Get(ctx context.Context) ([]domain.Article, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.arts) == 0 || r.ddl.Before(time.Now()) {
+		return nil, ErrLocalCacheExpired
+	}
+	return r.arts, nil
+}
+
+// ForceGet 不管有没有过期都返回，Redis 不可用的时候用
+func (r *RankingLocalCache) ForceGet(ctx context.Context) ([]domain.Article, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.arts) == 0 {
+		return nil, ErrKeyNotExist
+	}
+	return r.arts, nil
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"basic-go/webook/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// dailyExpiration 按天统计的 key 多留一天，跨过零点之后还能查到昨天的
+const dailyExpiration = time.Hour * 48
+
+// ReadCache 阅读去重以及每天的 PV、UV
+type ReadCache interface {
+	// Record 记录一批阅读，每一次都算进当天的 PV 和 UV。
+	// 返回的是每一次阅读是不是读者在去重窗口里面第一次读，没有读者标识的都当作第一次
+	Record(ctx context.Context, reads []domain.Read) ([]bool, error)
+	// GetDaily 某一天的 PV 和 UV
+	GetDaily(ctx context.Context, biz string, bizId int64, day time.Time) (pv int64, uv int64, err error)
+}
+
+type ReadRedisCache struct {
+	client redis.Cmdable
+	// window 同一个读者在这段时间里面重复阅读只算一次
+	window time.Duration
+}
+
+func NewReadRedisCache(client redis.Cmdable, window time.Duration) ReadCache {
+	return &ReadRedisCache{
+		client: client,
+		window: window,
+	}
+}
+
+func (r *ReadRedisCache) Record(ctx context.Context, reads []domain.Read) ([]bool, error) {
+	pipe := r.client.Pipeline()
+	dedup := make([]*redis.BoolCmd, len(reads))
+	for i, read := range reads {
+		day := read.Time.Format("20060102")
+		pvKey := r.pvKey(read.Biz, read.BizId, day)
+		pipe.Incr(ctx, pvKey)
+		pipe.Expire(ctx, pvKey, dailyExpiration)
+		reader := read.Reader()
+		if reader == "" {
+			continue
+		}
+		uvKey := r.uvKey(read.Biz, read.BizId, day)
+		pipe.PFAdd(ctx, uvKey, reader)
+		pipe.Expire(ctx, uvKey, dailyExpiration)
+		dedup[i] = pipe.SetNX(ctx, r.dedupKey(read.Biz, read.BizId, reader), 1, r.window)
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]bool, len(reads))
+	for i, cmd := range dedup {
+		res[i] = cmd == nil || cmd.Val()
+	}
+	return res, nil
+}
+
+func (r *ReadRedisCache) GetDaily(ctx context.Context, biz string,
+	bizId int64, day time.Time) (int64, int64, error) {
+	d := day.Format("20060102")
+	pipe := r.client.Pipeline()
+	pv := pipe.Get(ctx, r.pvKey(biz, bizId, d))
+	uv := pipe.PFCount(ctx, r.uvKey(biz, bizId, d))
+	_, err := pipe.Exec(ctx)
+	// 今天还没有人读过的话，PV 的 key 不存在
+	if err != nil && err != redis.Nil {
+		return 0, 0, err
+	}
+	pvCnt, _ := pv.Int64()
+	return pvCnt, uv.Val(), nil
+}
+
+func (r *ReadRedisCache) dedupKey(biz string, bizId int64, reader string) string {
+	return fmt.Sprintf("%sdedup:%s:%d:%s", keyPrefixRead, biz, bizId, reader)
+}
+
+func (r *ReadRedisCache) pvKey(biz string, bizId int64, day string) string {
+	return fmt.Sprintf("%spv:%s:%d:%s", keyPrefixRead, biz, bizId, day)
+}
+
+func (r *ReadRedisCache) uvKey(biz string, bizId int64, day string) string {
+	return fmt.Sprintf("%suv:%s:%d:%s", keyPrefixRead, biz, bizId, day)
+}
@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"basic-go/webook/internal/domain"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadRedisCache_Record(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	c := NewReadRedisCache(client, time.Minute)
+	ctx := context.Background()
+	now := time.Now()
+	read := func(uid int64, ip string) domain.Read {
+		return domain.Read{Biz: "article", BizId: 1, Uid: uid, IP: ip, Time: now}
+	}
+
+	// 同一批里面同一个读者也只算一次
+	first, err := c.Record(ctx, []domain.Read{read(1, ""), read(1, ""), read(2, "")})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, false, true}, first)
+	first, err = c.Record(ctx, []domain.Read{read(1, ""), read(0, "1.1.1.1"), read(0, "")})
+	require.NoError(t, err)
+	// 没有读者标识的没法去重，都当作第一次
+	assert.Equal(t, []bool{false, true, true}, first)
+	pv, uv, err := c.GetDaily(ctx, "article", 1, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), pv)
+	assert.Equal(t, int64(3), uv)
+
+	// 别的资源单独去重、单独统计
+	first, err = c.Record(ctx, []domain.Read{{Biz: "article", BizId: 2, Uid: 1, Time: now}})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true}, first)
+
+	// 过了去重窗口再读又算一次，但是当天的 UV 不变
+	mr.FastForward(time.Minute)
+	first, err = c.Record(ctx, []domain.Read{read(1, "")})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true}, first)
+	pv, uv, err = c.GetDaily(ctx, "article", 1, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), pv)
+	assert.Equal(t, int64(3), uv)
+
+	pv, uv, err = c.GetDaily(ctx, "article", 1, now.AddDate(0, 0, -1))
+	require.NoError(t, err)
+	assert.Zero(t, pv)
+	assert.Zero(t, uv)
+}
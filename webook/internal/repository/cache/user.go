@@ -16,6 +16,11 @@ var ErrKeyNotExist = redis.Nil
 type UserCache interface {
 	Get(ctx context.Context, uid int64) (domain.User, error)
 	Set(ctx context.Context, du domain.User) error
+	// GetByIds 用 MGET 一次查询多个，只返回缓存里面有的
+	GetByIds(ctx context.Context, uids []int64) (map[int64]domain.User, error)
+	// SetAll 用 pipeline 一次写入多个
+	SetAll(ctx context.Context, dus []domain.User) error
+	Del(ctx context.Context, uid int64) error
 }
 
 type RedisUserCache struct {
@@ -51,6 +56,48 @@ func (c *RedisUserCache) Set(ctx context.Context, du domain.User) error {
 	return c.cmd.Set(ctx, key, data, c.expiration).Err()
 }
 
+func (c *RedisUserCache) GetByIds(ctx context.Context, uids []int64) (map[int64]domain.User, error) {
+	keys := make([]string, 0, len(uids))
+	for _, uid := range uids {
+		keys = append(keys, c.key(uid))
+	}
+	vals, err := c.cmd.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[int64]domain.User, len(uids))
+	for idx, val := range vals {
+		data, ok := val.(string)
+		if !ok {
+			// 缓存里面没有
+			continue
+		}
+		var u domain.User
+		if json.Unmarshal([]byte(data), &u) != nil {
+			continue
+		}
+		res[uids[idx]] = u
+	}
+	return res, nil
+}
+
+func (c *RedisUserCache) SetAll(ctx context.Context, dus []domain.User) error {
+	pipe := c.cmd.Pipeline()
+	for _, du := range dus {
+		data, err := json.Marshal(du)
+		if err != nil {
+			return err
+		}
+		pipe.Set(ctx, c.key(du.Id), data, c.expiration)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *RedisUserCache) Del(ctx context.Context, uid int64) error {
+	return c.cmd.Del(ctx, c.key(uid)).Err()
+}
+
 func (c *RedisUserCache) key(uid int64) string {
 	// user-info-
 	// user.info.
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository/cache"
+	"basic-go/webook/internal/repository/dao"
+	"basic-go/webook/pkg/logger"
+
+	"github.com/ecodeclub/ekit/slice"
+)
+
+var (
+	ErrCollectionNotFound      = dao.ErrRecordNotFound
+	ErrDuplicateCollectionName = dao.ErrDuplicateCollectionName
+)
+
+type CollectionRepository interface {
+	Create(ctx context.Context, c domain.Collection) (int64, error)
+	Update(ctx context.Context, c domain.Collection) error
+	Delete(ctx context.Context, uid int64, id int64) error
+	FindById(ctx context.Context, id int64) (domain.Collection, error)
+	// FindByUid 用户的收藏夹，包含每个收藏夹的内容数量
+	FindByUid(ctx context.Context, uid int64, offset int, limit int) ([]domain.Collection, error)
+	FindItems(ctx context.Context, uid int64, cid int64, utime time.Time, maxId int64, limit int) ([]domain.CollectionItem, error)
+	MoveItem(ctx context.Context, uid int64, biz string, bizId int64, cid int64) error
+}
+
+type collectionRepository struct {
+	dao        dao.CollectionDAO
+	intrCache  cache.InteractiveCache
+	stateCache cache.InteractiveStateCache
+	l          logger.LoggerV1
+}
+
+func NewCollectionRepository(dao dao.CollectionDAO,
+	intrCache cache.InteractiveCache, stateCache cache.InteractiveStateCache,
+	l logger.LoggerV1) CollectionRepository {
+	return &collectionRepository{
+		dao:        dao,
+		intrCache:  intrCache,
+		stateCache: stateCache,
+		l:          l,
+	}
+}
+
+func (c *collectionRepository) Create(ctx context.Context, col domain.Collection) (int64, error) {
+	return c.dao.Insert(ctx, c.toEntity(col))
+}
+
+func (c *collectionRepository) Update(ctx context.Context, col domain.Collection) error {
+	return c.dao.Update(ctx, c.toEntity(col))
+}
+
+func (c *collectionRepository) Delete(ctx context.Context, uid int64, id int64) error {
+	items, err := c.dao.Delete(ctx, uid, id)
+	if err != nil {
+		return err
+	}
+	// 收藏数在数据库里面已经扣减了，缓存扣减失败只是短时间不准确
+	for _, item := range items {
+		er := c.intrCache.DecrCollectCntIfPresent(ctx, item.Biz, item.BizId)
+		if er != nil {
+			c.l.Error("扣减缓存里面的收藏数失败",
+				logger.String("biz", item.Biz),
+				logger.Int64("bizId", item.BizId),
+				logger.Error(er))
+		}
+		er = c.stateCache.RemoveIfPresent(ctx, cache.StateCollected, item.Biz, uid, item.BizId)
+		if er != nil {
+			c.l.Error("更新收藏状态的缓存失败",
+				logger.String("biz", item.Biz),
+				logger.Int64("bizId", item.BizId),
+				logger.Int64("uid", uid),
+				logger.Error(er))
+		}
+	}
+	return nil
+}
+
+func (c *collectionRepository) FindById(ctx context.Context, id int64) (domain.Collection, error) {
+	col, err := c.dao.FindById(ctx, id)
+	if err != nil {
+		return domain.Collection{}, err
+	}
+	return c.toDomain(col), nil
+}
+
+func (c *collectionRepository) FindByUid(ctx context.Context, uid int64, offset int, limit int) ([]domain.Collection, error) {
+	cols, err := c.dao.FindByUid(ctx, uid, offset, limit)
+	if err != nil || len(cols) == 0 {
+		return nil, err
+	}
+	cids := slice.Map[dao.Collection, int64](cols, func(idx int, src dao.Collection) int64 {
+		return src.Id
+	})
+	cnts, err := c.dao.CountItems(ctx, uid, cids)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.Collection, domain.Collection](cols, func(idx int, src dao.Collection) domain.Collection {
+		res := c.toDomain(src)
+		res.ItemCnt = cnts[src.Id]
+		return res
+	}), nil
+}
+
+func (c *collectionRepository) FindItems(ctx context.Context, uid int64, cid int64,
+	utime time.Time, maxId int64, limit int) ([]domain.CollectionItem, error) {
+	items, err := c.dao.FindItems(ctx, uid, cid, utime.UnixMilli(), maxId, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.UserCollectionBiz, domain.CollectionItem](items, func(idx int, src dao.UserCollectionBiz) domain.CollectionItem {
+		return domain.CollectionItem{
+			Id:    src.Id,
+			Cid:   src.Cid,
+			Biz:   src.Biz,
+			BizId: src.BizId,
+			Utime: time.UnixMilli(src.Utime),
+		}
+	}), nil
+}
+
+func (c *collectionRepository) MoveItem(ctx context.Context, uid int64, biz string, bizId int64, cid int64) error {
+	return c.dao.MoveItem(ctx, uid, biz, bizId, cid)
+}
+
+func (c *collectionRepository) toEntity(col domain.Collection) dao.Collection {
+	return dao.Collection{
+		Id:          col.Id,
+		Uid:         col.Uid,
+		Name:        col.Name,
+		Description: col.Description,
+	}
+}
+
+func (c *collectionRepository) toDomain(col dao.Collection) domain.Collection {
+	return domain.Collection{
+		Id:          col.Id,
+		Uid:         col.Uid,
+		Name:        col.Name,
+		Description: col.Description,
+		Ctime:       time.UnixMilli(col.Ctime),
+		Utime:       time.UnixMilli(col.Utime),
+	}
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository/dao"
+
+	"github.com/ecodeclub/ekit/slice"
+)
+
+var ErrCommentNotFound = dao.ErrRecordNotFound
+
+type CommentRepository interface {
+	Create(ctx context.Context, c domain.Comment) (int64, error)
+	FindById(ctx context.Context, id int64) (domain.Comment, error)
+	// FindNewest 根评论，最新的在前面，并且带上回复数
+	FindNewest(ctx context.Context, biz string, bizId int64, maxId int64, limit int) ([]domain.Comment, error)
+	// FindHottest 根评论，点赞多的在前面，并且带上回复数
+	FindHottest(ctx context.Context, biz string, bizId int64, likeCnt int64, maxId int64, limit int) ([]domain.Comment, error)
+	FindReplies(ctx context.Context, rootId int64, minId int64, limit int) ([]domain.Comment, error)
+	// Delete 返回被删除的所有评论 ID，包括回复它的评论
+	Delete(ctx context.Context, id int64) ([]int64, error)
+}
+
+type commentRepository struct {
+	dao      dao.CommentDAO
+	userRepo UserRepository
+}
+
+func NewCommentRepository(dao dao.CommentDAO, userRepo UserRepository) CommentRepository {
+	return &commentRepository{
+		dao:      dao,
+		userRepo: userRepo,
+	}
+}
+
+func (c *commentRepository) Create(ctx context.Context, cmt domain.Comment) (int64, error) {
+	return c.dao.Insert(ctx, dao.Comment{
+		Uid:      cmt.Commentator.Id,
+		Biz:      cmt.Biz,
+		BizId:    cmt.BizId,
+		RootId:   cmt.RootId,
+		ParentId: cmt.ParentId,
+		Content:  cmt.Content,
+	})
+}
+
+func (c *commentRepository) FindById(ctx context.Context, id int64) (domain.Comment, error) {
+	cmt, err := c.dao.FindById(ctx, id)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+	res := c.toDomain(cmt)
+	c.fillCommentators(ctx, []domain.Comment{res})
+	return res, nil
+}
+
+func (c *commentRepository) FindNewest(ctx context.Context, biz string, bizId int64,
+	maxId int64, limit int) ([]domain.Comment, error) {
+	cmts, err := c.dao.FindNewest(ctx, biz, bizId, maxId, limit)
+	if err != nil {
+		return nil, err
+	}
+	return c.toRootComments(ctx, cmts)
+}
+
+func (c *commentRepository) FindHottest(ctx context.Context, biz string, bizId int64,
+	likeCnt int64, maxId int64, limit int) ([]domain.Comment, error) {
+	cmts, err := c.dao.FindHottest(ctx, biz, bizId, likeCnt, maxId, limit)
+	if err != nil {
+		return nil, err
+	}
+	return c.toRootComments(ctx, cmts)
+}
+
+func (c *commentRepository) FindReplies(ctx context.Context, rootId int64,
+	minId int64, limit int) ([]domain.Comment, error) {
+	cmts, err := c.dao.FindReplies(ctx, rootId, minId, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := slice.Map[dao.Comment, domain.Comment](cmts, func(idx int, src dao.Comment) domain.Comment {
+		return c.toDomain(src)
+	})
+	c.fillCommentators(ctx, res)
+	return res, nil
+}
+
+func (c *commentRepository) Delete(ctx context.Context, id int64) ([]int64, error) {
+	return c.dao.Delete(ctx, id)
+}
+
+func (c *commentRepository) toRootComments(ctx context.Context, cmts []dao.Comment) ([]domain.Comment, error) {
+	ids := slice.Map[dao.Comment, int64](cmts, func(idx int, src dao.Comment) int64 {
+		return src.Id
+	})
+	cnts, err := c.dao.CountReplies(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	res := slice.Map[dao.Comment, domain.Comment](cmts, func(idx int, src dao.Comment) domain.Comment {
+		cmt := c.toDomain(src)
+		cmt.ReplyCnt = cnts[src.Id]
+		return cmt
+	})
+	c.fillCommentators(ctx, res)
+	return res, nil
+}
+
+// fillCommentators 补充评论者的昵称，查不到昵称不影响评论的展示，所以忽略错误
+func (c *commentRepository) fillCommentators(ctx context.Context, cmts []domain.Comment) {
+	names := make(map[int64]string, len(cmts))
+	for i := range cmts {
+		uid := cmts[i].Commentator.Id
+		name, ok := names[uid]
+		if !ok {
+			u, err := c.userRepo.FindById(ctx, uid)
+			if err == nil {
+				name = u.Nickname
+			}
+			names[uid] = name
+		}
+		cmts[i].Commentator.Name = name
+	}
+}
+
+func (c *commentRepository) toDomain(cmt dao.Comment) domain.Comment {
+	return domain.Comment{
+		Id:          cmt.Id,
+		Commentator: domain.Author{Id: cmt.Uid},
+		Biz:         cmt.Biz,
+		BizId:       cmt.BizId,
+		RootId:      cmt.RootId,
+		ParentId:    cmt.ParentId,
+		Content:     cmt.Content,
+		LikeCnt:     cmt.LikeCnt,
+		Ctime:       time.UnixMilli(cmt.Ctime),
+		Utime:       time.UnixMilli(cmt.Utime),
+	}
+}
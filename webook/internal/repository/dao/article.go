@@ -9,6 +9,16 @@ import (
 	"gorm.io/gorm/clause"
 )
 
+const (
+	// 和 domain.ArticleStatus 保持一致
+	articleStatusUnpublished   uint8 = 1
+	articleStatusPublished     uint8 = 2
+	articleStatusPrivate       uint8 = 3
+	articleStatusScheduled     uint8 = 4
+	articleStatusPendingReview uint8 = 5
+	articleStatusRejected      uint8 = 6
+)
+
 type ArticleDAO interface {
 	Insert(ctx context.Context, art Article) (int64, error)                               // Edit
 	UpdateById(ctx context.Context, entity Article) error                                 // Edit
@@ -17,6 +27,40 @@ type ArticleDAO interface {
 	GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]Article, error) // List
 	GetById(ctx context.Context, id int64) (Article, error)                               // Detail
 	GetPubById(ctx context.Context, id int64) (PublishedArticle, error)                   // PubDetail
+
+	// GetCategories 作者用过的所有分类
+	GetCategories(ctx context.Context, uid int64) ([]string, error)
+
+	// 定时发表
+	GetScheduledByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]Article, error)
+	// Reschedule 和 CancelSchedule 只能修改 uid 自己的定时发表的文章，不然返回 ErrScheduledArticleNotFound
+	Reschedule(ctx context.Context, uid int64, id int64, publishAt int64) error
+	CancelSchedule(ctx context.Context, uid int64, id int64) error
+	// PreemptScheduled 抢占一篇已经到了发表时间的文章，多个实例之间不会抢到同一篇
+	PreemptScheduled(ctx context.Context) (Article, error)
+
+	// ListPub 分批遍历 start 之后第一次发表的文章，新发表的在前面
+	ListPub(ctx context.Context, start int64, offset int, limit int) ([]PublishedArticle, error)
+	// GetPubByAuthor 作者已经发表的文章，按照 (utime, id) 游标分页，最近更新的在前面。
+	// maxId 为 0 代表第一页，否则返回排在 (utime, maxId) 后面的
+	GetPubByAuthor(ctx context.Context, uid int64, utime int64, maxId int64, limit int) ([]PublishedArticle, error)
+	// ListNewestPub 全站已经发表的文章，分页方式和 GetPubByAuthor 一样
+	ListNewestPub(ctx context.Context, utime int64, maxId int64, limit int) ([]PublishedArticle, error)
+
+	// 回收站。删除只是在制作库和线上库都设置 deleted_at，其余的查询都看不到它，
+	// 过了保留期之后才会被 Purge 真正删掉
+	Delete(ctx context.Context, uid int64, id int64) error
+	Restore(ctx context.Context, uid int64, id int64) error
+	// GetDeletedByAuthor 回收站里面的文章，最近删除的在前面
+	GetDeletedByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]Article, error)
+	// ListDeletedBefore 删除时间早于 before 的文章，先删除的在前面，
+	// 已经 ClaimPurge 但是还没有 Purge 的也在里面，排在最前面
+	ListDeletedBefore(ctx context.Context, before int64, limit int) ([]Article, error)
+	// ClaimPurge 把回收站里面的文章标记成正在清理，deleted_at 变成负数，之后作者就不能恢复了。
+	// 已经标记过的什么都不做，已经恢复了的返回 ErrRecordNotFound
+	ClaimPurge(ctx context.Context, id int64) error
+	// Purge 真正删除一篇 ClaimPurge 过的文章，没有标记过的返回 ErrRecordNotFound
+	Purge(ctx context.Context, id int64) error
 }
 
 type ArticleGORMDAO struct {
@@ -26,7 +70,7 @@ type ArticleGORMDAO struct {
 func (a *ArticleGORMDAO) GetPubById(ctx context.Context, id int64) (PublishedArticle, error) {
 	var res PublishedArticle
 	err := a.db.WithContext(ctx).
-		Where("id = ?", id).
+		Where("id = ? AND deleted_at = 0", id).
 		First(&res).Error
 	return res, err
 }
@@ -41,7 +85,7 @@ func (a *ArticleGORMDAO) GetById(ctx context.Context, id int64) (Article, error)
 func (a *ArticleGORMDAO) GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]Article, error) {
 	var arts []Article
 	err := a.db.WithContext(ctx).
-		Where("author_id = ?", uid).
+		Where("author_id = ? AND deleted_at = 0", uid).
 		Offset(offset).Limit(limit).
 		// a ASC, B DESC
 		Order("utime DESC").
@@ -53,7 +97,7 @@ func (a *ArticleGORMDAO) SyncStatus(ctx context.Context, uid int64, id int64, st
 	now := time.Now().UnixMilli()
 	return a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		res := tx.Model(&Article{}).
-			Where("id = ? and author_id = ?", id, uid).
+			Where("id = ? AND author_id = ? AND deleted_at = 0", id, uid).
 			Updates(map[string]any{
 				"utime":  now,
 				"status": status,
@@ -100,10 +144,11 @@ func (a *ArticleGORMDAO) Sync(ctx context.Context, art Article) (int64, error) {
 			// sqlite INSERT XXX ON CONFLICT DO UPDATES WHERE
 			Columns: []clause.Column{{Name: "id"}},
 			DoUpdates: clause.Assignments(map[string]interface{}{
-				"title":   pubArt.Title,
-				"content": pubArt.Content,
-				"utime":   now,
-				"status":  pubArt.Status,
+				"title":    pubArt.Title,
+				"content":  pubArt.Content,
+				"category": pubArt.Category,
+				"utime":    now,
+				"status":   pubArt.Status,
 			}),
 		}).Create(&pubArt).Error
 		return err
@@ -111,6 +156,52 @@ func (a *ArticleGORMDAO) Sync(ctx context.Context, art Article) (int64, error) {
 	return id, err
 }
 
+func (a *ArticleGORMDAO) ListPub(ctx context.Context, start int64, offset int, limit int) ([]PublishedArticle, error) {
+	var res []PublishedArticle
+	// 线上库的 ctime 是第一次发表的时间，重新发表不会改变它
+	err := a.db.WithContext(ctx).
+		Where("ctime > ? AND status = ? AND deleted_at = 0", start, articleStatusPublished).
+		Order("ctime DESC").
+		Offset(offset).Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (a *ArticleGORMDAO) GetPubByAuthor(ctx context.Context, uid int64,
+	utime int64, maxId int64, limit int) ([]PublishedArticle, error) {
+	var res []PublishedArticle
+	err := pubPage(a.db.WithContext(ctx), uid, utime, maxId, limit).Find(&res).Error
+	return res, err
+}
+
+func (a *ArticleGORMDAO) ListNewestPub(ctx context.Context,
+	utime int64, maxId int64, limit int) ([]PublishedArticle, error) {
+	var res []PublishedArticle
+	err := pubPage(a.db.WithContext(ctx), 0, utime, maxId, limit).Find(&res).Error
+	return res, err
+}
+
+// pubPage 线上库的游标分页，uid 为 0 的时候不限制作者
+func pubPage(db *gorm.DB, uid int64, utime int64, maxId int64, limit int) *gorm.DB {
+	query := db.Where("status = ? AND deleted_at = 0", articleStatusPublished)
+	if uid > 0 {
+		query = query.Where("author_id = ?", uid)
+	}
+	if maxId > 0 {
+		query = query.Where("utime < ? OR (utime = ? AND id < ?)", utime, utime, maxId)
+	}
+	return query.Order("utime DESC, id DESC").Limit(limit)
+}
+
+func (a *ArticleGORMDAO) GetCategories(ctx context.Context, uid int64) ([]string, error) {
+	var res []string
+	err := a.db.WithContext(ctx).Model(&Article{}).
+		Distinct("category").
+		Where("author_id = ? AND category <> ? AND deleted_at = 0", uid, "").
+		Pluck("category", &res).Error
+	return res, err
+}
+
 // func (a *ArticleGORMDAO) SyncV1(ctx context.Context, art Article) (int64, error) {
 // 	tx := a.db.WithContext(ctx).Begin()
 // 	if tx.Error != nil {
@@ -157,12 +248,15 @@ func (a *ArticleGORMDAO) Sync(ctx context.Context, art Article) (int64, error) {
 
 func (a *ArticleGORMDAO) UpdateById(ctx context.Context, art Article) error {
 	now := time.Now().UnixMilli()
+	// 回收站里面的文章要先恢复才能编辑
 	res := a.db.WithContext(ctx).Model(&art).
-		Where("id = ? AND author_id = ?", art.Id, art.AuthorId).Updates(map[string]any{
-		"title":   art.Title,
-		"content": art.Content,
-		"status":  art.Status,
-		"utime":   now,
+		Where("id = ? AND author_id = ? AND deleted_at = 0", art.Id, art.AuthorId).Updates(map[string]any{
+		"title":      art.Title,
+		"content":    art.Content,
+		"category":   art.Category,
+		"status":     art.Status,
+		"publish_at": art.PublishAt,
+		"utime":      now,
 	})
 	if res.Error != nil {
 		return res.Error
@@ -195,10 +289,16 @@ type Article struct {
 	Content string `gorm:"type=BLOB" bson:"content,omitempty"`
 	// 我要根据创作者ID来查询
 	AuthorId int64 `gorm:"index" bson:"author_id,omitempty"`
-	Status   uint8 `bson:"status,omitempty"`
-	Ctime    int64 `bson:"ctime,omitempty"`
-	// 更新时间
-	Utime int64 `bson:"utime,omitempty"`
+	// 作者自己选的分类
+	Category string `gorm:"type:varchar(128)" bson:"category,omitempty"`
+	Status   uint8  `bson:"status,omitempty"`
+	// 定时发表的时间，要根据它找到期的文章
+	PublishAt int64 `gorm:"index" bson:"publish_at,omitempty"`
+	Ctime     int64 `bson:"ctime,omitempty"`
+	// 更新时间，线上库按照它分页
+	Utime int64 `gorm:"index" bson:"utime,omitempty"`
+	// DeletedAt 放进回收站的时间，0 代表没有删除
+	DeletedAt int64 `gorm:"index" bson:"deleted_at,omitempty"`
 }
 
 type PublishedArticle Article
@@ -0,0 +1,118 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// 和 domain.ReviewStatus 保持一致
+	reviewStatusPending uint8 = 1
+)
+
+var ErrReviewResolved = errors.New("审核记录已经处理过了")
+
+type ArticleReviewDAO interface {
+	// Submit 提交人工审核。同一篇文章已经有待审核的记录的话，用新的内容覆盖它
+	Submit(ctx context.Context, r ArticleReview) (int64, error)
+	GetById(ctx context.Context, id int64) (ArticleReview, error)
+	// ListPending 待审核的记录，先提交的在前面
+	ListPending(ctx context.Context, offset int, limit int) ([]ArticleReview, error)
+	// Resolve 处理一条待审核的记录，已经处理过的返回 ErrReviewResolved
+	Resolve(ctx context.Context, id int64, status uint8, reviewerId int64, reason string) error
+}
+
+type ArticleReviewGORMDAO struct {
+	db *gorm.DB
+}
+
+func NewArticleReviewGORMDAO(db *gorm.DB) ArticleReviewDAO {
+	return &ArticleReviewGORMDAO{
+		db: db,
+	}
+}
+
+func (d *ArticleReviewGORMDAO) Submit(ctx context.Context, r ArticleReview) (int64, error) {
+	now := time.Now().UnixMilli()
+	r.Status = reviewStatusPending
+	r.Ctime = now
+	r.Utime = now
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var old ArticleReview
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("article_id = ? AND status = ?", r.ArticleId, reviewStatusPending).
+			First(&old).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return tx.Create(&r).Error
+		case err != nil:
+			return err
+		}
+		r.Id = old.Id
+		return tx.Model(&ArticleReview{}).
+			Where("id = ?", old.Id).
+			Updates(map[string]any{
+				"title":      r.Title,
+				"content":    r.Content,
+				"violations": r.Violations,
+				"utime":      now,
+			}).Error
+	})
+	return r.Id, err
+}
+
+func (d *ArticleReviewGORMDAO) GetById(ctx context.Context, id int64) (ArticleReview, error) {
+	var res ArticleReview
+	err := d.db.WithContext(ctx).Where("id = ?", id).First(&res).Error
+	return res, err
+}
+
+func (d *ArticleReviewGORMDAO) ListPending(ctx context.Context, offset int, limit int) ([]ArticleReview, error) {
+	var res []ArticleReview
+	err := d.db.WithContext(ctx).
+		Where("status = ?", reviewStatusPending).
+		Order("ctime ASC, id ASC").
+		Offset(offset).Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (d *ArticleReviewGORMDAO) Resolve(ctx context.Context, id int64, status uint8,
+	reviewerId int64, reason string) error {
+	// 带上状态作为条件，两个审核员同时处理的时候只有一个能成功
+	res := d.db.WithContext(ctx).Model(&ArticleReview{}).
+		Where("id = ? AND status = ?", id, reviewStatusPending).
+		Updates(map[string]any{
+			"status":      status,
+			"reviewer_id": reviewerId,
+			"reason":      reason,
+			"utime":       time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrReviewResolved
+	}
+	return nil
+}
+
+type ArticleReview struct {
+	Id int64 `gorm:"primaryKey,autoIncrement"`
+	// 按照文章找待审核的记录
+	ArticleId int64 `gorm:"index:idx_article_status"`
+	AuthorId  int64
+	Title     string `gorm:"type=varchar(4096)"`
+	Content   string `gorm:"type=BLOB"`
+	// Violations 机器审核没通过的原因，一行一个
+	Violations string `gorm:"type=varchar(4096)"`
+	Status     uint8  `gorm:"index:idx_article_status;index:idx_status_ctime"`
+	ReviewerId int64
+	Reason     string `gorm:"type=varchar(1024)"`
+	Ctime      int64  `gorm:"index:idx_status_ctime"`
+	Utime      int64
+}
@@ -0,0 +1,81 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ArticleRevisionDAO interface {
+	// Insert 插入一个新版本，版本号由 DAO 负责分配
+	Insert(ctx context.Context, rev ArticleRevision) (int64, error)
+	GetByArticle(ctx context.Context, aid int64, offset int, limit int) ([]ArticleRevision, error)
+	GetById(ctx context.Context, id int64) (ArticleRevision, error)
+	// DeleteByArticle 文章被彻底删除的时候，历史版本也一起删掉
+	DeleteByArticle(ctx context.Context, aid int64) error
+}
+
+type ArticleRevisionGORMDAO struct {
+	db *gorm.DB
+}
+
+func NewArticleRevisionGORMDAO(db *gorm.DB) ArticleRevisionDAO {
+	return &ArticleRevisionGORMDAO{
+		db: db,
+	}
+}
+
+func (a *ArticleRevisionGORMDAO) Insert(ctx context.Context, rev ArticleRevision) (int64, error) {
+	rev.Ctime = time.Now().UnixMilli()
+	err := a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var version int64
+		// 同一篇文章并发保存的概率很低，真冲突了也有唯一索引兜底
+		err := tx.Model(&ArticleRevision{}).
+			Select("COALESCE(MAX(version), 0)").
+			Where("article_id = ?", rev.ArticleId).
+			Scan(&version).Error
+		if err != nil {
+			return err
+		}
+		rev.Version = version + 1
+		return tx.Create(&rev).Error
+	})
+	return rev.Id, err
+}
+
+func (a *ArticleRevisionGORMDAO) GetByArticle(ctx context.Context, aid int64, offset int, limit int) ([]ArticleRevision, error) {
+	var res []ArticleRevision
+	err := a.db.WithContext(ctx).
+		Where("article_id = ?", aid).
+		Offset(offset).Limit(limit).
+		Order("version DESC").
+		Find(&res).Error
+	return res, err
+}
+
+func (a *ArticleRevisionGORMDAO) GetById(ctx context.Context, id int64) (ArticleRevision, error) {
+	var res ArticleRevision
+	err := a.db.WithContext(ctx).
+		Where("id = ?", id).
+		First(&res).Error
+	return res, err
+}
+
+func (a *ArticleRevisionGORMDAO) DeleteByArticle(ctx context.Context, aid int64) error {
+	return a.db.WithContext(ctx).
+		Where("article_id = ?", aid).
+		Delete(&ArticleRevision{}).Error
+}
+
+// ArticleRevision 文章的历史版本，只会插入，不会修改
+type ArticleRevision struct {
+	Id        int64  `gorm:"primaryKey,autoIncrement" bson:"id,omitempty"`
+	ArticleId int64  `gorm:"uniqueIndex:aid_version" bson:"article_id,omitempty"`
+	Version   int64  `gorm:"uniqueIndex:aid_version" bson:"version,omitempty"`
+	AuthorId  int64  `bson:"author_id,omitempty"`
+	Title     string `gorm:"type=varchar(4096)" bson:"title,omitempty"`
+	Content   string `gorm:"type=BLOB" bson:"content,omitempty"`
+	Status    uint8  `bson:"status,omitempty"`
+	Ctime     int64  `bson:"ctime,omitempty"`
+}
@@ -0,0 +1,86 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrScheduledArticleNotFound 没有到了发表时间的文章，或者修改、取消定时发表的时候
+// ID 不对、创作者不对、文章不是定时发表状态
+var ErrScheduledArticleNotFound = gorm.ErrRecordNotFound
+
+// scheduledPreemptInterval 抢占之后多久没发表成功，就允许别的节点再次抢占
+const scheduledPreemptInterval = time.Minute
+
+func (a *ArticleGORMDAO) GetScheduledByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]Article, error) {
+	var arts []Article
+	err := a.db.WithContext(ctx).
+		Where("author_id = ? AND status = ? AND deleted_at = 0", uid, articleStatusScheduled).
+		Offset(offset).Limit(limit).
+		Order("publish_at ASC").
+		Find(&arts).Error
+	return arts, err
+}
+
+func (a *ArticleGORMDAO) Reschedule(ctx context.Context, uid int64, id int64, publishAt int64) error {
+	res := a.db.WithContext(ctx).Model(&Article{}).
+		Where("id = ? AND author_id = ? AND status = ? AND deleted_at = 0", id, uid, articleStatusScheduled).
+		Updates(map[string]any{
+			"publish_at": publishAt,
+			"utime":      time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrScheduledArticleNotFound
+	}
+	return nil
+}
+
+func (a *ArticleGORMDAO) CancelSchedule(ctx context.Context, uid int64, id int64) error {
+	res := a.db.WithContext(ctx).Model(&Article{}).
+		Where("id = ? AND author_id = ? AND status = ? AND deleted_at = 0", id, uid, articleStatusScheduled).
+		Updates(map[string]any{
+			// 取消之后就退回草稿
+			"status":     articleStatusUnpublished,
+			"publish_at": 0,
+			"utime":      time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrScheduledArticleNotFound
+	}
+	return nil
+}
+
+// PreemptScheduled 和 GORMAsyncSmsDAO.GetWaitingSMS 一样，用 SELECT FOR UPDATE 抢占
+func (a *ArticleGORMDAO) PreemptScheduled(ctx context.Context) (Article, error) {
+	var art Article
+	err := a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UnixMilli()
+		// 定时的时候 publish_at 一定晚于 utime，所以 utime < publish_at 说明还没人抢过；
+		// 抢过的 utime 会被更新成抢占的时间，要等超过 scheduledPreemptInterval 才能再抢
+		endTime := now - scheduledPreemptInterval.Milliseconds()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("status = ? AND publish_at <= ? AND deleted_at = 0 AND (utime < publish_at OR utime < ?)",
+				articleStatusScheduled, now, endTime).
+			Order("publish_at ASC").
+			First(&art).Error
+		if err != nil {
+			return err
+		}
+		// 更新了 utime，别的节点在接下来一段时间内就抢不到了
+		return tx.Model(&Article{}).
+			Where("id = ?", art.Id).
+			Updates(map[string]any{
+				"utime": now,
+			}).Error
+	})
+	return art, err
+}
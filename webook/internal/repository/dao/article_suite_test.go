@@ -0,0 +1,500 @@
+package dao
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"basic-go/webook/pkg/objectstore"
+
+	"github.com/bwmarrin/snowflake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// ArticleDAOSuite 是 ArticleDAO 的一致性测试，MySQL 和 MongoDB 的实现都要通过
+type ArticleDAOSuite struct {
+	suite.Suite
+	// newDAO 每个测试开始之前调用，返回的 DAO 背后必须是空的
+	newDAO func(t *testing.T) ArticleDAO
+	dao    ArticleDAO
+}
+
+func (s *ArticleDAOSuite) SetupTest() {
+	s.dao = s.newDAO(s.T())
+}
+
+func (s *ArticleDAOSuite) TestInsertAndGetById() {
+	t := s.T()
+	ctx := context.Background()
+	id, err := s.dao.Insert(ctx, Article{Title: "标题", Content: "内容",
+		AuthorId: 1, Category: "Go", Status: articleStatusUnpublished})
+	require.NoError(t, err)
+	assert.True(t, id > 0)
+
+	art, err := s.dao.GetById(ctx, id)
+	require.NoError(t, err)
+	assert.True(t, art.Ctime > 0)
+	assert.Equal(t, art.Ctime, art.Utime)
+	art.Ctime, art.Utime = 0, 0
+	assert.Equal(t, Article{Id: id, Title: "标题", Content: "内容",
+		AuthorId: 1, Category: "Go", Status: articleStatusUnpublished}, art)
+
+	_, err = s.dao.GetById(ctx, id+1)
+	assert.Equal(t, ErrRecordNotFound, err)
+	// 没有发表过，线上库里面没有
+	_, err = s.dao.GetPubById(ctx, id)
+	assert.Equal(t, ErrRecordNotFound, err)
+}
+
+func (s *ArticleDAOSuite) TestUpdateById() {
+	t := s.T()
+	ctx := context.Background()
+	id, err := s.dao.Insert(ctx, Article{Title: "标题", Content: "内容", AuthorId: 1})
+	require.NoError(t, err)
+	before, err := s.dao.GetById(ctx, id)
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond * 2)
+
+	err = s.dao.UpdateById(ctx, Article{Id: id, Title: "新标题", Content: "新内容",
+		AuthorId: 1, Status: articleStatusUnpublished})
+	require.NoError(t, err)
+	art, err := s.dao.GetById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "新标题", art.Title)
+	assert.Equal(t, "新内容", art.Content)
+	assert.Equal(t, before.Ctime, art.Ctime)
+	assert.True(t, art.Utime > before.Utime)
+
+	// 别人的文章不能改
+	err = s.dao.UpdateById(ctx, Article{Id: id, Title: "坏人", AuthorId: 2})
+	assert.Error(t, err)
+	art, err = s.dao.GetById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "新标题", art.Title)
+}
+
+func (s *ArticleDAOSuite) TestSync() {
+	t := s.T()
+	ctx := context.Background()
+	// 新建并且发表
+	id, err := s.dao.Sync(ctx, Article{Title: "标题", Content: "内容",
+		AuthorId: 1, Category: "Go", Status: articleStatusPublished})
+	require.NoError(t, err)
+	art, err := s.dao.GetById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, articleStatusPublished, art.Status)
+	pub, err := s.dao.GetPubById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "标题", pub.Title)
+	assert.Equal(t, "内容", pub.Content)
+	assert.Equal(t, "Go", pub.Category)
+	assert.Equal(t, int64(1), pub.AuthorId)
+	assert.Equal(t, articleStatusPublished, pub.Status)
+	time.Sleep(time.Millisecond * 2)
+
+	// 修改之后重新发表，线上库的 ctime 还是第一次发表的时间
+	_, err = s.dao.Sync(ctx, Article{Id: id, Title: "新标题", Content: "新内容",
+		AuthorId: 1, Status: articleStatusPublished})
+	require.NoError(t, err)
+	newPub, err := s.dao.GetPubById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "新标题", newPub.Title)
+	assert.Equal(t, "新内容", newPub.Content)
+	assert.Equal(t, "", newPub.Category)
+	assert.Equal(t, pub.Ctime, newPub.Ctime)
+	assert.True(t, newPub.Utime > pub.Utime)
+
+	// 别人的文章不能发表
+	_, err = s.dao.Sync(ctx, Article{Id: id, Title: "坏人", AuthorId: 2,
+		Status: articleStatusPublished})
+	assert.Error(t, err)
+	newPub, err = s.dao.GetPubById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "新标题", newPub.Title)
+}
+
+func (s *ArticleDAOSuite) TestSyncStatus() {
+	t := s.T()
+	ctx := context.Background()
+	id, err := s.dao.Sync(ctx, Article{Title: "标题", AuthorId: 1, Status: articleStatusPublished})
+	require.NoError(t, err)
+	pub, err := s.dao.GetPubById(ctx, id)
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond * 2)
+
+	require.NoError(t, s.dao.SyncStatus(ctx, 1, id, articleStatusUnpublished))
+	art, err := s.dao.GetById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, articleStatusUnpublished, art.Status)
+	newPub, err := s.dao.GetPubById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, articleStatusUnpublished, newPub.Status)
+	assert.True(t, newPub.Utime > pub.Utime)
+
+	// 别人的文章不能撤回
+	assert.Error(t, s.dao.SyncStatus(ctx, 2, id, articleStatusPublished))
+	assert.Error(t, s.dao.SyncStatus(ctx, 1, id+1, articleStatusPublished))
+}
+
+func (s *ArticleDAOSuite) TestGetByAuthor() {
+	t := s.T()
+	ctx := context.Background()
+	ids := make([]int64, 0, 3)
+	for _, title := range []string{"第一篇", "第二篇", "第三篇"} {
+		id, err := s.dao.Insert(ctx, Article{Title: title, AuthorId: 1})
+		require.NoError(t, err)
+		ids = append(ids, id)
+		time.Sleep(time.Millisecond * 2)
+	}
+	_, err := s.dao.Insert(ctx, Article{Title: "别人的", AuthorId: 2})
+	require.NoError(t, err)
+
+	// 最近修改的在前面
+	arts, err := s.dao.GetByAuthor(ctx, 1, 0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{ids[2], ids[1]}, articleIds(arts))
+	arts, err = s.dao.GetByAuthor(ctx, 1, 2, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{ids[0]}, articleIds(arts))
+
+	// 修改之后排到最前面
+	require.NoError(t, s.dao.UpdateById(ctx, Article{Id: ids[0], Title: "改过", AuthorId: 1}))
+	arts, err = s.dao.GetByAuthor(ctx, 1, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{ids[0], ids[2], ids[1]}, articleIds(arts))
+
+	arts, err = s.dao.GetByAuthor(ctx, 3, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, arts)
+}
+
+func (s *ArticleDAOSuite) TestGetCategories() {
+	t := s.T()
+	ctx := context.Background()
+	for _, c := range []string{"Go", "Redis", "Go", ""} {
+		_, err := s.dao.Insert(ctx, Article{Title: "标题", AuthorId: 1, Category: c})
+		require.NoError(t, err)
+	}
+	_, err := s.dao.Insert(ctx, Article{Title: "标题", AuthorId: 2, Category: "MySQL"})
+	require.NoError(t, err)
+	res, err := s.dao.GetCategories(ctx, 1)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Go", "Redis"}, res)
+}
+
+func (s *ArticleDAOSuite) TestSchedule() {
+	t := s.T()
+	ctx := context.Background()
+	now := time.Now().UnixMilli()
+	later, err := s.dao.Insert(ctx, Article{Title: "明天", AuthorId: 1,
+		Status: articleStatusScheduled, PublishAt: now + time.Hour.Milliseconds()})
+	require.NoError(t, err)
+	soon, err := s.dao.Insert(ctx, Article{Title: "马上", AuthorId: 1,
+		Status: articleStatusScheduled, PublishAt: now + 200})
+	require.NoError(t, err)
+	draft, err := s.dao.Insert(ctx, Article{Title: "草稿", AuthorId: 1, Status: articleStatusUnpublished})
+	require.NoError(t, err)
+
+	arts, err := s.dao.GetScheduledByAuthor(ctx, 1, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{soon, later}, articleIds(arts))
+
+	assert.Error(t, s.dao.Reschedule(ctx, 1, draft, now))
+	assert.Error(t, s.dao.Reschedule(ctx, 2, later, now))
+	require.NoError(t, s.dao.Reschedule(ctx, 1, later, now+2*time.Hour.Milliseconds()))
+	art, err := s.dao.GetById(ctx, later)
+	require.NoError(t, err)
+	assert.Equal(t, now+2*time.Hour.Milliseconds(), art.PublishAt)
+
+	// 还没到时间
+	_, err = s.dao.PreemptScheduled(ctx)
+	assert.Equal(t, ErrScheduledArticleNotFound, err)
+	time.Sleep(time.Millisecond * 250)
+	art, err = s.dao.PreemptScheduled(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, soon, art.Id)
+	// 已经被抢占了
+	_, err = s.dao.PreemptScheduled(ctx)
+	assert.Equal(t, ErrScheduledArticleNotFound, err)
+
+	assert.Error(t, s.dao.CancelSchedule(ctx, 1, draft))
+	require.NoError(t, s.dao.CancelSchedule(ctx, 1, later))
+	art, err = s.dao.GetById(ctx, later)
+	require.NoError(t, err)
+	assert.Equal(t, articleStatusUnpublished, art.Status)
+	assert.Equal(t, int64(0), art.PublishAt)
+}
+
+func (s *ArticleDAOSuite) TestListPub() {
+	t := s.T()
+	ctx := context.Background()
+	start := time.Now().UnixMilli() - 1
+	ids := make([]int64, 0, 3)
+	for _, title := range []string{"第一篇", "第二篇", "第三篇"} {
+		id, err := s.dao.Sync(ctx, Article{Title: title, AuthorId: 1, Status: articleStatusPublished})
+		require.NoError(t, err)
+		ids = append(ids, id)
+		time.Sleep(time.Millisecond * 2)
+	}
+	// 撤回的不算
+	require.NoError(t, s.dao.SyncStatus(ctx, 1, ids[1], articleStatusUnpublished))
+
+	arts, err := s.dao.ListPub(ctx, start, 0, 1)
+	require.NoError(t, err)
+	require.Len(t, arts, 1)
+	assert.Equal(t, ids[2], arts[0].Id)
+	arts, err = s.dao.ListPub(ctx, start, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, arts, 1)
+	assert.Equal(t, ids[0], arts[0].Id)
+}
+
+func (s *ArticleDAOSuite) TestPubPage() {
+	t := s.T()
+	ctx := context.Background()
+	ids := make([]int64, 0, 4)
+	for _, uid := range []int64{1, 2, 1, 1} {
+		id, err := s.dao.Sync(ctx, Article{Title: "标题", AuthorId: uid, Status: articleStatusPublished})
+		require.NoError(t, err)
+		ids = append(ids, id)
+		time.Sleep(time.Millisecond * 2)
+	}
+	// 撤回和删除的都不算
+	require.NoError(t, s.dao.SyncStatus(ctx, 1, ids[2], articleStatusPrivate))
+	draft, err := s.dao.Insert(ctx, Article{Title: "草稿", AuthorId: 1})
+	require.NoError(t, err)
+	require.NoError(t, s.dao.Delete(ctx, 1, draft))
+
+	arts, err := s.dao.GetPubByAuthor(ctx, 1, 0, 0, 1)
+	require.NoError(t, err)
+	require.Len(t, arts, 1)
+	assert.Equal(t, ids[3], arts[0].Id)
+	arts, err = s.dao.GetPubByAuthor(ctx, 1, arts[0].Utime, arts[0].Id, 10)
+	require.NoError(t, err)
+	require.Len(t, arts, 1)
+	assert.Equal(t, ids[0], arts[0].Id)
+	arts, err = s.dao.GetPubByAuthor(ctx, 1, arts[0].Utime, arts[0].Id, 10)
+	require.NoError(t, err)
+	assert.Empty(t, arts)
+
+	arts, err = s.dao.ListNewestPub(ctx, 0, 0, 2)
+	require.NoError(t, err)
+	require.Len(t, arts, 2)
+	assert.Equal(t, ids[3], arts[0].Id)
+	assert.Equal(t, ids[1], arts[1].Id)
+	arts, err = s.dao.ListNewestPub(ctx, arts[1].Utime, arts[1].Id, 2)
+	require.NoError(t, err)
+	require.Len(t, arts, 1)
+	assert.Equal(t, ids[0], arts[0].Id)
+}
+
+func (s *ArticleDAOSuite) TestDeleteAndRestore() {
+	t := s.T()
+	ctx := context.Background()
+	start := time.Now().UnixMilli() - 1
+	pub, err := s.dao.Sync(ctx, Article{Title: "发表过", AuthorId: 1, Status: articleStatusPublished})
+	require.NoError(t, err)
+	draft, err := s.dao.Insert(ctx, Article{Title: "草稿", AuthorId: 1, Status: articleStatusUnpublished})
+	require.NoError(t, err)
+
+	// 别人的文章不能删
+	assert.Equal(t, ErrRecordNotFound, s.dao.Delete(ctx, 2, pub))
+	require.NoError(t, s.dao.Delete(ctx, 1, pub))
+	require.NoError(t, s.dao.Delete(ctx, 1, draft))
+	// 已经在回收站里面了
+	assert.Equal(t, ErrRecordNotFound, s.dao.Delete(ctx, 1, pub))
+
+	arts, err := s.dao.GetByAuthor(ctx, 1, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, arts)
+	_, err = s.dao.GetPubById(ctx, pub)
+	assert.Equal(t, ErrRecordNotFound, err)
+	pubs, err := s.dao.ListPub(ctx, start, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pubs)
+	// 回收站里面的文章不能编辑
+	assert.Error(t, s.dao.UpdateById(ctx, Article{Id: draft, Title: "改过", AuthorId: 1}))
+
+	arts, err = s.dao.GetDeletedByAuthor(ctx, 1, 0, 10)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int64{pub, draft}, articleIds(arts))
+	for _, art := range arts {
+		assert.True(t, art.DeletedAt > 0)
+	}
+
+	assert.Equal(t, ErrRecordNotFound, s.dao.Restore(ctx, 2, pub))
+	require.NoError(t, s.dao.Restore(ctx, 1, pub))
+	assert.Equal(t, ErrRecordNotFound, s.dao.Restore(ctx, 1, pub))
+	// 恢复之后还是原来的状态
+	art, err := s.dao.GetPubById(ctx, pub)
+	require.NoError(t, err)
+	assert.Equal(t, articleStatusPublished, art.Status)
+	assert.Equal(t, int64(0), art.DeletedAt)
+	arts, err = s.dao.GetDeletedByAuthor(ctx, 1, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{draft}, articleIds(arts))
+}
+
+func (s *ArticleDAOSuite) TestPurge() {
+	t := s.T()
+	ctx := context.Background()
+	pub, err := s.dao.Sync(ctx, Article{Title: "发表过", AuthorId: 1, Status: articleStatusPublished})
+	require.NoError(t, err)
+	kept, err := s.dao.Insert(ctx, Article{Title: "没删", AuthorId: 1})
+	require.NoError(t, err)
+	require.NoError(t, s.dao.Delete(ctx, 1, pub))
+	time.Sleep(time.Millisecond * 2)
+
+	arts, err := s.dao.ListDeletedBefore(ctx, time.Now().UnixMilli(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{pub}, articleIds(arts))
+	// 还没过保留期
+	arts, err = s.dao.ListDeletedBefore(ctx, time.Now().Add(-time.Hour).UnixMilli(), 10)
+	require.NoError(t, err)
+	assert.Empty(t, arts)
+
+	// 不在回收站里面的不能彻底删除
+	assert.Equal(t, ErrRecordNotFound, s.dao.ClaimPurge(ctx, kept))
+	assert.Equal(t, ErrRecordNotFound, s.dao.Purge(ctx, kept))
+	// 没有标记过的也不能
+	assert.Equal(t, ErrRecordNotFound, s.dao.Purge(ctx, pub))
+	require.NoError(t, s.dao.ClaimPurge(ctx, pub))
+	// 标记过的可以再标记，也不会被恢复，清理的时候还能找到它
+	require.NoError(t, s.dao.ClaimPurge(ctx, pub))
+	assert.Equal(t, ErrRecordNotFound, s.dao.Restore(ctx, 1, pub))
+	arts, err = s.dao.ListDeletedBefore(ctx, time.Now().Add(-time.Hour).UnixMilli(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{pub}, articleIds(arts))
+	arts, err = s.dao.GetDeletedByAuthor(ctx, 1, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, arts)
+	_, err = s.dao.GetPubById(ctx, pub)
+	assert.Equal(t, ErrRecordNotFound, err)
+
+	require.NoError(t, s.dao.Purge(ctx, pub))
+	_, err = s.dao.GetById(ctx, pub)
+	assert.Equal(t, ErrRecordNotFound, err)
+	arts, err = s.dao.GetDeletedByAuthor(ctx, 1, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, arts)
+	assert.Equal(t, ErrRecordNotFound, s.dao.Purge(ctx, pub))
+	_, err = s.dao.GetById(ctx, kept)
+	require.NoError(t, err)
+}
+
+func articleIds(arts []Article) []int64 {
+	res := make([]int64, 0, len(arts))
+	for _, art := range arts {
+		res = append(res, art.Id)
+	}
+	return res
+}
+
+func TestGORMArticleDAO(t *testing.T) {
+	suite.Run(t, &ArticleDAOSuite{
+		newDAO: func(t *testing.T) ArticleDAO {
+			return NewArticleGORMDAO(newSqliteDB(t))
+		},
+	})
+}
+
+func TestArticleS3DAO(t *testing.T) {
+	suite.Run(t, &ArticleDAOSuite{
+		newDAO: func(t *testing.T) ArticleDAO {
+			store, err := objectstore.NewLocalStore(t.TempDir())
+			require.NoError(t, err)
+			return NewArticleS3DAO(newSqliteDB(t), store, "articles/")
+		},
+	})
+}
+
+func TestArticleS3DAO_Content(t *testing.T) {
+	store, err := objectstore.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	dao := NewArticleS3DAO(newSqliteDB(t), store, "articles/")
+	ctx := context.Background()
+	id, err := dao.Sync(ctx, Article{Title: "标题", Content: "很长的内容", AuthorId: 1,
+		Status: articleStatusPublished})
+	require.NoError(t, err)
+	data, err := store.Get(ctx, "articles/"+strconv.FormatInt(id, 10))
+	require.NoError(t, err)
+	assert.Equal(t, "很长的内容", string(data))
+
+	// 仅自己可见之后内容就删掉了
+	require.NoError(t, dao.SyncStatus(ctx, 1, id, articleStatusPrivate))
+	_, err = store.Get(ctx, "articles/"+strconv.FormatInt(id, 10))
+	assert.Equal(t, objectstore.ErrObjectNotFound, err)
+	pub, err := dao.GetPubById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, articleStatusPrivate, pub.Status)
+	assert.Equal(t, "", pub.Content)
+}
+
+func newSqliteDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	// 内存里的 sqlite 每个连接都是一个独立的库
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&Article{}, &PublishedArticle{}, &PublishedArticleV2{}))
+	return db
+}
+
+// TestMySQLArticleDAO 需要一个可以随便清空的库，比如
+// WEBOOK_TEST_MYSQL_DSN="root:root@tcp(localhost:13316)/webook_test"
+func TestMySQLArticleDAO(t *testing.T) {
+	dsn := os.Getenv("WEBOOK_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("没有设置 WEBOOK_TEST_MYSQL_DSN")
+	}
+	suite.Run(t, &ArticleDAOSuite{
+		newDAO: func(t *testing.T) ArticleDAO {
+			db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+			require.NoError(t, err)
+			require.NoError(t, db.Migrator().DropTable(&Article{}, &PublishedArticle{}))
+			require.NoError(t, db.AutoMigrate(&Article{}, &PublishedArticle{}))
+			return NewArticleGORMDAO(db)
+		},
+	})
+}
+
+// TestMongoDBArticleDAO 会清空 webook_test 库，比如
+// WEBOOK_TEST_MONGO_URI="mongodb://localhost:27017"
+func TestMongoDBArticleDAO(t *testing.T) {
+	uri := os.Getenv("WEBOOK_TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("没有设置 WEBOOK_TEST_MONGO_URI")
+	}
+	node, err := snowflake.NewNode(1)
+	require.NoError(t, err)
+	suite.Run(t, &ArticleDAOSuite{
+		newDAO: func(t *testing.T) ArticleDAO {
+			return NewMongoDBArticleDAO(newMongoDB(t, uri), node)
+		},
+	})
+}
+
+// newMongoDB 清空 webook_test 库并且建好索引
+func newMongoDB(t *testing.T, uri string) *mongo.Database {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = client.Disconnect(context.Background())
+	})
+	mdb := client.Database("webook_test")
+	require.NoError(t, mdb.Drop(ctx))
+	require.NoError(t, InitCollections(ctx, mdb))
+	return mdb
+}
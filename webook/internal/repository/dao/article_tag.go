@@ -0,0 +1,151 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ArticleTagDAO 草稿和线上文章的标签分开存，draft 为 true 操作的是草稿的标签。
+// 作者编辑草稿的时候只改草稿的标签，发表的时候才会同步到线上
+type ArticleTagDAO interface {
+	// ReplaceTags 用 tags 整个替换掉文章原本的标签
+	ReplaceTags(ctx context.Context, aid int64, draft bool, tags []string) error
+	GetTagsByArticle(ctx context.Context, aid int64, draft bool) ([]string, error)
+	// GetPubByTag 按照更新时间倒序，找出打了这个标签的已发表文章
+	GetPubByTag(ctx context.Context, tag string, offset int, limit int) ([]PublishedArticle, error)
+	// CountPubByTag 统计每个标签下已发表文章的数量，数量多的在前面
+	CountPubByTag(ctx context.Context, limit int) ([]TagCount, error)
+}
+
+// ArticleTagGORMDAO 标签和文章的关系放在 MySQL 里面，
+// pubTable 是线上库的表，按照标签列出文章的时候要 JOIN 它
+type ArticleTagGORMDAO struct {
+	db       *gorm.DB
+	pubTable string
+}
+
+func NewArticleTagGORMDAO(db *gorm.DB) ArticleTagDAO {
+	return newArticleTagGORMDAO(db, &PublishedArticle{})
+}
+
+// NewArticleTagS3DAO 配合 ArticleS3DAO 使用，线上库是 PublishedArticleV2 那张表
+func NewArticleTagS3DAO(db *gorm.DB) ArticleTagDAO {
+	return newArticleTagGORMDAO(db, &PublishedArticleV2{})
+}
+
+func newArticleTagGORMDAO(db *gorm.DB, pub any) *ArticleTagGORMDAO {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(pub); err != nil {
+		panic(err)
+	}
+	return &ArticleTagGORMDAO{
+		db:       db,
+		pubTable: stmt.Schema.Table,
+	}
+}
+
+func (a *ArticleTagGORMDAO) ReplaceTags(ctx context.Context, aid int64, draft bool, tags []string) error {
+	now := time.Now().UnixMilli()
+	return a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var tagIds []int64
+		if len(tags) > 0 {
+			entities := make([]Tag, 0, len(tags))
+			for _, name := range tags {
+				entities = append(entities, Tag{Name: name, Ctime: now})
+			}
+			// 标签已经存在就什么都不做
+			err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&entities).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Model(&Tag{}).Where("name IN ?", tags).
+				Pluck("id", &tagIds).Error
+			if err != nil {
+				return err
+			}
+		}
+		del := tx.Where("aid = ? AND draft = ?", aid, draft)
+		if len(tagIds) > 0 {
+			del = del.Where("tag_id NOT IN ?", tagIds)
+		}
+		err := del.Delete(&ArticleTag{}).Error
+		if err != nil || len(tagIds) == 0 {
+			return err
+		}
+		rels := make([]ArticleTag, 0, len(tagIds))
+		for _, tid := range tagIds {
+			rels = append(rels, ArticleTag{Aid: aid, Draft: draft, TagId: tid, Ctime: now})
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&rels).Error
+	})
+}
+
+func (a *ArticleTagGORMDAO) GetTagsByArticle(ctx context.Context, aid int64, draft bool) ([]string, error) {
+	var res []string
+	err := a.db.WithContext(ctx).Model(&Tag{}).
+		Joins("JOIN article_tags ON article_tags.tag_id = tags.id").
+		Where("article_tags.aid = ? AND article_tags.draft = ?", aid, draft).
+		Order("article_tags.id ASC").
+		Pluck("tags.name", &res).Error
+	return res, err
+}
+
+func (a *ArticleTagGORMDAO) GetPubByTag(ctx context.Context, tag string, offset int, limit int) ([]PublishedArticle, error) {
+	var res []PublishedArticle
+	pub := a.pubTable
+	// PublishedArticleV2 的列是 PublishedArticle 的子集，没有的字段是零值
+	err := a.db.WithContext(ctx).Table(pub).
+		Select(pub+".*").
+		Joins(fmt.Sprintf("JOIN article_tags ON article_tags.aid = %s.id", pub)).
+		Joins("JOIN tags ON tags.id = article_tags.tag_id").
+		Where(fmt.Sprintf("tags.name = ? AND article_tags.draft = ? AND %s.status = ? AND %s.deleted_at = 0", pub, pub),
+			tag, false, articleStatusPublished).
+		Order(pub + ".utime DESC").
+		Offset(offset).Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (a *ArticleTagGORMDAO) CountPubByTag(ctx context.Context, limit int) ([]TagCount, error) {
+	var res []TagCount
+	pub := a.pubTable
+	err := a.db.WithContext(ctx).Model(&ArticleTag{}).
+		Select("tags.name AS name, COUNT(*) AS cnt").
+		Joins("JOIN tags ON tags.id = article_tags.tag_id").
+		Joins(fmt.Sprintf("JOIN %s ON %s.id = article_tags.aid", pub, pub)).
+		Where(fmt.Sprintf("article_tags.draft = ? AND %s.status = ? AND %s.deleted_at = 0", pub, pub),
+			false, articleStatusPublished).
+		Group("tags.name").
+		Order("cnt DESC").
+		Limit(limit).
+		Scan(&res).Error
+	return res, err
+}
+
+type Tag struct {
+	Id    int64  `gorm:"primaryKey,autoIncrement"`
+	Name  string `gorm:"type:varchar(64);uniqueIndex"`
+	Ctime int64
+}
+
+// ArticleTag 文章和标签的多对多关系
+type ArticleTag struct {
+	Id  int64 `gorm:"primaryKey,autoIncrement"`
+	Aid int64 `gorm:"uniqueIndex:aid_draft_tag_id"`
+	// Draft 草稿的标签，按照标签找文章、标签云只看线上的标签
+	Draft bool `gorm:"uniqueIndex:aid_draft_tag_id"`
+	// 按照标签找文章的时候要用
+	TagId int64 `gorm:"uniqueIndex:aid_draft_tag_id;index"`
+	Ctime int64
+}
+
+type TagCount struct {
+	Name string
+	Cnt  int64
+}
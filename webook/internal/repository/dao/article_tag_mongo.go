@@ -0,0 +1,123 @@
+package dao
+
+import (
+	"context"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gorm.io/gorm"
+)
+
+// mongoInBatch 一次 $in 最多带多少个 ID
+const mongoInBatch = 1000
+
+// ArticleTagMongoDBDAO 配合 MongoDBArticleDAO 使用。
+// 标签和文章的关系还是放在 MySQL 里面，线上库在 MongoDB 里面没法 JOIN，
+// 所以先从 MySQL 里面找出文章 ID，再去 MongoDB 里面过滤出已发表的
+type ArticleTagMongoDBDAO struct {
+	*ArticleTagGORMDAO
+	liveCol *mongo.Collection
+}
+
+func NewArticleTagMongoDBDAO(db *gorm.DB, mdb *mongo.Database) ArticleTagDAO {
+	return &ArticleTagMongoDBDAO{
+		ArticleTagGORMDAO: newArticleTagGORMDAO(db, &PublishedArticle{}),
+		liveCol:           mdb.Collection("published_articles"),
+	}
+}
+
+func (a *ArticleTagMongoDBDAO) GetPubByTag(ctx context.Context, tag string, offset int, limit int) ([]PublishedArticle, error) {
+	var aids []int64
+	err := a.db.WithContext(ctx).Model(&ArticleTag{}).
+		Joins("JOIN tags ON tags.id = article_tags.tag_id").
+		Where("tags.name = ? AND article_tags.draft = ?", tag, false).
+		Pluck("article_tags.aid", &aids).Error
+	if err != nil || len(aids) == 0 {
+		return nil, err
+	}
+	filter := bson.D{bson.E{Key: "id", Value: bson.M{"$in": aids}},
+		bson.E{Key: "status", Value: articleStatusPublished}, notDeleted}
+	cursor, err := a.liveCol.Find(ctx, filter, options.Find().
+		SetSort(bson.D{bson.E{Key: "utime", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	var res []PublishedArticle
+	err = cursor.All(ctx, &res)
+	return res, err
+}
+
+// CountPubByTag 要把所有线上的标签关系都过一遍，只适合标签云这种有缓存的场景
+func (a *ArticleTagMongoDBDAO) CountPubByTag(ctx context.Context, limit int) ([]TagCount, error) {
+	var rels []struct {
+		Aid  int64
+		Name string
+	}
+	err := a.db.WithContext(ctx).Model(&ArticleTag{}).
+		Select("article_tags.aid AS aid, tags.name AS name").
+		Joins("JOIN tags ON tags.id = article_tags.tag_id").
+		Where("article_tags.draft = ?", false).
+		Scan(&rels).Error
+	if err != nil {
+		return nil, err
+	}
+	aids := make([]int64, 0, len(rels))
+	seen := make(map[int64]struct{}, len(rels))
+	for _, rel := range rels {
+		if _, ok := seen[rel.Aid]; !ok {
+			seen[rel.Aid] = struct{}{}
+			aids = append(aids, rel.Aid)
+		}
+	}
+	published, err := a.publishedIds(ctx, aids)
+	if err != nil {
+		return nil, err
+	}
+	cnts := make(map[string]int64)
+	for _, rel := range rels {
+		if _, ok := published[rel.Aid]; ok {
+			cnts[rel.Name]++
+		}
+	}
+	res := make([]TagCount, 0, len(cnts))
+	for name, cnt := range cnts {
+		res = append(res, TagCount{Name: name, Cnt: cnt})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Cnt != res[j].Cnt {
+			return res[i].Cnt > res[j].Cnt
+		}
+		return res[i].Name < res[j].Name
+	})
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+// publishedIds 过滤出 aids 里面已经发表而且没有删除的
+func (a *ArticleTagMongoDBDAO) publishedIds(ctx context.Context, aids []int64) (map[int64]struct{}, error) {
+	res := make(map[int64]struct{}, len(aids))
+	for start := 0; start < len(aids); start += mongoInBatch {
+		batch := aids[start:min(start+mongoInBatch, len(aids))]
+		filter := bson.D{bson.E{Key: "id", Value: bson.M{"$in": batch}},
+			bson.E{Key: "status", Value: articleStatusPublished}, notDeleted}
+		cursor, err := a.liveCol.Find(ctx, filter,
+			options.Find().SetProjection(bson.M{"id": 1}))
+		if err != nil {
+			return nil, err
+		}
+		var arts []PublishedArticle
+		if err = cursor.All(ctx, &arts); err != nil {
+			return nil, err
+		}
+		for _, art := range arts {
+			res[art.Id] = struct{}{}
+		}
+	}
+	return res, nil
+}
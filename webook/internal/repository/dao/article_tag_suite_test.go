@@ -0,0 +1,123 @@
+package dao
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"basic-go/webook/pkg/objectstore"
+
+	"github.com/bwmarrin/snowflake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+// ArticleTagDAOSuite 按照标签列出文章要查线上库，每一种文章存储都要通过
+type ArticleTagDAOSuite struct {
+	suite.Suite
+	// newDAOs 每个测试开始之前调用，返回的两个 DAO 背后必须是空的
+	newDAOs func(t *testing.T) (ArticleDAO, ArticleTagDAO)
+	artDAO  ArticleDAO
+	tagDAO  ArticleTagDAO
+}
+
+func (s *ArticleTagDAOSuite) SetupTest() {
+	s.artDAO, s.tagDAO = s.newDAOs(s.T())
+}
+
+func (s *ArticleTagDAOSuite) TestPubByTag() {
+	t := s.T()
+	ctx := context.Background()
+	publish := func(title string, tags ...string) int64 {
+		id, err := s.artDAO.Sync(ctx, Article{Title: title, Content: title,
+			AuthorId: 1, Status: articleStatusPublished})
+		require.NoError(t, err)
+		require.NoError(t, s.tagDAO.ReplaceTags(ctx, id, true, tags))
+		require.NoError(t, s.tagDAO.ReplaceTags(ctx, id, false, tags))
+		return id
+	}
+	both := publish("两个标签", "go", "rust")
+	single := publish("一个标签", "go")
+	withdrawn := publish("撤回了", "go")
+	require.NoError(t, s.artDAO.SyncStatus(ctx, 1, withdrawn, articleStatusPrivate))
+	trashed := publish("删除了", "go")
+	require.NoError(t, s.artDAO.Delete(ctx, 1, trashed))
+	// 草稿的标签不算
+	draft, err := s.artDAO.Insert(ctx, Article{Title: "草稿", AuthorId: 1, Status: articleStatusUnpublished})
+	require.NoError(t, err)
+	require.NoError(t, s.tagDAO.ReplaceTags(ctx, draft, true, []string{"go", "java"}))
+	require.NoError(t, s.tagDAO.ReplaceTags(ctx, single, true, []string{"go", "java"}))
+
+	pubs, err := s.tagDAO.GetPubByTag(ctx, "go", 0, 10)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int64{both, single}, pubIds(pubs))
+	for _, pub := range pubs {
+		assert.NotEmpty(t, pub.Title)
+	}
+	pubs, err = s.tagDAO.GetPubByTag(ctx, "go", 1, 10)
+	require.NoError(t, err)
+	assert.Len(t, pubs, 1)
+	pubs, err = s.tagDAO.GetPubByTag(ctx, "java", 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pubs)
+
+	cnts, err := s.tagDAO.CountPubByTag(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []TagCount{{Name: "go", Cnt: 2}, {Name: "rust", Cnt: 1}}, cnts)
+	cnts, err = s.tagDAO.CountPubByTag(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []TagCount{{Name: "go", Cnt: 2}}, cnts)
+}
+
+func pubIds(arts []PublishedArticle) []int64 {
+	res := make([]int64, 0, len(arts))
+	for _, art := range arts {
+		res = append(res, art.Id)
+	}
+	return res
+}
+
+// newTagDB 标签和文章的关系总是在 MySQL 里面
+func newTagDB(t *testing.T) *gorm.DB {
+	db := newSqliteDB(t)
+	require.NoError(t, db.AutoMigrate(&Tag{}, &ArticleTag{}))
+	return db
+}
+
+func TestArticleTagGORMDAO(t *testing.T) {
+	suite.Run(t, &ArticleTagDAOSuite{
+		newDAOs: func(t *testing.T) (ArticleDAO, ArticleTagDAO) {
+			db := newTagDB(t)
+			return NewArticleGORMDAO(db), NewArticleTagGORMDAO(db)
+		},
+	})
+}
+
+func TestArticleTagS3DAO(t *testing.T) {
+	suite.Run(t, &ArticleTagDAOSuite{
+		newDAOs: func(t *testing.T) (ArticleDAO, ArticleTagDAO) {
+			store, err := objectstore.NewLocalStore(t.TempDir())
+			require.NoError(t, err)
+			db := newTagDB(t)
+			return NewArticleS3DAO(db, store, "articles/"), NewArticleTagS3DAO(db)
+		},
+	})
+}
+
+// TestArticleTagMongoDBDAO 和 TestMongoDBArticleDAO 一样需要 WEBOOK_TEST_MONGO_URI
+func TestArticleTagMongoDBDAO(t *testing.T) {
+	uri := os.Getenv("WEBOOK_TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("没有设置 WEBOOK_TEST_MONGO_URI")
+	}
+	node, err := snowflake.NewNode(1)
+	require.NoError(t, err)
+	suite.Run(t, &ArticleTagDAOSuite{
+		newDAOs: func(t *testing.T) (ArticleDAO, ArticleTagDAO) {
+			mdb := newMongoDB(t, uri)
+			return NewMongoDBArticleDAO(mdb, node), NewArticleTagMongoDBDAO(newTagDB(t), mdb)
+		},
+	})
+}
@@ -0,0 +1,112 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func (a *ArticleGORMDAO) Delete(ctx context.Context, uid int64, id int64) error {
+	return a.setDeletedAt(ctx, &PublishedArticle{}, uid, id, time.Now().UnixMilli())
+}
+
+func (a *ArticleGORMDAO) Restore(ctx context.Context, uid int64, id int64) error {
+	return a.setDeletedAt(ctx, &PublishedArticle{}, uid, id, 0)
+}
+
+// setDeletedAt 同时修改制作库和线上库，pub 是线上库的表，ArticleS3DAO 用的是另外一张表。
+// 删除和恢复都不改 utime，恢复之后文章还在原来的位置
+func (a *ArticleGORMDAO) setDeletedAt(ctx context.Context, pub any, uid int64, id int64, deletedAt int64) error {
+	return a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		cond := "deleted_at = 0"
+		if deletedAt == 0 {
+			cond = "deleted_at > 0"
+		}
+		res := tx.Model(&Article{}).
+			Where("id = ? AND author_id = ?", id, uid).
+			Where(cond).
+			Update("deleted_at", deletedAt)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected != 1 {
+			// ID 不对，创作者不对，或者已经删除（恢复）过了
+			return ErrRecordNotFound
+		}
+		// 没有发表过的文章线上库里面没有数据
+		return tx.Model(pub).
+			Where("id = ?", id).
+			Update("deleted_at", deletedAt).Error
+	})
+}
+
+func (a *ArticleGORMDAO) GetDeletedByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]Article, error) {
+	var arts []Article
+	err := a.db.WithContext(ctx).
+		Where("author_id = ? AND deleted_at > 0", uid).
+		Order("deleted_at DESC").
+		Offset(offset).Limit(limit).
+		Find(&arts).Error
+	return arts, err
+}
+
+func (a *ArticleGORMDAO) ListDeletedBefore(ctx context.Context, before int64, limit int) ([]Article, error) {
+	var arts []Article
+	err := a.db.WithContext(ctx).
+		Where("deleted_at <> 0 AND deleted_at < ?", before).
+		Order("deleted_at ASC").
+		Limit(limit).
+		Find(&arts).Error
+	return arts, err
+}
+
+func (a *ArticleGORMDAO) ClaimPurge(ctx context.Context, id int64) error {
+	return a.claimPurge(ctx, &PublishedArticle{}, id)
+}
+
+// claimPurge 带上 deleted_at > 0 作为条件，和作者恢复文章并发的时候只有一个会成功
+func (a *ArticleGORMDAO) claimPurge(ctx context.Context, pub any, id int64) error {
+	return a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Article{}).
+			Where("id = ? AND deleted_at > 0", id).
+			Update("deleted_at", gorm.Expr("-deleted_at"))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			// 上一次清理到一半失败了，这一次接着清理
+			var cnt int64
+			err := tx.Model(&Article{}).
+				Where("id = ? AND deleted_at < 0", id).
+				Count(&cnt).Error
+			if err != nil {
+				return err
+			}
+			if cnt == 0 {
+				return ErrRecordNotFound
+			}
+		}
+		return tx.Model(pub).
+			Where("id = ? AND deleted_at > 0", id).
+			Update("deleted_at", gorm.Expr("-deleted_at")).Error
+	})
+}
+
+func (a *ArticleGORMDAO) Purge(ctx context.Context, id int64) error {
+	return a.purge(ctx, &PublishedArticle{}, id)
+}
+
+func (a *ArticleGORMDAO) purge(ctx context.Context, pub any, id int64) error {
+	return a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 只删 ClaimPurge 过的，作者已经不能恢复它了
+		res := tx.Where("id = ? AND deleted_at < 0", id).Delete(&Article{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+		return tx.Where("id = ?", id).Delete(pub).Error
+	})
+}
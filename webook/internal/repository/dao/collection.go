@@ -0,0 +1,188 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrDuplicateCollectionName = errors.New("收藏夹名字冲突")
+
+type CollectionDAO interface {
+	Insert(ctx context.Context, c Collection) (int64, error)
+	// Update 只能修改自己的收藏夹，找不到就返回 ErrRecordNotFound
+	Update(ctx context.Context, c Collection) error
+	// Delete 删除收藏夹以及里面的内容，同时扣减收藏数，返回被删掉的内容
+	Delete(ctx context.Context, uid int64, id int64) ([]UserCollectionBiz, error)
+	FindById(ctx context.Context, id int64) (Collection, error)
+	FindByUid(ctx context.Context, uid int64, offset int, limit int) ([]Collection, error)
+	// CountItems 每个收藏夹有多少条内容，空的收藏夹不在结果里面
+	CountItems(ctx context.Context, uid int64, cids []int64) (map[int64]int64, error)
+	// FindItems 收藏夹里面的内容，最近收藏的在前面，
+	// (utime, maxId) 是上一页的最后一条，maxId 为 0 表示从头开始
+	FindItems(ctx context.Context, uid int64, cid int64, utime int64, maxId int64, limit int) ([]UserCollectionBiz, error)
+	// MoveItem 把收藏的内容移动到别的收藏夹，收藏数不变
+	MoveItem(ctx context.Context, uid int64, biz string, bizId int64, cid int64) error
+}
+
+type CollectionGORMDAO struct {
+	db *gorm.DB
+}
+
+func NewCollectionGORMDAO(db *gorm.DB) CollectionDAO {
+	return &CollectionGORMDAO{
+		db: db,
+	}
+}
+
+func (c *CollectionGORMDAO) Insert(ctx context.Context, col Collection) (int64, error) {
+	now := time.Now().UnixMilli()
+	col.Ctime = now
+	col.Utime = now
+	err := c.db.WithContext(ctx).Create(&col).Error
+	return col.Id, c.translateErr(err)
+}
+
+func (c *CollectionGORMDAO) Update(ctx context.Context, col Collection) error {
+	res := c.db.WithContext(ctx).Model(&Collection{}).
+		Where("id = ? AND uid = ?", col.Id, col.Uid).
+		Updates(map[string]any{
+			"name":        col.Name,
+			"description": col.Description,
+			"utime":       time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return c.translateErr(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (c *CollectionGORMDAO) translateErr(err error) error {
+	if me, ok := err.(*mysql.MySQLError); ok {
+		const duplicateErr uint16 = 1062
+		if me.Number == duplicateErr {
+			return ErrDuplicateCollectionName
+		}
+	}
+	return err
+}
+
+func (c *CollectionGORMDAO) Delete(ctx context.Context, uid int64, id int64) ([]UserCollectionBiz, error) {
+	var items []UserCollectionBiz
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id = ? AND uid = ?", id, uid).Delete(&Collection{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("uid = ? AND cid = ?", uid, id).
+			Find(&items).Error
+		if err != nil || len(items) == 0 {
+			return err
+		}
+		err = tx.Where("uid = ? AND cid = ?", uid, id).Delete(&UserCollectionBiz{}).Error
+		if err != nil {
+			return err
+		}
+		now := time.Now().UnixMilli()
+		for _, item := range items {
+			err = tx.Model(&Interactive{}).
+				Where("biz = ? AND biz_id = ?", item.Biz, item.BizId).
+				Updates(map[string]any{
+					"collect_cnt": gorm.Expr("`collect_cnt` - 1"),
+					"utime":       now,
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return items, err
+}
+
+func (c *CollectionGORMDAO) FindById(ctx context.Context, id int64) (Collection, error) {
+	var res Collection
+	err := c.db.WithContext(ctx).Where("id = ?", id).First(&res).Error
+	return res, err
+}
+
+func (c *CollectionGORMDAO) FindByUid(ctx context.Context, uid int64, offset int, limit int) ([]Collection, error) {
+	var res []Collection
+	err := c.db.WithContext(ctx).
+		Where("uid = ?", uid).
+		Order("id DESC").
+		Offset(offset).Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (c *CollectionGORMDAO) CountItems(ctx context.Context, uid int64, cids []int64) (map[int64]int64, error) {
+	type cnt struct {
+		Cid int64
+		Cnt int64
+	}
+	var cnts []cnt
+	err := c.db.WithContext(ctx).Model(&UserCollectionBiz{}).
+		Select("cid, COUNT(*) AS cnt").
+		Where("uid = ? AND cid IN ?", uid, cids).
+		Group("cid").
+		Scan(&cnts).Error
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[int64]int64, len(cnts))
+	for _, val := range cnts {
+		res[val.Cid] = val.Cnt
+	}
+	return res, nil
+}
+
+func (c *CollectionGORMDAO) FindItems(ctx context.Context, uid int64, cid int64,
+	utime int64, maxId int64, limit int) ([]UserCollectionBiz, error) {
+	query := c.db.WithContext(ctx).Where("uid = ? AND cid = ?", uid, cid)
+	if maxId > 0 {
+		query = query.Where("utime < ? OR (utime = ? AND id < ?)", utime, utime, maxId)
+	}
+	var res []UserCollectionBiz
+	err := query.Order("utime DESC, id DESC").
+		Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (c *CollectionGORMDAO) MoveItem(ctx context.Context, uid int64, biz string, bizId int64, cid int64) error {
+	res := c.db.WithContext(ctx).Model(&UserCollectionBiz{}).
+		Where("uid = ? AND biz = ? AND biz_id = ?", uid, biz, bizId).
+		Updates(map[string]any{
+			"cid":   cid,
+			"utime": time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// Collection 收藏夹，收藏的内容在 UserCollectionBiz 里面，用 Cid 关联
+type Collection struct {
+	Id          int64  `gorm:"primaryKey,autoIncrement"`
+	Uid         int64  `gorm:"uniqueIndex:uid_name"`
+	Name        string `gorm:"type:varchar(128);uniqueIndex:uid_name"`
+	Description string `gorm:"type:varchar(1024)"`
+	Ctime       int64
+	Utime       int64
+}
@@ -0,0 +1,176 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// interactiveBizComment 评论的点赞数记录在 Interactive 里面，biz 就是 comment
+const interactiveBizComment = "comment"
+
+type CommentDAO interface {
+	Insert(ctx context.Context, c Comment) (int64, error)
+	FindById(ctx context.Context, id int64) (Comment, error)
+	// FindNewest 按照 ID 倒序查找根评论，maxId 为 0 表示从头开始
+	FindNewest(ctx context.Context, biz string, bizId int64, maxId int64, limit int) ([]Comment, error)
+	// FindHottest 按照点赞数倒序查找根评论，点赞数一样的 ID 大的在前面，
+	// (likeCnt, maxId) 是上一页最后一条评论，maxId 为 0 表示从头开始
+	FindHottest(ctx context.Context, biz string, bizId int64, likeCnt int64, maxId int64, limit int) ([]Comment, error)
+	// FindReplies 按照 ID 正序查找根评论下面的回复
+	FindReplies(ctx context.Context, rootId int64, minId int64, limit int) ([]Comment, error)
+	// CountReplies 统计每条根评论下面的回复数
+	CountReplies(ctx context.Context, rootIds []int64) (map[int64]int64, error)
+	// Delete 删除评论以及回复它的所有评论，返回被删除的评论 ID
+	Delete(ctx context.Context, id int64) ([]int64, error)
+}
+
+type CommentGORMDAO struct {
+	db *gorm.DB
+}
+
+func NewCommentGORMDAO(db *gorm.DB) CommentDAO {
+	return &CommentGORMDAO{
+		db: db,
+	}
+}
+
+func (c *CommentGORMDAO) Insert(ctx context.Context, cmt Comment) (int64, error) {
+	now := time.Now().UnixMilli()
+	cmt.Ctime = now
+	cmt.Utime = now
+	err := c.db.WithContext(ctx).Create(&cmt).Error
+	return cmt.Id, err
+}
+
+func (c *CommentGORMDAO) FindById(ctx context.Context, id int64) (Comment, error) {
+	var res Comment
+	err := c.withLikeCnt(ctx).
+		Where("comments.id = ?", id).
+		First(&res).Error
+	return res, err
+}
+
+func (c *CommentGORMDAO) FindNewest(ctx context.Context, biz string, bizId int64,
+	maxId int64, limit int) ([]Comment, error) {
+	query := c.withLikeCnt(ctx).
+		Where("comments.biz = ? AND comments.biz_id = ? AND comments.root_id = 0", biz, bizId)
+	if maxId > 0 {
+		query = query.Where("comments.id < ?", maxId)
+	}
+	var res []Comment
+	err := query.Order("comments.id DESC").
+		Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (c *CommentGORMDAO) FindHottest(ctx context.Context, biz string, bizId int64,
+	likeCnt int64, maxId int64, limit int) ([]Comment, error) {
+	query := c.withLikeCnt(ctx).
+		Where("comments.biz = ? AND comments.biz_id = ? AND comments.root_id = 0", biz, bizId)
+	if maxId > 0 {
+		query = query.Where("COALESCE(interactives.like_cnt, 0) < ? OR "+
+			"(COALESCE(interactives.like_cnt, 0) = ? AND comments.id < ?)",
+			likeCnt, likeCnt, maxId)
+	}
+	var res []Comment
+	err := query.Order("like_cnt DESC, comments.id DESC").
+		Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (c *CommentGORMDAO) FindReplies(ctx context.Context, rootId int64,
+	minId int64, limit int) ([]Comment, error) {
+	var res []Comment
+	err := c.withLikeCnt(ctx).
+		Where("comments.root_id = ? AND comments.id > ?", rootId, minId).
+		Order("comments.id ASC").
+		Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (c *CommentGORMDAO) CountReplies(ctx context.Context, rootIds []int64) (map[int64]int64, error) {
+	res := make(map[int64]int64, len(rootIds))
+	if len(rootIds) == 0 {
+		return res, nil
+	}
+	type replyCnt struct {
+		RootId int64
+		Cnt    int64
+	}
+	var cnts []replyCnt
+	err := c.db.WithContext(ctx).Model(&Comment{}).
+		Select("root_id, COUNT(*) AS cnt").
+		Where("root_id IN ?", rootIds).
+		Group("root_id").
+		Scan(&cnts).Error
+	for _, cnt := range cnts {
+		res[cnt.RootId] = cnt.Cnt
+	}
+	return res, err
+}
+
+func (c *CommentGORMDAO) Delete(ctx context.Context, id int64) ([]int64, error) {
+	var ids []int64
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var cmt Comment
+		err := tx.Where("id = ?", id).First(&cmt).Error
+		if err != nil {
+			return err
+		}
+		if cmt.RootId == 0 {
+			// 根评论，它下面的回复都要删掉
+			err = tx.Model(&Comment{}).Where("root_id = ?", id).
+				Pluck("id", &ids).Error
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		} else {
+			// 回复，一层一层找出回复它的评论
+			ids = []int64{id}
+			parents := ids
+			for len(parents) > 0 {
+				var children []int64
+				err = tx.Model(&Comment{}).
+					Where("root_id = ? AND parent_id IN ?", cmt.RootId, parents).
+					Pluck("id", &children).Error
+				if err != nil {
+					return err
+				}
+				ids = append(ids, children...)
+				parents = children
+			}
+		}
+		return tx.Where("id IN ?", ids).Delete(&Comment{}).Error
+	})
+	return ids, err
+}
+
+// withLikeCnt 点赞数在 Interactive 表里面，顺便查出来
+func (c *CommentGORMDAO) withLikeCnt(ctx context.Context) *gorm.DB {
+	return c.db.WithContext(ctx).Model(&Comment{}).
+		Select("comments.*, COALESCE(interactives.like_cnt, 0) AS like_cnt").
+		Joins("LEFT JOIN interactives ON interactives.biz = ? AND interactives.biz_id = comments.id",
+			interactiveBizComment)
+}
+
+type Comment struct {
+	Id  int64 `gorm:"primaryKey,autoIncrement"`
+	Uid int64 `gorm:"index"`
+	// 查找某篇文章的根评论
+	Biz   string `gorm:"type:varchar(128);index:biz_type_id_root"`
+	BizId int64  `gorm:"index:biz_type_id_root"`
+	// 根评论是 0，查找根评论下面的回复
+	RootId   int64 `gorm:"index:biz_type_id_root;index"`
+	ParentId int64
+	Content  string `gorm:"type:varchar(4096)"`
+	// LikeCnt 只读，从 Interactive 表里面关联查询出来
+	LikeCnt int64 `gorm:"->;-:migration"`
+	Ctime   int64
+	Utime   int64
+}
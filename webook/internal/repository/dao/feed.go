@@ -0,0 +1,108 @@
+package dao
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FeedDAO interface {
+	// InsertOutbox 每篇发表的文章都会进作者的发件箱，已经存在就什么都不做
+	InsertOutbox(ctx context.Context, box FeedOutbox) error
+	// InsertInboxes 推模型，把文章放进粉丝的收件箱，已经存在就什么都不做
+	InsertInboxes(ctx context.Context, boxes []FeedInbox) error
+	// DeleteByAid 文章撤回了，从所有的收件箱和发件箱里面删掉
+	DeleteByAid(ctx context.Context, aid int64) error
+	// FindInbox 只返回 authorIds 的文章，按照 (ctime, aid) 倒序，
+	// (ctime, maxAid) 是上一页的最后一条，maxAid 为 0 表示从头开始
+	FindInbox(ctx context.Context, uid int64, authorIds []int64, ctime int64, maxAid int64, limit int) ([]FeedInbox, error)
+	// FindOutbox 拉模型，直接查作者的发件箱，排序和游标同 FindInbox
+	FindOutbox(ctx context.Context, authorIds []int64, ctime int64, maxAid int64, limit int) ([]FeedOutbox, error)
+}
+
+type FeedGORMDAO struct {
+	db *gorm.DB
+}
+
+func NewFeedGORMDAO(db *gorm.DB) FeedDAO {
+	return &FeedGORMDAO{
+		db: db,
+	}
+}
+
+func (f *FeedGORMDAO) InsertOutbox(ctx context.Context, box FeedOutbox) error {
+	return f.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&box).Error
+}
+
+func (f *FeedGORMDAO) InsertInboxes(ctx context.Context, boxes []FeedInbox) error {
+	if len(boxes) == 0 {
+		return nil
+	}
+	return f.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&boxes).Error
+}
+
+func (f *FeedGORMDAO) DeleteByAid(ctx context.Context, aid int64) error {
+	return f.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("aid = ?", aid).Delete(&FeedOutbox{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("aid = ?", aid).Delete(&FeedInbox{}).Error
+	})
+}
+
+func (f *FeedGORMDAO) FindInbox(ctx context.Context, uid int64, authorIds []int64,
+	ctime int64, maxAid int64, limit int) ([]FeedInbox, error) {
+	if len(authorIds) == 0 {
+		return nil, nil
+	}
+	query := f.db.WithContext(ctx).
+		Where("uid = ? AND author_id IN ?", uid, authorIds)
+	var res []FeedInbox
+	err := f.page(query, ctime, maxAid, limit).Find(&res).Error
+	return res, err
+}
+
+func (f *FeedGORMDAO) FindOutbox(ctx context.Context, authorIds []int64,
+	ctime int64, maxAid int64, limit int) ([]FeedOutbox, error) {
+	if len(authorIds) == 0 {
+		return nil, nil
+	}
+	query := f.db.WithContext(ctx).
+		Where("author_id IN ?", authorIds)
+	var res []FeedOutbox
+	err := f.page(query, ctime, maxAid, limit).Find(&res).Error
+	return res, err
+}
+
+func (f *FeedGORMDAO) page(query *gorm.DB, ctime int64, maxAid int64, limit int) *gorm.DB {
+	if maxAid > 0 {
+		query = query.Where("ctime < ? OR (ctime = ? AND aid < ?)", ctime, ctime, maxAid)
+	}
+	return query.Order("ctime DESC, aid DESC").Limit(limit)
+}
+
+// FeedInbox 收件箱，推模型下每个粉丝都有一份
+type FeedInbox struct {
+	Id  int64 `gorm:"primaryKey,autoIncrement"`
+	Uid int64 `gorm:"uniqueIndex:uid_aid;index:uid_ctime,priority:1"`
+	// 撤回的时候按照文章删除
+	Aid      int64 `gorm:"uniqueIndex:uid_aid;index"`
+	AuthorId int64
+	// Ctime 文章发表的时间
+	Ctime int64 `gorm:"index:uid_ctime,priority:2"`
+}
+
+// FeedOutbox 发件箱，拉模型下直接查大 V 的发件箱
+type FeedOutbox struct {
+	Id       int64 `gorm:"primaryKey,autoIncrement"`
+	AuthorId int64 `gorm:"index:author_ctime,priority:1"`
+	Aid      int64 `gorm:"uniqueIndex"`
+	// Ctime 文章发表的时间
+	Ctime int64 `gorm:"index:author_ctime,priority:2"`
+}
@@ -0,0 +1,210 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	followStatusInactive uint8 = iota
+	followStatusActive
+)
+
+type FollowDAO interface {
+	// Follow 关注，返回关注状态是否真的发生了变化，已经关注过了就是 false
+	Follow(ctx context.Context, follower int64, followee int64) (bool, error)
+	// CancelFollow 取消关注，返回关注状态是否真的发生了变化
+	CancelFollow(ctx context.Context, follower int64, followee int64) (bool, error)
+	GetRelation(ctx context.Context, follower int64, followee int64) (FollowRelation, error)
+	// FindFollowers 关注 followee 的人，最近关注的在前面，
+	// (utime, maxId) 是上一页的最后一条，maxId 为 0 表示从头开始
+	FindFollowers(ctx context.Context, followee int64, utime int64, maxId int64, limit int) ([]FollowRelation, error)
+	// FindFollowees follower 关注的人，排序和游标同 FindFollowers
+	FindFollowees(ctx context.Context, follower int64, utime int64, maxId int64, limit int) ([]FollowRelation, error)
+	GetStatistics(ctx context.Context, uid int64) (FollowStatistics, error)
+	// ScanFollowers 按照 ID 正序遍历 followee 的粉丝，minId 是上一批的最后一条
+	ScanFollowers(ctx context.Context, followee int64, minId int64, limit int) ([]FollowRelation, error)
+	// FindFolloweeStatistics follower 关注的人以及他们的粉丝数，最多 limit 个
+	FindFolloweeStatistics(ctx context.Context, follower int64, limit int) ([]FollowStatistics, error)
+}
+
+type FollowGORMDAO struct {
+	db *gorm.DB
+}
+
+func NewFollowGORMDAO(db *gorm.DB) FollowDAO {
+	return &FollowGORMDAO{
+		db: db,
+	}
+}
+
+func (f *FollowGORMDAO) Follow(ctx context.Context, follower int64, followee int64) (bool, error) {
+	return f.updateStatus(ctx, follower, followee, followStatusActive)
+}
+
+func (f *FollowGORMDAO) CancelFollow(ctx context.Context, follower int64, followee int64) (bool, error) {
+	return f.updateStatus(ctx, follower, followee, followStatusInactive)
+}
+
+// updateStatus 关系和计数在同一个事务里面更新，
+// 先锁住关系再判断状态有没有变化，保证重复请求不会让计数出错
+func (f *FollowGORMDAO) updateStatus(ctx context.Context, follower int64, followee int64,
+	status uint8) (bool, error) {
+	now := time.Now().UnixMilli()
+	changed := false
+	err := f.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rel FollowRelation
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("follower = ? AND followee = ?", follower, followee).
+			First(&rel).Error
+		switch err {
+		case nil:
+			if rel.Status == status {
+				return nil
+			}
+			err = tx.Model(&rel).Updates(map[string]any{
+				"status": status,
+				"utime":  now,
+			}).Error
+		case gorm.ErrRecordNotFound:
+			if status == followStatusInactive {
+				return nil
+			}
+			err = tx.Create(&FollowRelation{
+				Follower: follower,
+				Followee: followee,
+				Status:   status,
+				Ctime:    now,
+				Utime:    now,
+			}).Error
+		}
+		if err != nil {
+			return err
+		}
+		changed = true
+		delta := 1
+		if status == followStatusInactive {
+			delta = -1
+		}
+		err = f.incrStatistics(tx, follower, "followees", delta, now)
+		if err != nil {
+			return err
+		}
+		return f.incrStatistics(tx, followee, "followers", delta, now)
+	})
+	return changed, err
+}
+
+func (f *FollowGORMDAO) incrStatistics(tx *gorm.DB, uid int64, field string, delta int, now int64) error {
+	stat := FollowStatistics{
+		Uid:   uid,
+		Ctime: now,
+		Utime: now,
+	}
+	if field == "followers" {
+		stat.Followers = int64(delta)
+	} else {
+		stat.Followees = int64(delta)
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "uid"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			field:   gorm.Expr("`"+field+"` + ?", delta),
+			"utime": now,
+		}),
+	}).Create(&stat).Error
+}
+
+func (f *FollowGORMDAO) GetRelation(ctx context.Context, follower int64, followee int64) (FollowRelation, error) {
+	var res FollowRelation
+	err := f.db.WithContext(ctx).
+		Where("follower = ? AND followee = ? AND status = ?",
+			follower, followee, followStatusActive).
+		First(&res).Error
+	return res, err
+}
+
+func (f *FollowGORMDAO) FindFollowers(ctx context.Context, followee int64,
+	utime int64, maxId int64, limit int) ([]FollowRelation, error) {
+	return f.findPage(ctx, "followee", followee, utime, maxId, limit)
+}
+
+func (f *FollowGORMDAO) FindFollowees(ctx context.Context, follower int64,
+	utime int64, maxId int64, limit int) ([]FollowRelation, error) {
+	return f.findPage(ctx, "follower", follower, utime, maxId, limit)
+}
+
+func (f *FollowGORMDAO) findPage(ctx context.Context, col string, uid int64,
+	utime int64, maxId int64, limit int) ([]FollowRelation, error) {
+	query := f.db.WithContext(ctx).
+		Where(col+" = ? AND status = ?", uid, followStatusActive)
+	if maxId > 0 {
+		query = query.Where("utime < ? OR (utime = ? AND id < ?)", utime, utime, maxId)
+	}
+	var res []FollowRelation
+	err := query.Order("utime DESC, id DESC").
+		Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (f *FollowGORMDAO) GetStatistics(ctx context.Context, uid int64) (FollowStatistics, error) {
+	var res FollowStatistics
+	err := f.db.WithContext(ctx).
+		Where("uid = ?", uid).
+		First(&res).Error
+	return res, err
+}
+
+func (f *FollowGORMDAO) ScanFollowers(ctx context.Context, followee int64,
+	minId int64, limit int) ([]FollowRelation, error) {
+	var res []FollowRelation
+	err := f.db.WithContext(ctx).
+		Where("followee = ? AND status = ? AND id > ?", followee, followStatusActive, minId).
+		Order("id ASC").
+		Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (f *FollowGORMDAO) FindFolloweeStatistics(ctx context.Context, follower int64,
+	limit int) ([]FollowStatistics, error) {
+	var res []FollowStatistics
+	err := f.db.WithContext(ctx).Model(&FollowRelation{}).
+		Select("follow_relations.followee AS uid, "+
+			"COALESCE(follow_statistics.followers, 0) AS followers").
+		Joins("LEFT JOIN follow_statistics ON follow_statistics.uid = follow_relations.followee").
+		Where("follow_relations.follower = ? AND follow_relations.status = ?",
+			follower, followStatusActive).
+		Limit(limit).
+		Scan(&res).Error
+	return res, err
+}
+
+// FollowRelation 关注关系，取消关注的时候只是把 Status 改成 0
+type FollowRelation struct {
+	Id int64 `gorm:"primaryKey,autoIncrement"`
+	// 查询我关注了谁
+	Follower int64 `gorm:"uniqueIndex:follower_followee;index:follower_status_utime,priority:1"`
+	// 查询谁关注了我
+	Followee int64 `gorm:"uniqueIndex:follower_followee;index:followee_status_utime,priority:1"`
+	Status   uint8 `gorm:"index:follower_status_utime,priority:2;index:followee_status_utime,priority:2"`
+	Ctime    int64
+	// Utime 也就是最近一次关注的时间
+	Utime int64 `gorm:"index:follower_status_utime,priority:3;index:followee_status_utime,priority:3"`
+}
+
+// FollowStatistics 关注数和粉丝数，和 Interactive 一样单独存一张表
+type FollowStatistics struct {
+	Id  int64 `gorm:"primaryKey,autoIncrement"`
+	Uid int64 `gorm:"uniqueIndex"`
+	// 粉丝数
+	Followers int64
+	// 关注数
+	Followees int64
+	Ctime     int64
+	Utime     int64
+}
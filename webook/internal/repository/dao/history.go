@@ -0,0 +1,79 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type HistoryRecordDAO interface {
+	// Upsert 同一个内容只保留一条记录，LastReadTime 只会往后更新，
+	// 所以消息乱序也不会把阅读时间改回去
+	Upsert(ctx context.Context, r HistoryRecord) error
+	// FindByUid 最近阅读的在前面，(lastReadTime, maxId) 是上一页的最后一条，maxId 为 0 表示从头开始
+	FindByUid(ctx context.Context, uid int64, lastReadTime int64, maxId int64, limit int) ([]HistoryRecord, error)
+	Delete(ctx context.Context, uid int64, biz string, bizId int64) error
+	DeleteByUid(ctx context.Context, uid int64) error
+}
+
+type HistoryRecordGORMDAO struct {
+	db *gorm.DB
+}
+
+func NewHistoryRecordGORMDAO(db *gorm.DB) HistoryRecordDAO {
+	return &HistoryRecordGORMDAO{
+		db: db,
+	}
+}
+
+func (h *HistoryRecordGORMDAO) Upsert(ctx context.Context, r HistoryRecord) error {
+	now := time.Now().UnixMilli()
+	r.Ctime = now
+	r.Utime = now
+	return h.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]any{
+			"last_read_time": gorm.Expr("CASE WHEN `last_read_time` < ? THEN ? ELSE `last_read_time` END",
+				r.LastReadTime, r.LastReadTime),
+			"utime": now,
+		}),
+	}).Create(&r).Error
+}
+
+func (h *HistoryRecordGORMDAO) FindByUid(ctx context.Context, uid int64,
+	lastReadTime int64, maxId int64, limit int) ([]HistoryRecord, error) {
+	query := h.db.WithContext(ctx).Where("uid = ?", uid)
+	if maxId > 0 {
+		query = query.Where("last_read_time < ? OR (last_read_time = ? AND id < ?)",
+			lastReadTime, lastReadTime, maxId)
+	}
+	var res []HistoryRecord
+	err := query.Order("last_read_time DESC, id DESC").
+		Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (h *HistoryRecordGORMDAO) Delete(ctx context.Context, uid int64, biz string, bizId int64) error {
+	return h.db.WithContext(ctx).
+		Where("uid = ? AND biz = ? AND biz_id = ?", uid, biz, bizId).
+		Delete(&HistoryRecord{}).Error
+}
+
+func (h *HistoryRecordGORMDAO) DeleteByUid(ctx context.Context, uid int64) error {
+	return h.db.WithContext(ctx).
+		Where("uid = ?", uid).
+		Delete(&HistoryRecord{}).Error
+}
+
+// HistoryRecord 阅读历史，一个用户对一个内容只有一条
+type HistoryRecord struct {
+	Id           int64  `gorm:"primaryKey,autoIncrement"`
+	Uid          int64  `gorm:"uniqueIndex:uid_biz_type_id;index:uid_read_time"`
+	BizId        int64  `gorm:"uniqueIndex:uid_biz_type_id"`
+	Biz          string `gorm:"type:varchar(128);uniqueIndex:uid_biz_type_id"`
+	LastReadTime int64  `gorm:"index:uid_read_time"`
+	Ctime        int64
+	Utime        int64
+}
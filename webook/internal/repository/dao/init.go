@@ -7,9 +7,26 @@ func InitTables(db *gorm.DB) error {
 	return db.AutoMigrate(&User{},
 		&Article{},
 		&PublishedArticle{},
+		// ArticleS3DAO 用的线上库
+		&PublishedArticleV2{},
+		&ArticleRevision{},
+		&ArticleReview{},
+		&Tag{},
+		&ArticleTag{},
 		&Interactive{},
 		&UserLikeBiz{},
 		&UserCollectionBiz{},
+		&InteractiveFlush{},
+		&Collection{},
+		&HistoryRecord{},
+		&Comment{},
+		&FollowRelation{},
+		&FollowStatistics{},
+		&FeedInbox{},
+		&FeedOutbox{},
+		&Job{},
+		&Upload{},
+		&UploadUsage{},
 		// &AsyncSms{},
 	)
 }
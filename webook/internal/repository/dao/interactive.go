@@ -12,14 +12,41 @@ type InteractiveDAO interface {
 	IncrReadCnt(ctx context.Context, biz string, bizId int64) error
 	BatchIncrReadCnt(ctx context.Context, bizs []string, bizIds []int64) error
 
-	InsertLikeInfo(ctx context.Context, biz string, id int64, uid int64) error
-	DeleteLikeInfo(ctx context.Context, biz string, id int64, uid int64) error
-	InsertCollectionBiz(ctx context.Context, cb UserCollectionBiz) error
+	// InsertLikeInfo 和 DeleteLikeInfo 只在点赞状态真的变化的时候才修改点赞数，
+	// 返回的 changed 代表状态有没有变化，重复点赞、重复取消都是 false
+	InsertLikeInfo(ctx context.Context, biz string, id int64, uid int64) (changed bool, err error)
+	DeleteLikeInfo(ctx context.Context, biz string, id int64, uid int64) (changed bool, err error)
+	// InsertCollectionBiz 已经收藏过的（不管在哪个收藏夹）什么都不做，返回 false
+	InsertCollectionBiz(ctx context.Context, cb UserCollectionBiz) (changed bool, err error)
+	// DeleteCollectionBiz 取消收藏，没有收藏过就返回 ErrRecordNotFound
+	DeleteCollectionBiz(ctx context.Context, biz string, id int64, uid int64) error
 	GetLikeInfo(ctx context.Context,
 		biz string, id int64, uid int64) (UserLikeBiz, error)
 	GetCollectInfo(ctx context.Context,
 		biz string, id int64, uid int64) (UserCollectionBiz, error)
+	// GetLikedBizIds 和 GetCollectedBizIds 返回 ids 里面 uid 点过赞（收藏过）的，列表页批量查询用
+	GetLikedBizIds(ctx context.Context, biz string, ids []int64, uid int64) ([]int64, error)
+	GetCollectedBizIds(ctx context.Context, biz string, ids []int64, uid int64) ([]int64, error)
+	// GetAllLikedBizIds 和 GetAllCollectedBizIds 返回 uid 点过赞（收藏过）的全部 id，最多 limit 个
+	GetAllLikedBizIds(ctx context.Context, biz string, uid int64, limit int) ([]int64, error)
+	GetAllCollectedBizIds(ctx context.Context, biz string, uid int64, limit int) ([]int64, error)
+	// GetLikesByUser 和 GetLikers 分别是 uid 的点赞记录和 bizId 收到的点赞记录，
+	// 最近点赞的在前面，(utime, maxId) 是上一页的最后一条，取消了的不算
+	GetLikesByUser(ctx context.Context, biz string, uid int64, utime int64, maxId int64, limit int) ([]UserLikeBiz, error)
+	GetLikers(ctx context.Context, biz string, bizId int64, utime int64, maxId int64, limit int) ([]UserLikeBiz, error)
 	Get(ctx context.Context, biz string, id int64) (Interactive, error)
+	// GetByIds 批量查询，没有记录的 id 不会出现在结果里面
+	GetByIds(ctx context.Context, biz string, ids []int64) ([]Interactive, error)
+	// DeleteByBiz 删除计数以及所有用户的点赞和收藏记录，资源被彻底删除的时候用
+	DeleteByBiz(ctx context.Context, biz string, id int64) error
+
+	// SetLikeStatus 只修改点赞记录，不动计数，写回模式下计数由 ApplyDeltas 合并写入。
+	// 返回的 changed 和 InsertLikeInfo 一样
+	SetLikeStatus(ctx context.Context, biz string, id int64, uid int64, liked bool) (changed bool, err error)
+	// ApplyDeltas 在一个事务里面把一批合并好的增量加到计数上。
+	// 同一个 biz 的 batch 和上一次写入的一样的话什么都不做，
+	// 写入之后、确认之前崩溃的话，重启之后同一批会再来一次
+	ApplyDeltas(ctx context.Context, biz string, batch string, deltas []Interactive) error
 }
 
 type GORMInteractiveDAO struct {
@@ -47,6 +74,14 @@ func (dao *GORMInteractiveDAO) Get(ctx context.Context, biz string, id int64) (I
 	return res, err
 }
 
+func (dao *GORMInteractiveDAO) GetByIds(ctx context.Context, biz string, ids []int64) ([]Interactive, error) {
+	var res []Interactive
+	err := dao.db.WithContext(ctx).
+		Where("biz = ? AND biz_id IN ?", biz, ids).
+		Find(&res).Error
+	return res, err
+}
+
 func (dao *GORMInteractiveDAO) GetLikeInfo(ctx context.Context,
 	biz string, id int64, uid int64) (UserLikeBiz, error) {
 	var res UserLikeBiz
@@ -66,16 +101,85 @@ func (dao *GORMInteractiveDAO) GetCollectInfo(ctx context.Context,
 	return res, err
 }
 
+func (dao *GORMInteractiveDAO) GetLikedBizIds(ctx context.Context,
+	biz string, ids []int64, uid int64) ([]int64, error) {
+	var res []int64
+	err := dao.db.WithContext(ctx).Model(&UserLikeBiz{}).
+		Where("uid = ? AND biz = ? AND biz_id IN ? AND status = ?", uid, biz, ids, 1).
+		Pluck("biz_id", &res).Error
+	return res, err
+}
+
+func (dao *GORMInteractiveDAO) GetCollectedBizIds(ctx context.Context,
+	biz string, ids []int64, uid int64) ([]int64, error) {
+	var res []int64
+	err := dao.db.WithContext(ctx).Model(&UserCollectionBiz{}).
+		Where("uid = ? AND biz = ? AND biz_id IN ?", uid, biz, ids).
+		Pluck("biz_id", &res).Error
+	return res, err
+}
+
+func (dao *GORMInteractiveDAO) GetAllLikedBizIds(ctx context.Context,
+	biz string, uid int64, limit int) ([]int64, error) {
+	var res []int64
+	err := dao.db.WithContext(ctx).Model(&UserLikeBiz{}).
+		Where("uid = ? AND biz = ? AND status = ?", uid, biz, 1).
+		Limit(limit).
+		Pluck("biz_id", &res).Error
+	return res, err
+}
+
+func (dao *GORMInteractiveDAO) GetAllCollectedBizIds(ctx context.Context,
+	biz string, uid int64, limit int) ([]int64, error) {
+	var res []int64
+	err := dao.db.WithContext(ctx).Model(&UserCollectionBiz{}).
+		Where("uid = ? AND biz = ?", uid, biz).
+		Limit(limit).
+		Pluck("biz_id", &res).Error
+	return res, err
+}
+
+func (dao *GORMInteractiveDAO) GetLikesByUser(ctx context.Context, biz string,
+	uid int64, utime int64, maxId int64, limit int) ([]UserLikeBiz, error) {
+	var res []UserLikeBiz
+	err := likePage(dao.db.WithContext(ctx).Where("uid = ? AND biz = ?", uid, biz),
+		utime, maxId, limit).Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMInteractiveDAO) GetLikers(ctx context.Context, biz string,
+	bizId int64, utime int64, maxId int64, limit int) ([]UserLikeBiz, error) {
+	var res []UserLikeBiz
+	err := likePage(dao.db.WithContext(ctx).Where("biz = ? AND biz_id = ?", biz, bizId),
+		utime, maxId, limit).Find(&res).Error
+	return res, err
+}
+
+// likePage 点赞记录的游标分页，取消点赞只是把 status 改成 0，要过滤掉
+func likePage(db *gorm.DB, utime int64, maxId int64, limit int) *gorm.DB {
+	query := db.Where("status = ?", 1)
+	if maxId > 0 {
+		query = query.Where("utime < ? OR (utime = ? AND id < ?)", utime, utime, maxId)
+	}
+	return query.Order("utime DESC, id DESC").Limit(limit)
+}
+
 func (dao *GORMInteractiveDAO) InsertCollectionBiz(ctx context.Context,
-	cb UserCollectionBiz) error {
+	cb UserCollectionBiz) (bool, error) {
 	now := time.Now().UnixMilli()
 	cb.Ctime = now
 	cb.Utime = now
-	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		err := tx.Create(&cb).Error
-		if err != nil {
-			return err
+	var changed bool
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&cb)
+		if res.Error != nil {
+			return res.Error
+		}
+		// 已经收藏过了，重复收藏不能再加收藏数
+		if res.RowsAffected == 0 {
+			return nil
 		}
+		changed = true
 		return tx.WithContext(ctx).Clauses(clause.OnConflict{
 			DoUpdates: clause.Assignments(map[string]interface{}{
 				"collect_cnt": gorm.Expr("`collect_cnt` + 1"),
@@ -89,26 +193,39 @@ func (dao *GORMInteractiveDAO) InsertCollectionBiz(ctx context.Context,
 			Utime:      now,
 		}).Error
 	})
+	return changed, err
 }
 
-func (dao *GORMInteractiveDAO) InsertLikeInfo(ctx context.Context,
+func (dao *GORMInteractiveDAO) DeleteCollectionBiz(ctx context.Context,
 	biz string, id int64, uid int64) error {
 	now := time.Now().UnixMilli()
 	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		err := tx.Clauses(clause.OnConflict{
-			DoUpdates: clause.Assignments(map[string]interface{}{
-				"utime":  now,
-				"status": 1,
-			}),
-		}).Create(&UserLikeBiz{
-			Uid:    uid,
-			Biz:    biz,
-			BizId:  id,
-			Status: 1,
-			Utime:  now,
-			Ctime:  now,
-		}).Error
-		if err != nil {
+		res := tx.Where("uid = ? AND biz_id = ? AND biz = ?", uid, id, biz).
+			Delete(&UserCollectionBiz{})
+		if res.Error != nil {
+			return res.Error
+		}
+		// 没有删掉记录就不能扣减，不然重复取消收藏会把计数减成负数
+		if res.RowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+		return tx.Model(&Interactive{}).
+			Where("biz = ? AND biz_id = ?", biz, id).
+			Updates(map[string]interface{}{
+				"collect_cnt": gorm.Expr("`collect_cnt` - 1"),
+				"utime":       now,
+			}).Error
+	})
+}
+
+func (dao *GORMInteractiveDAO) InsertLikeInfo(ctx context.Context,
+	biz string, id int64, uid int64) (bool, error) {
+	now := time.Now().UnixMilli()
+	var changed bool
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		changed, err = dao.upsertLike(tx, biz, id, uid, now)
+		if err != nil || !changed {
 			return err
 		}
 		return tx.WithContext(ctx).Clauses(clause.OnConflict{
@@ -124,19 +241,17 @@ func (dao *GORMInteractiveDAO) InsertLikeInfo(ctx context.Context,
 			Utime:   now,
 		}).Error
 	})
+	return changed, err
 }
 
 func (dao *GORMInteractiveDAO) DeleteLikeInfo(ctx context.Context,
-	biz string, id int64, uid int64) error {
+	biz string, id int64, uid int64) (bool, error) {
 	now := time.Now().UnixMilli()
-	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		err := tx.Model(&UserLikeBiz{}).
-			Where("uid=? AND biz_id = ? AND biz=?", uid, id, biz).
-			Updates(map[string]interface{}{
-				"utime":  now,
-				"status": 0,
-			}).Error
-		if err != nil {
+	var changed bool
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		changed, err = dao.cancelLike(tx, biz, id, uid, now)
+		if err != nil || !changed {
 			return err
 		}
 		return tx.Model(&Interactive{}).
@@ -146,6 +261,115 @@ func (dao *GORMInteractiveDAO) DeleteLikeInfo(ctx context.Context,
 				"utime":    now,
 			}).Error
 	})
+	return changed, err
+}
+
+func (dao *GORMInteractiveDAO) SetLikeStatus(ctx context.Context,
+	biz string, id int64, uid int64, liked bool) (bool, error) {
+	now := time.Now().UnixMilli()
+	db := dao.db.WithContext(ctx)
+	if liked {
+		return dao.upsertLike(db, biz, id, uid, now)
+	}
+	return dao.cancelLike(db, biz, id, uid, now)
+}
+
+// upsertLike 带上 status 作为条件，已经点过赞的什么都不改，返回 false。
+// 同一个用户并发点赞的时候，只有一个能改成功或者插入成功
+func (dao *GORMInteractiveDAO) upsertLike(db *gorm.DB, biz string, id int64, uid int64, now int64) (bool, error) {
+	res := db.Model(&UserLikeBiz{}).
+		Where("uid = ? AND biz_id = ? AND biz = ? AND status = ?", uid, id, biz, 0).
+		Updates(map[string]interface{}{
+			"utime":  now,
+			"status": 1,
+		})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected > 0 {
+		return true, nil
+	}
+	// 没有取消过的记录，要么是第一次点赞，要么已经点过赞了
+	res = db.Clauses(clause.OnConflict{DoNothing: true}).Create(&UserLikeBiz{
+		Uid:    uid,
+		Biz:    biz,
+		BizId:  id,
+		Status: 1,
+		Utime:  now,
+		Ctime:  now,
+	})
+	return res.RowsAffected > 0, res.Error
+}
+
+// cancelLike 取消点赞不删除记录，只把 status 改成 0。没有点过赞的返回 false
+func (dao *GORMInteractiveDAO) cancelLike(db *gorm.DB, biz string, id int64, uid int64, now int64) (bool, error) {
+	res := db.Model(&UserLikeBiz{}).
+		Where("uid = ? AND biz_id = ? AND biz = ? AND status = ?", uid, id, biz, 1).
+		Updates(map[string]interface{}{
+			"utime":  now,
+			"status": 0,
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (dao *GORMInteractiveDAO) ApplyDeltas(ctx context.Context,
+	biz string, batch string, deltas []Interactive) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 先保证有这一行，再加锁读，
+		// 多个实例同时写同一批的时候，后面的会等前面的提交，然后发现已经写过了
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&InteractiveFlush{Biz: biz, Utime: now}).Error
+		if err != nil {
+			return err
+		}
+		var flush InteractiveFlush
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("biz = ?", biz).First(&flush).Error
+		if err != nil {
+			return err
+		}
+		if flush.Batch == batch {
+			return nil
+		}
+		for _, d := range deltas {
+			err = tx.Clauses(clause.OnConflict{
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"read_cnt": gorm.Expr("`read_cnt` + ?", d.ReadCnt),
+					"like_cnt": gorm.Expr("`like_cnt` + ?", d.LikeCnt),
+					"utime":    now,
+				}),
+			}).Create(&Interactive{
+				Biz:     biz,
+				BizId:   d.BizId,
+				ReadCnt: d.ReadCnt,
+				LikeCnt: d.LikeCnt,
+				Ctime:   now,
+				Utime:   now,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Model(&InteractiveFlush{}).
+			Where("biz = ?", biz).
+			Updates(map[string]interface{}{
+				"batch": batch,
+				"utime": now,
+			}).Error
+	})
+}
+
+func (dao *GORMInteractiveDAO) DeleteByBiz(ctx context.Context, biz string, id int64) error {
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, entity := range []any{&UserLikeBiz{}, &UserCollectionBiz{}, &Interactive{}} {
+			err := tx.Where("biz = ? AND biz_id = ?", biz, id).Delete(entity).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func NewGORMInteractiveDAO(db *gorm.DB) InteractiveDAO {
@@ -169,13 +393,14 @@ func (dao *GORMInteractiveDAO) IncrReadCnt(ctx context.Context, biz string, bizI
 }
 
 // Status表示一个用户对一个帖子的点赞
+// idx_uid_biz_utime 查用户点过赞的，idx_biz_id_utime 查谁点了赞，都按照点赞时间倒序
 type UserLikeBiz struct {
 	Id     int64  `gorm:"primaryKey,autoIncrement"`
-	Uid    int64  `gorm:"uniqueIndex:uid_biz_type_id"`
-	BizId  int64  `gorm:"uniqueIndex:uid_biz_type_id"`
-	Biz    string `gorm:"type:varchar(128);uniqueIndex:uid_biz_type_id"`
+	Uid    int64  `gorm:"uniqueIndex:uid_biz_type_id;index:idx_uid_biz_utime,priority:1"`
+	BizId  int64  `gorm:"uniqueIndex:uid_biz_type_id;index:idx_biz_id_utime,priority:2"`
+	Biz    string `gorm:"type:varchar(128);uniqueIndex:uid_biz_type_id;index:idx_uid_biz_utime,priority:2;index:idx_biz_id_utime,priority:1"`
 	Status int
-	Utime  int64
+	Utime  int64 `gorm:"index:idx_uid_biz_utime,priority:3;index:idx_biz_id_utime,priority:3"`
 	Ctime  int64
 }
 
@@ -205,3 +430,11 @@ type Interactive struct {
 	Utime      int64
 	Ctime      int64
 }
+
+// InteractiveFlush 写回模式下每个 biz 最后一次写入数据库的增量批次，用来去重
+type InteractiveFlush struct {
+	Id    int64  `gorm:"primaryKey,autoIncrement"`
+	Biz   string `gorm:"type:varchar(128);uniqueIndex"`
+	Batch string `gorm:"type:varchar(256)"`
+	Utime int64
+}
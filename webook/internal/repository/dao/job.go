@@ -0,0 +1,151 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNoJobToPreempt = gorm.ErrRecordNotFound
+	// ErrJobNotHold 任务已经被别的节点抢走了，一般是心跳太久没有更新
+	ErrJobNotHold = errors.New("任务已经不属于当前节点")
+)
+
+const (
+	jobStatusWaiting = iota
+	jobStatusRunning
+	// jobStatusPaused 暂停的任务不会被调度，比如 cron 表达式不对
+	jobStatusPaused
+)
+
+//go:generate mockgen -source=./job.go -package=daomocks -destination=mocks/job.mock.go JobDAO
+type JobDAO interface {
+	// Upsert 按照名字注册任务，已经存在的任务只更新表达式和执行器，不影响调度状态。
+	// 表达式变了的话下一次执行的时间用新注册的 NextTime
+	Upsert(ctx context.Context, j Job) error
+	// Preempt 抢占一个到期的任务，或者一个心跳早于 reclaimBefore 的运行中的任务
+	Preempt(ctx context.Context, reclaimBefore int64) (Job, error)
+	// Heartbeat 更新心跳，version 对不上说明任务已经被别人抢走了
+	Heartbeat(ctx context.Context, id int64, version int64) error
+	// Release 释放任务，并且设置下一次执行的时间
+	Release(ctx context.Context, id int64, version int64, nextTime int64) error
+	// Pause 暂停任务，直到重新注册
+	Pause(ctx context.Context, id int64, version int64) error
+}
+
+type GORMJobDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMJobDAO(db *gorm.DB) JobDAO {
+	return &GORMJobDAO{
+		db: db,
+	}
+}
+
+func (g *GORMJobDAO) Upsert(ctx context.Context, j Job) error {
+	now := time.Now().UnixMilli()
+	j.Ctime = now
+	j.Utime = now
+	return g.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "name"}},
+		// 不用 clause.Assignments，它会按照列名排序。
+		// MySQL 是按照顺序赋值的，后面的赋值看到的是更新之后的值，所以 next_time 必须在 expression 前面
+		DoUpdates: clause.Set{
+			// 表达式变了，按照旧的表达式算出来的时间就不对了
+			{Column: clause.Column{Name: "next_time"}, Value: gorm.Expr(
+				"CASE WHEN expression <> ? THEN ? ELSE next_time END", j.Expression, j.NextTime)},
+			{Column: clause.Column{Name: "expression"}, Value: j.Expression},
+			{Column: clause.Column{Name: "executor"}, Value: j.Executor},
+			{Column: clause.Column{Name: "cfg"}, Value: j.Cfg},
+			// 暂停的任务重新注册之后恢复调度
+			{Column: clause.Column{Name: "status"}, Value: gorm.Expr(
+				"CASE WHEN status = ? THEN ? ELSE status END", jobStatusPaused, jobStatusWaiting)},
+		},
+	}).Create(&j).Error
+}
+
+// Preempt 用乐观锁抢占：先查出候选的任务，再带着 version 更新，
+// 更新不到说明被别的节点抢先了，那就再找下一个
+func (g *GORMJobDAO) Preempt(ctx context.Context, reclaimBefore int64) (Job, error) {
+	db := g.db.WithContext(ctx)
+	for {
+		now := time.Now().UnixMilli()
+		var j Job
+		err := db.Where("(status = ? AND next_time <= ?) OR (status = ? AND utime < ?)",
+			jobStatusWaiting, now, jobStatusRunning, reclaimBefore).
+			Order("next_time ASC").First(&j).Error
+		if err != nil {
+			return Job{}, err
+		}
+		res := db.Model(&Job{}).
+			Where("id = ? AND version = ?", j.Id, j.Version).
+			Updates(map[string]any{
+				"status":  jobStatusRunning,
+				"version": j.Version + 1,
+				"utime":   now,
+			})
+		if res.Error != nil {
+			return Job{}, res.Error
+		}
+		if res.RowsAffected == 1 {
+			j.Status = jobStatusRunning
+			j.Version++
+			j.Utime = now
+			return j, nil
+		}
+	}
+}
+
+func (g *GORMJobDAO) Heartbeat(ctx context.Context, id int64, version int64) error {
+	return g.updateHeld(ctx, id, version, map[string]any{
+		"utime": time.Now().UnixMilli(),
+	})
+}
+
+func (g *GORMJobDAO) Release(ctx context.Context, id int64, version int64, nextTime int64) error {
+	return g.updateHeld(ctx, id, version, map[string]any{
+		"status":    jobStatusWaiting,
+		"next_time": nextTime,
+		"utime":     time.Now().UnixMilli(),
+	})
+}
+
+func (g *GORMJobDAO) Pause(ctx context.Context, id int64, version int64) error {
+	return g.updateHeld(ctx, id, version, map[string]any{
+		"status": jobStatusPaused,
+		"utime":  time.Now().UnixMilli(),
+	})
+}
+
+func (g *GORMJobDAO) updateHeld(ctx context.Context, id int64, version int64, updates map[string]any) error {
+	res := g.db.WithContext(ctx).Model(&Job{}).
+		Where("id = ? AND version = ? AND status = ?", id, version, jobStatusRunning).
+		Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrJobNotHold
+	}
+	return nil
+}
+
+type Job struct {
+	Id         int64  `gorm:"primaryKey,autoIncrement"`
+	Name       string `gorm:"type:varchar(128);unique"`
+	Expression string
+	Executor   string
+	Cfg        string
+	Status     int `gorm:"index:idx_status_next_time"`
+	// Version 乐观锁，每次抢占加一
+	Version  int64
+	NextTime int64 `gorm:"index:idx_status_next_time"`
+	Ctime    int64
+	// Utime 运行中的任务，Utime 就是心跳时间
+	Utime int64
+}
@@ -11,51 +11,53 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// MongoDBArticleDAO 是用于与 MongoDB 交互的文章数据访问对象（DAO）。
-// 它提供了对文章进行增删改查的操作，并且支持同步操作。
+// MongoDBArticleDAO 是 ArticleDAO 的 MongoDB 实现，
+// 制作库和线上库分别是 articles 和 published_articles 两个集合，ID 由 Snowflake 生成。
+var (
+	// notDeleted 匹配不在回收站里面的文章。
+	// deleted_at 是 omitempty 的，没删除过的文档里面根本没有这个字段，所以不能直接比较 0
+	notDeleted = bson.E{Key: "deleted_at", Value: bson.M{"$in": bson.A{0, nil}}}
+	deleted    = bson.E{Key: "deleted_at", Value: bson.M{"$gt": 0}}
+	// claimed 匹配 ClaimPurge 过、正在清理的文章
+	claimed = bson.E{Key: "deleted_at", Value: bson.M{"$lt": 0}}
+)
+
 type MongoDBArticleDAO struct {
-	node    *snowflake.Node   // 用于生成唯一 ID 的 Snowflake 节点
-	col     *mongo.Collection // 文章集合
-	liveCol *mongo.Collection // 已发布文章集合
-}
-
-// GetByAuthor 根据作者 ID 获取文章列表。
-// 参数：
-//   - ctx: 上下文
-//   - uid: 作者 ID
-//   - offset: 分页偏移量
-//   - limit: 每页条数
-//
-// 返回值：
-//   - []Article: 文章列表
-//   - error: 错误信息
+	node    *snowflake.Node
+	col     *mongo.Collection
+	liveCol *mongo.Collection
+}
+
 func (m *MongoDBArticleDAO) GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]Article, error) {
-	panic("implement me")
+	cursor, err := m.col.Find(ctx, bson.D{bson.E{Key: "author_id", Value: uid}, notDeleted},
+		options.Find().
+			SetSort(bson.D{bson.E{Key: "utime", Value: -1}}).
+			SetSkip(int64(offset)).
+			SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	var res []Article
+	err = cursor.All(ctx, &res)
+	return res, err
 }
 
-// GetById 根据文章 ID 获取文章详情。
-// 参数：
-//   - ctx: 上下文
-//   - id: 文章 ID
-//
-// 返回值：
-//   - Article: 文章详情
-//   - error: 错误信息
 func (m *MongoDBArticleDAO) GetById(ctx context.Context, id int64) (Article, error) {
-	panic("implement me")
-}
-
-// PubDetail
-// GetPubById 根据文章 ID 获取已发布的文章详情。
-// 参数：
-//   - ctx: 上下文
-//   - id: 文章 ID
-//
-// 返回值：
-//   - PublishedArticle: 已发布的文章详情
-//   - error: 错误信息
+	var res Article
+	err := m.col.FindOne(ctx, bson.D{bson.E{Key: "id", Value: id}}).Decode(&res)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return Article{}, ErrRecordNotFound
+	}
+	return res, err
+}
+
 func (m *MongoDBArticleDAO) GetPubById(ctx context.Context, id int64) (PublishedArticle, error) {
-	panic("implement me")
+	var res PublishedArticle
+	err := m.liveCol.FindOne(ctx, bson.D{bson.E{Key: "id", Value: id}, notDeleted}).Decode(&res)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return PublishedArticle{}, ErrRecordNotFound
+	}
+	return res, err
 }
 
 func (m *MongoDBArticleDAO) Insert(ctx context.Context, art Article) (int64, error) {
@@ -69,13 +71,15 @@ func (m *MongoDBArticleDAO) Insert(ctx context.Context, art Article) (int64, err
 
 func (m *MongoDBArticleDAO) UpdateById(ctx context.Context, art Article) error {
 	now := time.Now().UnixMilli()
-	filter := bson.D{bson.E{"id", art.Id},
-		bson.E{"author_id", art.AuthorId}}
-	set := bson.D{bson.E{"$set", bson.M{
-		"title":   art.Title,
-		"content": art.Content,
-		"status":  art.Status,
-		"utime":   now,
+	filter := bson.D{bson.E{Key: "id", Value: art.Id},
+		bson.E{Key: "author_id", Value: art.AuthorId}, notDeleted}
+	set := bson.D{bson.E{Key: "$set", Value: bson.M{
+		"title":      art.Title,
+		"content":    art.Content,
+		"category":   art.Category,
+		"status":     art.Status,
+		"publish_at": art.PublishAt,
+		"utime":      now,
 	}}}
 	res, err := m.col.UpdateOne(ctx, filter, set)
 	if err != nil {
@@ -101,15 +105,22 @@ func (m *MongoDBArticleDAO) Sync(ctx context.Context, art Article) (int64, error
 	if err != nil {
 		return 0, err
 	}
-	art.Id = id
 	now := time.Now().UnixMilli()
-	art.Utime = now
-	// liveCol 是 INSERT or Update 语义
-	filter := bson.D{bson.E{"id", art.Id},
-		bson.E{"author_id", art.AuthorId}}
-	set := bson.D{bson.E{"$set", art},
-		bson.E{"$setOnInsert",
-			bson.D{bson.E{"ctime", now}}}}
+	// liveCol 是 INSERT or Update 语义。
+	// 字段要一个个列出来，因为 Article 的 bson 标签都是 omitempty，
+	// 直接 $set 整个结构体的话，清空了的分类之类的字段不会被覆盖掉
+	filter := bson.D{bson.E{Key: "id", Value: id},
+		bson.E{Key: "author_id", Value: art.AuthorId}}
+	set := bson.D{bson.E{Key: "$set", Value: bson.M{
+		"title":    art.Title,
+		"content":  art.Content,
+		"category": art.Category,
+		"status":   art.Status,
+		"utime":    now,
+	}}, bson.E{Key: "$setOnInsert", Value: bson.M{
+		"publish_at": art.PublishAt,
+		"ctime":      now,
+	}}}
 	_, err = m.liveCol.UpdateOne(ctx,
 		filter, set,
 		options.Update().SetUpsert(true))
@@ -118,22 +129,287 @@ func (m *MongoDBArticleDAO) Sync(ctx context.Context, art Article) (int64, error
 
 func (m *MongoDBArticleDAO) SyncStatus(ctx context.Context, uid int64, id int64, status uint8) error {
 	filter := bson.D{bson.E{Key: "id", Value: id},
-		bson.E{Key: "author_id", Value: uid}}
-	sets := bson.D{bson.E{Key: "$set",
-		Value: bson.D{bson.E{Key: "status", Value: status}}}}
+		bson.E{Key: "author_id", Value: uid}, notDeleted}
+	sets := bson.D{bson.E{Key: "$set", Value: bson.M{
+		"status": status,
+		"utime":  time.Now().UnixMilli(),
+	}}}
 	res, err := m.col.UpdateOne(ctx, filter, sets)
 	if err != nil {
 		return err
 	}
-	if res.ModifiedCount != 1 {
+	if res.MatchedCount != 1 {
 		return errors.New("ID 不对或者创作者不对")
 	}
 	_, err = m.liveCol.UpdateOne(ctx, filter, sets)
 	return err
 }
 
+func (m *MongoDBArticleDAO) GetCategories(ctx context.Context, uid int64) ([]string, error) {
+	vals, err := m.col.Distinct(ctx, "category",
+		bson.D{bson.E{Key: "author_id", Value: uid}, notDeleted})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, 0, len(vals))
+	for _, val := range vals {
+		if str, ok := val.(string); ok && str != "" {
+			res = append(res, str)
+		}
+	}
+	return res, nil
+}
+
+func (m *MongoDBArticleDAO) GetScheduledByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]Article, error) {
+	filter := bson.D{bson.E{Key: "author_id", Value: uid},
+		bson.E{Key: "status", Value: articleStatusScheduled}, notDeleted}
+	cursor, err := m.col.Find(ctx, filter, options.Find().
+		SetSort(bson.D{bson.E{Key: "publish_at", Value: 1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	var res []Article
+	err = cursor.All(ctx, &res)
+	return res, err
+}
+
+func (m *MongoDBArticleDAO) Reschedule(ctx context.Context, uid int64, id int64, publishAt int64) error {
+	filter := bson.D{bson.E{Key: "id", Value: id},
+		bson.E{Key: "author_id", Value: uid},
+		bson.E{Key: "status", Value: articleStatusScheduled}, notDeleted}
+	sets := bson.D{bson.E{Key: "$set", Value: bson.M{
+		"publish_at": publishAt,
+		"utime":      time.Now().UnixMilli(),
+	}}}
+	res, err := m.col.UpdateOne(ctx, filter, sets)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrScheduledArticleNotFound
+	}
+	return nil
+}
+
+func (m *MongoDBArticleDAO) CancelSchedule(ctx context.Context, uid int64, id int64) error {
+	filter := bson.D{bson.E{Key: "id", Value: id},
+		bson.E{Key: "author_id", Value: uid},
+		bson.E{Key: "status", Value: articleStatusScheduled}, notDeleted}
+	sets := bson.D{bson.E{Key: "$set", Value: bson.M{
+		"status":     articleStatusUnpublished,
+		"publish_at": 0,
+		"utime":      time.Now().UnixMilli(),
+	}}}
+	res, err := m.col.UpdateOne(ctx, filter, sets)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrScheduledArticleNotFound
+	}
+	return nil
+}
+
+// PreemptScheduled MongoDB 没有 SELECT FOR UPDATE，
+// 但是 FindOneAndUpdate 本身是原子的，效果一样
+func (m *MongoDBArticleDAO) PreemptScheduled(ctx context.Context) (Article, error) {
+	now := time.Now().UnixMilli()
+	endTime := now - scheduledPreemptInterval.Milliseconds()
+	filter := bson.D{
+		bson.E{Key: "status", Value: articleStatusScheduled},
+		bson.E{Key: "publish_at", Value: bson.M{"$lte": now}},
+		notDeleted,
+		bson.E{Key: "$or", Value: bson.A{
+			bson.M{"$expr": bson.M{"$lt": bson.A{"$utime", "$publish_at"}}},
+			bson.M{"utime": bson.M{"$lt": endTime}},
+		}},
+	}
+	var art Article
+	err := m.col.FindOneAndUpdate(ctx, filter,
+		bson.D{bson.E{Key: "$set", Value: bson.M{"utime": now}}},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{bson.E{Key: "publish_at", Value: 1}})).
+		Decode(&art)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return Article{}, ErrScheduledArticleNotFound
+	}
+	return art, err
+}
+
+func (m *MongoDBArticleDAO) ListPub(ctx context.Context, start int64, offset int, limit int) ([]PublishedArticle, error) {
+	filter := bson.D{bson.E{Key: "ctime", Value: bson.M{"$gt": start}},
+		bson.E{Key: "status", Value: articleStatusPublished}, notDeleted}
+	cursor, err := m.liveCol.Find(ctx, filter, options.Find().
+		SetSort(bson.D{bson.E{Key: "ctime", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	var res []PublishedArticle
+	err = cursor.All(ctx, &res)
+	return res, err
+}
+
+func (m *MongoDBArticleDAO) GetPubByAuthor(ctx context.Context, uid int64,
+	utime int64, maxId int64, limit int) ([]PublishedArticle, error) {
+	return m.pubPage(ctx, uid, utime, maxId, limit)
+}
+
+func (m *MongoDBArticleDAO) ListNewestPub(ctx context.Context,
+	utime int64, maxId int64, limit int) ([]PublishedArticle, error) {
+	return m.pubPage(ctx, 0, utime, maxId, limit)
+}
+
+// pubPage 线上库的游标分页，uid 为 0 的时候不限制作者
+func (m *MongoDBArticleDAO) pubPage(ctx context.Context, uid int64,
+	utime int64, maxId int64, limit int) ([]PublishedArticle, error) {
+	filter := bson.D{bson.E{Key: "status", Value: articleStatusPublished}, notDeleted}
+	if uid > 0 {
+		filter = append(filter, bson.E{Key: "author_id", Value: uid})
+	}
+	if maxId > 0 {
+		filter = append(filter, bson.E{Key: "$or", Value: bson.A{
+			bson.M{"utime": bson.M{"$lt": utime}},
+			bson.M{"utime": utime, "id": bson.M{"$lt": maxId}},
+		}})
+	}
+	cursor, err := m.liveCol.Find(ctx, filter, options.Find().
+		SetSort(bson.D{bson.E{Key: "utime", Value: -1}, bson.E{Key: "id", Value: -1}}).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	var res []PublishedArticle
+	err = cursor.All(ctx, &res)
+	return res, err
+}
+
+func (m *MongoDBArticleDAO) Delete(ctx context.Context, uid int64, id int64) error {
+	return m.setDeletedAt(ctx, uid, id, notDeleted, time.Now().UnixMilli())
+}
+
+func (m *MongoDBArticleDAO) Restore(ctx context.Context, uid int64, id int64) error {
+	return m.setDeletedAt(ctx, uid, id, deleted, 0)
+}
+
+func (m *MongoDBArticleDAO) setDeletedAt(ctx context.Context, uid int64, id int64,
+	cond bson.E, deletedAt int64) error {
+	sets := bson.D{bson.E{Key: "$set", Value: bson.M{"deleted_at": deletedAt}}}
+	res, err := m.col.UpdateOne(ctx, bson.D{bson.E{Key: "id", Value: id},
+		bson.E{Key: "author_id", Value: uid}, cond}, sets)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrRecordNotFound
+	}
+	_, err = m.liveCol.UpdateOne(ctx, bson.D{bson.E{Key: "id", Value: id}}, sets)
+	return err
+}
+
+func (m *MongoDBArticleDAO) GetDeletedByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]Article, error) {
+	cursor, err := m.col.Find(ctx, bson.D{bson.E{Key: "author_id", Value: uid}, deleted},
+		options.Find().
+			SetSort(bson.D{bson.E{Key: "deleted_at", Value: -1}}).
+			SetSkip(int64(offset)).
+			SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	var res []Article
+	err = cursor.All(ctx, &res)
+	return res, err
+}
+
+func (m *MongoDBArticleDAO) ListDeletedBefore(ctx context.Context, before int64, limit int) ([]Article, error) {
+	filter := bson.D{bson.E{Key: "deleted_at", Value: bson.M{"$ne": 0, "$lt": before}}}
+	cursor, err := m.col.Find(ctx, filter, options.Find().
+		SetSort(bson.D{bson.E{Key: "deleted_at", Value: 1}}).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	var res []Article
+	err = cursor.All(ctx, &res)
+	return res, err
+}
+
+// ClaimPurge 没有事务，先改制作库，恢复的时候也是先改制作库，所以两个只有一个会成功
+func (m *MongoDBArticleDAO) ClaimPurge(ctx context.Context, id int64) error {
+	negate := bson.D{bson.E{Key: "$mul", Value: bson.M{"deleted_at": -1}}}
+	res, err := m.col.UpdateOne(ctx, bson.D{bson.E{Key: "id", Value: id}, deleted}, negate)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		// 上一次清理到一半失败了，这一次接着清理
+		cnt, err := m.col.CountDocuments(ctx, bson.D{bson.E{Key: "id", Value: id}, claimed})
+		if err != nil {
+			return err
+		}
+		if cnt == 0 {
+			return ErrRecordNotFound
+		}
+	}
+	_, err = m.liveCol.UpdateOne(ctx, bson.D{bson.E{Key: "id", Value: id}, deleted}, negate)
+	return err
+}
+
+// Purge 没有事务，先删制作库，线上库删失败的话它也查不出来，因为 deleted_at 还在
+func (m *MongoDBArticleDAO) Purge(ctx context.Context, id int64) error {
+	res, err := m.col.DeleteOne(ctx, bson.D{bson.E{Key: "id", Value: id}, claimed})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrRecordNotFound
+	}
+	_, err = m.liveCol.DeleteOne(ctx, bson.D{bson.E{Key: "id", Value: id}})
+	return err
+}
+
 var _ ArticleDAO = &MongoDBArticleDAO{}
 
+// InitCollections 创建文章相关集合的索引，和 InitTables 一样在启动的时候调用。
+// 索引已经存在的话 CreateMany 什么也不做。
+func InitCollections(ctx context.Context, mdb *mongo.Database) error {
+	indexes := map[string][]mongo.IndexModel{
+		"articles": {
+			{Keys: bson.D{bson.E{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			// GetByAuthor
+			{Keys: bson.D{bson.E{Key: "author_id", Value: 1}, bson.E{Key: "utime", Value: -1}}},
+			{Keys: bson.D{bson.E{Key: "utime", Value: -1}}},
+			// PreemptScheduled
+			{Keys: bson.D{bson.E{Key: "status", Value: 1}, bson.E{Key: "publish_at", Value: 1}}},
+			// 回收站
+			{Keys: bson.D{bson.E{Key: "author_id", Value: 1}, bson.E{Key: "deleted_at", Value: -1}}},
+			{Keys: bson.D{bson.E{Key: "deleted_at", Value: 1}}},
+		},
+		"published_articles": {
+			{Keys: bson.D{bson.E{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			// GetPubByAuthor 和 ListNewestPub
+			{Keys: bson.D{bson.E{Key: "author_id", Value: 1}, bson.E{Key: "utime", Value: -1}}},
+			{Keys: bson.D{bson.E{Key: "utime", Value: -1}}},
+			// ListPub
+			{Keys: bson.D{bson.E{Key: "ctime", Value: -1}}},
+		},
+		"article_revisions": {
+			{Keys: bson.D{bson.E{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{bson.E{Key: "article_id", Value: 1}, bson.E{Key: "version", Value: -1}},
+				Options: options.Index().SetUnique(true)},
+		},
+	}
+	for name, models := range indexes {
+		if _, err := mdb.Collection(name).Indexes().CreateMany(ctx, models); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func NewMongoDBArticleDAO(mdb *mongo.Database, node *snowflake.Node) *MongoDBArticleDAO {
 	return &MongoDBArticleDAO{
 		node:    node,
@@ -141,3 +417,62 @@ func NewMongoDBArticleDAO(mdb *mongo.Database, node *snowflake.Node) *MongoDBArt
 		col:     mdb.Collection("articles"),
 	}
 }
+
+// MongoDBArticleRevisionDAO 是 ArticleRevisionDAO 的 MongoDB 实现，
+// 和 MongoDBArticleDAO 搭配使用，ID 同样由 Snowflake 生成。
+type MongoDBArticleRevisionDAO struct {
+	node *snowflake.Node
+	col  *mongo.Collection
+}
+
+var _ ArticleRevisionDAO = &MongoDBArticleRevisionDAO{}
+
+func NewMongoDBArticleRevisionDAO(mdb *mongo.Database, node *snowflake.Node) *MongoDBArticleRevisionDAO {
+	return &MongoDBArticleRevisionDAO{
+		node: node,
+		col:  mdb.Collection("article_revisions"),
+	}
+}
+
+func (m *MongoDBArticleRevisionDAO) Insert(ctx context.Context, rev ArticleRevision) (int64, error) {
+	var last ArticleRevision
+	err := m.col.FindOne(ctx, bson.D{bson.E{Key: "article_id", Value: rev.ArticleId}},
+		options.FindOne().SetSort(bson.D{bson.E{Key: "version", Value: -1}})).
+		Decode(&last)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, err
+	}
+	rev.Version = last.Version + 1
+	rev.Id = m.node.Generate().Int64()
+	rev.Ctime = time.Now().UnixMilli()
+	_, err = m.col.InsertOne(ctx, &rev)
+	return rev.Id, err
+}
+
+func (m *MongoDBArticleRevisionDAO) GetByArticle(ctx context.Context, aid int64, offset int, limit int) ([]ArticleRevision, error) {
+	cursor, err := m.col.Find(ctx, bson.D{bson.E{Key: "article_id", Value: aid}},
+		options.Find().
+			SetSort(bson.D{bson.E{Key: "version", Value: -1}}).
+			SetSkip(int64(offset)).
+			SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	var res []ArticleRevision
+	err = cursor.All(ctx, &res)
+	return res, err
+}
+
+func (m *MongoDBArticleRevisionDAO) GetById(ctx context.Context, id int64) (ArticleRevision, error) {
+	var res ArticleRevision
+	err := m.col.FindOne(ctx, bson.D{bson.E{Key: "id", Value: id}}).Decode(&res)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ArticleRevision{}, ErrRecordNotFound
+	}
+	return res, err
+}
+
+func (m *MongoDBArticleRevisionDAO) DeleteByArticle(ctx context.Context, aid int64) error {
+	_, err := m.col.DeleteMany(ctx, bson.D{bson.E{Key: "article_id", Value: aid}})
+	return err
+}
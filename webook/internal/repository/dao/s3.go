@@ -1,28 +1,32 @@
 package dao
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"strconv"
 	"time"
 
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/ecodeclub/ekit"
+	"basic-go/webook/pkg/objectstore"
+
+	"github.com/ecodeclub/ekit/slice"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+// ArticleS3DAO 制作库和 ArticleGORMDAO 一样，
+// 但是线上库的内容放在对象存储里面，MySQL 里只有 PublishedArticleV2 这些元数据。
+// 对象的 key 是 prefix 加上文章 ID。
 type ArticleS3DAO struct {
 	ArticleGORMDAO
-	oss *s3.S3
+	oss    objectstore.ObjectStore
+	prefix string
 }
 
 func (a *ArticleS3DAO) SyncStatus(ctx context.Context, uid int64, id int64, status uint8) error {
 	now := time.Now().UnixMilli()
 	err := a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		res := tx.Model(&Article{}).
-			Where("id = ? and author_id = ?", uid, id).
+			Where("id = ? AND author_id = ? AND deleted_at = 0", id, uid).
 			Updates(map[string]any{
 				"utime":  now,
 				"status": status,
@@ -34,7 +38,7 @@ func (a *ArticleS3DAO) SyncStatus(ctx context.Context, uid int64, id int64, stat
 			return errors.New("ID 不对或者创作者不对")
 		}
 		return tx.Model(&PublishedArticleV2{}).
-			Where("id = ?", uid).
+			Where("id = ?", id).
 			Updates(map[string]any{
 				"utime":  now,
 				"status": status,
@@ -43,12 +47,9 @@ func (a *ArticleS3DAO) SyncStatus(ctx context.Context, uid int64, id int64, stat
 	if err != nil {
 		return err
 	}
-	const statusPrivate = 3
-	if status == statusPrivate {
-		_, err = a.oss.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
-			Bucket: ekit.ToPtr[string]("webook-1314583317"),
-			Key:    ekit.ToPtr[string](strconv.FormatInt(id, 10)),
-		})
+	// 仅自己可见的文章，内容不需要留在线上
+	if status == articleStatusPrivate {
+		err = a.oss.Delete(ctx, a.key(id))
 	}
 	return err
 }
@@ -74,45 +75,138 @@ func (a *ArticleS3DAO) Sync(ctx context.Context, art Article) (int64, error) {
 			Id:       art.Id,
 			Title:    art.Title,
 			AuthorId: art.AuthorId,
+			Category: art.Category,
 			Ctime:    now,
 			Utime:    now,
 			Status:   art.Status,
 		}
-		pubArt.Ctime = now
-		pubArt.Utime = now
 		err = tx.Clauses(clause.OnConflict{
 			Columns: []clause.Column{{Name: "id"}},
 			DoUpdates: clause.Assignments(map[string]interface{}{
-				"title":  pubArt.Title,
-				"utime":  now,
-				"status": pubArt.Status,
+				"title":    pubArt.Title,
+				"category": pubArt.Category,
+				"utime":    now,
+				"status":   pubArt.Status,
 			}),
 		}).Create(&pubArt).Error
-		return err
+		if err != nil {
+			return err
+		}
+		// 放在事务里面，内容写失败的话元数据也会回滚
+		return a.oss.Put(ctx, a.key(id), []byte(art.Content), "text/plain;charset=utf-8")
 	})
 	if err != nil {
 		return 0, err
 	}
-	_, err = a.oss.PutObjectWithContext(ctx, &s3.PutObjectInput{
-		Bucket:      ekit.ToPtr[string]("webook-1314583317"),
-		Key:         ekit.ToPtr[string](strconv.FormatInt(art.Id, 10)),
-		Body:        bytes.NewReader([]byte(art.Content)),
-		ContentType: ekit.ToPtr[string]("text/plain;charset=utf-8"),
-	})
-	return id, err
+	return id, nil
 }
 
-func NewArticleS3DAO(db *gorm.DB, oss *s3.S3) *ArticleS3DAO {
-	return &ArticleS3DAO{ArticleGORMDAO: ArticleGORMDAO{db: db}, oss: oss}
+func (a *ArticleS3DAO) GetPubById(ctx context.Context, id int64) (PublishedArticle, error) {
+	var pubArt PublishedArticleV2
+	err := a.db.WithContext(ctx).
+		Where("id = ? AND deleted_at = 0", id).
+		First(&pubArt).Error
+	if err != nil {
+		return PublishedArticle{}, err
+	}
+	res := pubArt.toPublishedArticle()
+	if res.Status == articleStatusPrivate {
+		// 内容已经删掉了
+		return res, nil
+	}
+	content, err := a.oss.Get(ctx, a.key(id))
+	if err != nil {
+		return PublishedArticle{}, err
+	}
+	res.Content = string(content)
+	return res, nil
+}
+
+// ListPub 只返回元数据，不会去对象存储里面读内容
+func (a *ArticleS3DAO) ListPub(ctx context.Context, start int64, offset int, limit int) ([]PublishedArticle, error) {
+	var res []PublishedArticleV2
+	err := a.db.WithContext(ctx).
+		Where("ctime > ? AND status = ? AND deleted_at = 0", start, articleStatusPublished).
+		Order("ctime DESC").
+		Offset(offset).Limit(limit).
+		Find(&res).Error
+	return slice.Map(res, func(idx int, src PublishedArticleV2) PublishedArticle {
+		return src.toPublishedArticle()
+	}), err
+}
+
+// GetPubByAuthor 列表页只需要标题这些，不会去对象存储里面读内容
+func (a *ArticleS3DAO) GetPubByAuthor(ctx context.Context, uid int64,
+	utime int64, maxId int64, limit int) ([]PublishedArticle, error) {
+	var res []PublishedArticleV2
+	err := pubPage(a.db.WithContext(ctx), uid, utime, maxId, limit).Find(&res).Error
+	return slice.Map(res, func(idx int, src PublishedArticleV2) PublishedArticle {
+		return src.toPublishedArticle()
+	}), err
+}
+
+func (a *ArticleS3DAO) ListNewestPub(ctx context.Context,
+	utime int64, maxId int64, limit int) ([]PublishedArticle, error) {
+	var res []PublishedArticleV2
+	err := pubPage(a.db.WithContext(ctx), 0, utime, maxId, limit).Find(&res).Error
+	return slice.Map(res, func(idx int, src PublishedArticleV2) PublishedArticle {
+		return src.toPublishedArticle()
+	}), err
+}
+
+func (a *ArticleS3DAO) Delete(ctx context.Context, uid int64, id int64) error {
+	return a.setDeletedAt(ctx, &PublishedArticleV2{}, uid, id, time.Now().UnixMilli())
+}
+
+func (a *ArticleS3DAO) Restore(ctx context.Context, uid int64, id int64) error {
+	return a.setDeletedAt(ctx, &PublishedArticleV2{}, uid, id, 0)
+}
+
+func (a *ArticleS3DAO) ClaimPurge(ctx context.Context, id int64) error {
+	return a.claimPurge(ctx, &PublishedArticleV2{}, id)
+}
+
+// Purge 内容最后删，删失败了也只是在对象存储里面留下一点垃圾
+func (a *ArticleS3DAO) Purge(ctx context.Context, id int64) error {
+	err := a.purge(ctx, &PublishedArticleV2{}, id)
+	if err != nil {
+		return err
+	}
+	return a.oss.Delete(ctx, a.key(id))
+}
+
+func (a *ArticleS3DAO) key(id int64) string {
+	return a.prefix + strconv.FormatInt(id, 10)
+}
+
+var _ ArticleDAO = &ArticleS3DAO{}
+
+func NewArticleS3DAO(db *gorm.DB, oss objectstore.ObjectStore, prefix string) *ArticleS3DAO {
+	return &ArticleS3DAO{ArticleGORMDAO: ArticleGORMDAO{db: db}, oss: oss, prefix: prefix}
 }
 
 type PublishedArticleV2 struct {
 	Id    int64  `gorm:"primaryKey,autoIncrement" bson:"id,omitempty"`
 	Title string `gorm:"type=varchar(4096)" bson:"title,omitempty"`
 	// 我要根据创作者ID来查询
-	AuthorId int64 `gorm:"index" bson:"author_id,omitempty"`
-	Status   uint8 `bson:"status,omitempty"`
-	Ctime    int64 `bson:"ctime,omitempty"`
-	// 更新时间
-	Utime int64 `bson:"utime,omitempty"`
+	AuthorId int64  `gorm:"index" bson:"author_id,omitempty"`
+	Category string `gorm:"type:varchar(128)" bson:"category,omitempty"`
+	Status   uint8  `bson:"status,omitempty"`
+	Ctime    int64  `bson:"ctime,omitempty"`
+	// 更新时间，按照它分页
+	Utime     int64 `gorm:"index" bson:"utime,omitempty"`
+	DeletedAt int64 `gorm:"index" bson:"deleted_at,omitempty"`
+}
+
+func (p PublishedArticleV2) toPublishedArticle() PublishedArticle {
+	return PublishedArticle{
+		Id:        p.Id,
+		Title:     p.Title,
+		AuthorId:  p.AuthorId,
+		Category:  p.Category,
+		Status:    p.Status,
+		Ctime:     p.Ctime,
+		Utime:     p.Utime,
+		DeletedAt: p.DeletedAt,
+	}
 }
@@ -0,0 +1,163 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrUploadQuotaExceeded = errors.New("上传空间不足")
+	errDuplicateUpload     = errors.New("重复上传")
+)
+
+type UploadDAO interface {
+	// Insert 记录一次上传并且扣减空间，quota 是用户的总空间。
+	// 用户之前传过同样内容的文件的话，不会重复扣减，只更新 ctime，返回之前的记录
+	Insert(ctx context.Context, u Upload, quota int64) (Upload, error)
+	// CountByHash 有多少条上传记录是这个内容
+	CountByHash(ctx context.Context, hash string) (int64, error)
+	GetUsage(ctx context.Context, uid int64) (int64, error)
+	// FindBefore 按照 ID 遍历 ctime 之前的上传记录，afterId 是上一批的最后一条
+	FindBefore(ctx context.Context, ctime int64, afterId int64, limit int) ([]Upload, error)
+	// Delete 删除上传记录并且归还空间
+	Delete(ctx context.Context, id int64) error
+}
+
+type UploadGORMDAO struct {
+	db *gorm.DB
+}
+
+func NewUploadGORMDAO(db *gorm.DB) UploadDAO {
+	return &UploadGORMDAO{
+		db: db,
+	}
+}
+
+func (d *UploadGORMDAO) Insert(ctx context.Context, u Upload, quota int64) (Upload, error) {
+	now := time.Now().UnixMilli()
+	old, err := d.findByUidHash(ctx, u.Uid, u.Hash)
+	if err == nil {
+		// 重新上传说明马上又要用了，不更新的话清理的时候可能在文章保存之前就把它删掉
+		err = d.db.WithContext(ctx).Model(&Upload{}).
+			Where("id = ?", old.Id).
+			Update("ctime", now).Error
+		old.Ctime = now
+		return old, err
+	}
+	u.Ctime = now
+	err = d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&UploadUsage{Uid: u.Uid, Utime: u.Ctime}).Error
+		if err != nil {
+			return err
+		}
+		res := tx.Model(&UploadUsage{}).
+			Where("uid = ? AND used + ? <= ?", u.Uid, u.Size, quota).
+			Updates(map[string]any{
+				"used":  gorm.Expr("used + ?", u.Size),
+				"utime": u.Ctime,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrUploadQuotaExceeded
+		}
+		err = tx.Create(&u).Error
+		if me, ok := err.(*mysql.MySQLError); ok {
+			const duplicateErr uint16 = 1062
+			if me.Number == duplicateErr {
+				return errDuplicateUpload
+			}
+		}
+		return err
+	})
+	if errors.Is(err, errDuplicateUpload) {
+		// 并发上传了同一个文件，别人已经插入成功了，事务回滚之后空间也没有扣减
+		return d.findByUidHash(ctx, u.Uid, u.Hash)
+	}
+	return u, err
+}
+
+func (d *UploadGORMDAO) findByUidHash(ctx context.Context, uid int64, hash string) (Upload, error) {
+	var res Upload
+	err := d.db.WithContext(ctx).
+		Where("uid = ? AND hash = ?", uid, hash).
+		First(&res).Error
+	return res, err
+}
+
+func (d *UploadGORMDAO) CountByHash(ctx context.Context, hash string) (int64, error) {
+	var cnt int64
+	err := d.db.WithContext(ctx).Model(&Upload{}).
+		Where("hash = ?", hash).Count(&cnt).Error
+	return cnt, err
+}
+
+func (d *UploadGORMDAO) GetUsage(ctx context.Context, uid int64) (int64, error) {
+	var usage UploadUsage
+	err := d.db.WithContext(ctx).
+		Where("uid = ?", uid).First(&usage).Error
+	if errors.Is(err, ErrRecordNotFound) {
+		return 0, nil
+	}
+	return usage.Used, err
+}
+
+func (d *UploadGORMDAO) FindBefore(ctx context.Context, ctime int64, afterId int64, limit int) ([]Upload, error) {
+	var res []Upload
+	err := d.db.WithContext(ctx).
+		Where("ctime < ? AND id > ?", ctime, afterId).
+		Order("id ASC").
+		Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (d *UploadGORMDAO) Delete(ctx context.Context, id int64) error {
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var u Upload
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", id).First(&u).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Delete(&Upload{}, id).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&UploadUsage{}).
+			Where("uid = ?", u.Uid).
+			Updates(map[string]any{
+				"used":  gorm.Expr("used - ?", u.Size),
+				"utime": time.Now().UnixMilli(),
+			}).Error
+	})
+}
+
+// Upload 同一个用户同样的内容只有一条记录
+type Upload struct {
+	Id          int64  `gorm:"primaryKey,autoIncrement"`
+	Uid         int64  `gorm:"uniqueIndex:uid_hash"`
+	Hash        string `gorm:"type:char(64);uniqueIndex:uid_hash;index"`
+	Name        string `gorm:"type:varchar(256)"`
+	ContentType string `gorm:"type:varchar(128)"`
+	Size        int64
+	// HasThumbnail 有没有单独生成缩略图
+	HasThumbnail bool
+	// Ctime 最后一次上传的时间，重复上传也会更新。
+	// 清理的时候按照 ctime 找很久之前的上传
+	Ctime int64 `gorm:"index"`
+}
+
+type UploadUsage struct {
+	Id    int64 `gorm:"primaryKey,autoIncrement"`
+	Uid   int64 `gorm:"uniqueIndex"`
+	Used  int64
+	Utime int64
+}
@@ -20,8 +20,12 @@ type UserDAO interface {
 	FindByEmail(ctx context.Context, email string) (User, error)
 	UpdateById(ctx context.Context, entity User) error
 	FindById(ctx context.Context, uid int64) (User, error)
+	// FindByIds 批量查询，不存在的用户不会出现在结果里面
+	FindByIds(ctx context.Context, uids []int64) ([]User, error)
 	FindByPhone(ctx context.Context, phone string) (User, error)
 	FindByWechat(ctx context.Context, openId string) (User, error)
+	// UpdateAvatar 单独更新头像，空字符串就是清空
+	UpdateAvatar(ctx context.Context, uid int64, avatar string) error
 }
 
 type GORMUserDAO struct {
@@ -78,6 +82,20 @@ func (dao *GORMUserDAO) UpdateById(ctx context.Context, entity User) error {
 		}).Error
 }
 
+func (dao *GORMUserDAO) UpdateAvatar(ctx context.Context, uid int64, avatar string) error {
+	return dao.db.WithContext(ctx).Model(&User{}).Where("id = ?", uid).
+		Updates(map[string]any{
+			"utime":  time.Now().UnixMilli(),
+			"avatar": avatar,
+		}).Error
+}
+
+func (dao *GORMUserDAO) FindByIds(ctx context.Context, uids []int64) ([]User, error) {
+	var res []User
+	err := dao.db.WithContext(ctx).Where("id IN ?", uids).Find(&res).Error
+	return res, err
+}
+
 func (dao *GORMUserDAO) FindById(ctx context.Context, uid int64) (User, error) {
 	var res User
 	err := dao.db.WithContext(ctx).Where("id = ?", uid).First(&res).Error
@@ -92,6 +110,7 @@ type User struct {
 	Password string
 
 	Nickname string `gorm:"type=varchar(128)"`
+	Avatar   string `gorm:"type:varchar(1024)"`
 	// YYYY-MM-DD
 	Birthday int64
 	AboutMe  string `gorm:"type=varchar(4096)"`
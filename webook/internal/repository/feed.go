@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository/dao"
+
+	"github.com/ecodeclub/ekit/slice"
+)
+
+type FeedRepository interface {
+	// AddOutbox 文章进入作者的发件箱，Article 只需要 Id 和 Author
+	AddOutbox(ctx context.Context, item domain.FeedItem) error
+	// AddInboxes 文章推送到这些粉丝的收件箱
+	AddInboxes(ctx context.Context, uids []int64, item domain.FeedItem) error
+	// DeleteArticle 从所有的收件箱和发件箱里面删掉这篇文章
+	DeleteArticle(ctx context.Context, aid int64) error
+	// FindInbox 返回的 Article 只有 Id 和 Author.Id
+	FindInbox(ctx context.Context, uid int64, authorIds []int64, ctime int64, maxAid int64, limit int) ([]domain.FeedItem, error)
+	// FindOutbox 返回的 Article 只有 Id 和 Author.Id
+	FindOutbox(ctx context.Context, authorIds []int64, ctime int64, maxAid int64, limit int) ([]domain.FeedItem, error)
+}
+
+type feedRepository struct {
+	dao dao.FeedDAO
+}
+
+func NewFeedRepository(dao dao.FeedDAO) FeedRepository {
+	return &feedRepository{
+		dao: dao,
+	}
+}
+
+func (f *feedRepository) AddOutbox(ctx context.Context, item domain.FeedItem) error {
+	return f.dao.InsertOutbox(ctx, dao.FeedOutbox{
+		AuthorId: item.Article.Author.Id,
+		Aid:      item.Article.Id,
+		Ctime:    item.Ctime.UnixMilli(),
+	})
+}
+
+func (f *feedRepository) AddInboxes(ctx context.Context, uids []int64, item domain.FeedItem) error {
+	boxes := slice.Map[int64, dao.FeedInbox](uids, func(idx int, src int64) dao.FeedInbox {
+		return dao.FeedInbox{
+			Uid:      src,
+			Aid:      item.Article.Id,
+			AuthorId: item.Article.Author.Id,
+			Ctime:    item.Ctime.UnixMilli(),
+		}
+	})
+	return f.dao.InsertInboxes(ctx, boxes)
+}
+
+func (f *feedRepository) DeleteArticle(ctx context.Context, aid int64) error {
+	return f.dao.DeleteByAid(ctx, aid)
+}
+
+func (f *feedRepository) FindInbox(ctx context.Context, uid int64, authorIds []int64,
+	ctime int64, maxAid int64, limit int) ([]domain.FeedItem, error) {
+	boxes, err := f.dao.FindInbox(ctx, uid, authorIds, ctime, maxAid, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.FeedInbox, domain.FeedItem](boxes, func(idx int, src dao.FeedInbox) domain.FeedItem {
+		return f.toDomain(src.Aid, src.AuthorId, src.Ctime)
+	}), nil
+}
+
+func (f *feedRepository) FindOutbox(ctx context.Context, authorIds []int64,
+	ctime int64, maxAid int64, limit int) ([]domain.FeedItem, error) {
+	boxes, err := f.dao.FindOutbox(ctx, authorIds, ctime, maxAid, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.FeedOutbox, domain.FeedItem](boxes, func(idx int, src dao.FeedOutbox) domain.FeedItem {
+		return f.toDomain(src.Aid, src.AuthorId, src.Ctime)
+	}), nil
+}
+
+func (f *feedRepository) toDomain(aid int64, authorId int64, ctime int64) domain.FeedItem {
+	return domain.FeedItem{
+		Article: domain.Article{
+			Id:     aid,
+			Author: domain.Author{Id: authorId},
+		},
+		Ctime: time.UnixMilli(ctime),
+	}
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository/cache"
+	"basic-go/webook/internal/repository/dao"
+	"basic-go/webook/pkg/logger"
+
+	"github.com/ecodeclub/ekit/slice"
+)
+
+type FollowRepository interface {
+	Follow(ctx context.Context, follower int64, followee int64) error
+	CancelFollow(ctx context.Context, follower int64, followee int64) error
+	Followed(ctx context.Context, follower int64, followee int64) (bool, error)
+	FindFollowers(ctx context.Context, followee int64, utime int64, maxId int64, limit int) ([]domain.FollowRelation, error)
+	FindFollowees(ctx context.Context, follower int64, utime int64, maxId int64, limit int) ([]domain.FollowRelation, error)
+	GetStatistics(ctx context.Context, uid int64) (domain.FollowStatistics, error)
+	// ScanFollowers 按照 ID 正序遍历粉丝，不会补充昵称，给推送之类的后台任务用
+	ScanFollowers(ctx context.Context, followee int64, minId int64, limit int) ([]domain.FollowRelation, error)
+	// FindFolloweeStatistics 关注的人以及他们的粉丝数，Followees 字段没有意义
+	FindFolloweeStatistics(ctx context.Context, follower int64, limit int) ([]domain.FollowStatistics, error)
+}
+
+type CachedFollowRepository struct {
+	dao      dao.FollowDAO
+	cache    cache.FollowCache
+	userRepo UserRepository
+	l        logger.LoggerV1
+}
+
+func NewCachedFollowRepository(dao dao.FollowDAO,
+	cache cache.FollowCache,
+	userRepo UserRepository,
+	l logger.LoggerV1) FollowRepository {
+	return &CachedFollowRepository{
+		dao:      dao,
+		cache:    cache,
+		userRepo: userRepo,
+		l:        l,
+	}
+}
+
+func (c *CachedFollowRepository) Follow(ctx context.Context, follower int64, followee int64) error {
+	changed, err := c.dao.Follow(ctx, follower, followee)
+	if err != nil || !changed {
+		return err
+	}
+	return c.cache.Follow(ctx, follower, followee)
+}
+
+func (c *CachedFollowRepository) CancelFollow(ctx context.Context, follower int64, followee int64) error {
+	changed, err := c.dao.CancelFollow(ctx, follower, followee)
+	if err != nil || !changed {
+		return err
+	}
+	return c.cache.CancelFollow(ctx, follower, followee)
+}
+
+func (c *CachedFollowRepository) Followed(ctx context.Context, follower int64, followee int64) (bool, error) {
+	_, err := c.dao.GetRelation(ctx, follower, followee)
+	switch err {
+	case nil:
+		return true, nil
+	case dao.ErrRecordNotFound:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func (c *CachedFollowRepository) FindFollowers(ctx context.Context, followee int64,
+	utime int64, maxId int64, limit int) ([]domain.FollowRelation, error) {
+	rels, err := c.dao.FindFollowers(ctx, followee, utime, maxId, limit)
+	if err != nil {
+		return nil, err
+	}
+	return c.toDomains(ctx, rels), nil
+}
+
+func (c *CachedFollowRepository) FindFollowees(ctx context.Context, follower int64,
+	utime int64, maxId int64, limit int) ([]domain.FollowRelation, error) {
+	rels, err := c.dao.FindFollowees(ctx, follower, utime, maxId, limit)
+	if err != nil {
+		return nil, err
+	}
+	return c.toDomains(ctx, rels), nil
+}
+
+func (c *CachedFollowRepository) GetStatistics(ctx context.Context, uid int64) (domain.FollowStatistics, error) {
+	stat, err := c.cache.GetStatistics(ctx, uid)
+	if err == nil {
+		return stat, nil
+	}
+	entity, err := c.dao.GetStatistics(ctx, uid)
+	switch err {
+	case nil:
+		stat = domain.FollowStatistics{
+			Uid:       uid,
+			Followers: entity.Followers,
+			Followees: entity.Followees,
+		}
+	case dao.ErrRecordNotFound:
+		// 没有关注过别人，也没有被别人关注过
+		stat = domain.FollowStatistics{Uid: uid}
+	default:
+		return domain.FollowStatistics{}, err
+	}
+	err = c.cache.SetStatistics(ctx, stat)
+	if err != nil {
+		c.l.Error("回写关注数缓存失败",
+			logger.Int64("uid", uid),
+			logger.Error(err))
+	}
+	return stat, nil
+}
+
+func (c *CachedFollowRepository) ScanFollowers(ctx context.Context, followee int64,
+	minId int64, limit int) ([]domain.FollowRelation, error) {
+	rels, err := c.dao.ScanFollowers(ctx, followee, minId, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.FollowRelation, domain.FollowRelation](rels, func(idx int, src dao.FollowRelation) domain.FollowRelation {
+		return domain.FollowRelation{
+			Id:       src.Id,
+			Follower: domain.Author{Id: src.Follower},
+			Followee: domain.Author{Id: src.Followee},
+			Utime:    time.UnixMilli(src.Utime),
+		}
+	}), nil
+}
+
+func (c *CachedFollowRepository) FindFolloweeStatistics(ctx context.Context, follower int64,
+	limit int) ([]domain.FollowStatistics, error) {
+	stats, err := c.dao.FindFolloweeStatistics(ctx, follower, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.FollowStatistics, domain.FollowStatistics](stats, func(idx int, src dao.FollowStatistics) domain.FollowStatistics {
+		return domain.FollowStatistics{
+			Uid:       src.Uid,
+			Followers: src.Followers,
+		}
+	}), nil
+}
+
+// toDomains 补充双方的昵称，查不到昵称不影响列表的展示，所以忽略错误
+func (c *CachedFollowRepository) toDomains(ctx context.Context, rels []dao.FollowRelation) []domain.FollowRelation {
+	names := make(map[int64]string, len(rels)+1)
+	name := func(uid int64) string {
+		res, ok := names[uid]
+		if !ok {
+			u, err := c.userRepo.FindById(ctx, uid)
+			if err == nil {
+				res = u.Nickname
+			}
+			names[uid] = res
+		}
+		return res
+	}
+	return slice.Map[dao.FollowRelation, domain.FollowRelation](rels, func(idx int, src dao.FollowRelation) domain.FollowRelation {
+		return domain.FollowRelation{
+			Id:       src.Id,
+			Follower: domain.Author{Id: src.Follower, Name: name(src.Follower)},
+			Followee: domain.Author{Id: src.Followee, Name: name(src.Followee)},
+			Utime:    time.UnixMilli(src.Utime),
+		}
+	})
+}
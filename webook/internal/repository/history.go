@@ -2,10 +2,118 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository/cache"
+	"basic-go/webook/internal/repository/dao"
+	"basic-go/webook/pkg/logger"
+
+	"github.com/ecodeclub/ekit/slice"
 )
 
+// historyFirstPageSize 第一页缓存的记录数量，每页不超过这个数量的第一页查询都走缓存
+const historyFirstPageSize = 50
+
 type HistoryRecordRepository interface {
 	AddRecord(ctx context.Context, record domain.HistoryRecord) error
+	// GetByUid 最近阅读的在前面，(lastReadTime, maxId) 是上一页的最后一条
+	GetByUid(ctx context.Context, uid int64, lastReadTime time.Time, maxId int64, limit int) ([]domain.HistoryRecord, error)
+	DeleteRecord(ctx context.Context, uid int64, biz string, bizId int64) error
+	Clear(ctx context.Context, uid int64) error
+}
+
+type CachedHistoryRecordRepository struct {
+	dao   dao.HistoryRecordDAO
+	cache cache.HistoryRecordCache
+	l     logger.LoggerV1
+}
+
+func NewCachedHistoryRecordRepository(dao dao.HistoryRecordDAO,
+	cache cache.HistoryRecordCache, l logger.LoggerV1) HistoryRecordRepository {
+	return &CachedHistoryRecordRepository{
+		dao:   dao,
+		cache: cache,
+		l:     l,
+	}
+}
+
+func (c *CachedHistoryRecordRepository) AddRecord(ctx context.Context, record domain.HistoryRecord) error {
+	err := c.dao.Upsert(ctx, dao.HistoryRecord{
+		Uid:          record.Uid,
+		Biz:          record.Biz,
+		BizId:        record.BizId,
+		LastReadTime: record.LastReadTime.UnixMilli(),
+	})
+	if err != nil {
+		return err
+	}
+	c.delFirstPage(ctx, record.Uid)
+	return nil
+}
+
+func (c *CachedHistoryRecordRepository) GetByUid(ctx context.Context, uid int64,
+	lastReadTime time.Time, maxId int64, limit int) ([]domain.HistoryRecord, error) {
+	firstPage := maxId == 0 && limit <= historyFirstPageSize
+	if firstPage {
+		res, err := c.cache.GetFirstPage(ctx, uid)
+		if err == nil {
+			return res[:min(limit, len(res))], nil
+		}
+	}
+	size := limit
+	if firstPage {
+		// 第一页一次多查一点，方便缓存
+		size = historyFirstPageSize
+	}
+	records, err := c.dao.FindByUid(ctx, uid, lastReadTime.UnixMilli(), maxId, size)
+	if err != nil {
+		return nil, err
+	}
+	res := slice.Map[dao.HistoryRecord, domain.HistoryRecord](records, func(idx int, src dao.HistoryRecord) domain.HistoryRecord {
+		return domain.HistoryRecord{
+			Id:           src.Id,
+			Uid:          src.Uid,
+			Biz:          src.Biz,
+			BizId:        src.BizId,
+			LastReadTime: time.UnixMilli(src.LastReadTime),
+		}
+	})
+	if firstPage {
+		err = c.cache.SetFirstPage(ctx, uid, res)
+		if err != nil {
+			c.l.Error("回写阅读历史的缓存失败",
+				logger.Int64("uid", uid),
+				logger.Error(err))
+		}
+	}
+	return res[:min(limit, len(res))], nil
+}
+
+func (c *CachedHistoryRecordRepository) DeleteRecord(ctx context.Context, uid int64, biz string, bizId int64) error {
+	err := c.dao.Delete(ctx, uid, biz, bizId)
+	if err != nil {
+		return err
+	}
+	c.delFirstPage(ctx, uid)
+	return nil
+}
+
+func (c *CachedHistoryRecordRepository) Clear(ctx context.Context, uid int64) error {
+	err := c.dao.DeleteByUid(ctx, uid)
+	if err != nil {
+		return err
+	}
+	c.delFirstPage(ctx, uid)
+	return nil
+}
+
+// delFirstPage 删除缓存失败，最多十分钟之内看到的是旧数据
+func (c *CachedHistoryRecordRepository) delFirstPage(ctx context.Context, uid int64) {
+	err := c.cache.DelFirstPage(ctx, uid)
+	if err != nil {
+		c.l.Error("删除阅读历史的缓存失败",
+			logger.Int64("uid", uid),
+			logger.Error(err))
+	}
 }
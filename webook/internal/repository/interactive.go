@@ -2,135 +2,513 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"basic-go/webook/internal/domain"
 	"basic-go/webook/internal/repository/cache"
 	"basic-go/webook/internal/repository/dao"
 	"basic-go/webook/pkg/logger"
+
+	"github.com/ecodeclub/ekit/slice"
 )
 
 type InteractiveRepository interface {
 	IncrReadCnt(ctx context.Context, biz string, bizId int64) error
 	BatchIncrReadCnt(ctx context.Context, biz []string, bizId []int64) error
+	// RecordReads 记录一批阅读，每一次都算进当天的 PV 和 UV，
+	// 阅读数只有读者在去重窗口里面第一次读的时候才加一
+	RecordReads(ctx context.Context, reads []domain.Read) error
 
 	IncrLike(ctx context.Context, biz string, id int64, uid int64) error
 	DecrLike(ctx context.Context, biz string, id int64, uid int64) error
 	AddCollectionItem(ctx context.Context, biz string, id int64, cid int64, uid int64) error
+	// DeleteCollectionItem 取消收藏，没有收藏过也不会报错
+	DeleteCollectionItem(ctx context.Context, biz string, id int64, uid int64) error
+	// Get 计数以及今天的 PV 和 UV
 	Get(ctx context.Context, biz string, id int64) (domain.Interactive, error)
+	// GetByIds 批量查询计数，先查缓存，没命中的再一次性查数据库。
+	// 没有记录的 id 不会出现在结果里面
+	GetByIds(ctx context.Context, biz string, ids []int64) ([]domain.Interactive, error)
 	Liked(ctx context.Context, biz string, id int64, uid int64) (bool, error)
 	Collected(ctx context.Context, biz string, id int64, uid int64) (bool, error)
+	// LikedIds 和 CollectedIds 返回 ids 里面 uid 点过赞（收藏过）的
+	LikedIds(ctx context.Context, biz string, ids []int64, uid int64) ([]int64, error)
+	CollectedIds(ctx context.Context, biz string, ids []int64, uid int64) ([]int64, error)
+	// ListLikesByUser 和 ListLikers 分别是 uid 的点赞记录和 bizId 收到的点赞记录，
+	// 最近点赞的在前面，(utime, maxId) 是上一页的最后一条。Liker 里面只有 Id
+	ListLikesByUser(ctx context.Context, biz string, uid int64, utime time.Time, maxId int64, limit int) ([]domain.Like, error)
+	ListLikers(ctx context.Context, biz string, bizId int64, utime time.Time, maxId int64, limit int) ([]domain.Like, error)
+	// DeleteByBiz 删除计数、点赞和收藏，资源被彻底删除的时候用
+	DeleteByBiz(ctx context.Context, biz string, id int64) error
+
+	// Reconcile 检查一批缓存里面的计数，和数据库对不上的删掉缓存，下次读的时候从数据库重新加载。
+	// cursor 从 0 开始，返回的 next 为 0 代表遍历完了
+	Reconcile(ctx context.Context, cursor uint64, batch int64) (res ReconcileResult, next uint64, err error)
+	// ReconcileCursor 上一次 Reconcile 停下来的位置，多个实例、重启之后都能接着检查
+	ReconcileCursor(ctx context.Context) (uint64, error)
+	SetReconcileCursor(ctx context.Context, cursor uint64) error
+	// Rebuild 用数据库里面的计数覆盖缓存
+	Rebuild(ctx context.Context, biz string, id int64) (domain.Interactive, error)
+	// Flush 写回模式下把 biz 积攒的增量写入数据库，返回写入了多少个资源的计数。
+	// 不是写回模式的 biz 什么都不做
+	Flush(ctx context.Context, biz string) (int, error)
+}
+
+// ReconcileResult 一批缓存的检查结果
+type ReconcileResult struct {
+	Checked int
+	// Drifted 和数据库对不上的数量，这些缓存已经被删掉了
+	Drifted int
 }
 
+// maxCachedState 点赞（收藏）超过这么多的用户不缓存状态集合，直接查数据库
+const maxCachedState = 5000
+
 type CachedInteractiveRepository struct {
-	dao   dao.InteractiveDAO
-	cache cache.InteractiveCache
-	l     logger.LoggerV1
+	dao        dao.InteractiveDAO
+	cache      cache.InteractiveCache
+	readCache  cache.ReadCache
+	deltaCache cache.InteractiveDeltaCache
+	stateCache cache.InteractiveStateCache
+	// writeBehind 这些 biz 的点赞数和阅读数先记增量，由 Flush 合并写入数据库。
+	// 这段时间里面缓存里面的计数才是准确的，数据库里面的要加上还没写入的增量
+	writeBehind map[string]bool
+	l           logger.LoggerV1
 }
 
 func NewCachedInteractiveRepository(dao dao.InteractiveDAO,
 	l logger.LoggerV1,
-	cache cache.InteractiveCache) InteractiveRepository {
-	return &CachedInteractiveRepository{dao: dao, cache: cache}
+	cache cache.InteractiveCache,
+	readCache cache.ReadCache,
+	deltaCache cache.InteractiveDeltaCache,
+	stateCache cache.InteractiveStateCache,
+	writeBehindBizs []string) InteractiveRepository {
+	writeBehind := make(map[string]bool, len(writeBehindBizs))
+	for _, biz := range writeBehindBizs {
+		writeBehind[biz] = true
+	}
+	return &CachedInteractiveRepository{dao: dao, cache: cache, readCache: readCache,
+		deltaCache: deltaCache, stateCache: stateCache, writeBehind: writeBehind, l: l}
 }
 
 func (c *CachedInteractiveRepository) BatchIncrReadCnt(ctx context.Context, biz []string, bizId []int64) error {
-	err := c.dao.BatchIncrReadCnt(ctx, biz, bizId)
+	// 写回模式的 biz 合并成增量，剩下的直接写数据库
+	var deltas []domain.Interactive
+	merged := make(map[string]int)
+	bizs := make([]string, 0, len(biz))
+	bizIds := make([]int64, 0, len(bizId))
+	for i := 0; i < len(biz); i++ {
+		if !c.writeBehind[biz[i]] {
+			bizs = append(bizs, biz[i])
+			bizIds = append(bizIds, bizId[i])
+			continue
+		}
+		key := fmt.Sprintf("%s:%d", biz[i], bizId[i])
+		idx, ok := merged[key]
+		if !ok {
+			idx = len(deltas)
+			merged[key] = idx
+			deltas = append(deltas, domain.Interactive{Biz: biz[i], BizId: bizId[i]})
+		}
+		deltas[idx].ReadCnt++
+	}
+	if len(deltas) > 0 {
+		err := c.deltaCache.Add(ctx, deltas)
+		if err != nil {
+			return err
+		}
+	}
+	if len(bizs) == 0 {
+		return nil
+	}
+	err := c.dao.BatchIncrReadCnt(ctx, bizs, bizIds)
 	// fmt.Println("dao.BatchIncrReadCnt", err, len(biz))
 	if err != nil {
 		return err
 	}
-	go func() {
-		for i := 0; i < len(biz); i++ {
-			er := c.cache.IncrReadCntIfPresent(ctx, biz[i], bizId[i])
-			if er != nil {
-				// 记录日志
-			}
+	// 数据库已经成功了，缓存失败只记日志，对不上的由 Reconcile 修复
+	for i := 0; i < len(bizs); i++ {
+		er := c.cache.IncrReadCntIfPresent(ctx, bizs[i], bizIds[i])
+		if er != nil {
+			c.l.Error("更新阅读数缓存失败",
+				logger.String("biz", bizs[i]),
+				logger.Int64("bizId", bizIds[i]),
+				logger.Error(er))
 		}
-	}()
+	}
 	return nil
 }
 
 func (c *CachedInteractiveRepository) Get(ctx context.Context, biz string, id int64) (domain.Interactive, error) {
+	intr, err := c.get(ctx, biz, id)
+	if err != nil {
+		return domain.Interactive{}, err
+	}
+	// PV 和 UV 查不到不影响计数
+	intr.PV, intr.UV, err = c.readCache.GetDaily(ctx, biz, id, time.Now())
+	if err != nil {
+		c.l.Error("查询当天的 PV 和 UV 失败",
+			logger.String("biz", biz),
+			logger.Int64("bizId", id),
+			logger.Error(err))
+	}
+	return intr, nil
+}
+
+func (c *CachedInteractiveRepository) get(ctx context.Context, biz string, id int64) (domain.Interactive, error) {
 	intr, err := c.cache.Get(ctx, biz, id)
 	if err == nil {
 		return intr, nil
 	}
+	res, err := c.load(ctx, biz, id)
+	if err != nil {
+		return domain.Interactive{}, err
+	}
+	err = c.cache.Set(ctx, biz, id, res)
+	if err != nil {
+		c.l.Error("回写缓存失败",
+			logger.String("biz", biz),
+			logger.Int64("bizId", id),
+			logger.Error(err))
+	}
+	return res, nil
+}
+
+// load 从数据库加载计数，写回模式下加上还没写入的增量
+func (c *CachedInteractiveRepository) load(ctx context.Context, biz string, id int64) (domain.Interactive, error) {
+	var res domain.Interactive
 	ie, err := c.dao.Get(ctx, biz, id)
+	switch err {
+	case nil:
+		res = c.toDomain(ie)
+	case dao.ErrRecordNotFound:
+		// 还没有人读过、点赞过，计数都是 0
+		res = domain.Interactive{Biz: biz, BizId: id}
+	default:
+		return domain.Interactive{}, err
+	}
+	intrs, err := c.withPending(ctx, biz, []int64{id}, []domain.Interactive{res})
 	if err != nil {
 		return domain.Interactive{}, err
 	}
-	if err == nil {
-		res := c.toDomain(ie)
-		err = c.cache.Set(ctx, biz, id, res)
+	return intrs[0], nil
+}
+
+// withPending 写回模式下，数据库里面的计数加上还没写入的增量才是准确的。
+// ids 里面数据库没有记录、但是有增量的，也会加到结果里面。
+// 先查数据库再查增量，中间正好写入了一批的话这一批会漏掉，由 Reconcile 修复
+func (c *CachedInteractiveRepository) withPending(ctx context.Context, biz string,
+	ids []int64, intrs []domain.Interactive) ([]domain.Interactive, error) {
+	if !c.writeBehind[biz] {
+		return intrs, nil
+	}
+	pending, err := c.deltaCache.Pending(ctx, biz, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range intrs {
+		d, ok := pending[intrs[i].BizId]
+		if !ok {
+			continue
+		}
+		intrs[i].ReadCnt += d.ReadCnt
+		intrs[i].LikeCnt += d.LikeCnt
+		delete(pending, intrs[i].BizId)
+	}
+	for _, d := range pending {
+		intrs = append(intrs, d)
+	}
+	return intrs, nil
+}
+
+func (c *CachedInteractiveRepository) GetByIds(ctx context.Context, biz string, ids []int64) ([]domain.Interactive, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	cached, err := c.cache.GetByIds(ctx, biz, ids)
+	if err != nil {
+		// 缓存出问题了就全部查数据库
+		c.l.Error("批量查询计数缓存失败",
+			logger.String("biz", biz),
+			logger.Int("cnt", len(ids)),
+			logger.Error(err))
+	}
+	res := make([]domain.Interactive, 0, len(ids))
+	missed := make([]int64, 0, len(ids)-len(cached))
+	for _, id := range ids {
+		intr, ok := cached[id]
+		if ok {
+			res = append(res, intr)
+		} else {
+			missed = append(missed, id)
+		}
+	}
+	if len(missed) == 0 {
+		return res, nil
+	}
+	intrs, err := c.dao.GetByIds(ctx, biz, missed)
+	if err != nil {
+		return nil, err
+	}
+	loaded := slice.Map[dao.Interactive, domain.Interactive](intrs, func(idx int, src dao.Interactive) domain.Interactive {
+		return c.toDomain(src)
+	})
+	loaded, err = c.withPending(ctx, biz, missed, loaded)
+	if err != nil {
+		return nil, err
+	}
+	if len(loaded) > 0 {
+		err = c.cache.SetAll(ctx, loaded)
 		if err != nil {
-			c.l.Error("回写缓存失败",
+			c.l.Error("批量回写计数缓存失败",
 				logger.String("biz", biz),
-				logger.Int64("bizId", id),
+				logger.Int("cnt", len(loaded)),
 				logger.Error(err))
 		}
-		return res, nil
 	}
-	return intr, err
+	return append(res, loaded...), nil
 }
 
 func (c *CachedInteractiveRepository) Liked(ctx context.Context,
 	biz string, id int64, uid int64) (bool, error) {
-	_, err := c.dao.GetLikeInfo(ctx, biz, id, uid)
-	switch err {
-	case nil:
-		return true, nil
-	case dao.ErrRecordNotFound:
-		return false, nil
-	default:
-		return false, err
-	}
+	ids, err := c.stateIds(ctx, cache.StateLiked, biz, uid, []int64{id})
+	return len(ids) > 0, err
 }
 
 func (c *CachedInteractiveRepository) Collected(ctx context.Context,
 	biz string, id int64, uid int64) (bool, error) {
-	_, err := c.dao.GetCollectInfo(ctx, biz, id, uid)
-	switch err {
-	case nil:
-		return true, nil
-	case dao.ErrRecordNotFound:
-		return false, nil
-	default:
-		return false, err
+	ids, err := c.stateIds(ctx, cache.StateCollected, biz, uid, []int64{id})
+	return len(ids) > 0, err
+}
+
+func (c *CachedInteractiveRepository) LikedIds(ctx context.Context,
+	biz string, ids []int64, uid int64) ([]int64, error) {
+	return c.stateIds(ctx, cache.StateLiked, biz, uid, ids)
+}
+
+func (c *CachedInteractiveRepository) CollectedIds(ctx context.Context,
+	biz string, ids []int64, uid int64) ([]int64, error) {
+	return c.stateIds(ctx, cache.StateCollected, biz, uid, ids)
+}
+
+// stateIds 返回 ids 里面 uid 点过赞（收藏过）的。缓存里面没有这个用户的集合的话，
+// 把他全部的记录加载进缓存，之后的查询都不需要再查数据库
+func (c *CachedInteractiveRepository) stateIds(ctx context.Context, state string,
+	biz string, uid int64, ids []int64) ([]int64, error) {
+	res, err := c.stateCache.Contains(ctx, state, biz, uid, ids)
+	if err == nil {
+		return res, nil
+	}
+	query, loadAll := c.dao.GetLikedBizIds, c.dao.GetAllLikedBizIds
+	if state == cache.StateCollected {
+		query, loadAll = c.dao.GetCollectedBizIds, c.dao.GetAllCollectedBizIds
+	}
+	if err != cache.ErrKeyNotExist {
+		// Redis 出问题了就直接查数据库
+		c.l.Error("查询点赞收藏状态的缓存失败",
+			logger.String("state", state),
+			logger.String("biz", biz),
+			logger.Int64("uid", uid),
+			logger.Error(err))
+		return query(ctx, biz, ids, uid)
+	}
+	// 先打标记再查数据库，查询之后有点赞、取消的话标记会被删掉，Init 就什么都不做
+	token, err := c.stateCache.BeginInit(ctx, state, biz, uid)
+	if err != nil {
+		c.l.Error("标记加载点赞收藏状态失败",
+			logger.String("state", state),
+			logger.String("biz", biz),
+			logger.Int64("uid", uid),
+			logger.Error(err))
+		return query(ctx, biz, ids, uid)
+	}
+	all, err := loadAll(ctx, biz, uid, maxCachedState+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(all) > maxCachedState {
+		return query(ctx, biz, ids, uid)
+	}
+	err = c.stateCache.Init(ctx, state, biz, uid, token, all)
+	if err != nil {
+		c.l.Error("回写点赞收藏状态的缓存失败",
+			logger.String("state", state),
+			logger.String("biz", biz),
+			logger.Int64("uid", uid),
+			logger.Error(err))
+	}
+	set := make(map[int64]struct{}, len(all))
+	for _, id := range all {
+		set[id] = struct{}{}
+	}
+	res = make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := set[id]; ok {
+			res = append(res, id)
+		}
+	}
+	return res, nil
+}
+
+// updateState 状态变化之后更新缓存里面的集合。数据库已经改好了，
+// 缓存失败只会让用户短时间看到旧的状态，计数不会错，因为重复操作由数据库判断
+func (c *CachedInteractiveRepository) updateState(ctx context.Context, state string,
+	biz string, uid int64, id int64, add bool) {
+	var err error
+	if add {
+		err = c.stateCache.AddIfPresent(ctx, state, biz, uid, id)
+	} else {
+		err = c.stateCache.RemoveIfPresent(ctx, state, biz, uid, id)
+	}
+	if err != nil {
+		c.l.Error("更新点赞收藏状态的缓存失败",
+			logger.String("state", state),
+			logger.String("biz", biz),
+			logger.Int64("bizId", id),
+			logger.Int64("uid", uid),
+			logger.Error(err))
+	}
+}
+
+func (c *CachedInteractiveRepository) ListLikesByUser(ctx context.Context, biz string,
+	uid int64, utime time.Time, maxId int64, limit int) ([]domain.Like, error) {
+	likes, err := c.dao.GetLikesByUser(ctx, biz, uid, utime.UnixMilli(), maxId, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.UserLikeBiz, domain.Like](likes, c.likeToDomain), nil
+}
+
+func (c *CachedInteractiveRepository) ListLikers(ctx context.Context, biz string,
+	bizId int64, utime time.Time, maxId int64, limit int) ([]domain.Like, error) {
+	likes, err := c.dao.GetLikers(ctx, biz, bizId, utime.UnixMilli(), maxId, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.UserLikeBiz, domain.Like](likes, c.likeToDomain), nil
+}
+
+func (c *CachedInteractiveRepository) likeToDomain(idx int, src dao.UserLikeBiz) domain.Like {
+	return domain.Like{
+		Id:    src.Id,
+		Biz:   src.Biz,
+		BizId: src.BizId,
+		Liker: domain.Liker{Id: src.Uid},
+		Utime: time.UnixMilli(src.Utime),
 	}
 }
 
 func (c *CachedInteractiveRepository) AddCollectionItem(ctx context.Context,
 	biz string, id int64, cid int64, uid int64) error {
-	err := c.dao.InsertCollectionBiz(ctx, dao.UserCollectionBiz{
+	changed, err := c.dao.InsertCollectionBiz(ctx, dao.UserCollectionBiz{
 		Biz:   biz,
 		BizId: id,
 		Cid:   cid,
 		Uid:   uid,
 	})
-	if err != nil {
+	if err != nil || !changed {
 		return err
 	}
+	c.updateState(ctx, cache.StateCollected, biz, uid, id, true)
 	return c.cache.IncrCollectCntIfPresent(ctx, biz, id)
 }
 
+func (c *CachedInteractiveRepository) DeleteCollectionItem(ctx context.Context,
+	biz string, id int64, uid int64) error {
+	err := c.dao.DeleteCollectionBiz(ctx, biz, id, uid)
+	switch err {
+	case nil:
+		c.updateState(ctx, cache.StateCollected, biz, uid, id, false)
+		return c.cache.DecrCollectCntIfPresent(ctx, biz, id)
+	case dao.ErrRecordNotFound:
+		return nil
+	default:
+		return err
+	}
+}
+
+// IncrLike 和 DecrLike 只有点赞状态真的变化了才修改点赞数，重复点赞、重复取消什么都不做
 func (c *CachedInteractiveRepository) IncrLike(ctx context.Context, biz string, id int64, uid int64) error {
-	err := c.dao.InsertLikeInfo(ctx, biz, id, uid)
-	if err != nil {
+	if c.writeBehind[biz] {
+		return c.setLikeWriteBehind(ctx, biz, id, uid, true)
+	}
+	changed, err := c.dao.InsertLikeInfo(ctx, biz, id, uid)
+	if err != nil || !changed {
 		return err
 	}
+	c.updateState(ctx, cache.StateLiked, biz, uid, id, true)
 	return c.cache.IncrLikeCntIfPresent(ctx, biz, id)
 }
 
 func (c *CachedInteractiveRepository) DecrLike(ctx context.Context, biz string, id int64, uid int64) error {
-	err := c.dao.DeleteLikeInfo(ctx, biz, id, uid)
-	if err != nil {
+	if c.writeBehind[biz] {
+		return c.setLikeWriteBehind(ctx, biz, id, uid, false)
+	}
+	changed, err := c.dao.DeleteLikeInfo(ctx, biz, id, uid)
+	if err != nil || !changed {
 		return err
 	}
+	c.updateState(ctx, cache.StateLiked, biz, uid, id, false)
 	return c.cache.DecrLikeCntIfPresent(ctx, biz, id)
 }
 
+// setLikeWriteBehind 点赞记录直接写数据库，点赞数只记增量，不再更新计数那一行。
+// 增量要先记下来再写点赞记录，反过来的话写完点赞记录之后崩溃，这个增量就永远丢了。
+// 点赞记录没有写成功或者状态本来就是这样，再记一个反向的增量抵消掉
+func (c *CachedInteractiveRepository) setLikeWriteBehind(ctx context.Context,
+	biz string, id int64, uid int64, liked bool) error {
+	delta := domain.Interactive{Biz: biz, BizId: id, LikeCnt: 1}
+	if !liked {
+		delta.LikeCnt = -1
+	}
+	err := c.deltaCache.Add(ctx, []domain.Interactive{delta})
+	if err != nil {
+		return err
+	}
+	changed, err := c.dao.SetLikeStatus(ctx, biz, id, uid, liked)
+	if err == nil && changed {
+		c.updateState(ctx, cache.StateLiked, biz, uid, id, liked)
+		return nil
+	}
+	delta.LikeCnt = -delta.LikeCnt
+	// 请求的 ctx 可能已经超时了，抵消不能因为这个失败
+	cctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+	defer cancel()
+	er := c.deltaCache.Add(cctx, []domain.Interactive{delta})
+	if er != nil {
+		c.l.Error("抵消点赞数增量失败，点赞数会偏差 1",
+			logger.String("biz", biz),
+			logger.Int64("bizId", id),
+			logger.Error(er))
+	}
+	return err
+}
+
+func (c *CachedInteractiveRepository) RecordReads(ctx context.Context, reads []domain.Read) error {
+	first, err := c.readCache.Record(ctx, reads)
+	if err != nil {
+		return err
+	}
+	bizs := make([]string, 0, len(reads))
+	bizIds := make([]int64, 0, len(reads))
+	for i, read := range reads {
+		if first[i] {
+			bizs = append(bizs, read.Biz)
+			bizIds = append(bizIds, read.BizId)
+		}
+	}
+	if len(bizs) == 0 {
+		return nil
+	}
+	return c.BatchIncrReadCnt(ctx, bizs, bizIds)
+}
+
 // 确保数据一致性，数据库优先，缓存辅助，缓存不那么准确也OK
 func (c *CachedInteractiveRepository) IncrReadCnt(ctx context.Context, biz string, bizId int64) error {
+	if c.writeBehind[biz] {
+		return c.deltaCache.Add(ctx, []domain.Interactive{{Biz: biz, BizId: bizId, ReadCnt: 1}})
+	}
 	err := c.dao.IncrReadCnt(ctx, biz, bizId)
 	if err != nil {
 		return err
@@ -140,8 +518,118 @@ func (c *CachedInteractiveRepository) IncrReadCnt(ctx context.Context, biz strin
 	return c.cache.IncrReadCntIfPresent(ctx, biz, bizId)
 }
 
+func (c *CachedInteractiveRepository) DeleteByBiz(ctx context.Context, biz string, id int64) error {
+	// 先把增量写进去，不然删除之后写入的增量会把计数那一行又建出来
+	_, err := c.Flush(ctx, biz)
+	if err != nil {
+		return err
+	}
+	err = c.dao.DeleteByBiz(ctx, biz, id)
+	if err != nil {
+		return err
+	}
+	return c.cache.Del(ctx, biz, id)
+}
+
+func (c *CachedInteractiveRepository) ReconcileCursor(ctx context.Context) (uint64, error) {
+	return c.cache.GetReconcileCursor(ctx)
+}
+
+func (c *CachedInteractiveRepository) SetReconcileCursor(ctx context.Context, cursor uint64) error {
+	return c.cache.SetReconcileCursor(ctx, cursor)
+}
+
+func (c *CachedInteractiveRepository) Reconcile(ctx context.Context,
+	cursor uint64, batch int64) (ReconcileResult, uint64, error) {
+	cached, next, err := c.cache.Scan(ctx, cursor, batch)
+	if err != nil {
+		return ReconcileResult{}, 0, err
+	}
+	res := ReconcileResult{Checked: len(cached)}
+	// 按照 biz 分组，一个 biz 查一次数据库
+	groups := make(map[string][]domain.Interactive)
+	for _, intr := range cached {
+		groups[intr.Biz] = append(groups[intr.Biz], intr)
+	}
+	for biz, intrs := range groups {
+		ids := slice.Map[domain.Interactive, int64](intrs, func(idx int, src domain.Interactive) int64 {
+			return src.BizId
+		})
+		entities, err := c.dao.GetByIds(ctx, biz, ids)
+		if err != nil {
+			return res, 0, err
+		}
+		loaded := slice.Map[dao.Interactive, domain.Interactive](entities, func(idx int, src dao.Interactive) domain.Interactive {
+			return c.toDomain(src)
+		})
+		// 写回模式下缓存比数据库多出来的是还没写入的增量，不算不一致
+		loaded, err = c.withPending(ctx, biz, ids, loaded)
+		if err != nil {
+			return res, 0, err
+		}
+		// 数据库里面没有记录的，计数都是 0
+		stored := make(map[int64]domain.Interactive, len(loaded))
+		for _, ie := range loaded {
+			stored[ie.BizId] = ie
+		}
+		for _, intr := range intrs {
+			ie := stored[intr.BizId]
+			if intr.ReadCnt == ie.ReadCnt && intr.LikeCnt == ie.LikeCnt &&
+				intr.CollectCnt == ie.CollectCnt {
+				continue
+			}
+			// 读缓存和读数据库之间正好有人点赞的话也会对不上，
+			// 不过删除缓存总是安全的，最多多一次回源
+			res.Drifted++
+			c.l.Warn("计数缓存和数据库不一致",
+				logger.String("biz", biz),
+				logger.Int64("bizId", intr.BizId),
+				logger.Int64("cacheRead", intr.ReadCnt),
+				logger.Int64("dbRead", ie.ReadCnt),
+				logger.Int64("cacheLike", intr.LikeCnt),
+				logger.Int64("dbLike", ie.LikeCnt),
+				logger.Int64("cacheCollect", intr.CollectCnt),
+				logger.Int64("dbCollect", ie.CollectCnt))
+			err = c.cache.Del(ctx, biz, intr.BizId)
+			if err != nil {
+				return res, 0, err
+			}
+		}
+	}
+	return res, next, nil
+}
+
+func (c *CachedInteractiveRepository) Rebuild(ctx context.Context, biz string, id int64) (domain.Interactive, error) {
+	res, err := c.load(ctx, biz, id)
+	if err != nil {
+		return domain.Interactive{}, err
+	}
+	return res, c.cache.Set(ctx, biz, id, res)
+}
+
+func (c *CachedInteractiveRepository) Flush(ctx context.Context, biz string) (int, error) {
+	if !c.writeBehind[biz] {
+		return 0, nil
+	}
+	batch, deltas, err := c.deltaCache.Drain(ctx, biz)
+	if err != nil || len(deltas) == 0 {
+		return 0, err
+	}
+	err = c.dao.ApplyDeltas(ctx, biz, batch,
+		slice.Map[domain.Interactive, dao.Interactive](deltas, func(idx int, src domain.Interactive) dao.Interactive {
+			return dao.Interactive{BizId: src.BizId, ReadCnt: src.ReadCnt, LikeCnt: src.LikeCnt}
+		}))
+	if err != nil {
+		// 没有确认，下一次 Drain 拿到的还是这一批
+		return 0, err
+	}
+	return len(deltas), c.deltaCache.Ack(ctx, biz, batch)
+}
+
 func (c *CachedInteractiveRepository) toDomain(ie dao.Interactive) domain.Interactive {
 	return domain.Interactive{
+		Biz:        ie.Biz,
+		BizId:      ie.BizId,
 		ReadCnt:    ie.ReadCnt,
 		LikeCnt:    ie.LikeCnt,
 		CollectCnt: ie.CollectCnt,
@@ -0,0 +1,316 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository/cache"
+	"basic-go/webook/internal/repository/dao"
+	"basic-go/webook/pkg/logger"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bwmarrin/snowflake"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// interactiveTest miniredis 加上内存里的 sqlite，缓存和数据库都是真的
+type interactiveTest struct {
+	repo       *CachedInteractiveRepository
+	mr         *miniredis.Miniredis
+	db         *gorm.DB
+	dao        dao.InteractiveDAO
+	deltaCache cache.InteractiveDeltaCache
+}
+
+func newInteractiveTest(t *testing.T, writeBehind ...string) *interactiveTest {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	// 内存里的 sqlite 每个连接都是一个独立的库
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	require.NoError(t, db.AutoMigrate(&dao.Interactive{}, &dao.UserLikeBiz{}, &dao.InteractiveFlush{}))
+	// 收藏和点赞的唯一索引同名，sqlite 里面索引名是全局的，只能自己建表
+	require.NoError(t, db.Exec("CREATE TABLE `user_collection_bizs` (`id` integer PRIMARY KEY AUTOINCREMENT,"+
+		"`uid` integer,`biz_id` integer,`biz` varchar(128),`cid` integer,`utime` integer,`ctime` integer)").Error)
+	require.NoError(t, db.Exec("CREATE UNIQUE INDEX `uid_biz_type_id_collection` "+
+		"ON `user_collection_bizs`(`uid`,`biz_id`,`biz`)").Error)
+
+	node, err := snowflake.NewNode(1)
+	require.NoError(t, err)
+	d := dao.NewGORMInteractiveDAO(db)
+	deltaCache := cache.NewInteractiveDeltaRedisCache(client, node)
+	repo := NewCachedInteractiveRepository(d, logger.NewZapLogger(zap.NewNop()),
+		cache.NewInteractiveRedisCache(client),
+		cache.NewReadRedisCache(client, time.Minute),
+		deltaCache,
+		cache.NewInteractiveStateRedisCache(client),
+		writeBehind).(*CachedInteractiveRepository)
+	return &interactiveTest{repo: repo, mr: mr, db: db, dao: d, deltaCache: deltaCache}
+}
+
+// dbCnt 数据库里面的计数，没有记录就是 0
+func (it *interactiveTest) dbCnt(t *testing.T, biz string, id int64) dao.Interactive {
+	ie, err := it.dao.Get(context.Background(), biz, id)
+	if err == dao.ErrRecordNotFound {
+		return dao.Interactive{}
+	}
+	require.NoError(t, err)
+	return ie
+}
+
+func TestInteractiveFlush_Replay(t *testing.T) {
+	it := newInteractiveTest(t, "article")
+	ctx := context.Background()
+	require.NoError(t, it.repo.IncrReadCnt(ctx, "article", 1))
+	require.NoError(t, it.repo.IncrReadCnt(ctx, "article", 1))
+	require.NoError(t, it.repo.IncrLike(ctx, "article", 1, 10))
+
+	// 写入数据库之后、确认之前崩溃
+	batch, deltas, err := it.deltaCache.Drain(ctx, "article")
+	require.NoError(t, err)
+	require.NotEmpty(t, batch)
+	assert.Equal(t, []domain.Interactive{{Biz: "article", BizId: 1, ReadCnt: 2, LikeCnt: 1}}, deltas)
+	require.NoError(t, it.dao.ApplyDeltas(ctx, "article", batch,
+		[]dao.Interactive{{BizId: 1, ReadCnt: 2, LikeCnt: 1}}))
+	// 这期间新来的增量进了新的日志
+	require.NoError(t, it.repo.IncrReadCnt(ctx, "article", 1))
+
+	// 重启之后拿到的还是同一批，新的增量不在里面
+	replay, replayDeltas, err := it.deltaCache.Drain(ctx, "article")
+	require.NoError(t, err)
+	assert.Equal(t, batch, replay)
+	assert.Equal(t, deltas, replayDeltas)
+
+	cnt, err := it.repo.Flush(ctx, "article")
+	require.NoError(t, err)
+	assert.Equal(t, 1, cnt)
+	ie := it.dbCnt(t, "article", 1)
+	assert.Equal(t, int64(2), ie.ReadCnt)
+	assert.Equal(t, int64(1), ie.LikeCnt)
+
+	// 确认只删掉了写完的那一批，新的增量还在
+	pending, err := it.deltaCache.Pending(ctx, "article", []int64{1})
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]domain.Interactive{1: {Biz: "article", BizId: 1, ReadCnt: 1}}, pending)
+
+	cnt, err = it.repo.Flush(ctx, "article")
+	require.NoError(t, err)
+	assert.Equal(t, 1, cnt)
+	assert.Equal(t, int64(3), it.dbCnt(t, "article", 1).ReadCnt)
+	cnt, err = it.repo.Flush(ctx, "article")
+	require.NoError(t, err)
+	assert.Equal(t, 0, cnt)
+}
+
+func TestInteractiveDAO_ApplyDeltasSameBatch(t *testing.T) {
+	it := newInteractiveTest(t)
+	ctx := context.Background()
+	deltas := []dao.Interactive{{BizId: 1, ReadCnt: 3, LikeCnt: 1}, {BizId: 2, LikeCnt: -1}}
+	require.NoError(t, it.dao.ApplyDeltas(ctx, "article", "b1", deltas))
+	require.NoError(t, it.dao.ApplyDeltas(ctx, "article", "b1", deltas))
+	ie := it.dbCnt(t, "article", 1)
+	assert.Equal(t, int64(3), ie.ReadCnt)
+	assert.Equal(t, int64(1), ie.LikeCnt)
+	assert.Equal(t, int64(-1), it.dbCnt(t, "article", 2).LikeCnt)
+
+	// 批次是按照 biz 记的，别的 biz 用同一个批次照样写入
+	require.NoError(t, it.dao.ApplyDeltas(ctx, "comment", "b1", deltas))
+	assert.Equal(t, int64(3), it.dbCnt(t, "comment", 1).ReadCnt)
+
+	require.NoError(t, it.dao.ApplyDeltas(ctx, "article", "b2", deltas))
+	assert.Equal(t, int64(6), it.dbCnt(t, "article", 1).ReadCnt)
+}
+
+func TestInteractiveDeltaCache_Ack(t *testing.T) {
+	it := newInteractiveTest(t, "article", "comment")
+	ctx := context.Background()
+	require.NoError(t, it.repo.IncrReadCnt(ctx, "article", 1))
+	require.NoError(t, it.repo.IncrReadCnt(ctx, "comment", 1))
+	batch, _, err := it.deltaCache.Drain(ctx, "article")
+	require.NoError(t, err)
+	require.NoError(t, it.repo.IncrReadCnt(ctx, "article", 2))
+
+	require.NoError(t, it.deltaCache.Ack(ctx, "article", batch))
+	pending, err := it.deltaCache.Pending(ctx, "article", []int64{1, 2})
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]domain.Interactive{2: {Biz: "article", BizId: 2, ReadCnt: 1}}, pending)
+	pending, err = it.deltaCache.Pending(ctx, "comment", []int64{1})
+	require.NoError(t, err)
+	assert.Len(t, pending, 1)
+
+	// 别的实例已经确认过、又取出了新的一批，旧批次的确认不能把新的一批删掉
+	next, _, err := it.deltaCache.Drain(ctx, "article")
+	require.NoError(t, err)
+	require.NotEqual(t, batch, next)
+	require.NoError(t, it.deltaCache.Ack(ctx, "article", batch))
+	replay, deltas, err := it.deltaCache.Drain(ctx, "article")
+	require.NoError(t, err)
+	assert.Equal(t, next, replay)
+	assert.Equal(t, []domain.Interactive{{Biz: "article", BizId: 2, ReadCnt: 1}}, deltas)
+}
+
+// hookDAO 在真正的 DAO 前后插入一些操作，模拟并发和数据库失败
+type hookDAO struct {
+	dao.InteractiveDAO
+	setLikeErr error
+	// afterLoadAll 查完用户全部的点赞记录之后、返回之前调用
+	afterLoadAll func()
+}
+
+func (d *hookDAO) SetLikeStatus(ctx context.Context, biz string, id int64, uid int64, liked bool) (bool, error) {
+	if d.setLikeErr != nil {
+		return false, d.setLikeErr
+	}
+	return d.InteractiveDAO.SetLikeStatus(ctx, biz, id, uid, liked)
+}
+
+func (d *hookDAO) GetAllLikedBizIds(ctx context.Context, biz string, uid int64, limit int) ([]int64, error) {
+	ids, err := d.InteractiveDAO.GetAllLikedBizIds(ctx, biz, uid, limit)
+	if d.afterLoadAll != nil {
+		d.afterLoadAll()
+	}
+	return ids, err
+}
+
+func TestInteractiveRepository_StateInitRace(t *testing.T) {
+	it := newInteractiveTest(t)
+	ctx := context.Background()
+	hook := &hookDAO{InteractiveDAO: it.dao}
+	it.repo.dao = hook
+	// 查完数据库、建集合之前，用户点了赞
+	hook.afterLoadAll = func() {
+		hook.afterLoadAll = nil
+		require.NoError(t, it.repo.IncrLike(ctx, "article", 1, 10))
+	}
+	liked, err := it.repo.Liked(ctx, "article", 1, 10)
+	require.NoError(t, err)
+	assert.False(t, liked)
+	// 查到的是点赞之前的数据，不能用来建集合
+	_, err = it.repo.stateCache.Contains(ctx, cache.StateLiked, "article", 10, []int64{1})
+	assert.Equal(t, cache.ErrKeyNotExist, err)
+
+	liked, err = it.repo.Liked(ctx, "article", 1, 10)
+	require.NoError(t, err)
+	assert.True(t, liked)
+	ids, err := it.repo.stateCache.Contains(ctx, cache.StateLiked, "article", 10, []int64{1, 2})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1}, ids)
+
+	// 后开始的加载生效，先开始的那个拿着旧的 token 什么都不做
+	stale, err := it.repo.stateCache.BeginInit(ctx, cache.StateLiked, "article", 11)
+	require.NoError(t, err)
+	latest, err := it.repo.stateCache.BeginInit(ctx, cache.StateLiked, "article", 11)
+	require.NoError(t, err)
+	require.NoError(t, it.repo.stateCache.Init(ctx, cache.StateLiked, "article", 11, stale, []int64{1}))
+	_, err = it.repo.stateCache.Contains(ctx, cache.StateLiked, "article", 11, []int64{1})
+	assert.Equal(t, cache.ErrKeyNotExist, err)
+	require.NoError(t, it.repo.stateCache.Init(ctx, cache.StateLiked, "article", 11, latest, []int64{2}))
+	ids, err = it.repo.stateCache.Contains(ctx, cache.StateLiked, "article", 11, []int64{1, 2})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2}, ids)
+}
+
+func TestInteractiveRepository_Idempotent(t *testing.T) {
+	testCases := []struct {
+		name        string
+		writeBehind []string
+	}{
+		{name: "直接写数据库"},
+		{name: "写回", writeBehind: []string{"article"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			it := newInteractiveTest(t, tc.writeBehind...)
+			ctx := context.Background()
+			// 先读一次，让计数进缓存，缓存和数据库都要检查
+			_, err := it.repo.Get(ctx, "article", 1)
+			require.NoError(t, err)
+			assertCnt := func(like, collect int64) {
+				intr, err := it.repo.Get(ctx, "article", 1)
+				require.NoError(t, err)
+				assert.Equal(t, like, intr.LikeCnt)
+				assert.Equal(t, collect, intr.CollectCnt)
+				_, err = it.repo.Flush(ctx, "article")
+				require.NoError(t, err)
+				ie := it.dbCnt(t, "article", 1)
+				assert.Equal(t, like, ie.LikeCnt)
+				assert.Equal(t, collect, ie.CollectCnt)
+			}
+
+			require.NoError(t, it.repo.IncrLike(ctx, "article", 1, 10))
+			require.NoError(t, it.repo.IncrLike(ctx, "article", 1, 10))
+			assertCnt(1, 0)
+			require.NoError(t, it.repo.DecrLike(ctx, "article", 1, 10))
+			require.NoError(t, it.repo.DecrLike(ctx, "article", 1, 10))
+			assertCnt(0, 0)
+
+			require.NoError(t, it.repo.AddCollectionItem(ctx, "article", 1, 100, 10))
+			require.NoError(t, it.repo.AddCollectionItem(ctx, "article", 1, 101, 10))
+			assertCnt(0, 1)
+			require.NoError(t, it.repo.DeleteCollectionItem(ctx, "article", 1, 10))
+			require.NoError(t, it.repo.DeleteCollectionItem(ctx, "article", 1, 10))
+			assertCnt(0, 0)
+		})
+	}
+}
+
+func TestInteractiveRepository_LikeCompensation(t *testing.T) {
+	it := newInteractiveTest(t, "article")
+	ctx := context.Background()
+	_, err := it.repo.Get(ctx, "article", 1)
+	require.NoError(t, err)
+	hook := &hookDAO{InteractiveDAO: it.dao, setLikeErr: errors.New("模拟数据库错误")}
+	it.repo.dao = hook
+
+	err = it.repo.IncrLike(ctx, "article", 1, 10)
+	assert.Equal(t, hook.setLikeErr, err)
+	intr, err := it.repo.Get(ctx, "article", 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), intr.LikeCnt)
+	liked, err := it.repo.Liked(ctx, "article", 1, 10)
+	require.NoError(t, err)
+	assert.False(t, liked)
+
+	// 数据库恢复之后再点赞，只加一次
+	hook.setLikeErr = nil
+	require.NoError(t, it.repo.IncrLike(ctx, "article", 1, 10))
+	_, err = it.repo.Flush(ctx, "article")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), it.dbCnt(t, "article", 1).LikeCnt)
+	intr, err = it.repo.Get(ctx, "article", 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), intr.LikeCnt)
+}
+
+func TestInteractiveRepository_RecordReads(t *testing.T) {
+	it := newInteractiveTest(t)
+	ctx := context.Background()
+	now := time.Now()
+	reads := []domain.Read{
+		{Biz: "article", BizId: 1, Uid: 10, Time: now},
+		{Biz: "article", BizId: 1, Uid: 10, Time: now},
+		{Biz: "article", BizId: 1, Uid: 11, Time: now},
+	}
+	require.NoError(t, it.repo.RecordReads(ctx, reads))
+	require.NoError(t, it.repo.RecordReads(ctx, reads[:1]))
+	intr, err := it.repo.Get(ctx, "article", 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), intr.ReadCnt)
+	assert.Equal(t, int64(4), intr.PV)
+	assert.Equal(t, int64(2), intr.UV)
+	assert.Equal(t, int64(2), it.dbCnt(t, "article", 1).ReadCnt)
+}
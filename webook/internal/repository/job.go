@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository/dao"
+)
+
+var (
+	ErrNoJobToPreempt = dao.ErrNoJobToPreempt
+	ErrJobNotHold     = dao.ErrJobNotHold
+)
+
+type CronJobRepository interface {
+	AddJob(ctx context.Context, j domain.Job) error
+	Preempt(ctx context.Context, reclaimBefore time.Time) (domain.Job, error)
+	Heartbeat(ctx context.Context, j domain.Job) error
+	Release(ctx context.Context, j domain.Job, next time.Time) error
+	Pause(ctx context.Context, j domain.Job) error
+}
+
+type PreemptCronJobRepository struct {
+	dao dao.JobDAO
+}
+
+func NewPreemptCronJobRepository(dao dao.JobDAO) CronJobRepository {
+	return &PreemptCronJobRepository{
+		dao: dao,
+	}
+}
+
+func (p *PreemptCronJobRepository) AddJob(ctx context.Context, j domain.Job) error {
+	return p.dao.Upsert(ctx, dao.Job{
+		Name:       j.Name,
+		Expression: j.Expression,
+		Executor:   j.Executor,
+		Cfg:        j.Cfg,
+		NextTime:   j.NextTime.UnixMilli(),
+	})
+}
+
+func (p *PreemptCronJobRepository) Preempt(ctx context.Context, reclaimBefore time.Time) (domain.Job, error) {
+	j, err := p.dao.Preempt(ctx, reclaimBefore.UnixMilli())
+	if err != nil {
+		return domain.Job{}, err
+	}
+	return domain.Job{
+		Id:         j.Id,
+		Name:       j.Name,
+		Expression: j.Expression,
+		Executor:   j.Executor,
+		Cfg:        j.Cfg,
+		Version:    j.Version,
+		NextTime:   time.UnixMilli(j.NextTime),
+	}, nil
+}
+
+func (p *PreemptCronJobRepository) Heartbeat(ctx context.Context, j domain.Job) error {
+	return p.dao.Heartbeat(ctx, j.Id, j.Version)
+}
+
+func (p *PreemptCronJobRepository) Release(ctx context.Context, j domain.Job, next time.Time) error {
+	return p.dao.Release(ctx, j.Id, j.Version, next.UnixMilli())
+}
+
+func (p *PreemptCronJobRepository) Pause(ctx context.Context, j domain.Job) error {
+	return p.dao.Pause(ctx, j.Id, j.Version)
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository/cache"
+)
+
+type RankingRepository interface {
+	ReplaceTopN(ctx context.Context, arts []domain.Article) error
+	GetTopN(ctx context.Context) ([]domain.Article, error)
+}
+
+// CachedRankingRepository 榜单只存在缓存里面：
+// 先查本地缓存，再查 Redis，Redis 也出错了就用本地缓存里面过期的数据兜底
+type CachedRankingRepository struct {
+	redis *cache.RankingRedisCache
+	local *cache.RankingLocalCache
+}
+
+func NewCachedRankingRepository(redis *cache.RankingRedisCache,
+	local *cache.RankingLocalCache) RankingRepository {
+	return &CachedRankingRepository{
+		redis: redis,
+		local: local,
+	}
+}
+
+func (c *CachedRankingRepository) ReplaceTopN(ctx context.Context, arts []domain.Article) error {
+	// 榜单只需要展示摘要
+	res := make([]domain.Article, 0, len(arts))
+	for _, art := range arts {
+		art.Content = art.Abstract()
+		res = append(res, art)
+	}
+	_ = c.local.Set(ctx, res)
+	return c.redis.Set(ctx, res)
+}
+
+func (c *CachedRankingRepository) GetTopN(ctx context.Context) ([]domain.Article, error) {
+	res, err := c.local.Get(ctx)
+	if err == nil {
+		return res, nil
+	}
+	res, err = c.redis.Get(ctx)
+	if err != nil {
+		return c.local.ForceGet(ctx)
+	}
+	_ = c.local.Set(ctx, res)
+	return res, nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/pkg/fulltext"
+)
+
+const (
+	// searchSnippetLen 摘要最多多少个字
+	searchSnippetLen    = 120
+	searchHighlightPre  = "<em>"
+	searchHighlightPost = "</em>"
+)
+
+type SearchRepository interface {
+	// InputArticle 把已发表的文章放进索引，已经存在就覆盖
+	InputArticle(ctx context.Context, art domain.Article) error
+	DeleteArticle(ctx context.Context, id int64) error
+	// SearchArticle 按照相关度排序，返回这一页的结果以及命中的总数
+	SearchArticle(ctx context.Context, query string, offset int, limit int) ([]domain.ArticleSearchHit, int, error)
+}
+
+// LocalSearchRepository 基于进程内的倒排索引，不依赖外部的搜索集群
+type LocalSearchRepository struct {
+	idx *fulltext.Index
+}
+
+func NewLocalSearchRepository(idx *fulltext.Index) SearchRepository {
+	return &LocalSearchRepository{
+		idx: idx,
+	}
+}
+
+func (r *LocalSearchRepository) InputArticle(ctx context.Context, art domain.Article) error {
+	return r.idx.Upsert(fulltext.Document{
+		Id:       art.Id,
+		Title:    art.Title,
+		Content:  art.Content,
+		AuthorId: art.Author.Id,
+		Utime:    art.Utime.UnixMilli(),
+	})
+}
+
+func (r *LocalSearchRepository) DeleteArticle(ctx context.Context, id int64) error {
+	return r.idx.Delete(id)
+}
+
+func (r *LocalSearchRepository) SearchArticle(ctx context.Context, query string,
+	offset int, limit int) ([]domain.ArticleSearchHit, int, error) {
+	hits, total := r.idx.Search(query, offset, limit)
+	terms := r.idx.Terms(query)
+	res := make([]domain.ArticleSearchHit, 0, len(hits))
+	for _, hit := range hits {
+		res = append(res, domain.ArticleSearchHit{
+			Id: hit.Doc.Id,
+			// 标题不截断
+			Title: fulltext.Highlight(hit.Doc.Title, terms, len([]rune(hit.Doc.Title)),
+				searchHighlightPre, searchHighlightPost),
+			Snippet: fulltext.Highlight(hit.Doc.Content, terms, searchSnippetLen,
+				searchHighlightPre, searchHighlightPost),
+			Author: domain.Author{Id: hit.Doc.AuthorId},
+			Utime:  time.UnixMilli(hit.Doc.Utime),
+			Score:  hit.Score,
+		})
+	}
+	return res, total, nil
+}
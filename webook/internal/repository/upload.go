@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository/dao"
+
+	"github.com/ecodeclub/ekit/slice"
+)
+
+var ErrUploadQuotaExceeded = dao.ErrUploadQuotaExceeded
+
+type UploadRepository interface {
+	// Create 用户之前传过同样内容的文件的话，返回之前的记录
+	Create(ctx context.Context, u domain.Upload, quota int64) (domain.Upload, error)
+	CountByHash(ctx context.Context, hash string) (int64, error)
+	GetUsage(ctx context.Context, uid int64) (int64, error)
+	FindBefore(ctx context.Context, ctime time.Time, afterId int64, limit int) ([]domain.Upload, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+type uploadRepository struct {
+	dao dao.UploadDAO
+}
+
+func NewUploadRepository(dao dao.UploadDAO) UploadRepository {
+	return &uploadRepository{
+		dao: dao,
+	}
+}
+
+func (u *uploadRepository) Create(ctx context.Context, up domain.Upload, quota int64) (domain.Upload, error) {
+	res, err := u.dao.Insert(ctx, u.toEntity(up), quota)
+	if err != nil {
+		return domain.Upload{}, err
+	}
+	return u.toDomain(res), nil
+}
+
+func (u *uploadRepository) CountByHash(ctx context.Context, hash string) (int64, error) {
+	return u.dao.CountByHash(ctx, hash)
+}
+
+func (u *uploadRepository) GetUsage(ctx context.Context, uid int64) (int64, error) {
+	return u.dao.GetUsage(ctx, uid)
+}
+
+func (u *uploadRepository) FindBefore(ctx context.Context, ctime time.Time, afterId int64, limit int) ([]domain.Upload, error) {
+	res, err := u.dao.FindBefore(ctx, ctime.UnixMilli(), afterId, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map(res, func(idx int, src dao.Upload) domain.Upload {
+		return u.toDomain(src)
+	}), nil
+}
+
+func (u *uploadRepository) Delete(ctx context.Context, id int64) error {
+	return u.dao.Delete(ctx, id)
+}
+
+func (u *uploadRepository) toEntity(up domain.Upload) dao.Upload {
+	return dao.Upload{
+		Id:           up.Id,
+		Uid:          up.Uid,
+		Hash:         up.Hash,
+		Name:         up.Name,
+		ContentType:  up.ContentType,
+		Size:         up.Size,
+		HasThumbnail: up.HasThumbnail,
+	}
+}
+
+func (u *uploadRepository) toDomain(up dao.Upload) domain.Upload {
+	return domain.Upload{
+		Id:           up.Id,
+		Uid:          up.Uid,
+		Hash:         up.Hash,
+		Name:         up.Name,
+		ContentType:  up.ContentType,
+		Size:         up.Size,
+		HasThumbnail: up.HasThumbnail,
+		Ctime:        time.UnixMilli(up.Ctime),
+	}
+}
@@ -23,7 +23,11 @@ type UserRepository interface {
 	UpdateNonZeroFields(ctx context.Context, user domain.User) error
 	FindByPhone(ctx context.Context, phone string) (domain.User, error)
 	FindById(ctx context.Context, uid int64) (domain.User, error)
+	// FindByIds 批量查询，先查缓存，没命中的再一次性查数据库。
+	// 不存在的用户不会出现在结果里面
+	FindByIds(ctx context.Context, uids []int64) (map[int64]domain.User, error)
 	FindByWechat(ctx context.Context, openId string) (domain.User, error)
+	UpdateAvatar(ctx context.Context, uid int64, avatar string) error
 }
 
 type CachedUserRepository struct {
@@ -61,6 +65,18 @@ func (repo *CachedUserRepository) UpdateNonZeroFields(ctx context.Context,
 	user domain.User) error {
 	return repo.dao.UpdateById(ctx, repo.toEntity(user))
 }
+func (repo *CachedUserRepository) UpdateAvatar(ctx context.Context, uid int64, avatar string) error {
+	err := repo.dao.UpdateAvatar(ctx, uid, avatar)
+	if err != nil {
+		return err
+	}
+	// 头像会出现在点赞列表、作者主页这些地方，删掉缓存让下一次查询重新加载
+	err = repo.cache.Del(ctx, uid)
+	if err != nil {
+		log.Println(err)
+	}
+	return nil
+}
 func (repo *CachedUserRepository) FindById(ctx context.Context, uid int64) (domain.User, error) {
 	du, err := repo.cache.Get(ctx, uid)
 	// 只要 err 为 nil，就返回
@@ -95,6 +111,44 @@ func (repo *CachedUserRepository) FindById(ctx context.Context, uid int64) (doma
 	return du, nil
 }
 
+func (repo *CachedUserRepository) FindByIds(ctx context.Context, uids []int64) (map[int64]domain.User, error) {
+	if len(uids) == 0 {
+		return map[int64]domain.User{}, nil
+	}
+	res, err := repo.cache.GetByIds(ctx, uids)
+	if err != nil {
+		// 缓存出问题了就全部查数据库
+		log.Println(err)
+		res = make(map[int64]domain.User, len(uids))
+	}
+	missed := make([]int64, 0, len(uids)-len(res))
+	for _, uid := range uids {
+		if _, ok := res[uid]; !ok {
+			missed = append(missed, uid)
+		}
+	}
+	if len(missed) == 0 {
+		return res, nil
+	}
+	ues, err := repo.dao.FindByIds(ctx, missed)
+	if err != nil {
+		return nil, err
+	}
+	loaded := make([]domain.User, 0, len(ues))
+	for _, ue := range ues {
+		du := repo.toDomain(ue)
+		res[du.Id] = du
+		loaded = append(loaded, du)
+	}
+	if len(loaded) > 0 {
+		err = repo.cache.SetAll(ctx, loaded)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+	return res, nil
+}
+
 func (repo *CachedUserRepository) FindByIdV1(ctx context.Context, uid int64) (domain.User, error) {
 	du, err := repo.cache.Get(ctx, uid)
 	// 只要 err 为 nil，就返回
@@ -155,6 +209,7 @@ func (repo *CachedUserRepository) toEntity(u domain.User) dao.User {
 		},
 		AboutMe:  u.AboutMe,
 		Nickname: u.Nickname,
+		Avatar:   u.Avatar,
 	}
 }
 func (repo *CachedUserRepository) toDomain(u dao.User) domain.User {
@@ -165,6 +220,7 @@ func (repo *CachedUserRepository) toDomain(u dao.User) domain.User {
 		Password: u.Password,
 		AboutMe:  u.AboutMe,
 		Nickname: u.Nickname,
+		Avatar:   u.Avatar,
 		Birthday: time.UnixMilli(u.Birthday),
 		Ctime:    time.UnixMilli(u.Ctime),
 		WechatInfo: domain.WechatInfo{
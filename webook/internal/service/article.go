@@ -4,9 +4,12 @@ import (
 	"basic-go/webook/internal/domain"
 	"basic-go/webook/internal/events/article"
 	"basic-go/webook/internal/repository"
+	"basic-go/webook/internal/service/moderation"
+	"basic-go/webook/pkg/diff"
 	"basic-go/webook/pkg/logger"
 	"context"
 	"fmt"
+	"time"
 )
 
 type ArticleService interface {
@@ -15,12 +18,46 @@ type ArticleService interface {
 	Withdraw(ctx context.Context, uid int64, id int64) error
 	GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error)
 	GetById(ctx context.Context, id int64) (domain.Article, error)
-	GetPubById(ctx context.Context, id, uid int64) (domain.Article, error)
+	// GetPubById 读者看文章，uid 没有登录的时候是 0，ip 用来给没有登录的读者去重
+	GetPubById(ctx context.Context, id, uid int64, ip string) (domain.Article, error)
+
+	// ListRevisions 列出文章的历史版本，最新的在前面
+	ListRevisions(ctx context.Context, uid int64, aid int64, offset int, limit int) ([]domain.ArticleRevision, error)
+	GetRevision(ctx context.Context, uid int64, id int64) (domain.ArticleRevision, error)
+	// DiffRevisions 按行比较两个版本，from 是旧版本，to 是新版本
+	DiffRevisions(ctx context.Context, uid int64, from int64, to int64) ([]diff.Line, error)
+	// RestoreRevision 用历史版本覆盖当前草稿，返回文章 ID
+	RestoreRevision(ctx context.Context, uid int64, id int64) (int64, error)
+
+	// ListScheduled 列出等待定时发表的文章，快要发表的在前面
+	ListScheduled(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error)
+	Reschedule(ctx context.Context, uid int64, id int64, publishAt time.Time) error
+	// CancelSchedule 取消定时发表，文章退回草稿
+	CancelSchedule(ctx context.Context, uid int64, id int64) error
+
+	ListPubByTag(ctx context.Context, tag string, offset int, limit int) ([]domain.Article, error)
+	TagCloud(ctx context.Context, limit int) ([]domain.TagCount, error)
+	GetCategories(ctx context.Context, uid int64) ([]string, error)
+
+	// ListPubByAuthor 作者主页上已经发表的文章，ListNewestPub 全站最新发表的文章，
+	// 都按照 (utime, id) 游标分页，maxId 为 0 代表第一页
+	ListPubByAuthor(ctx context.Context, uid int64, utime time.Time, maxId int64, limit int) ([]domain.Article, error)
+	ListNewestPub(ctx context.Context, utime time.Time, maxId int64, limit int) ([]domain.Article, error)
+
+	// Delete 放进回收站，保留期内可以 Restore
+	Delete(ctx context.Context, uid int64, id int64) error
+	Restore(ctx context.Context, uid int64, id int64) error
+	// ListTrash 回收站里面的文章，最近删除的在前面
+	ListTrash(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error)
 }
 
 type articleService struct {
-	repo     repository.ArticleRepository
-	producer article.Producer
+	repo         repository.ArticleRepository
+	revisionRepo repository.ArticleRevisionRepository
+	producer     article.Producer
+	// 发表之前先过一遍机器审核，没通过的交给人工审核
+	moderator  *moderation.Moderator
+	reviewRepo repository.ArticleReviewRepository
 
 	// V1 写法专用
 	// readerRepo repository.ArticleReaderRepository
@@ -39,22 +76,35 @@ type articleService struct {
 // }
 
 func NewArticleService(repo repository.ArticleRepository,
-	producer article.Producer) ArticleService {
+	revisionRepo repository.ArticleRevisionRepository,
+	producer article.Producer,
+	moderator *moderation.Moderator,
+	reviewRepo repository.ArticleReviewRepository,
+	l logger.LoggerV1) ArticleService {
 	return &articleService{
-		repo:     repo,
-		producer: producer,
+		repo:         repo,
+		revisionRepo: revisionRepo,
+		producer:     producer,
+		moderator:    moderator,
+		reviewRepo:   reviewRepo,
+		l:            l,
 	}
 }
 
-func (a *articleService) GetPubById(ctx context.Context, id, uid int64) (domain.Article, error) {
+func (a *articleService) GetPubById(ctx context.Context, id, uid int64, ip string) (domain.Article, error) {
 	res, err := a.repo.GetPubById(ctx, id)
+	if err == nil {
+		res = a.ensureRendered(ctx, res)
+	}
 	go func() {
 		if err == nil {
 			// 在这里发一个消息
 			fmt.Println("Aid: ", id, "   uid:", uid)
 			er := a.producer.ProduceReadEvent(article.ReadEvent{
-				Aid: id,
-				Uid: uid,
+				Aid:   id,
+				Uid:   uid,
+				IP:    ip,
+				Ctime: time.Now().UnixMilli(),
 			})
 			if er != nil {
 				a.l.Error("发送 ReadEvent 失败",
@@ -79,16 +129,58 @@ func (a *articleService) GetByAuthor(ctx context.Context, uid int64, offset int,
 	return a.repo.GetByAuthor(ctx, uid, offset, limit)
 }
 
+func (a *articleService) ListPubByAuthor(ctx context.Context, uid int64,
+	utime time.Time, maxId int64, limit int) ([]domain.Article, error) {
+	return a.repo.GetPubByAuthor(ctx, uid, utime, maxId, limit)
+}
+
+func (a *articleService) ListNewestPub(ctx context.Context,
+	utime time.Time, maxId int64, limit int) ([]domain.Article, error) {
+	return a.repo.ListNewestPub(ctx, utime, maxId, limit)
+}
+
 func (a *articleService) Withdraw(ctx context.Context, uid int64, id int64) error {
-	return a.repo.SyncStatus(ctx, uid, id, domain.ArticleStatusPrivate)
+	err := a.repo.SyncStatus(ctx, uid, id, domain.ArticleStatusPrivate)
+	if err == nil {
+		go producePublishEvent(a.producer, a.l, domain.Article{
+			Id:     id,
+			Author: domain.Author{Id: uid},
+		}, article.PublishTypeWithdraw)
+	}
+	return err
 }
 
+// Publish 发表文章，如果 art.PublishAt 在未来，那么只是定时，到点了才会真的发表。
+// 机器审核没通过的话，返回文章 ID 和 ErrArticlePendingReview
 func (a *articleService) Publish(ctx context.Context, art domain.Article) (int64, error) {
+	var err error
+	art.Tags, err = normalizeTags(art.Tags)
+	if err != nil {
+		return 0, err
+	}
+	if violations := a.moderator.Check(ctx, art); len(violations) > 0 {
+		return a.submitForReview(ctx, art, violations)
+	}
+	if art.PublishAt.After(time.Now()) {
+		return a.schedule(ctx, art)
+	}
+	art.PublishAt = time.Time{}
 	// art.Status = domain.ArticleStatusPublished
 	// return a.repo.Sync(ctx, art)
 	art.Status = domain.ArticleStatusPublished
+	art.Rendered = renderContent(a.l, art)
 	res, err := a.repo.Sync(ctx, art)
 	fmt.Println("res: ", res)
+	if err == nil {
+		art.Id = res
+		a.snapshot(ctx, art)
+		go func() {
+			producePublishEvent(a.producer, a.l, art, article.PublishTypePublish)
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			a.moderator.Record(ctx, art)
+		}()
+	}
 	go func() {
 		if err == nil {
 			// 在这里发一个消息
@@ -108,6 +200,26 @@ func (a *articleService) Publish(ctx context.Context, art domain.Article) (int64
 	return res, err
 }
 
+// producePublishEvent 发表事件发送失败不影响发表本身，
+// 只是搜索之类的下游会晚一点才能看到这篇文章
+func producePublishEvent(producer article.Producer, l logger.LoggerV1,
+	art domain.Article, typ string) {
+	er := producer.ProducePublishEvent(article.PublishEvent{
+		Aid:     art.Id,
+		Uid:     art.Author.Id,
+		Title:   art.Title,
+		Content: art.Content,
+		Type:    typ,
+		Utime:   time.Now().UnixMilli(),
+	})
+	if er != nil {
+		l.Error("发送 PublishEvent 失败",
+			logger.Int64("aid", art.Id),
+			logger.String("type", typ),
+			logger.Error(er))
+	}
+}
+
 // 具备重试机制的发布
 // func (a *articleService) PublishV1(ctx context.Context, art domain.Article) (int64, error) {
 // 	// 想到这里要先操作制作库
@@ -145,10 +257,23 @@ func (a *articleService) Publish(ctx context.Context, art domain.Article) (int64
 // }
 
 func (a *articleService) Save(ctx context.Context, art domain.Article) (int64, error) {
+	var err error
+	art.Tags, err = normalizeTags(art.Tags)
+	if err != nil {
+		return 0, err
+	}
 	art.Status = domain.ArticleStatusUnpublished
 	if art.Id > 0 {
-		err := a.repo.Update(ctx, art)
+		err = a.repo.Update(ctx, art)
+		if err == nil {
+			a.snapshot(ctx, art)
+		}
 		return art.Id, err
 	}
-	return a.repo.Create(ctx, art)
+	id, err := a.repo.Create(ctx, art)
+	if err == nil {
+		art.Id = id
+		a.snapshot(ctx, art)
+	}
+	return id, err
 }
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/pkg/logger"
+	"basic-go/webook/pkg/markdown"
+
+	"github.com/ecodeclub/ekit/slice"
+)
+
+// renderContent 把 Markdown 渲染成过滤过的 HTML，同时生成目录和阅读时间。
+// 发表的时候渲染一次，和线上库的文章一起缓存，读的时候就不需要每次都渲染了
+func renderContent(l logger.LoggerV1, art domain.Article) domain.RenderedContent {
+	doc, err := markdown.Render(art.Content)
+	if err != nil {
+		// 渲染失败不影响发表，读的时候会再渲染一次
+		l.Error("渲染文章内容失败",
+			logger.Int64("aid", art.Id),
+			logger.Error(err))
+		return domain.RenderedContent{}
+	}
+	return domain.RenderedContent{
+		HTML: doc.HTML,
+		TOC: slice.Map(doc.Headings, func(idx int, src markdown.Heading) domain.TOCItem {
+			return domain.TOCItem{
+				Level:  src.Level,
+				Title:  src.Text,
+				Anchor: src.Id,
+			}
+		}),
+		ReadingTime: markdown.ReadingTime(doc.Text),
+	}
+}
+
+// ensureRendered 数据库里面只有原始内容，缓存没有命中的时候要重新渲染，并且放回缓存。
+// 仓库查数据库之后也会异步回写一次没有渲染的缓存，如果它覆盖了这里的结果，下次读的时候再渲染一次就好
+func (a *articleService) ensureRendered(ctx context.Context, art domain.Article) domain.Article {
+	if art.Rendered.HTML != "" || art.Content == "" {
+		return art
+	}
+	art.Rendered = renderContent(a.l, art)
+	if art.Rendered.HTML == "" {
+		return art
+	}
+	if err := a.repo.CachePub(ctx, art); err != nil {
+		a.l.Error("缓存渲染之后的文章失败",
+			logger.Int64("aid", art.Id),
+			logger.Error(err))
+	}
+	return art
+}
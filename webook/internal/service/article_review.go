@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/events/article"
+	"basic-go/webook/internal/repository"
+	"basic-go/webook/internal/service/moderation"
+	"basic-go/webook/pkg/logger"
+)
+
+var (
+	ErrArticlePendingReview = errors.New("文章需要人工审核，审核通过之后会自动发表")
+	ErrReviewNotFound       = repository.ErrReviewNotFound
+	ErrReviewResolved       = repository.ErrReviewResolved
+	ErrReviewOutdated       = errors.New("作者已经修改或者删除了文章，这次审核作废")
+	ErrEmptyRejectReason    = errors.New("驳回必须填写理由")
+)
+
+// ArticleReviewService 审核员处理机器审核没通过的文章
+type ArticleReviewService interface {
+	ListPending(ctx context.Context, offset int, limit int) ([]domain.ArticleReview, error)
+	// Approve 通过之后文章立刻发表，定时发表的文章还没到时间的话继续等
+	Approve(ctx context.Context, reviewerId int64, id int64, reason string) error
+	// Reject 驳回之后文章只有作者自己能看到，作者修改之后可以重新发表
+	Reject(ctx context.Context, reviewerId int64, id int64, reason string) error
+}
+
+type articleReviewService struct {
+	repo       repository.ArticleRepository
+	reviewRepo repository.ArticleReviewRepository
+	moderator  *moderation.Moderator
+	producer   article.Producer
+	l          logger.LoggerV1
+}
+
+func NewArticleReviewService(repo repository.ArticleRepository,
+	reviewRepo repository.ArticleReviewRepository,
+	moderator *moderation.Moderator,
+	producer article.Producer,
+	l logger.LoggerV1) ArticleReviewService {
+	return &articleReviewService{
+		repo:       repo,
+		reviewRepo: reviewRepo,
+		moderator:  moderator,
+		producer:   producer,
+		l:          l,
+	}
+}
+
+func (s *articleReviewService) ListPending(ctx context.Context, offset int, limit int) ([]domain.ArticleReview, error) {
+	return s.reviewRepo.ListPending(ctx, offset, limit)
+}
+
+func (s *articleReviewService) Approve(ctx context.Context, reviewerId int64, id int64, reason string) error {
+	r, art, err := s.pending(ctx, reviewerId, id)
+	if err != nil {
+		return err
+	}
+	published := !art.PublishAt.After(time.Now())
+	if published {
+		art.PublishAt = time.Time{}
+		art.Status = domain.ArticleStatusPublished
+		art.Rendered = renderContent(s.l, art)
+		_, err = s.repo.Sync(ctx, art)
+	} else {
+		art.Status = domain.ArticleStatusScheduled
+		err = s.repo.Update(ctx, art)
+	}
+	if err != nil {
+		return err
+	}
+	r.Status = domain.ReviewStatusApproved
+	r.ReviewerId = reviewerId
+	r.Reason = strings.TrimSpace(reason)
+	err = s.reviewRepo.Resolve(ctx, r)
+	if err != nil {
+		return err
+	}
+	if published {
+		go func() {
+			producePublishEvent(s.producer, s.l, art, article.PublishTypePublish)
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			s.moderator.Record(ctx, art)
+		}()
+	}
+	go s.produceReviewEvent(r, article.ReviewResultApproved)
+	return nil
+}
+
+func (s *articleReviewService) Reject(ctx context.Context, reviewerId int64, id int64, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return ErrEmptyRejectReason
+	}
+	r, art, err := s.pending(ctx, reviewerId, id)
+	if err != nil {
+		return err
+	}
+	art.Status = domain.ArticleStatusRejected
+	err = s.repo.Update(ctx, art)
+	if err != nil {
+		return err
+	}
+	r.Status = domain.ReviewStatusRejected
+	r.ReviewerId = reviewerId
+	r.Reason = reason
+	err = s.reviewRepo.Resolve(ctx, r)
+	if err != nil {
+		return err
+	}
+	go s.produceReviewEvent(r, article.ReviewResultRejected)
+	return nil
+}
+
+// pending 找到待审核的记录和对应的文章。
+// 提交审核之后作者又保存过，或者删除了文章的话，这条记录直接作废
+func (s *articleReviewService) pending(ctx context.Context, reviewerId int64,
+	id int64) (domain.ArticleReview, domain.Article, error) {
+	r, err := s.reviewRepo.GetById(ctx, id)
+	if err != nil {
+		return domain.ArticleReview{}, domain.Article{}, err
+	}
+	if r.Status != domain.ReviewStatusPending {
+		return domain.ArticleReview{}, domain.Article{}, ErrReviewResolved
+	}
+	art, err := s.repo.GetById(ctx, r.ArticleId)
+	if err != nil && err != repository.ErrArticleNotFound {
+		return domain.ArticleReview{}, domain.Article{}, err
+	}
+	if err != nil || art.Status != domain.ArticleStatusPendingReview || !art.DeletedAt.IsZero() {
+		r.Status = domain.ReviewStatusOutdated
+		r.ReviewerId = reviewerId
+		err = s.reviewRepo.Resolve(ctx, r)
+		if err != nil {
+			return domain.ArticleReview{}, domain.Article{}, err
+		}
+		return domain.ArticleReview{}, domain.Article{}, ErrReviewOutdated
+	}
+	return r, art, nil
+}
+
+func (s *articleReviewService) produceReviewEvent(r domain.ArticleReview, result string) {
+	er := s.producer.ProduceReviewEvent(article.ReviewEvent{
+		ReviewId:   r.Id,
+		Aid:        r.ArticleId,
+		Uid:        r.Author.Id,
+		ReviewerId: r.ReviewerId,
+		Result:     result,
+		Reason:     r.Reason,
+		Utime:      time.Now().UnixMilli(),
+	})
+	if er != nil {
+		s.l.Error("发送 ReviewEvent 失败",
+			logger.Int64("review_id", r.Id),
+			logger.Int64("aid", r.ArticleId),
+			logger.String("result", result),
+			logger.Error(er))
+	}
+}
+
+// submitForReview 机器审核没通过，文章保存成待审核状态，等审核员处理
+func (a *articleService) submitForReview(ctx context.Context, art domain.Article,
+	violations []string) (int64, error) {
+	art.Status = domain.ArticleStatusPendingReview
+	var (
+		id  = art.Id
+		err error
+	)
+	if id > 0 {
+		err = a.repo.Update(ctx, art)
+	} else {
+		id, err = a.repo.Create(ctx, art)
+	}
+	if err != nil {
+		return 0, err
+	}
+	art.Id = id
+	a.snapshot(ctx, art)
+	_, err = a.reviewRepo.Submit(ctx, domain.ArticleReview{
+		ArticleId:  id,
+		Author:     art.Author,
+		Title:      art.Title,
+		Content:    art.Content,
+		Violations: violations,
+	})
+	if err != nil {
+		return id, err
+	}
+	return id, ErrArticlePendingReview
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/pkg/diff"
+	"basic-go/webook/pkg/logger"
+)
+
+var ErrRevisionNotBelongToAuthor = errors.New("历史版本不属于当前用户")
+
+// snapshot 保存历史版本失败不影响主流程，文章本身已经保存成功了
+func (a *articleService) snapshot(ctx context.Context, art domain.Article) {
+	_, err := a.revisionRepo.Add(ctx, art)
+	if err != nil {
+		a.l.Error("保存文章历史版本失败",
+			logger.Int64("aid", art.Id),
+			logger.Int64("uid", art.Author.Id),
+			logger.Error(err))
+	}
+}
+
+func (a *articleService) ListRevisions(ctx context.Context, uid int64, aid int64, offset int, limit int) ([]domain.ArticleRevision, error) {
+	art, err := a.repo.GetById(ctx, aid)
+	if err != nil {
+		return nil, err
+	}
+	if art.Author.Id != uid {
+		return nil, ErrRevisionNotBelongToAuthor
+	}
+	return a.revisionRepo.GetByArticle(ctx, aid, offset, limit)
+}
+
+func (a *articleService) GetRevision(ctx context.Context, uid int64, id int64) (domain.ArticleRevision, error) {
+	rev, err := a.revisionRepo.GetById(ctx, id)
+	if err != nil {
+		return domain.ArticleRevision{}, err
+	}
+	if rev.Author.Id != uid {
+		return domain.ArticleRevision{}, ErrRevisionNotBelongToAuthor
+	}
+	return rev, nil
+}
+
+func (a *articleService) DiffRevisions(ctx context.Context, uid int64, from int64, to int64) ([]diff.Line, error) {
+	src, err := a.GetRevision(ctx, uid, from)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := a.GetRevision(ctx, uid, to)
+	if err != nil {
+		return nil, err
+	}
+	if src.ArticleId != dst.ArticleId {
+		return nil, errors.New("只能比较同一篇文章的历史版本")
+	}
+	// 标题也当成一行参与比较，这样标题的修改也能看出来
+	return diff.Lines(src.Title+"\n"+src.Content, dst.Title+"\n"+dst.Content), nil
+}
+
+func (a *articleService) RestoreRevision(ctx context.Context, uid int64, id int64) (int64, error) {
+	rev, err := a.GetRevision(ctx, uid, id)
+	if err != nil {
+		return 0, err
+	}
+	cur, err := a.repo.GetById(ctx, rev.ArticleId)
+	if err != nil {
+		return 0, err
+	}
+	art := rev.Article()
+	// 历史版本只记录了标题和内容，分类和标签保持现状
+	art.Category = cur.Category
+	art.Tags = cur.Tags
+	// 恢复本身也是一次保存，所以也会留下一个新的版本
+	return a.Save(ctx, art)
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/events/article"
+	"basic-go/webook/internal/repository"
+	"basic-go/webook/internal/service/moderation"
+	"basic-go/webook/pkg/logger"
+)
+
+var (
+	ErrInvalidPublishTime = errors.New("定时发表的时间必须晚于当前时间")
+	// ErrScheduledArticleNotFound 文章不存在、不是自己的，或者不是定时发表状态
+	ErrScheduledArticleNotFound = repository.ErrScheduledArticleNotFound
+)
+
+func (a *articleService) schedule(ctx context.Context, art domain.Article) (int64, error) {
+	art.Status = domain.ArticleStatusScheduled
+	var (
+		id  = art.Id
+		err error
+	)
+	if id > 0 {
+		err = a.repo.Update(ctx, art)
+	} else {
+		id, err = a.repo.Create(ctx, art)
+	}
+	if err == nil {
+		art.Id = id
+		a.snapshot(ctx, art)
+	}
+	return id, err
+}
+
+func (a *articleService) ListScheduled(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error) {
+	return a.repo.GetScheduledByAuthor(ctx, uid, offset, limit)
+}
+
+func (a *articleService) Reschedule(ctx context.Context, uid int64, id int64, publishAt time.Time) error {
+	if !publishAt.After(time.Now()) {
+		return ErrInvalidPublishTime
+	}
+	return a.repo.Reschedule(ctx, uid, id, publishAt)
+}
+
+func (a *articleService) CancelSchedule(ctx context.Context, uid int64, id int64) error {
+	return a.repo.CancelSchedule(ctx, uid, id)
+}
+
+// ScheduledPublisher 负责把到点的定时文章发表出去
+// 和 async.Service 一样，是最简单的抢占式调度，部署多少个实例都可以
+type ScheduledPublisher struct {
+	repo      repository.ArticleRepository
+	producer  article.Producer
+	moderator *moderation.Moderator
+	l         logger.LoggerV1
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewScheduledPublisher 定时发表的文章在定时的时候已经审核过了，这里不需要再审核
+func NewScheduledPublisher(repo repository.ArticleRepository,
+	producer article.Producer, moderator *moderation.Moderator,
+	l logger.LoggerV1) *ScheduledPublisher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ScheduledPublisher{
+		repo:      repo,
+		producer:  producer,
+		moderator: moderator,
+		l:         l,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Start 开启后台循环
+func (s *ScheduledPublisher) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for s.ctx.Err() == nil {
+			s.PublishDue()
+		}
+	}()
+}
+
+// Stop 停止抢占新的文章，等待正在发表的那一篇发表完。
+// ctx 到期了还没结束就直接返回，抢占了没发表的文章过一会儿会被别的节点再次抢占
+func (s *ScheduledPublisher) Stop(ctx context.Context) error {
+	s.cancel()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// PublishDue 抢占一篇到点的文章并发表
+func (s *ScheduledPublisher) PublishDue() {
+	ctx, cancel := context.WithTimeout(s.ctx, time.Second)
+	art, err := s.repo.PreemptScheduled(ctx)
+	cancel()
+	switch err {
+	case nil:
+		ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		art.Status = domain.ArticleStatusPublished
+		art.Rendered = renderContent(s.l, art)
+		_, err = s.repo.Sync(ctx, art)
+		if err != nil {
+			// 不需要额外处理，过一会儿别的节点（或者自己）会再次抢占它
+			s.l.Error("定时发表文章失败",
+				logger.Int64("aid", art.Id),
+				logger.Int64("uid", art.Author.Id),
+				logger.Error(err))
+			return
+		}
+		producePublishEvent(s.producer, s.l, art, article.PublishTypePublish)
+		s.moderator.Record(ctx, art)
+	case repository.ErrScheduledArticleNotFound:
+		// 没有到点的文章，睡一秒
+		s.sleep(time.Second)
+	default:
+		if s.ctx.Err() != nil {
+			// 正在停止
+			return
+		}
+		s.l.Error("抢占定时发表的文章失败", logger.Error(err))
+		s.sleep(time.Second)
+	}
+}
+
+// sleep 停止的时候马上醒过来
+func (s *ScheduledPublisher) sleep(d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-s.ctx.Done():
+	}
+}
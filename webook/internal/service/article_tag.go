@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"basic-go/webook/internal/domain"
+)
+
+const (
+	// maxTagsPerArticle 一篇文章最多能打多少个标签
+	maxTagsPerArticle = 10
+	// maxTagLength 标签的最大长度，按照字符算
+	maxTagLength = 32
+)
+
+var ErrInvalidTags = errors.New("标签数量太多或者标签太长")
+
+// normalizeTags 去掉首尾空白、空标签和重复的标签，保留原本的顺序
+func normalizeTags(tags []string) ([]string, error) {
+	res := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, ErrInvalidTags
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		res = append(res, tag)
+	}
+	if len(res) > maxTagsPerArticle {
+		return nil, ErrInvalidTags
+	}
+	return res, nil
+}
+
+func (a *articleService) ListPubByTag(ctx context.Context, tag string, offset int, limit int) ([]domain.Article, error) {
+	return a.repo.ListPubByTag(ctx, strings.TrimSpace(tag), offset, limit)
+}
+
+func (a *articleService) TagCloud(ctx context.Context, limit int) ([]domain.TagCount, error) {
+	return a.repo.TagCloud(ctx, limit)
+}
+
+func (a *articleService) GetCategories(ctx context.Context, uid int64) ([]string, error) {
+	return a.repo.GetCategories(ctx, uid)
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/events/article"
+	"basic-go/webook/internal/repository"
+	"basic-go/webook/pkg/logger"
+)
+
+var ErrArticleNotFound = repository.ErrArticleNotFound
+
+const (
+	// purgeBatch 清理回收站的时候每一批处理多少篇文章
+	purgeBatch = 100
+	// articleBiz 文章在点赞、收藏这些通用功能里面的 biz
+	articleBiz = "article"
+)
+
+func (a *articleService) Delete(ctx context.Context, uid int64, id int64) error {
+	err := a.repo.Delete(ctx, uid, id)
+	if err == nil {
+		// 对搜索和关注流来说，删除和撤回是一样的
+		go producePublishEvent(a.producer, a.l, domain.Article{
+			Id:     id,
+			Author: domain.Author{Id: uid},
+		}, article.PublishTypeWithdraw)
+	}
+	return err
+}
+
+func (a *articleService) Restore(ctx context.Context, uid int64, id int64) error {
+	err := a.repo.Restore(ctx, uid, id)
+	if err != nil {
+		return err
+	}
+	// 删除之前是发表状态的话，搜索和关注流要重新加回来
+	pub, err := a.repo.GetPubById(ctx, id)
+	switch {
+	case err == nil && pub.Status == domain.ArticleStatusPublished:
+		go producePublishEvent(a.producer, a.l, pub, article.PublishTypePublish)
+	case err != nil && err != repository.ErrArticleNotFound:
+		a.l.Error("恢复文章之后查询线上文章失败",
+			logger.Int64("aid", id), logger.Error(err))
+	}
+	return nil
+}
+
+func (a *articleService) ListTrash(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error) {
+	return a.repo.GetDeletedByAuthor(ctx, uid, offset, limit)
+}
+
+// ArticlePurger 彻底删除在回收站里面超过保留期的文章，
+// 连同它的历史版本、计数、点赞和收藏记录
+type ArticlePurger struct {
+	repo         repository.ArticleRepository
+	revisionRepo repository.ArticleRevisionRepository
+	intrRepo     repository.InteractiveRepository
+	l            logger.LoggerV1
+}
+
+func NewArticlePurger(repo repository.ArticleRepository,
+	revisionRepo repository.ArticleRevisionRepository,
+	intrRepo repository.InteractiveRepository,
+	l logger.LoggerV1) *ArticlePurger {
+	return &ArticlePurger{
+		repo:         repo,
+		revisionRepo: revisionRepo,
+		intrRepo:     intrRepo,
+		l:            l,
+	}
+}
+
+// Purge 删除 before 之前放进回收站的文章，返回删除的数量
+func (p *ArticlePurger) Purge(ctx context.Context, before time.Time) (int, error) {
+	cnt := 0
+	for {
+		arts, err := p.repo.ListDeletedBefore(ctx, before, purgeBatch)
+		if err != nil {
+			return cnt, err
+		}
+		for _, art := range arts {
+			// 先标记，标记之后作者就不能恢复了，不会出现恢复回来的文章计数和历史版本没了的情况。
+			// 关联的数据删除失败的话文章还是标记的状态，下次还会再来；
+			// 文章最后删，不然没删完的关联数据就再也找不到了
+			err = p.repo.ClaimPurge(ctx, art.Id)
+			switch err {
+			case nil:
+			case repository.ErrArticleNotFound:
+				// 作者刚刚恢复了这篇文章
+				p.l.Warn("清理回收站的时候文章已经被恢复",
+					logger.Int64("aid", art.Id))
+				continue
+			default:
+				return cnt, err
+			}
+			err = p.intrRepo.DeleteByBiz(ctx, articleBiz, art.Id)
+			if err != nil {
+				return cnt, err
+			}
+			err = p.revisionRepo.DeleteByArticle(ctx, art.Id)
+			if err != nil {
+				return cnt, err
+			}
+			err = p.repo.Purge(ctx, art.Id)
+			switch err {
+			case nil:
+				cnt++
+			case repository.ErrArticleNotFound:
+				// 别的实例已经删掉了
+			default:
+				return cnt, err
+			}
+		}
+		if len(arts) < purgeBatch {
+			return cnt, nil
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository"
+)
+
+var (
+	ErrInvalidCollectionName   = errors.New("收藏夹名字不能为空，并且不能超过 64 个字")
+	ErrCollectionNotFound      = errors.New("收藏夹不存在")
+	ErrDuplicateCollectionName = repository.ErrDuplicateCollectionName
+	ErrCollectionItemNotFound  = errors.New("没有收藏过这条内容")
+)
+
+const maxCollectionNameLen = 64
+
+type CollectionService interface {
+	Create(ctx context.Context, c domain.Collection) (int64, error)
+	Update(ctx context.Context, c domain.Collection) error
+	// Delete 删除收藏夹，里面的内容也会取消收藏
+	Delete(ctx context.Context, uid int64, id int64) error
+	List(ctx context.Context, uid int64, offset int, limit int) ([]domain.Collection, error)
+	// Items 收藏夹里面的内容，cid 为 0 就是默认收藏夹，
+	// (utime, maxId) 是上一页的最后一条
+	Items(ctx context.Context, uid int64, cid int64, utime time.Time, maxId int64, limit int) ([]domain.CollectionItem, error)
+	// Move 把收藏的内容移动到收藏夹 cid，收藏数不变
+	Move(ctx context.Context, uid int64, biz string, bizId int64, cid int64) error
+}
+
+type collectionService struct {
+	repo repository.CollectionRepository
+}
+
+func NewCollectionService(repo repository.CollectionRepository) CollectionService {
+	return &collectionService{
+		repo: repo,
+	}
+}
+
+func (c *collectionService) Create(ctx context.Context, col domain.Collection) (int64, error) {
+	if err := c.validate(&col); err != nil {
+		return 0, err
+	}
+	return c.repo.Create(ctx, col)
+}
+
+func (c *collectionService) Update(ctx context.Context, col domain.Collection) error {
+	if err := c.validate(&col); err != nil {
+		return err
+	}
+	err := c.repo.Update(ctx, col)
+	if err == repository.ErrCollectionNotFound {
+		return ErrCollectionNotFound
+	}
+	return err
+}
+
+func (c *collectionService) validate(col *domain.Collection) error {
+	col.Name = strings.TrimSpace(col.Name)
+	if col.Name == "" || utf8.RuneCountInString(col.Name) > maxCollectionNameLen {
+		return ErrInvalidCollectionName
+	}
+	return nil
+}
+
+func (c *collectionService) Delete(ctx context.Context, uid int64, id int64) error {
+	err := c.repo.Delete(ctx, uid, id)
+	if err == repository.ErrCollectionNotFound {
+		return ErrCollectionNotFound
+	}
+	return err
+}
+
+func (c *collectionService) List(ctx context.Context, uid int64, offset int, limit int) ([]domain.Collection, error) {
+	return c.repo.FindByUid(ctx, uid, offset, limit)
+}
+
+func (c *collectionService) Items(ctx context.Context, uid int64, cid int64,
+	utime time.Time, maxId int64, limit int) ([]domain.CollectionItem, error) {
+	if err := checkCollectionOwner(ctx, c.repo, uid, cid); err != nil {
+		return nil, err
+	}
+	return c.repo.FindItems(ctx, uid, cid, utime, maxId, limit)
+}
+
+func (c *collectionService) Move(ctx context.Context, uid int64, biz string, bizId int64, cid int64) error {
+	if err := checkCollectionOwner(ctx, c.repo, uid, cid); err != nil {
+		return err
+	}
+	err := c.repo.MoveItem(ctx, uid, biz, bizId, cid)
+	if err == repository.ErrCollectionNotFound {
+		return ErrCollectionItemNotFound
+	}
+	return err
+}
+
+// checkCollectionOwner 收藏夹 cid 是不是 uid 的，默认收藏夹是所有人的
+func checkCollectionOwner(ctx context.Context, repo repository.CollectionRepository, uid int64, cid int64) error {
+	if cid == 0 {
+		return nil
+	}
+	col, err := repo.FindById(ctx, cid)
+	switch {
+	case err == repository.ErrCollectionNotFound:
+		return ErrCollectionNotFound
+	case err != nil:
+		return err
+	case col.Uid != uid:
+		return ErrCollectionNotFound
+	}
+	return nil
+}
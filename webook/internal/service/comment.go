@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository"
+)
+
+const (
+	// maxCommentLen 评论最多多少个字
+	maxCommentLen = 1000
+	// commentBiz 评论本身在点赞这些通用功能里面的 biz
+	commentBiz = "comment"
+)
+
+var (
+	ErrInvalidComment          = errors.New("评论内容不能为空，也不能太长")
+	ErrCommentTargetNotFound   = errors.New("评论的对象不存在")
+	ErrCommentPermissionDenied = errors.New("只有评论者和文章作者可以删除评论")
+)
+
+type CommentService interface {
+	// Create 发表评论或者回复，返回评论的 ID
+	Create(ctx context.Context, cmt domain.Comment) (int64, error)
+	// ListNewest 最新的根评论，maxId 是上一页最后一条评论的 ID
+	ListNewest(ctx context.Context, biz string, bizId int64, maxId int64, limit int) ([]domain.Comment, error)
+	// ListHottest 最热的根评论，likeCnt 和 maxId 是上一页最后一条评论的点赞数和 ID
+	ListHottest(ctx context.Context, biz string, bizId int64, likeCnt int64, maxId int64, limit int) ([]domain.Comment, error)
+	// ListReplies 根评论下面的回复，先回复的在前面，minId 是上一页最后一条回复的 ID
+	ListReplies(ctx context.Context, rootId int64, minId int64, limit int) ([]domain.Comment, error)
+	// Delete 评论者和文章作者都可以删除评论，回复它的评论也会一起删掉，
+	// 这些评论的点赞也一起删掉
+	Delete(ctx context.Context, uid int64, id int64) error
+	// FindById 评论不存在的时候返回 ErrCommentTargetNotFound
+	FindById(ctx context.Context, id int64) (domain.Comment, error)
+}
+
+type commentService struct {
+	repo     repository.CommentRepository
+	artRepo  repository.ArticleRepository
+	intrRepo repository.InteractiveRepository
+}
+
+func NewCommentService(repo repository.CommentRepository,
+	artRepo repository.ArticleRepository,
+	intrRepo repository.InteractiveRepository) CommentService {
+	return &commentService{
+		repo:     repo,
+		artRepo:  artRepo,
+		intrRepo: intrRepo,
+	}
+}
+
+func (c *commentService) Create(ctx context.Context, cmt domain.Comment) (int64, error) {
+	cmt.Content = strings.TrimSpace(cmt.Content)
+	if cmt.Content == "" || utf8.RuneCountInString(cmt.Content) > maxCommentLen {
+		return 0, ErrInvalidComment
+	}
+	cmt.RootId = 0
+	if cmt.ParentId > 0 {
+		parent, err := c.repo.FindById(ctx, cmt.ParentId)
+		if err == repository.ErrCommentNotFound {
+			return 0, ErrCommentTargetNotFound
+		}
+		if err != nil {
+			return 0, err
+		}
+		// 回复的对象必须是同一篇文章下面的评论
+		cmt.Biz, cmt.BizId = parent.Biz, parent.BizId
+		cmt.RootId = parent.RootId
+		if parent.IsRoot() {
+			cmt.RootId = parent.Id
+		}
+	}
+	if err := c.checkTarget(ctx, cmt.Biz, cmt.BizId); err != nil {
+		return 0, err
+	}
+	return c.repo.Create(ctx, cmt)
+}
+
+// checkTarget 只能评论已发表的文章
+func (c *commentService) checkTarget(ctx context.Context, biz string, bizId int64) error {
+	if biz != "article" {
+		return ErrCommentTargetNotFound
+	}
+	art, err := c.artRepo.GetPubById(ctx, bizId)
+	if err == repository.ErrArticleNotFound {
+		return ErrCommentTargetNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if art.Status != domain.ArticleStatusPublished {
+		return ErrCommentTargetNotFound
+	}
+	return nil
+}
+
+func (c *commentService) ListNewest(ctx context.Context, biz string, bizId int64,
+	maxId int64, limit int) ([]domain.Comment, error) {
+	return c.repo.FindNewest(ctx, biz, bizId, maxId, limit)
+}
+
+func (c *commentService) ListHottest(ctx context.Context, biz string, bizId int64,
+	likeCnt int64, maxId int64, limit int) ([]domain.Comment, error) {
+	return c.repo.FindHottest(ctx, biz, bizId, likeCnt, maxId, limit)
+}
+
+func (c *commentService) ListReplies(ctx context.Context, rootId int64,
+	minId int64, limit int) ([]domain.Comment, error) {
+	return c.repo.FindReplies(ctx, rootId, minId, limit)
+}
+
+func (c *commentService) Delete(ctx context.Context, uid int64, id int64) error {
+	cmt, err := c.repo.FindById(ctx, id)
+	if err == repository.ErrCommentNotFound {
+		return ErrCommentTargetNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if cmt.Commentator.Id != uid {
+		if cmt.Biz != "article" {
+			return ErrCommentPermissionDenied
+		}
+		art, err := c.artRepo.GetById(ctx, cmt.BizId)
+		if err != nil {
+			return err
+		}
+		if art.Author.Id != uid {
+			return ErrCommentPermissionDenied
+		}
+	}
+	ids, err := c.repo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	// 评论已经删掉了，点赞删除失败的也不影响别的评论，都试一遍
+	var errs []error
+	for _, cid := range ids {
+		errs = append(errs, c.intrRepo.DeleteByBiz(ctx, commentBiz, cid))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *commentService) FindById(ctx context.Context, id int64) (domain.Comment, error) {
+	cmt, err := c.repo.FindById(ctx, id)
+	if err == repository.ErrCommentNotFound {
+		return domain.Comment{}, ErrCommentTargetNotFound
+	}
+	return cmt, err
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository"
+	"basic-go/webook/pkg/logger"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// feedPushBatchSize 推送的时候每一批处理多少个粉丝
+	feedPushBatchSize = 500
+	// maxFeedFollowees 拉时间线的时候最多考虑多少个关注的人
+	maxFeedFollowees = 2000
+)
+
+type FeedService interface {
+	// PushArticle 文章发表之后调用。文章总是进入作者的发件箱，
+	// 如果作者的粉丝数不超过阈值，还会推送到每个粉丝的收件箱
+	PushArticle(ctx context.Context, item domain.FeedItem) error
+	// RemoveArticle 文章撤回之后调用
+	RemoveArticle(ctx context.Context, aid int64) error
+	// Feed 首页时间线，新的在前面，(ctime, maxAid) 是上一页最后一条的时间和文章 ID。
+	// 已经撤回、删除的文章会被过滤掉，所以一页可能不满 limit 条，
+	// 下一页从 next 后面开始，next 是过滤之前的最后一条，next.Article.Id 为 0 代表没有下一页了
+	Feed(ctx context.Context, uid int64, ctime int64, maxAid int64, limit int) (items []domain.FeedItem, next domain.FeedItem, err error)
+}
+
+// feedService 推拉结合：粉丝少的作者发表文章的时候直接推到粉丝的收件箱，
+// 粉丝多的作者（大 V）只写发件箱，读时间线的时候再去拉。
+// 作者的粉丝数从阈值以上掉到阈值以下的时候，之前的文章不会补推，这是可以接受的
+type feedService struct {
+	repo       repository.FeedRepository
+	followRepo repository.FollowRepository
+	artRepo    repository.ArticleRepository
+	// pullThreshold 粉丝数超过这个值的作者就用拉模型
+	pullThreshold int64
+	l             logger.LoggerV1
+}
+
+func NewFeedService(repo repository.FeedRepository,
+	followRepo repository.FollowRepository,
+	artRepo repository.ArticleRepository,
+	pullThreshold int64,
+	l logger.LoggerV1) FeedService {
+	return &feedService{
+		repo:          repo,
+		followRepo:    followRepo,
+		artRepo:       artRepo,
+		pullThreshold: pullThreshold,
+		l:             l,
+	}
+}
+
+func (f *feedService) PushArticle(ctx context.Context, item domain.FeedItem) error {
+	err := f.repo.AddOutbox(ctx, item)
+	if err != nil {
+		return err
+	}
+	author := item.Article.Author.Id
+	stat, err := f.followRepo.GetStatistics(ctx, author)
+	if err != nil {
+		return err
+	}
+	if stat.Followers > f.pullThreshold {
+		return nil
+	}
+	var minId int64
+	for {
+		rels, err := f.followRepo.ScanFollowers(ctx, author, minId, feedPushBatchSize)
+		if err != nil {
+			return err
+		}
+		uids := make([]int64, 0, len(rels))
+		for _, rel := range rels {
+			uids = append(uids, rel.Follower.Id)
+		}
+		err = f.repo.AddInboxes(ctx, uids, item)
+		if err != nil {
+			return err
+		}
+		if len(rels) < feedPushBatchSize {
+			return nil
+		}
+		minId = rels[len(rels)-1].Id
+	}
+}
+
+func (f *feedService) RemoveArticle(ctx context.Context, aid int64) error {
+	return f.repo.DeleteArticle(ctx, aid)
+}
+
+func (f *feedService) Feed(ctx context.Context, uid int64,
+	ctime int64, maxAid int64, limit int) ([]domain.FeedItem, domain.FeedItem, error) {
+	stats, err := f.followRepo.FindFolloweeStatistics(ctx, uid, maxFeedFollowees)
+	if err != nil {
+		return nil, domain.FeedItem{}, err
+	}
+	// 推和拉的作者不重叠，所以两边的结果不会重复
+	var pushed, pulled []int64
+	for _, stat := range stats {
+		if stat.Followers > f.pullThreshold {
+			pulled = append(pulled, stat.Uid)
+		} else {
+			pushed = append(pushed, stat.Uid)
+		}
+	}
+	var (
+		eg            errgroup.Group
+		inbox, outbox []domain.FeedItem
+	)
+	eg.Go(func() error {
+		var er error
+		inbox, er = f.repo.FindInbox(ctx, uid, pushed, ctime, maxAid, limit)
+		return er
+	})
+	eg.Go(func() error {
+		var er error
+		outbox, er = f.repo.FindOutbox(ctx, pulled, ctime, maxAid, limit)
+		return er
+	})
+	if err = eg.Wait(); err != nil {
+		return nil, domain.FeedItem{}, err
+	}
+	merged := mergeFeed(inbox, outbox, limit)
+	var next domain.FeedItem
+	if len(merged) == limit {
+		next = merged[len(merged)-1]
+	}
+	items, err := f.fillArticles(ctx, merged)
+	if err != nil {
+		return nil, domain.FeedItem{}, err
+	}
+	return items, next, nil
+}
+
+// fillArticles 补充文章的内容，已经撤回或者查不到的文章直接跳过
+func (f *feedService) fillArticles(ctx context.Context, items []domain.FeedItem) ([]domain.FeedItem, error) {
+	var (
+		eg errgroup.Group
+		// 每个 goroutine 只写自己的下标，不需要加锁
+		ok = make([]bool, len(items))
+	)
+	for i := range items {
+		i := i
+		eg.Go(func() error {
+			art, err := f.artRepo.GetPubById(ctx, items[i].Article.Id)
+			if err == repository.ErrArticleNotFound {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if art.Status != domain.ArticleStatusPublished {
+				return nil
+			}
+			items[i].Article = art
+			ok[i] = true
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	res := make([]domain.FeedItem, 0, len(items))
+	for i, item := range items {
+		if ok[i] {
+			res = append(res, item)
+		}
+	}
+	return res, nil
+}
+
+// mergeFeed 两边都是按照 (Ctime, Article.Id) 倒序排好的，归并之后取前 limit 条
+func mergeFeed(a, b []domain.FeedItem, limit int) []domain.FeedItem {
+	res := make([]domain.FeedItem, 0, min(len(a)+len(b), limit))
+	i, j := 0, 0
+	for len(res) < limit && (i < len(a) || j < len(b)) {
+		if j >= len(b) || (i < len(a) && feedBefore(a[i], b[j])) {
+			res = append(res, a[i])
+			i++
+		} else {
+			res = append(res, b[j])
+			j++
+		}
+	}
+	return res
+}
+
+func feedBefore(a, b domain.FeedItem) bool {
+	if !a.Ctime.Equal(b.Ctime) {
+		return a.Ctime.After(b.Ctime)
+	}
+	return a.Article.Id > b.Article.Id
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository"
+	"basic-go/webook/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeFeedRepository 收件箱里面的内容按照 (Ctime, Article.Id) 倒序排好
+type fakeFeedRepository struct {
+	repository.FeedRepository
+	inbox []domain.FeedItem
+}
+
+func (r *fakeFeedRepository) FindInbox(ctx context.Context, uid int64, authorIds []int64,
+	ctime int64, maxAid int64, limit int) ([]domain.FeedItem, error) {
+	res := make([]domain.FeedItem, 0, limit)
+	for _, item := range r.inbox {
+		if len(res) == limit {
+			break
+		}
+		c := item.Ctime.UnixMilli()
+		if c < ctime || (c == ctime && item.Article.Id < maxAid) {
+			res = append(res, item)
+		}
+	}
+	return res, nil
+}
+
+func (r *fakeFeedRepository) FindOutbox(ctx context.Context, authorIds []int64,
+	ctime int64, maxAid int64, limit int) ([]domain.FeedItem, error) {
+	return nil, nil
+}
+
+type fakeFollowRepository struct {
+	repository.FollowRepository
+}
+
+func (r *fakeFollowRepository) FindFolloweeStatistics(ctx context.Context,
+	follower int64, limit int) ([]domain.FollowStatistics, error) {
+	return []domain.FollowStatistics{{Uid: 2, Followers: 1}}, nil
+}
+
+// fakeArticleRepository withdrawn 里面的文章已经撤回了
+type fakeArticleRepository struct {
+	repository.ArticleRepository
+	withdrawn map[int64]bool
+}
+
+func (r *fakeArticleRepository) GetPubById(ctx context.Context, id int64) (domain.Article, error) {
+	var status domain.ArticleStatus = domain.ArticleStatusPublished
+	if r.withdrawn[id] {
+		status = domain.ArticleStatusPrivate
+	}
+	return domain.Article{Id: id, Author: domain.Author{Id: 2}, Status: status}, nil
+}
+
+func TestFeedService_FeedSkipsWithdrawn(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	// 文章 5 到 1，从新到旧，3 已经撤回了
+	var inbox []domain.FeedItem
+	for id := int64(5); id >= 1; id-- {
+		inbox = append(inbox, domain.FeedItem{
+			Article: domain.Article{Id: id, Author: domain.Author{Id: 2}},
+			Ctime:   now.Add(time.Duration(id) * time.Minute),
+		})
+	}
+	svc := NewFeedService(&fakeFeedRepository{inbox: inbox},
+		&fakeFollowRepository{},
+		&fakeArticleRepository{withdrawn: map[int64]bool{3: true}},
+		1000, logger.NewZapLogger(zap.NewNop()))
+	ctx := context.Background()
+	ids := func(items []domain.FeedItem) []int64 {
+		res := make([]int64, 0, len(items))
+		for _, item := range items {
+			res = append(res, item.Article.Id)
+		}
+		return res
+	}
+
+	// 第一页 5 4 3，3 被过滤掉了，但是还有下一页
+	items, next, err := svc.Feed(ctx, 1, now.Add(time.Hour).UnixMilli(), 0, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{5, 4}, ids(items))
+	require.Equal(t, int64(3), next.Article.Id)
+
+	items, next, err = svc.Feed(ctx, 1, next.Ctime.UnixMilli(), next.Article.Id, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2, 1}, ids(items))
+	assert.Equal(t, int64(0), next.Article.Id)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository"
+)
+
+var (
+	ErrFollowSelf       = errors.New("不能关注自己")
+	ErrFolloweeNotFound = errors.New("关注的用户不存在")
+)
+
+type FollowService interface {
+	// Follow 关注，重复关注不会报错
+	Follow(ctx context.Context, follower int64, followee int64) error
+	// CancelFollow 取消关注，没有关注过也不会报错
+	CancelFollow(ctx context.Context, follower int64, followee int64) error
+	// Followed follower 是否关注了 followee
+	Followed(ctx context.Context, follower int64, followee int64) (bool, error)
+	// Followers 粉丝列表，最近关注的在前面，(utime, maxId) 是上一页的最后一条
+	Followers(ctx context.Context, uid int64, utime int64, maxId int64, limit int) ([]domain.FollowRelation, error)
+	// Followees 关注列表，排序和游标同 Followers
+	Followees(ctx context.Context, uid int64, utime int64, maxId int64, limit int) ([]domain.FollowRelation, error)
+	GetStatistics(ctx context.Context, uid int64) (domain.FollowStatistics, error)
+}
+
+type followService struct {
+	repo     repository.FollowRepository
+	userRepo repository.UserRepository
+}
+
+func NewFollowService(repo repository.FollowRepository,
+	userRepo repository.UserRepository) FollowService {
+	return &followService{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+func (f *followService) Follow(ctx context.Context, follower int64, followee int64) error {
+	if follower == followee {
+		return ErrFollowSelf
+	}
+	_, err := f.userRepo.FindById(ctx, followee)
+	if err == repository.ErrUserNotFound {
+		return ErrFolloweeNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return f.repo.Follow(ctx, follower, followee)
+}
+
+func (f *followService) CancelFollow(ctx context.Context, follower int64, followee int64) error {
+	return f.repo.CancelFollow(ctx, follower, followee)
+}
+
+func (f *followService) Followed(ctx context.Context, follower int64, followee int64) (bool, error) {
+	if follower == followee {
+		return false, nil
+	}
+	return f.repo.Followed(ctx, follower, followee)
+}
+
+func (f *followService) Followers(ctx context.Context, uid int64,
+	utime int64, maxId int64, limit int) ([]domain.FollowRelation, error) {
+	return f.repo.FindFollowers(ctx, uid, utime, maxId, limit)
+}
+
+func (f *followService) Followees(ctx context.Context, uid int64,
+	utime int64, maxId int64, limit int) ([]domain.FollowRelation, error) {
+	return f.repo.FindFollowees(ctx, uid, utime, maxId, limit)
+}
+
+func (f *followService) GetStatistics(ctx context.Context, uid int64) (domain.FollowStatistics, error) {
+	return f.repo.GetStatistics(ctx, uid)
+}
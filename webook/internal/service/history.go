@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository"
+
+	"golang.org/x/sync/errgroup"
+)
+
+type HistoryService interface {
+	// List 阅读历史，最近阅读的在前面，(lastReadTime, maxId) 是上一页的最后一条
+	List(ctx context.Context, uid int64, lastReadTime time.Time, maxId int64, limit int) ([]domain.HistoryRecord, error)
+	Delete(ctx context.Context, uid int64, biz string, bizId int64) error
+	Clear(ctx context.Context, uid int64) error
+}
+
+type historyService struct {
+	repo    repository.HistoryRecordRepository
+	artRepo repository.ArticleRepository
+}
+
+func NewHistoryService(repo repository.HistoryRecordRepository,
+	artRepo repository.ArticleRepository) HistoryService {
+	return &historyService{
+		repo:    repo,
+		artRepo: artRepo,
+	}
+}
+
+func (h *historyService) List(ctx context.Context, uid int64,
+	lastReadTime time.Time, maxId int64, limit int) ([]domain.HistoryRecord, error) {
+	records, err := h.repo.GetByUid(ctx, uid, lastReadTime, maxId, limit)
+	if err != nil {
+		return nil, err
+	}
+	return records, h.fillTitles(ctx, records)
+}
+
+// fillTitles 补充文章标题。已经撤回或者查不到的文章，记录还是保留的，
+// 不然游标分页的时候会出现空页
+func (h *historyService) fillTitles(ctx context.Context, records []domain.HistoryRecord) error {
+	var eg errgroup.Group
+	for i := range records {
+		if records[i].Biz != "article" {
+			continue
+		}
+		i := i
+		eg.Go(func() error {
+			art, err := h.artRepo.GetPubById(ctx, records[i].BizId)
+			if err == repository.ErrArticleNotFound {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if art.Status == domain.ArticleStatusPublished {
+				records[i].Title = art.Title
+			}
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
+func (h *historyService) Delete(ctx context.Context, uid int64, biz string, bizId int64) error {
+	return h.repo.DeleteRecord(ctx, uid, biz, bizId)
+}
+
+func (h *historyService) Clear(ctx context.Context, uid int64) error {
+	return h.repo.Clear(ctx, uid)
+}
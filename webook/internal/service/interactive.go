@@ -2,23 +2,36 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"basic-go/webook/internal/domain"
 	"basic-go/webook/internal/repository"
 
+	"github.com/ecodeclub/ekit/slice"
 	"golang.org/x/sync/errgroup"
 )
 
 type InteractiveService interface {
-	IncrReadCnt(ctx context.Context, biz string, bizId int64) error
 	Like(c context.Context, biz string, id int64, uid int64) error
 	CancelLike(c context.Context, biz string, id int64, uid int64) error
+	// Collect 收藏到收藏夹 cid，cid 为 0 就是默认收藏夹
 	Collect(ctx context.Context, biz string, bizId, cid, uid int64) error
+	// CancelCollect 取消收藏，没有收藏过也不会报错
+	CancelCollect(ctx context.Context, biz string, bizId, uid int64) error
 	Get(ctx context.Context, biz string, id int64, uid int64) (domain.Interactive, error)
+	// GetByIds 批量查询计数以及 uid 有没有点赞、收藏，列表页用，每个 id 在结果里面都有一项。
+	// uid 为 0 的时候只查计数，比如不需要登录的页面
+	GetByIds(ctx context.Context, biz string, ids []int64, uid int64) (map[int64]domain.Interactive, error)
+	// ListLiked uid 点过赞的内容，最近点赞的在前面，(utime, maxId) 是上一页的最后一条
+	ListLiked(ctx context.Context, biz string, uid int64, utime time.Time, maxId int64, limit int) ([]domain.Like, error)
+	// ListLikers 最近给 bizId 点赞的人，带上昵称和头像，(utime, maxId) 是上一页的最后一条
+	ListLikers(ctx context.Context, biz string, bizId int64, utime time.Time, maxId int64, limit int) ([]domain.Like, error)
 }
 
 type interactiveService struct {
-	repo repository.InteractiveRepository
+	repo           repository.InteractiveRepository
+	collectionRepo repository.CollectionRepository
+	userRepo       repository.UserRepository
 }
 
 func (i *interactiveService) Get(ctx context.Context, biz string, id int64, uid int64) (domain.Interactive, error) {
@@ -41,10 +54,95 @@ func (i *interactiveService) Get(ctx context.Context, biz string, id int64, uid
 	return intr, eg.Wait()
 }
 
+func (i *interactiveService) GetByIds(ctx context.Context, biz string,
+	ids []int64, uid int64) (map[int64]domain.Interactive, error) {
+	if len(ids) == 0 {
+		return map[int64]domain.Interactive{}, nil
+	}
+	var (
+		eg        errgroup.Group
+		intrs     []domain.Interactive
+		liked     []int64
+		collected []int64
+	)
+	eg.Go(func() error {
+		var er error
+		intrs, er = i.repo.GetByIds(ctx, biz, ids)
+		return er
+	})
+	if uid > 0 {
+		eg.Go(func() error {
+			var er error
+			liked, er = i.repo.LikedIds(ctx, biz, ids, uid)
+			return er
+		})
+		eg.Go(func() error {
+			var er error
+			collected, er = i.repo.CollectedIds(ctx, biz, ids, uid)
+			return er
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	res := make(map[int64]domain.Interactive, len(ids))
+	for _, id := range ids {
+		res[id] = domain.Interactive{Biz: biz, BizId: id}
+	}
+	for _, intr := range intrs {
+		res[intr.BizId] = intr
+	}
+	for _, id := range liked {
+		intr := res[id]
+		intr.Liked = true
+		res[id] = intr
+	}
+	for _, id := range collected {
+		intr := res[id]
+		intr.Collected = true
+		res[id] = intr
+	}
+	return res, nil
+}
+
+func (i *interactiveService) ListLiked(ctx context.Context, biz string, uid int64,
+	utime time.Time, maxId int64, limit int) ([]domain.Like, error) {
+	return i.repo.ListLikesByUser(ctx, biz, uid, utime, maxId, limit)
+}
+
+func (i *interactiveService) ListLikers(ctx context.Context, biz string, bizId int64,
+	utime time.Time, maxId int64, limit int) ([]domain.Like, error) {
+	likes, err := i.repo.ListLikers(ctx, biz, bizId, utime, maxId, limit)
+	if err != nil || len(likes) == 0 {
+		return likes, err
+	}
+	uids := slice.Map[domain.Like, int64](likes, func(idx int, src domain.Like) int64 {
+		return src.Liker.Id
+	})
+	users, err := i.userRepo.FindByIds(ctx, uids)
+	if err != nil {
+		return nil, err
+	}
+	// 注销了的用户只有 Id
+	for idx := range likes {
+		u := users[likes[idx].Liker.Id]
+		likes[idx].Liker.Name = u.Nickname
+		likes[idx].Liker.Avatar = u.Avatar
+	}
+	return likes, nil
+}
+
 func (i *interactiveService) Collect(ctx context.Context, biz string, bizId, cid, uid int64) error {
+	if err := checkCollectionOwner(ctx, i.collectionRepo, uid, cid); err != nil {
+		return err
+	}
 	return i.repo.AddCollectionItem(ctx, biz, bizId, cid, uid)
 }
 
+func (i *interactiveService) CancelCollect(ctx context.Context, biz string, bizId, uid int64) error {
+	return i.repo.DeleteCollectionItem(ctx, biz, bizId, uid)
+}
+
 func (i *interactiveService) Like(c context.Context, biz string, id int64, uid int64) error {
 	return i.repo.IncrLike(c, biz, id, uid)
 }
@@ -53,10 +151,8 @@ func (i *interactiveService) CancelLike(c context.Context, biz string, id int64,
 	return i.repo.DecrLike(c, biz, id, uid)
 }
 
-func NewInteractiveService(repo repository.InteractiveRepository) InteractiveService {
-	return &interactiveService{repo: repo}
-}
-
-func (i *interactiveService) IncrReadCnt(ctx context.Context, biz string, bizId int64) error {
-	return i.repo.IncrReadCnt(ctx, biz, bizId)
+func NewInteractiveService(repo repository.InteractiveRepository,
+	collectionRepo repository.CollectionRepository,
+	userRepo repository.UserRepository) InteractiveService {
+	return &interactiveService{repo: repo, collectionRepo: collectionRepo, userRepo: userRepo}
 }
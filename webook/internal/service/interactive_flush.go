@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"basic-go/webook/internal/repository"
+	"basic-go/webook/pkg/logger"
+)
+
+// InteractiveFlusher 写回模式下定时把计数的增量合并写入数据库。
+// 每个实例都可以跑，同一批增量只会写入一次
+type InteractiveFlusher struct {
+	repo     repository.InteractiveRepository
+	bizs     []string
+	interval time.Duration
+	l        logger.LoggerV1
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewInteractiveFlusher(repo repository.InteractiveRepository, bizs []string,
+	interval time.Duration, l logger.LoggerV1) *InteractiveFlusher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &InteractiveFlusher{
+		repo:     repo,
+		bizs:     bizs,
+		interval: interval,
+		l:        l,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start 开启后台循环，没有写回模式的 biz 的话什么都不做
+func (f *InteractiveFlusher) Start() {
+	if len(f.bizs) == 0 {
+		return
+	}
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		ticker := time.NewTicker(f.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				f.Flush(f.ctx)
+			case <-f.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止后台循环，再把剩下的增量写一次。
+// ctx 到期了还没写完也没关系，增量还在 Redis 里面，下次启动之后继续写
+func (f *InteractiveFlusher) Stop(ctx context.Context) error {
+	f.cancel()
+	done := make(chan struct{})
+	go func() {
+		f.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	f.Flush(ctx)
+	return ctx.Err()
+}
+
+// Flush 每个 biz 写入一批增量
+func (f *InteractiveFlusher) Flush(ctx context.Context) {
+	for _, biz := range f.bizs {
+		ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+		cnt, err := f.repo.Flush(ctx, biz)
+		cancel()
+		if err != nil {
+			f.l.Error("计数的增量写入数据库失败",
+				logger.String("biz", biz), logger.Error(err))
+			continue
+		}
+		if cnt > 0 {
+			f.l.Debug("计数的增量写入了数据库",
+				logger.String("biz", biz), logger.Int("cnt", cnt))
+		}
+	}
+}
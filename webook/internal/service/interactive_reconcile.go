@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository"
+)
+
+// reconcileBatch 每次 SCAN 给 Redis 的 count 提示
+const reconcileBatch = 100
+
+// InteractiveReconciler 检查计数缓存和数据库是不是一致，对不上的删掉缓存。
+// 一次只检查一部分的话，下一次从上一次停下来的位置继续，这样多跑几次就能覆盖全部的 key。
+// 这个位置存在 Redis 里面，任务换了实例或者重启之后也不会每次都从头开始
+type InteractiveReconciler struct {
+	repo repository.InteractiveRepository
+	mu   sync.Mutex
+}
+
+func NewInteractiveReconciler(repo repository.InteractiveRepository) *InteractiveReconciler {
+	return &InteractiveReconciler{
+		repo: repo,
+	}
+}
+
+// Reconcile 检查最多 maxKeys 个 key，maxKeys 小于等于 0 的时候把剩下的都检查完
+func (r *InteractiveReconciler) Reconcile(ctx context.Context, maxKeys int) (repository.ReconcileResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var total repository.ReconcileResult
+	cursor, err := r.repo.ReconcileCursor(ctx)
+	if err != nil {
+		return total, err
+	}
+	for {
+		res, next, err := r.repo.Reconcile(ctx, cursor, reconcileBatch)
+		total.Checked += res.Checked
+		total.Drifted += res.Drifted
+		if err != nil {
+			return total, err
+		}
+		// 保存失败的话下次从旧的位置开始，只是多检查一些
+		err = r.repo.SetReconcileCursor(ctx, next)
+		if err != nil {
+			return total, err
+		}
+		cursor = next
+		if next == 0 || (maxKeys > 0 && total.Checked >= maxKeys) {
+			return total, nil
+		}
+	}
+}
+
+// Rebuild 强制用数据库里面的计数重建一个资源的缓存
+func (r *InteractiveReconciler) Rebuild(ctx context.Context, biz string, id int64) (domain.Interactive, error) {
+	return r.repo.Rebuild(ctx, biz, id)
+}
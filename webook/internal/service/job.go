@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository"
+	"basic-go/webook/pkg/logger"
+)
+
+var ErrInvalidCronExpression = errors.New("cron 表达式不对")
+
+type CronJobService interface {
+	// AddJob 注册任务，第一次执行的时间根据 cron 表达式计算
+	AddJob(ctx context.Context, j domain.Job) error
+	// Preempt 抢占一个到期的任务，心跳超时的运行中任务也会被抢过来
+	Preempt(ctx context.Context) (domain.Job, error)
+	// Heartbeat 续约，返回 repository.ErrJobNotHold 说明任务已经被别的节点抢走了
+	Heartbeat(ctx context.Context, j domain.Job) error
+	// Release 执行完之后释放任务，并计算下一次执行的时间
+	Release(ctx context.Context, j domain.Job) error
+}
+
+type cronJobService struct {
+	repo repository.CronJobRepository
+	l    logger.LoggerV1
+	// reclaimAfter 心跳超过这么久没有更新，就认为节点已经崩溃了，任务可以被重新抢占
+	reclaimAfter time.Duration
+}
+
+func NewCronJobService(repo repository.CronJobRepository, l logger.LoggerV1,
+	reclaimAfter time.Duration) CronJobService {
+	return &cronJobService{
+		repo:         repo,
+		l:            l,
+		reclaimAfter: reclaimAfter,
+	}
+}
+
+func (c *cronJobService) AddJob(ctx context.Context, j domain.Job) error {
+	next, err := j.Next(time.Now())
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidCronExpression, j.Expression)
+	}
+	j.NextTime = next
+	return c.repo.AddJob(ctx, j)
+}
+
+func (c *cronJobService) Preempt(ctx context.Context) (domain.Job, error) {
+	return c.repo.Preempt(ctx, time.Now().Add(-c.reclaimAfter))
+}
+
+func (c *cronJobService) Heartbeat(ctx context.Context, j domain.Job) error {
+	return c.repo.Heartbeat(ctx, j)
+}
+
+func (c *cronJobService) Release(ctx context.Context, j domain.Job) error {
+	next, err := j.Next(time.Now())
+	if err != nil {
+		// 表达式不对，再怎么调度也没用，暂停掉，等重新注册
+		c.l.Error("cron 表达式不对，暂停任务",
+			logger.Int64("jid", j.Id),
+			logger.String("name", j.Name),
+			logger.String("expression", j.Expression),
+			logger.Error(err))
+		return c.repo.Pause(ctx, j)
+	}
+	return c.repo.Release(ctx, j, next)
+}
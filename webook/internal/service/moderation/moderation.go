@@ -0,0 +1,65 @@
+package moderation
+
+import (
+	"context"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/pkg/logger"
+)
+
+// Rule 一条机器审核规则，返回的是没通过的原因，没有问题就返回空切片。
+// 返回 error 说明规则本身出了问题，比如依赖的 Redis 不可用，不代表文章有问题。
+type Rule interface {
+	Name() string
+	Check(ctx context.Context, art domain.Article) ([]string, error)
+}
+
+// Recorder 需要记住已经发表的文章的规则实现这个接口，比如重复内容检测
+type Recorder interface {
+	Record(ctx context.Context, art domain.Article) error
+}
+
+// Moderator 按顺序执行所有的规则，汇总没通过的原因
+type Moderator struct {
+	rules []Rule
+	l     logger.LoggerV1
+}
+
+func NewModerator(l logger.LoggerV1, rules ...Rule) *Moderator {
+	return &Moderator{rules: rules, l: l}
+}
+
+// Check 返回所有规则没通过的原因，为空就是通过了。
+// 某条规则执行出错的时候，保守起见当作没通过，交给人工审核
+func (m *Moderator) Check(ctx context.Context, art domain.Article) []string {
+	var res []string
+	for _, r := range m.rules {
+		reasons, err := r.Check(ctx, art)
+		if err != nil {
+			m.l.Error("执行审核规则失败",
+				logger.String("rule", r.Name()),
+				logger.Int64("aid", art.Id),
+				logger.Error(err))
+			res = append(res, r.Name()+"：审核出错，需要人工复核")
+			continue
+		}
+		res = append(res, reasons...)
+	}
+	return res
+}
+
+// Record 文章发表之后调用，失败了只影响后续的检测，所以只记录日志
+func (m *Moderator) Record(ctx context.Context, art domain.Article) {
+	for _, r := range m.rules {
+		rec, ok := r.(Recorder)
+		if !ok {
+			continue
+		}
+		if err := rec.Record(ctx, art); err != nil {
+			m.l.Error("记录已发表的文章失败",
+				logger.String("rule", r.Name()),
+				logger.Int64("aid", art.Id),
+				logger.Error(err))
+		}
+	}
+}
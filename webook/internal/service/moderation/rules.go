@@ -0,0 +1,148 @@
+package moderation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/pkg/markdown"
+	"basic-go/webook/pkg/sensitive"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SensitiveWordRule 标题和内容里面不能出现词库里的词
+type SensitiveWordRule struct {
+	dict *sensitive.Dictionary
+}
+
+func NewSensitiveWordRule(dict *sensitive.Dictionary) *SensitiveWordRule {
+	return &SensitiveWordRule{dict: dict}
+}
+
+func (s *SensitiveWordRule) Name() string {
+	return "敏感词"
+}
+
+func (s *SensitiveWordRule) Check(ctx context.Context, art domain.Article) ([]string, error) {
+	// 用原始内容检查，链接和图片的描述里面也不能有敏感词
+	words := s.dict.FindAll(art.Title + "\n" + art.Content)
+	if len(words) == 0 {
+		return nil, nil
+	}
+	return []string{"包含敏感词：" + strings.Join(words, "、")}, nil
+}
+
+var linkPattern = regexp.MustCompile(`https?://[^\s)\]>"']+`)
+
+// LinkDensityRule 链接太密集的文章多半是广告
+type LinkDensityRule struct {
+	// minLinks 链接少于这个数的文章不检查，正常的文章也会贴几个参考链接
+	minLinks int
+	// maxPerThousand 每一千个字最多允许多少个链接
+	maxPerThousand float64
+}
+
+func NewLinkDensityRule(minLinks int, maxPerThousand float64) *LinkDensityRule {
+	return &LinkDensityRule{minLinks: minLinks, maxPerThousand: maxPerThousand}
+}
+
+func (r *LinkDensityRule) Name() string {
+	return "链接密度"
+}
+
+func (r *LinkDensityRule) Check(ctx context.Context, art domain.Article) ([]string, error) {
+	links := len(linkPattern.FindAllStringIndex(art.Content, -1))
+	if links < r.minLinks {
+		return nil, nil
+	}
+	runes := max(len([]rune(markdown.PlainText(art.Content))), 1)
+	density := float64(links) * 1000 / float64(runes)
+	if density <= r.maxPerThousand {
+		return nil, nil
+	}
+	return []string{fmt.Sprintf("链接过多：%d 个链接，每千字 %.1f 个", links, density)}, nil
+}
+
+// DuplicateContentRule 和已经发表的文章内容完全一样（忽略格式和标点）。
+// 只能发现原样搬运，改几个字就发现不了，需要的话可以换成 SimHash
+type DuplicateContentRule struct {
+	client redis.Cmdable
+	// minRunes 太短的文章很容易撞上，不检查
+	minRunes   int
+	expiration time.Duration
+}
+
+func NewDuplicateContentRule(client redis.Cmdable, minRunes int,
+	expiration time.Duration) *DuplicateContentRule {
+	return &DuplicateContentRule{
+		client:     client,
+		minRunes:   minRunes,
+		expiration: expiration,
+	}
+}
+
+func (r *DuplicateContentRule) Name() string {
+	return "重复内容"
+}
+
+func (r *DuplicateContentRule) Check(ctx context.Context, art domain.Article) ([]string, error) {
+	fp, ok := r.fingerprint(art)
+	if !ok {
+		return nil, nil
+	}
+	val, err := r.client.Get(ctx, r.key(fp)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	owner, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	// 同一篇文章重新发表
+	if owner == art.Id {
+		return nil, nil
+	}
+	return []string{fmt.Sprintf("和文章 %d 的内容重复", owner)}, nil
+}
+
+// Record 只记第一篇，后面内容一样的文章都算重复
+func (r *DuplicateContentRule) Record(ctx context.Context, art domain.Article) error {
+	fp, ok := r.fingerprint(art)
+	if !ok {
+		return nil
+	}
+	return r.client.SetNX(ctx, r.key(fp), art.Id, r.expiration).Err()
+}
+
+// fingerprint 去掉格式、标点和空白之后的内容的哈希
+func (r *DuplicateContentRule) fingerprint(art domain.Article) (string, bool) {
+	var sb strings.Builder
+	cnt := 0
+	for _, c := range markdown.PlainText(art.Content) {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			sb.WriteRune(unicode.ToLower(c))
+			cnt++
+		}
+	}
+	if cnt < r.minRunes {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(sb.String()))
+	return hex.EncodeToString(sum[:]), true
+}
+
+func (r *DuplicateContentRule) key(fp string) string {
+	return "moderation:fingerprint:" + fp
+}
@@ -0,0 +1,66 @@
+package moderation
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"basic-go/webook/pkg/logger"
+	"basic-go/webook/pkg/sensitive"
+)
+
+// LoadWords 读取词库文件，一行一个词，空行和 # 开头的行会被忽略
+func LoadWords(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var res []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		res = append(res, line)
+	}
+	return res, scanner.Err()
+}
+
+// WatchWords 每隔 interval 检查一次词库文件，修改时间变了就重新加载。
+// 加载失败的时候继续用旧的词库，ctx 取消之后退出
+func WatchWords(ctx context.Context, dict *sensitive.Dictionary, path string,
+	interval time.Duration, l logger.LoggerV1) {
+	var modTime time.Time
+	if stat, err := os.Stat(path); err == nil {
+		modTime = stat.ModTime()
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		stat, err := os.Stat(path)
+		if err != nil {
+			l.Warn("读取敏感词库失败", logger.String("path", path), logger.Error(err))
+			continue
+		}
+		if stat.ModTime().Equal(modTime) {
+			continue
+		}
+		words, err := LoadWords(path)
+		if err != nil {
+			l.Warn("加载敏感词库失败", logger.String("path", path), logger.Error(err))
+			continue
+		}
+		modTime = stat.ModTime()
+		dict.Reload(words)
+		l.Debug("重新加载了敏感词库", logger.Int("cnt", len(words)))
+	}
+}
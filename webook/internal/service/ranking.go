@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository"
+
+	"github.com/ecodeclub/ekit/queue"
+	"github.com/ecodeclub/ekit/slice"
+)
+
+const (
+	// readsPerLike 多少次阅读相当于一次点赞
+	readsPerLike = 10
+	// rankingGravity 越大，分数随时间衰减得越快
+	rankingGravity = 1.5
+)
+
+type RankingService interface {
+	// TopN 重新计算热榜
+	TopN(ctx context.Context) error
+	// GetTopN 查询热榜，热度高的在前面
+	GetTopN(ctx context.Context) ([]domain.Article, error)
+}
+
+// BatchRankingService 分批遍历最近发表的文章，批量查询计数，
+// 用一个容量为 n 的小顶堆维护分数最高的 n 篇文章
+type BatchRankingService struct {
+	artRepo  repository.ArticleRepository
+	intrRepo repository.InteractiveRepository
+	userRepo repository.UserRepository
+	repo     repository.RankingRepository
+
+	batchSize int
+	n         int
+	// window 只考虑最近这么久发表的文章，再早的文章分数已经衰减得差不多了
+	window time.Duration
+	// scoreFunc 可以替换，方便测试和调整算法
+	scoreFunc func(likeCnt int64, readCnt int64, pubTime time.Time) float64
+	biz       string
+}
+
+func NewBatchRankingService(artRepo repository.ArticleRepository,
+	intrRepo repository.InteractiveRepository,
+	userRepo repository.UserRepository,
+	repo repository.RankingRepository) RankingService {
+	return &BatchRankingService{
+		artRepo:   artRepo,
+		intrRepo:  intrRepo,
+		userRepo:  userRepo,
+		repo:      repo,
+		batchSize: 100,
+		n:         100,
+		window:    time.Hour * 24 * 7,
+		scoreFunc: hackerNewsScore,
+		biz:       "article",
+	}
+}
+
+// hackerNewsScore 类似 Hacker News 的 P / (T+2)^G，
+// P 是点赞数加上折算之后的阅读数，T 是发表了多少个小时
+func hackerNewsScore(likeCnt int64, readCnt int64, pubTime time.Time) float64 {
+	points := float64(likeCnt) + float64(readCnt)/readsPerLike
+	hours := max(time.Since(pubTime).Hours(), 0)
+	return points / math.Pow(hours+2, rankingGravity)
+}
+
+func (b *BatchRankingService) GetTopN(ctx context.Context) ([]domain.Article, error) {
+	return b.repo.GetTopN(ctx)
+}
+
+func (b *BatchRankingService) TopN(ctx context.Context) error {
+	arts, err := b.topN(ctx)
+	if err != nil {
+		return err
+	}
+	b.fillAuthors(ctx, arts)
+	return b.repo.ReplaceTopN(ctx, arts)
+}
+
+func (b *BatchRankingService) topN(ctx context.Context) ([]domain.Article, error) {
+	type scored struct {
+		art   domain.Article
+		score float64
+	}
+	// 小顶堆，堆顶是目前入选的文章里面分数最低的
+	topN := queue.NewPriorityQueue[scored](b.n, func(src scored, dst scored) int {
+		switch {
+		case src.score > dst.score:
+			return 1
+		case src.score < dst.score:
+			return -1
+		default:
+			return 0
+		}
+	})
+	start := time.Now().Add(-b.window)
+	for offset := 0; ; offset += b.batchSize {
+		arts, err := b.artRepo.ListPub(ctx, start, offset, b.batchSize)
+		if err != nil {
+			return nil, err
+		}
+		ids := slice.Map[domain.Article, int64](arts, func(idx int, src domain.Article) int64 {
+			return src.Id
+		})
+		intrs, err := b.intrRepo.GetByIds(ctx, b.biz, ids)
+		if err != nil {
+			return nil, err
+		}
+		intrMap := make(map[int64]domain.Interactive, len(intrs))
+		for _, intr := range intrs {
+			intrMap[intr.BizId] = intr
+		}
+		for _, art := range arts {
+			intr := intrMap[art.Id]
+			cur := scored{
+				art:   art,
+				score: b.scoreFunc(intr.LikeCnt, intr.ReadCnt, art.Ctime),
+			}
+			if topN.Len() < b.n {
+				_ = topN.Enqueue(cur)
+				continue
+			}
+			lowest, _ := topN.Peek()
+			if cur.score > lowest.score {
+				_, _ = topN.Dequeue()
+				_ = topN.Enqueue(cur)
+			}
+		}
+		if len(arts) < b.batchSize {
+			break
+		}
+	}
+	res := make([]domain.Article, topN.Len())
+	for i := len(res) - 1; i >= 0; i-- {
+		val, _ := topN.Dequeue()
+		res[i] = val.art
+	}
+	return res, nil
+}
+
+// fillAuthors 补充作者昵称，查不到昵称不影响榜单，所以忽略错误
+func (b *BatchRankingService) fillAuthors(ctx context.Context, arts []domain.Article) {
+	names := make(map[int64]string, len(arts))
+	for i := range arts {
+		uid := arts[i].Author.Id
+		name, ok := names[uid]
+		if !ok {
+			u, err := b.userRepo.FindById(ctx, uid)
+			if err == nil {
+				name = u.Nickname
+			}
+			names[uid] = name
+		}
+		arts[i].Author.Name = name
+	}
+}
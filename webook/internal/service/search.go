@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository"
+)
+
+// maxSearchQueryLen 查询太长的话二元切分出来的词太多，没有意义
+const maxSearchQueryLen = 64
+
+var ErrInvalidSearchQuery = errors.New("搜索关键字不能为空，也不能太长")
+
+type SearchService interface {
+	// SearchArticle 搜索已发表的文章，返回这一页的结果和命中的总数
+	SearchArticle(ctx context.Context, query string, offset int, limit int) ([]domain.ArticleSearchHit, int, error)
+}
+
+type searchService struct {
+	repo repository.SearchRepository
+}
+
+func NewSearchService(repo repository.SearchRepository) SearchService {
+	return &searchService{
+		repo: repo,
+	}
+}
+
+func (s *searchService) SearchArticle(ctx context.Context, query string,
+	offset int, limit int) ([]domain.ArticleSearchHit, int, error) {
+	query = strings.TrimSpace(query)
+	if query == "" || utf8.RuneCountInString(query) > maxSearchQueryLen {
+		return nil, 0, ErrInvalidSearchQuery
+	}
+	return s.repo.SearchArticle(ctx, query, offset, limit)
+}
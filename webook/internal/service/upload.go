@@ -0,0 +1,339 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"mime"
+	"net/http"
+	"regexp"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/repository"
+	"basic-go/webook/pkg/logger"
+	"basic-go/webook/pkg/objectstore"
+	"basic-go/webook/pkg/rlock"
+	"basic-go/webook/pkg/thumbnail"
+)
+
+var (
+	ErrEmptyFile           = errors.New("文件是空的")
+	ErrFileTooLarge        = errors.New("文件太大")
+	ErrUnsupportedFileType = errors.New("不支持的文件类型")
+	ErrUploadQuotaExceeded = repository.ErrUploadQuotaExceeded
+	ErrUploadNotFound      = errors.New("文件不存在")
+)
+
+const (
+	// uploadKeyPrefix 上传的文件在对象存储里面的前缀
+	uploadKeyPrefix = "uploads/"
+	thumbnailSuffix = "_thumb.jpg"
+	// orphanScanBatch 清理的时候每一批检查多少条上传记录，以及每次读多少篇文章
+	orphanScanBatch = 50
+)
+
+// uploadTypes 允许上传的文件类型以及对应的扩展名，类型是根据内容判断的，不相信客户端
+var uploadTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"application/zip": ".zip",
+	"text/plain":      ".txt",
+}
+
+// thumbnailTypes 标准库能解码的图片才生成缩略图
+var thumbnailTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+var (
+	uploadNamePattern = regexp.MustCompile(`^([0-9a-f]{64})(_thumb\.jpg|\.[a-z]+)$`)
+	// uploadHashPattern 文章内容里面只要出现了哈希，就认为引用了这个文件
+	uploadHashPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+)
+
+type UploadService interface {
+	// Upload 上传文件，同样内容的文件只会存一份，也只扣减一次空间
+	Upload(ctx context.Context, uid int64, name string, data []byte) (domain.Upload, error)
+	// File 根据 URL 里面的文件名读取文件，返回内容和类型
+	File(ctx context.Context, name string) ([]byte, string, error)
+	Usage(ctx context.Context, uid int64) (domain.UploadUsage, error)
+	// CleanOrphans 删除 before 之前上传，但是没有被上传者的任何文章引用的文件，返回删除的数量
+	CleanOrphans(ctx context.Context, before time.Time) (int, error)
+}
+
+type UploadConfig struct {
+	// MaxSize 单个文件的大小上限
+	MaxSize int64
+	// Quota 每个用户的上传空间
+	Quota int64
+	// BaseURL 拼在文件名前面就是 URL，可以是 CDN 的地址
+	BaseURL string
+	// ThumbnailSize 缩略图长边的像素数
+	ThumbnailSize int
+}
+
+type uploadService struct {
+	repo    repository.UploadRepository
+	artRepo repository.ArticleRepository
+	store   objectstore.ObjectStore
+	lock    *rlock.Client
+	l       logger.LoggerV1
+	cfg     UploadConfig
+}
+
+func NewUploadService(repo repository.UploadRepository,
+	artRepo repository.ArticleRepository,
+	store objectstore.ObjectStore,
+	lock *rlock.Client,
+	l logger.LoggerV1,
+	cfg UploadConfig) UploadService {
+	return &uploadService{
+		repo:    repo,
+		artRepo: artRepo,
+		store:   store,
+		lock:    lock,
+		l:       l,
+		cfg:     cfg,
+	}
+}
+
+func (s *uploadService) Upload(ctx context.Context, uid int64, name string, data []byte) (domain.Upload, error) {
+	if len(data) == 0 {
+		return domain.Upload{}, ErrEmptyFile
+	}
+	if int64(len(data)) > s.cfg.MaxSize {
+		return domain.Upload{}, ErrFileTooLarge
+	}
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(data))
+	if err != nil {
+		return domain.Upload{}, ErrUnsupportedFileType
+	}
+	ext, ok := uploadTypes[contentType]
+	if !ok {
+		return domain.Upload{}, ErrUnsupportedFileType
+	}
+	var thumb []byte
+	if thumbnailTypes[contentType] {
+		thumb, _, err = thumbnail.Generate(data, s.cfg.ThumbnailSize)
+		if err != nil {
+			// 看起来是图片，但是解码不了
+			return domain.Upload{}, ErrUnsupportedFileType
+		}
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	fileName := hash + ext
+
+	// 和清理任务互斥，否则清理任务可能会删掉刚刚上传的文件
+	lock, err := s.lockHash(ctx, hash)
+	if err != nil {
+		return domain.Upload{}, err
+	}
+	defer s.unlock(lock)
+	cnt, err := s.repo.CountByHash(ctx, hash)
+	if err != nil {
+		return domain.Upload{}, err
+	}
+	up, err := s.repo.Create(ctx, domain.Upload{
+		Uid:          uid,
+		Hash:         hash,
+		Name:         name,
+		ContentType:  contentType,
+		Size:         int64(len(data)),
+		HasThumbnail: len(thumb) > 0,
+	}, s.cfg.Quota)
+	if err != nil {
+		return domain.Upload{}, err
+	}
+	// 已经有人传过了，不需要再存一次
+	if cnt == 0 {
+		err = s.putObjects(ctx, fileName, contentType, data, hash, thumb)
+		if err != nil {
+			if er := s.repo.Delete(ctx, up.Id); er != nil {
+				s.l.Error("上传失败之后删除上传记录失败",
+					logger.Int64("id", up.Id), logger.Error(er))
+			}
+			return domain.Upload{}, err
+		}
+	}
+	return s.withURL(up), nil
+}
+
+func (s *uploadService) putObjects(ctx context.Context, fileName string, contentType string,
+	data []byte, hash string, thumb []byte) error {
+	err := s.store.Put(ctx, uploadKeyPrefix+fileName, data, contentType)
+	if err != nil || len(thumb) == 0 {
+		return err
+	}
+	return s.store.Put(ctx, uploadKeyPrefix+hash+thumbnailSuffix, thumb, "image/jpeg")
+}
+
+func (s *uploadService) File(ctx context.Context, name string) ([]byte, string, error) {
+	matches := uploadNamePattern.FindStringSubmatch(name)
+	if matches == nil {
+		return nil, "", ErrUploadNotFound
+	}
+	contentType := "image/jpeg"
+	if matches[2] != thumbnailSuffix {
+		contentType = ""
+		for typ, ext := range uploadTypes {
+			if ext == matches[2] {
+				contentType = typ
+				break
+			}
+		}
+		if contentType == "" {
+			return nil, "", ErrUploadNotFound
+		}
+	}
+	data, err := s.store.Get(ctx, uploadKeyPrefix+name)
+	if errors.Is(err, objectstore.ErrObjectNotFound) {
+		return nil, "", ErrUploadNotFound
+	}
+	return data, contentType, err
+}
+
+func (s *uploadService) Usage(ctx context.Context, uid int64) (domain.UploadUsage, error) {
+	used, err := s.repo.GetUsage(ctx, uid)
+	if err != nil {
+		return domain.UploadUsage{}, err
+	}
+	return domain.UploadUsage{
+		Used:  used,
+		Quota: s.cfg.Quota,
+	}, nil
+}
+
+func (s *uploadService) CleanOrphans(ctx context.Context, before time.Time) (int, error) {
+	var (
+		afterId int64
+		cnt     int
+	)
+	for {
+		ups, err := s.repo.FindBefore(ctx, before, afterId, orphanScanBatch)
+		if err != nil {
+			return cnt, err
+		}
+		// 一批里面同一个用户的上传，只需要读一次他的文章
+		refs := make(map[int64]map[string]struct{})
+		for _, up := range ups {
+			hashes, ok := refs[up.Uid]
+			if !ok {
+				hashes, err = s.referencedHashes(ctx, up.Uid)
+				if err != nil {
+					return cnt, err
+				}
+				refs[up.Uid] = hashes
+			}
+			if _, ok = hashes[up.Hash]; ok {
+				continue
+			}
+			err = s.remove(ctx, up)
+			if err != nil {
+				return cnt, err
+			}
+			cnt++
+		}
+		if len(ups) < orphanScanBatch {
+			return cnt, nil
+		}
+		afterId = ups[len(ups)-1].Id
+	}
+}
+
+// referencedHashes 作者所有文章里面出现过的哈希。
+// 线上的版本可能和草稿不一样，比如草稿里面删掉了一张图片但是还没有重新发表，所以两个都要看。
+// 回收站里面的文章也算，不然恢复之后图片就没了
+func (s *uploadService) referencedHashes(ctx context.Context, uid int64) (map[string]struct{}, error) {
+	res := make(map[string]struct{})
+	collect := func(content string) {
+		for _, h := range uploadHashPattern.FindAllString(content, -1) {
+			res[h] = struct{}{}
+		}
+	}
+	for offset := 0; ; offset += orphanScanBatch {
+		arts, err := s.artRepo.GetDeletedByAuthor(ctx, uid, offset, orphanScanBatch)
+		if err != nil {
+			return nil, err
+		}
+		// 删除之后线上库的内容也看不到了，草稿的内容就是最后的内容
+		for _, art := range arts {
+			collect(art.Content)
+		}
+		if len(arts) < orphanScanBatch {
+			break
+		}
+	}
+	for offset := 0; ; offset += orphanScanBatch {
+		arts, err := s.artRepo.GetByAuthor(ctx, uid, offset, orphanScanBatch)
+		if err != nil {
+			return nil, err
+		}
+		for _, art := range arts {
+			collect(art.Content)
+			if art.Status != domain.ArticleStatusPublished {
+				continue
+			}
+			pub, err := s.artRepo.GetPubById(ctx, art.Id)
+			if err != nil {
+				return nil, err
+			}
+			collect(pub.Content)
+		}
+		if len(arts) < orphanScanBatch {
+			return res, nil
+		}
+	}
+}
+
+func (s *uploadService) remove(ctx context.Context, up domain.Upload) error {
+	lock, err := s.lockHash(ctx, up.Hash)
+	if err != nil {
+		return err
+	}
+	defer s.unlock(lock)
+	err = s.repo.Delete(ctx, up.Id)
+	if err != nil {
+		return err
+	}
+	cnt, err := s.repo.CountByHash(ctx, up.Hash)
+	if err != nil || cnt > 0 {
+		// 还有别人传过同样的文件
+		return err
+	}
+	err = s.store.Delete(ctx, uploadKeyPrefix+up.Hash+uploadTypes[up.ContentType])
+	if err != nil || !up.HasThumbnail {
+		return err
+	}
+	return s.store.Delete(ctx, uploadKeyPrefix+up.Hash+thumbnailSuffix)
+}
+
+func (s *uploadService) lockHash(ctx context.Context, hash string) (*rlock.Lock, error) {
+	return s.lock.Lock(ctx, "lock:upload:"+hash, time.Second*30, time.Second,
+		&rlock.FixIntervalRetry{Interval: time.Millisecond * 100, Max: 50})
+}
+
+func (s *uploadService) unlock(lock *rlock.Lock) {
+	// 解锁失败也没关系，过期之后会自动释放
+	if err := lock.Unlock(context.Background()); err != nil {
+		s.l.Warn("释放上传文件的锁失败", logger.Error(err))
+	}
+}
+
+func (s *uploadService) withURL(up domain.Upload) domain.Upload {
+	up.URL = s.cfg.BaseURL + up.Hash + uploadTypes[up.ContentType]
+	switch {
+	case up.HasThumbnail:
+		up.ThumbnailURL = s.cfg.BaseURL + up.Hash + thumbnailSuffix
+	case thumbnailTypes[up.ContentType] || up.ContentType == "image/webp":
+		up.ThumbnailURL = up.URL
+	}
+	return up
+}
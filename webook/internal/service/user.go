@@ -14,6 +14,7 @@ import (
 var (
 	ErrDuplicateEmail        = repository.ErrDuplicateUser
 	ErrInvalidUserOrPassword = errors.New("用户不存在或者密码不对")
+	ErrUserNotFound          = repository.ErrUserNotFound
 )
 
 // type UserService struct {
@@ -28,6 +29,7 @@ type UserService interface {
 		uid int64) (domain.User, error)
 	FindOrCreate(ctx context.Context, phone string) (domain.User, error)
 	FindOrCreateByWechat(ctx context.Context, info domain.WechatInfo) (domain.User, error)
+	UpdateAvatar(ctx context.Context, uid int64, avatar string) error
 }
 type userService struct {
 	repo repository.UserRepository
@@ -70,6 +72,9 @@ func (svc *userService) UpdateNonSensitiveInfo(ctx context.Context,
 	// UpdateNicknameAndXXAnd
 	return svc.repo.UpdateNonZeroFields(ctx, user)
 }
+func (svc *userService) UpdateAvatar(ctx context.Context, uid int64, avatar string) error {
+	return svc.repo.UpdateAvatar(ctx, uid, avatar)
+}
 func (svc *userService) FindById(ctx context.Context,
 	uid int64) (domain.User, error) {
 	return svc.repo.FindById(ctx, uid)
@@ -5,7 +5,6 @@ import (
 	"basic-go/webook/internal/service"
 	"basic-go/webook/internal/web/jwt"
 	"basic-go/webook/pkg/logger"
-	"context"
 	"net/http"
 	"strconv"
 	"time"
@@ -16,20 +15,23 @@ import (
 )
 
 type ArticleHandler struct {
-	svc     service.ArticleService
-	intrSvc service.InteractiveService
-	l       logger.LoggerV1
-	biz     string
+	svc       service.ArticleService
+	intrSvc   service.InteractiveService
+	followSvc service.FollowService
+	l         logger.LoggerV1
+	biz       string
 }
 
 func NewArticleHandler(l logger.LoggerV1,
 	svc service.ArticleService,
-	intrSvc service.InteractiveService) *ArticleHandler {
+	intrSvc service.InteractiveService,
+	followSvc service.FollowService) *ArticleHandler {
 	return &ArticleHandler{
-		l:       l,
-		svc:     svc,
-		intrSvc: intrSvc,
-		biz:     "article",
+		l:         l,
+		svc:       svc,
+		intrSvc:   intrSvc,
+		followSvc: followSvc,
+		biz:       "article",
 	}
 }
 func (h *ArticleHandler) RegisterRoutes(server *gin.Engine) {
@@ -39,6 +41,7 @@ func (h *ArticleHandler) RegisterRoutes(server *gin.Engine) {
 	g.POST("/edit", h.Edit)
 	g.POST("/publish", h.Publish)
 	g.POST("/withdraw", h.Withdraw)
+	g.POST("/delete", h.Delete)
 
 	// 创作者接口
 	g.GET("/detail/:id", h.Detail) // 作者获取文章详情
@@ -46,11 +49,44 @@ func (h *ArticleHandler) RegisterRoutes(server *gin.Engine) {
 	// /list?offset=?&limit=?
 	g.POST("/list", h.List)
 
+	// 历史版本
+	rev := g.Group("/revisions")
+	rev.POST("/list", h.ListRevisions)
+	rev.GET("/detail/:id", h.RevisionDetail)
+	rev.POST("/diff", h.DiffRevisions)
+	rev.POST("/restore", h.RestoreRevision)
+
+	// 定时发表
+	sch := g.Group("/scheduled")
+	sch.POST("/list", h.ListScheduled)
+	sch.POST("/reschedule", h.Reschedule)
+	sch.POST("/cancel", h.CancelSchedule)
+
+	// 回收站
+	trash := g.Group("/trash")
+	trash.POST("/list", h.ListTrash)
+	trash.POST("/restore", h.Restore)
+
+	g.GET("/categories", h.Categories)
+	// 我点过赞的文章
+	g.GET("/liked", h.ListLiked)
+
+	// 已经发表的文章列表，不需要登录
+	g.GET("/author/:uid", h.ListByAuthor)
+	g.GET("/newest", h.ListNewest)
+
+	// 标签相关的接口不需要登录
+	tg := server.Group("/tags")
+	tg.GET("/articles", h.ListByTag)
+	tg.GET("/cloud", h.TagCloud)
+
 	pub := g.Group("/pub")
 	pub.GET("/:id", h.PubDetail)
+	pub.GET("/:id/likers", h.Likers)
 	// 传入一个参数，true 就是点赞, false 就是不点赞
 	pub.POST("/like", h.Like)
 	pub.POST("/collect", h.Collect)
+	pub.POST("/cancel_collect", h.CancelCollect)
 }
 
 func (h *ArticleHandler) PubDetail(ctx *gin.Context) {
@@ -68,16 +104,17 @@ func (h *ArticleHandler) PubDetail(ctx *gin.Context) {
 		return
 	}
 	var (
-		eg   errgroup.Group
-		art  domain.Article
-		intr domain.Interactive
+		eg     errgroup.Group
+		art    domain.Article
+		intr   domain.Interactive
+		likers []domain.Like
 	)
 
 	uc := ctx.MustGet("user").(jwt.UserClaims)
 	eg.Go(func() error {
 		var er error
 		// art, er = h.svc.GetPubById(ctx, id)
-		art, er = h.svc.GetPubById(ctx, id, uc.Uid)
+		art, er = h.svc.GetPubById(ctx, id, uc.Uid, ctx.ClientIP())
 		return er
 	})
 	eg.Go(func() error {
@@ -85,6 +122,17 @@ func (h *ArticleHandler) PubDetail(ctx *gin.Context) {
 		intr, er = h.intrSvc.Get(ctx, h.biz, id, uc.Uid)
 		return er
 	})
+	eg.Go(func() error {
+		var er error
+		likers, er = h.intrSvc.ListLikers(ctx, h.biz, id, time.Time{}, 0, likerPreviewCnt)
+		if er != nil {
+			// 点赞的人查不到不影响文章的展示
+			h.l.Error("查询最近点赞的人失败",
+				logger.Int64("aid", id),
+				logger.Error(er))
+		}
+		return nil
+	})
 
 	// 等待结果
 	err = eg.Wait()
@@ -100,35 +148,47 @@ func (h *ArticleHandler) PubDetail(ctx *gin.Context) {
 		return
 	}
 
-	go func() {
-		// 1. 如果你想摆脱原本主链路的超时控制，你就创建一个新的
-		// 2. 如果你不想，你就用 ctx
-		newCtx, cancel := context.WithTimeout(context.Background(), time.Second)
-		defer cancel()
-		er := h.intrSvc.IncrReadCnt(newCtx, h.biz, art.Id)
-		if er != nil {
-			h.l.Error("更新阅读数失败",
-				logger.Int64("aid", art.Id),
-				logger.Error(err))
-		}
-	}()
+	// 要先拿到作者才能知道有没有关注，查不到不影响文章的展示
+	followed, err := h.followSvc.Followed(ctx, uc.Uid, art.Author.Id)
+	if err != nil {
+		h.l.Error("查询是否关注作者失败",
+			logger.Int64("uid", uc.Uid),
+			logger.Int64("author", art.Author.Id),
+			logger.Error(err))
+	}
 
+	// 阅读数不在这里加，GetPubById 发出去的阅读事件里面会去重之后再加
 	ctx.JSON(http.StatusOK, Result{
 		Data: ArticleVo{
 			Id:    art.Id,
 			Title: art.Title,
 			// Abstract:   "",
-			Content:    art.Content,
-			AuthorId:   art.Author.Id,
-			AuthorName: art.Author.Name,
-			ReadCnt:    intr.ReadCnt,
-			CollectCnt: intr.CollectCnt,
-			LikeCnt:    intr.LikeCnt,
-			Liked:      intr.Liked,
-			Collected:  intr.Collected,
-			Status:     art.Status.ToUint8(),
-			Ctime:      art.Ctime.Format(time.DateTime),
-			Utime:      art.Utime.Format(time.DateTime),
+			Content: art.Content,
+			HTML:    art.Rendered.HTML,
+			TOC: slice.Map(art.Rendered.TOC, func(idx int, src domain.TOCItem) TOCItemVo {
+				return TOCItemVo{
+					Level:  src.Level,
+					Title:  src.Title,
+					Anchor: src.Anchor,
+				}
+			}),
+			ReadingTime:  int(art.Rendered.ReadingTime.Minutes()),
+			Category:     art.Category,
+			Tags:         art.Tags,
+			AuthorId:     art.Author.Id,
+			AuthorName:   art.Author.Name,
+			Followed:     followed,
+			ReadCnt:      intr.ReadCnt,
+			PV:           intr.PV,
+			UV:           intr.UV,
+			CollectCnt:   intr.CollectCnt,
+			LikeCnt:      intr.LikeCnt,
+			Liked:        intr.Liked,
+			Collected:    intr.Collected,
+			RecentLikers: slice.Map[domain.Like, LikerVo](likers, toLikerVo),
+			Status:       art.Status.ToUint8(),
+			Ctime:        art.Ctime.Format(time.DateTime),
+			Utime:        art.Utime.Format(time.DateTime),
 		},
 	})
 }
@@ -179,7 +239,16 @@ func (h *ArticleHandler) Collect(ctx *gin.Context) {
 	uc := ctx.MustGet("user").(jwt.UserClaims)
 
 	err := h.intrSvc.Collect(ctx, h.biz, req.Id, req.Cid, uc.Uid)
-	if err != nil {
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "OK",
+		})
+	case service.ErrCollectionNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4, Msg: err.Error(),
+		})
+	default:
 		ctx.JSON(http.StatusOK, Result{
 			Code: 5, Msg: "系统错误",
 		})
@@ -187,6 +256,27 @@ func (h *ArticleHandler) Collect(ctx *gin.Context) {
 			logger.Error(err),
 			logger.Int64("uid", uc.Uid),
 			logger.Int64("aid", req.Id))
+	}
+}
+
+func (h *ArticleHandler) CancelCollect(ctx *gin.Context) {
+	type Req struct {
+		Id int64 `json:"id"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	err := h.intrSvc.CancelCollect(ctx, h.biz, req.Id, uc.Uid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5, Msg: "系统错误",
+		})
+		h.l.Error("取消收藏失败",
+			logger.Error(err),
+			logger.Int64("uid", uc.Uid),
+			logger.Int64("aid", req.Id))
 		return
 	}
 	ctx.JSON(http.StatusOK, Result{
@@ -197,9 +287,11 @@ func (h *ArticleHandler) Collect(ctx *gin.Context) {
 // Edit 接收 Article 输入，返回一个 ID，文章的 ID
 func (h *ArticleHandler) Edit(ctx *gin.Context) {
 	type Req struct {
-		Id      int64
-		Title   string `json:"title"`
-		Content string `json:"content"`
+		Id       int64
+		Title    string   `json:"title"`
+		Content  string   `json:"content"`
+		Category string   `json:"category"`
+		Tags     []string `json:"tags"`
 	}
 	var req Req
 	if err := ctx.Bind(&req); err != nil {
@@ -207,13 +299,22 @@ func (h *ArticleHandler) Edit(ctx *gin.Context) {
 	}
 	uc := ctx.MustGet("user").(jwt.UserClaims)
 	id, err := h.svc.Save(ctx, domain.Article{
-		Id:      req.Id,
-		Title:   req.Title,
-		Content: req.Content,
+		Id:       req.Id,
+		Title:    req.Title,
+		Content:  req.Content,
+		Category: req.Category,
+		Tags:     req.Tags,
 		Author: domain.Author{
 			Id: uc.Uid,
 		},
 	})
+	if err == service.ErrInvalidTags {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "标签不合法",
+		})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusOK, Result{
 			Msg: "系统错误",
@@ -230,9 +331,13 @@ func (h *ArticleHandler) Edit(ctx *gin.Context) {
 
 func (h *ArticleHandler) Publish(ctx *gin.Context) {
 	type Req struct {
-		Id      int64
-		Title   string `json:"title"`
-		Content string `json:"content"`
+		Id       int64
+		Title    string   `json:"title"`
+		Content  string   `json:"content"`
+		Category string   `json:"category"`
+		Tags     []string `json:"tags"`
+		// 定时发表的时间，毫秒数，不传就是立刻发表
+		PublishAt int64 `json:"publishAt"`
 	}
 	var req Req
 	if err := ctx.Bind(&req); err != nil {
@@ -247,14 +352,35 @@ func (h *ArticleHandler) Publish(ctx *gin.Context) {
 	//	return
 	//}
 	uc := ctx.MustGet("user").(jwt.UserClaims)
-	id, err := h.svc.Publish(ctx, domain.Article{
-		Id:      req.Id,
-		Title:   req.Title,
-		Content: req.Content,
+	art := domain.Article{
+		Id:       req.Id,
+		Title:    req.Title,
+		Content:  req.Content,
+		Category: req.Category,
+		Tags:     req.Tags,
 		Author: domain.Author{
 			Id: uc.Uid,
 		},
-	})
+	}
+	if req.PublishAt > 0 {
+		art.PublishAt = time.UnixMilli(req.PublishAt)
+	}
+	id, err := h.svc.Publish(ctx, art)
+	if err == service.ErrInvalidTags {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "标签不合法",
+		})
+		return
+	}
+	if err == service.ErrArticlePendingReview {
+		// 文章已经保存了，前端拿着 ID 跳到文章详情，看到的状态是待审核
+		ctx.JSON(http.StatusOK, Result{
+			Msg:  err.Error(),
+			Data: id,
+		})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusOK, Result{
 			Msg:  "系统错误",
@@ -375,6 +501,8 @@ func (h *ArticleHandler) Detail(ctx *gin.Context) {
 		Title: art.Title,
 		//Abstract: art.Abstract(),
 		Content:  art.Content,
+		Category: art.Category,
+		Tags:     art.Tags,
 		AuthorId: art.Author.Id,
 		// 列表，你不需要
 		Status: art.Status.ToUint8(),
@@ -0,0 +1,122 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/web/jwt"
+	"basic-go/webook/pkg/logger"
+
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+)
+
+// likerPreviewCnt 文章详情页展示最近几个点赞的人
+const likerPreviewCnt = 5
+
+// ListLiked 我点过赞的文章，最近点赞的在前面
+// /articles/liked?cursor=?&limit=?
+func (h *ArticleHandler) ListLiked(ctx *gin.Context) {
+	utime, maxId, limit, ok := h.likePage(ctx)
+	if !ok {
+		return
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	likes, err := h.intrSvc.ListLiked(ctx, h.biz, uc.Uid, utime, maxId, limit)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询点过赞的文章失败",
+			logger.Int64("uid", uc.Uid),
+			logger.Error(err))
+		return
+	}
+	res := LikedListVo{
+		Items: slice.Map[domain.Like, LikedItemVo](likes, func(idx int, src domain.Like) LikedItemVo {
+			return LikedItemVo{
+				Biz:      src.Biz,
+				BizId:    src.BizId,
+				LikeTime: src.Utime.Format(time.DateTime),
+			}
+		}),
+	}
+	if len(likes) == limit {
+		last := likes[len(likes)-1]
+		res.Cursor = encodeCursor(last.Utime.UnixMilli(), last.Id)
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
+
+// Likers 给文章点赞的人，最近点赞的在前面
+// /articles/pub/:id/likers?cursor=?&limit=?
+func (h *ArticleHandler) Likers(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	utime, maxId, limit, ok := h.likePage(ctx)
+	if !ok {
+		return
+	}
+	likes, err := h.intrSvc.ListLikers(ctx, h.biz, id, utime, maxId, limit)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询点赞的人失败",
+			logger.Int64("aid", id),
+			logger.Error(err))
+		return
+	}
+	res := LikerListVo{
+		Likers: slice.Map[domain.Like, LikerVo](likes, toLikerVo),
+	}
+	if len(likes) == limit {
+		last := likes[len(likes)-1]
+		res.Cursor = encodeCursor(last.Utime.UnixMilli(), last.Id)
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
+
+// likePage 解析游标和 limit，参数不对的时候已经写好了响应
+func (h *ArticleHandler) likePage(ctx *gin.Context) (time.Time, int64, int, bool) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return time.Time{}, 0, 0, false
+	}
+	utime, maxId, err := decodeCursor(ctx.Query("cursor"))
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  err.Error(),
+		})
+		return time.Time{}, 0, 0, false
+	}
+	return time.UnixMilli(utime), maxId, limit, true
+}
+
+func toLikerVo(idx int, src domain.Like) LikerVo {
+	return LikerVo{
+		Uid:      src.Liker.Id,
+		Nickname: src.Liker.Name,
+		Avatar:   src.Liker.Avatar,
+		LikeTime: src.Utime.Format(time.DateTime),
+	}
+}
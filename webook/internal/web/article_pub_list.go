@@ -0,0 +1,103 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/pkg/logger"
+
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+)
+
+// ListByAuthor 作者主页上已经发表的文章，不需要登录
+// /articles/author/:uid?cursor=?&limit=?
+func (h *ArticleHandler) ListByAuthor(ctx *gin.Context) {
+	uid, err := strconv.ParseInt(ctx.Param("uid"), 10, 64)
+	if err != nil || uid <= 0 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	h.listPub(ctx, func(utime time.Time, maxId int64, limit int) ([]domain.Article, error) {
+		return h.svc.ListPubByAuthor(ctx, uid, utime, maxId, limit)
+	}, logger.Int64("author", uid))
+}
+
+// ListNewest 全站最新发表的文章，不需要登录
+// /articles/newest?cursor=?&limit=?
+func (h *ArticleHandler) ListNewest(ctx *gin.Context) {
+	h.listPub(ctx, func(utime time.Time, maxId int64, limit int) ([]domain.Article, error) {
+		return h.svc.ListNewestPub(ctx, utime, maxId, limit)
+	})
+}
+
+// listPub 线上库文章列表的公共部分：解析游标，查询文章，再批量补上计数
+func (h *ArticleHandler) listPub(ctx *gin.Context,
+	list func(utime time.Time, maxId int64, limit int) ([]domain.Article, error),
+	fields ...logger.Field) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	utime, maxId, err := decodeCursor(ctx.Query("cursor"))
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  err.Error(),
+		})
+		return
+	}
+	arts, err := list(time.UnixMilli(utime), maxId, limit)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询已发表的文章列表失败",
+			append(fields, logger.Error(err))...)
+		return
+	}
+	ids := slice.Map[domain.Article, int64](arts, func(idx int, src domain.Article) int64 {
+		return src.Id
+	})
+	// 计数查不到不影响列表的展示
+	// 不需要登录的页面，只查计数
+	intrs, err := h.intrSvc.GetByIds(ctx, h.biz, ids, 0)
+	if err != nil {
+		h.l.Error("批量查询文章计数失败",
+			append(fields, logger.Int("cnt", len(ids)), logger.Error(err))...)
+	}
+	res := ArticleListVo{
+		Articles: slice.Map[domain.Article, ArticleVo](arts, func(idx int, src domain.Article) ArticleVo {
+			intr := intrs[src.Id]
+			return ArticleVo{
+				Id:         src.Id,
+				Title:      src.Title,
+				Abstract:   src.Abstract(),
+				Category:   src.Category,
+				AuthorId:   src.Author.Id,
+				Ctime:      src.Ctime.Format(time.DateTime),
+				Utime:      src.Utime.Format(time.DateTime),
+				ReadCnt:    intr.ReadCnt,
+				LikeCnt:    intr.LikeCnt,
+				CollectCnt: intr.CollectCnt,
+			}
+		}),
+	}
+	if len(arts) == limit {
+		last := arts[len(arts)-1]
+		res.Cursor = encodeCursor(last.Utime.UnixMilli(), last.Id)
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
@@ -0,0 +1,139 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/service"
+	"basic-go/webook/internal/web/jwt"
+	"basic-go/webook/pkg/logger"
+
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+)
+
+// ArticleReviewHandler 审核员的接口，只有配置里的审核员能访问
+type ArticleReviewHandler struct {
+	svc       service.ArticleReviewService
+	reviewers map[int64]struct{}
+	l         logger.LoggerV1
+}
+
+func NewArticleReviewHandler(svc service.ArticleReviewService,
+	reviewers []int64, l logger.LoggerV1) *ArticleReviewHandler {
+	set := make(map[int64]struct{}, len(reviewers))
+	for _, uid := range reviewers {
+		set[uid] = struct{}{}
+	}
+	return &ArticleReviewHandler{
+		svc:       svc,
+		reviewers: set,
+		l:         l,
+	}
+}
+
+func (h *ArticleReviewHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/reviews", h.checkReviewer)
+	g.POST("/list", h.List)
+	g.POST("/approve", h.Approve)
+	g.POST("/reject", h.Reject)
+}
+
+func (h *ArticleReviewHandler) checkReviewer(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	if _, ok := h.reviewers[uc.Uid]; !ok {
+		ctx.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+}
+
+func (h *ArticleReviewHandler) List(ctx *gin.Context) {
+	var page Page
+	if err := ctx.Bind(&page); err != nil {
+		return
+	}
+	rs, err := h.svc.ListPending(ctx, page.Offset, page.Limit)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查找待审核的文章失败",
+			logger.Int("offset", page.Offset),
+			logger.Int("limit", page.Limit),
+			logger.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: slice.Map[domain.ArticleReview, ArticleReviewVo](rs,
+			func(idx int, src domain.ArticleReview) ArticleReviewVo {
+				return ArticleReviewVo{
+					Id:         src.Id,
+					ArticleId:  src.ArticleId,
+					AuthorId:   src.Author.Id,
+					Title:      src.Title,
+					Content:    src.Content,
+					Violations: src.Violations,
+					Ctime:      src.Ctime.Format(time.DateTime),
+					Utime:      src.Utime.Format(time.DateTime),
+				}
+			}),
+	})
+}
+
+type ReviewReq struct {
+	Id     int64  `json:"id"`
+	Reason string `json:"reason"`
+}
+
+func (h *ArticleReviewHandler) Approve(ctx *gin.Context) {
+	var req ReviewReq
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	err := h.svc.Approve(ctx, uc.Uid, req.Id, req.Reason)
+	h.respond(ctx, uc.Uid, req.Id, "审核通过文章失败", err)
+}
+
+func (h *ArticleReviewHandler) Reject(ctx *gin.Context) {
+	var req ReviewReq
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	err := h.svc.Reject(ctx, uc.Uid, req.Id, req.Reason)
+	h.respond(ctx, uc.Uid, req.Id, "驳回文章失败", err)
+}
+
+func (h *ArticleReviewHandler) respond(ctx *gin.Context, uid int64, id int64, msg string, err error) {
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "OK",
+		})
+	case errors.Is(err, service.ErrReviewNotFound):
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "审核记录不存在",
+		})
+	case errors.Is(err, service.ErrReviewResolved),
+		errors.Is(err, service.ErrReviewOutdated),
+		errors.Is(err, service.ErrEmptyRejectReason):
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error(msg,
+			logger.Int64("reviewer", uid),
+			logger.Int64("review_id", id),
+			logger.Error(err))
+	}
+}
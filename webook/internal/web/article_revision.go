@@ -0,0 +1,155 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/web/jwt"
+	"basic-go/webook/pkg/diff"
+	"basic-go/webook/pkg/logger"
+
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *ArticleHandler) ListRevisions(ctx *gin.Context) {
+	type Req struct {
+		Id     int64 `json:"id"`
+		Offset int   `json:"offset"`
+		Limit  int   `json:"limit"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	revs, err := h.svc.ListRevisions(ctx, uc.Uid, req.Id, req.Offset, req.Limit)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查找文章历史版本失败",
+			logger.Error(err),
+			logger.Int64("aid", req.Id),
+			logger.Int64("uid", uc.Uid))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: slice.Map[domain.ArticleRevision, ArticleRevisionVo](revs,
+			func(idx int, src domain.ArticleRevision) ArticleRevisionVo {
+				return ArticleRevisionVo{
+					Id:        src.Id,
+					ArticleId: src.ArticleId,
+					Version:   src.Version,
+					Title:     src.Title,
+					Abstract:  src.Article().Abstract(),
+					Status:    src.Status.ToUint8(),
+					Ctime:     src.Ctime.Format(time.DateTime),
+				}
+			}),
+	})
+}
+
+func (h *ArticleHandler) RevisionDetail(ctx *gin.Context) {
+	idstr := ctx.Param("id")
+	id, err := strconv.ParseInt(idstr, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Msg:  "id 参数错误",
+			Code: 4,
+		})
+		h.l.Warn("查询文章历史版本失败，id 格式不对",
+			logger.String("id", idstr),
+			logger.Error(err))
+		return
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	rev, err := h.svc.GetRevision(ctx, uc.Uid, id)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Msg:  "系统错误",
+			Code: 5,
+		})
+		h.l.Error("查询文章历史版本失败",
+			logger.Int64("id", id),
+			logger.Int64("uid", uc.Uid),
+			logger.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: ArticleRevisionVo{
+			Id:        rev.Id,
+			ArticleId: rev.ArticleId,
+			Version:   rev.Version,
+			Title:     rev.Title,
+			Content:   rev.Content,
+			Status:    rev.Status.ToUint8(),
+			Ctime:     rev.Ctime.Format(time.DateTime),
+		},
+	})
+}
+
+func (h *ArticleHandler) DiffRevisions(ctx *gin.Context) {
+	type Req struct {
+		// 旧版本的 ID
+		From int64 `json:"from"`
+		// 新版本的 ID
+		To int64 `json:"to"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	lines, err := h.svc.DiffRevisions(ctx, uc.Uid, req.From, req.To)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Msg:  "系统错误",
+			Code: 5,
+		})
+		h.l.Error("比较文章历史版本失败",
+			logger.Int64("from", req.From),
+			logger.Int64("to", req.To),
+			logger.Int64("uid", uc.Uid),
+			logger.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: slice.Map[diff.Line, DiffLineVo](lines, func(idx int, src diff.Line) DiffLineVo {
+			return DiffLineVo{
+				Op:   src.Op.String(),
+				Text: src.Text,
+			}
+		}),
+	})
+}
+
+func (h *ArticleHandler) RestoreRevision(ctx *gin.Context) {
+	type Req struct {
+		// 历史版本的 ID
+		Id int64 `json:"id"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	aid, err := h.svc.RestoreRevision(ctx, uc.Uid, req.Id)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Msg:  "系统错误",
+			Code: 5,
+		})
+		h.l.Error("恢复文章历史版本失败",
+			logger.Int64("id", req.Id),
+			logger.Int64("uid", uc.Uid),
+			logger.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: aid,
+	})
+}
@@ -0,0 +1,119 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/web/jwt"
+	"basic-go/webook/pkg/logger"
+
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+)
+
+// ListByTag 分页查询打了某个标签的已发表文章
+// /tags/articles?tag=?&offset=?&limit=?
+func (h *ArticleHandler) ListByTag(ctx *gin.Context) {
+	tag := ctx.Query("tag")
+	offset, _ := strconv.Atoi(ctx.Query("offset"))
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if tag == "" || offset < 0 || err != nil || limit <= 0 || limit > 100 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	arts, err := h.svc.ListPubByTag(ctx, tag, offset, limit)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("按照标签查找文章失败",
+			logger.Error(err),
+			logger.String("tag", tag),
+			logger.Int("offset", offset),
+			logger.Int("limit", limit))
+		return
+	}
+	ids := slice.Map[domain.Article, int64](arts, func(idx int, src domain.Article) int64 {
+		return src.Id
+	})
+	// 不需要登录的页面，只查计数，查不到也不影响列表的展示
+	intrs, err := h.intrSvc.GetByIds(ctx, h.biz, ids, 0)
+	if err != nil {
+		h.l.Error("批量查询文章计数失败",
+			logger.Error(err),
+			logger.String("tag", tag))
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: slice.Map[domain.Article, ArticleVo](arts, func(idx int, src domain.Article) ArticleVo {
+			intr := intrs[src.Id]
+			return ArticleVo{
+				Id:         src.Id,
+				Title:      src.Title,
+				Abstract:   src.Abstract(),
+				Category:   src.Category,
+				AuthorId:   src.Author.Id,
+				Ctime:      src.Ctime.Format(time.DateTime),
+				Utime:      src.Utime.Format(time.DateTime),
+				ReadCnt:    intr.ReadCnt,
+				LikeCnt:    intr.LikeCnt,
+				CollectCnt: intr.CollectCnt,
+			}
+		}),
+	})
+}
+
+// TagCloud /tags/cloud?limit=?
+func (h *ArticleHandler) TagCloud(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	tcs, err := h.svc.TagCloud(ctx, limit)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询标签云失败",
+			logger.Error(err),
+			logger.Int("limit", limit))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: slice.Map[domain.TagCount, TagCountVo](tcs, func(idx int, src domain.TagCount) TagCountVo {
+			return TagCountVo{
+				Name: src.Name,
+				Cnt:  src.Cnt,
+			}
+		}),
+	})
+}
+
+// Categories 作者用过的所有分类，编辑文章的时候给作者选
+func (h *ArticleHandler) Categories(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	res, err := h.svc.GetCategories(ctx, uc.Uid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询文章分类失败",
+			logger.Error(err),
+			logger.Int64("uid", uc.Uid))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
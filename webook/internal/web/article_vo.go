@@ -1,18 +1,104 @@
 package web
 
 type ArticleVo struct {
-	Id         int64  `json:"id,omitempty"`
-	Title      string `json:"title,omitempty"`
-	Abstract   string `json:"abstract,omitempty"`
-	Content    string `json:"content,omitempty"`
-	AuthorId   int64  `json:"authorId,omitempty"`
-	AuthorName string `json:"authorName,omitempty"`
-	Status     uint8  `json:"status,omitempty"`
-	Ctime      string `json:"ctime,omitempty"`
-	Utime      string `json:"utime,omitempty"`
-	ReadCnt    int64  `json:"readCnt"`
-	LikeCnt    int64  `json:"likeCnt"`
-	CollectCnt int64  `json:"collectCnt"`
-	Liked      bool   `json:"liked"`
-	Collected  bool   `json:"collected"`
+	Id       int64  `json:"id,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Abstract string `json:"abstract,omitempty"`
+	// Content 是作者写的原始 Markdown，HTML 是渲染并且过滤之后的，前端应该展示 HTML
+	Content string      `json:"content,omitempty"`
+	HTML    string      `json:"html,omitempty"`
+	TOC     []TOCItemVo `json:"toc,omitempty"`
+	// ReadingTime 预计阅读时间，单位是分钟
+	ReadingTime int `json:"readingTime,omitempty"`
+	// 分类和标签
+	Category   string   `json:"category,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	AuthorId   int64    `json:"authorId,omitempty"`
+	AuthorName string   `json:"authorName,omitempty"`
+	// Followed 当前用户是否关注了作者
+	Followed bool   `json:"followed"`
+	Status   uint8  `json:"status,omitempty"`
+	Ctime    string `json:"ctime,omitempty"`
+	Utime    string `json:"utime,omitempty"`
+	// 定时发表的时间
+	PublishAt string `json:"publishAt,omitempty"`
+	// 放进回收站的时间
+	DeletedAt string `json:"deletedAt,omitempty"`
+	ReadCnt   int64  `json:"readCnt"`
+	// PV 今天的浏览量，UV 今天的独立读者数
+	PV         int64 `json:"pv,omitempty"`
+	UV         int64 `json:"uv,omitempty"`
+	LikeCnt    int64 `json:"likeCnt"`
+	CollectCnt int64 `json:"collectCnt"`
+	Liked      bool  `json:"liked"`
+	Collected  bool  `json:"collected"`
+	// RecentLikers 最近点赞的几个人，只有详情页有
+	RecentLikers []LikerVo `json:"recentLikers,omitempty"`
+}
+
+type TOCItemVo struct {
+	Level  int    `json:"level"`
+	Title  string `json:"title"`
+	Anchor string `json:"anchor"`
+}
+
+type ArticleRevisionVo struct {
+	Id        int64  `json:"id"`
+	ArticleId int64  `json:"articleId"`
+	Version   int64  `json:"version"`
+	Title     string `json:"title,omitempty"`
+	Abstract  string `json:"abstract,omitempty"`
+	Content   string `json:"content,omitempty"`
+	Status    uint8  `json:"status,omitempty"`
+	Ctime     string `json:"ctime,omitempty"`
+}
+
+type DiffLineVo struct {
+	// equal, insert 或者 delete
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+type TagCountVo struct {
+	Name string `json:"name"`
+	Cnt  int64  `json:"cnt"`
+}
+
+type SearchResultVo struct {
+	Total    int               `json:"total"`
+	Articles []ArticleSearchVo `json:"articles"`
+}
+
+// ArticleSearchVo 里面的 Title 和 Snippet 是已经转义过的 HTML，命中的词用 <em> 标记
+type ArticleSearchVo struct {
+	Id       int64   `json:"id"`
+	Title    string  `json:"title"`
+	Snippet  string  `json:"snippet"`
+	AuthorId int64   `json:"authorId"`
+	Utime    string  `json:"utime"`
+	Score    float64 `json:"score"`
+}
+
+// FeedVo 游标分页的结果，Cursor 为空代表没有下一页了
+type FeedVo struct {
+	Articles []ArticleVo `json:"articles"`
+	Cursor   string      `json:"cursor,omitempty"`
+}
+
+// ArticleListVo 游标分页的结果，Cursor 为空代表没有下一页了
+type ArticleListVo struct {
+	Articles []ArticleVo `json:"articles"`
+	Cursor   string      `json:"cursor,omitempty"`
+}
+
+// ArticleReviewVo 审核员看到的待审核文章，Content 是提交审核时候的原始内容
+type ArticleReviewVo struct {
+	Id         int64    `json:"id"`
+	ArticleId  int64    `json:"articleId"`
+	AuthorId   int64    `json:"authorId"`
+	Title      string   `json:"title"`
+	Content    string   `json:"content"`
+	Violations []string `json:"violations"`
+	Ctime      string   `json:"ctime"`
+	Utime      string   `json:"utime"`
 }
@@ -0,0 +1,255 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/service"
+	"basic-go/webook/internal/web/jwt"
+	"basic-go/webook/pkg/logger"
+
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+)
+
+type CollectionHandler struct {
+	svc service.CollectionService
+	l   logger.LoggerV1
+	biz string
+}
+
+func NewCollectionHandler(svc service.CollectionService, l logger.LoggerV1) *CollectionHandler {
+	return &CollectionHandler{
+		svc: svc,
+		l:   l,
+		biz: "article",
+	}
+}
+
+func (h *CollectionHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/collections")
+	g.POST("/create", h.Create)
+	g.POST("/edit", h.Edit)
+	g.POST("/delete", h.Delete)
+	// /list?offset=?&limit=?
+	g.GET("/list", h.List)
+	// /items?cid=?&cursor=?&limit=?，cid 为 0 是默认收藏夹
+	g.GET("/items", h.Items)
+	g.POST("/move", h.Move)
+}
+
+type CollectionReq struct {
+	Id          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (h *CollectionHandler) Create(ctx *gin.Context) {
+	var req CollectionReq
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	id, err := h.svc.Create(ctx, domain.Collection{
+		Uid:         uc.Uid,
+		Name:        req.Name,
+		Description: req.Description,
+	})
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Data: id,
+		})
+	case service.ErrInvalidCollectionName, service.ErrDuplicateCollectionName:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("创建收藏夹失败",
+			logger.Error(err),
+			logger.Int64("uid", uc.Uid))
+	}
+}
+
+func (h *CollectionHandler) Edit(ctx *gin.Context) {
+	var req CollectionReq
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	err := h.svc.Update(ctx, domain.Collection{
+		Id:          req.Id,
+		Uid:         uc.Uid,
+		Name:        req.Name,
+		Description: req.Description,
+	})
+	h.respond(ctx, err, "修改收藏夹失败", uc.Uid, req.Id)
+}
+
+func (h *CollectionHandler) Delete(ctx *gin.Context) {
+	type Req struct {
+		Id int64 `json:"id"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	err := h.svc.Delete(ctx, uc.Uid, req.Id)
+	h.respond(ctx, err, "删除收藏夹失败", uc.Uid, req.Id)
+}
+
+func (h *CollectionHandler) Move(ctx *gin.Context) {
+	type Req struct {
+		// Id 收藏的文章
+		Id int64 `json:"id"`
+		// Cid 目标收藏夹，0 是默认收藏夹
+		Cid int64 `json:"cid"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	err := h.svc.Move(ctx, uc.Uid, h.biz, req.Id, req.Cid)
+	h.respond(ctx, err, "移动收藏失败", uc.Uid, req.Cid)
+}
+
+// respond 修改类的接口，业务错误原样返回给前端
+func (h *CollectionHandler) respond(ctx *gin.Context, err error, msg string, uid int64, cid int64) {
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "OK",
+		})
+	case service.ErrInvalidCollectionName, service.ErrDuplicateCollectionName,
+		service.ErrCollectionNotFound, service.ErrCollectionItemNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error(msg,
+			logger.Error(err),
+			logger.Int64("uid", uid),
+			logger.Int64("cid", cid))
+	}
+}
+
+func (h *CollectionHandler) List(ctx *gin.Context) {
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	cols, err := h.svc.List(ctx, uc.Uid, offset, limit)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询收藏夹失败",
+			logger.Error(err),
+			logger.Int64("uid", uc.Uid))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: slice.Map[domain.Collection, CollectionVo](cols, func(idx int, src domain.Collection) CollectionVo {
+			return CollectionVo{
+				Id:          src.Id,
+				Name:        src.Name,
+				Description: src.Description,
+				ItemCnt:     src.ItemCnt,
+				Ctime:       src.Ctime.Format(time.DateTime),
+				Utime:       src.Utime.Format(time.DateTime),
+			}
+		}),
+	})
+}
+
+func (h *CollectionHandler) Items(ctx *gin.Context) {
+	cid, err := strconv.ParseInt(ctx.DefaultQuery("cid", "0"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	utime, maxId, err := decodeCursor(ctx.Query("cursor"))
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  err.Error(),
+		})
+		return
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	items, err := h.svc.Items(ctx, uc.Uid, cid, time.UnixMilli(utime), maxId, limit)
+	switch err {
+	case nil:
+	case service.ErrCollectionNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  err.Error(),
+		})
+		return
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询收藏夹内容失败",
+			logger.Error(err),
+			logger.Int64("uid", uc.Uid),
+			logger.Int64("cid", cid))
+		return
+	}
+	res := CollectionItemListVo{
+		Items: slice.Map[domain.CollectionItem, CollectionItemVo](items, func(idx int, src domain.CollectionItem) CollectionItemVo {
+			return CollectionItemVo{
+				Biz:         src.Biz,
+				BizId:       src.BizId,
+				CollectTime: src.Utime.Format(time.DateTime),
+			}
+		}),
+	}
+	if len(items) == limit {
+		last := items[len(items)-1]
+		res.Cursor = encodeCursor(last.Utime.UnixMilli(), last.Id)
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
@@ -0,0 +1,23 @@
+package web
+
+type CollectionVo struct {
+	Id          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	ItemCnt     int64  `json:"itemCnt"`
+	Ctime       string `json:"ctime"`
+	Utime       string `json:"utime"`
+}
+
+type CollectionItemVo struct {
+	Biz   string `json:"biz"`
+	BizId int64  `json:"bizId"`
+	// 收藏的时间
+	CollectTime string `json:"collectTime"`
+}
+
+// CollectionItemListVo 游标分页的结果，Cursor 为空代表没有下一页了
+type CollectionItemListVo struct {
+	Items  []CollectionItemVo `json:"items"`
+	Cursor string             `json:"cursor,omitempty"`
+}
@@ -0,0 +1,322 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/service"
+	"basic-go/webook/internal/web/jwt"
+	"basic-go/webook/pkg/logger"
+
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	commentSortNewest  = "newest"
+	commentSortHottest = "hottest"
+)
+
+type CommentHandler struct {
+	svc     service.CommentService
+	intrSvc service.InteractiveService
+	l       logger.LoggerV1
+	// biz 评论本身的点赞用的 biz
+	biz string
+}
+
+func NewCommentHandler(svc service.CommentService,
+	intrSvc service.InteractiveService,
+	l logger.LoggerV1) *CommentHandler {
+	return &CommentHandler{
+		svc:     svc,
+		intrSvc: intrSvc,
+		l:       l,
+		biz:     "comment",
+	}
+}
+
+func (h *CommentHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/comments")
+	g.POST("/create", h.Create)
+	// /list?biz=article&bizId=?&sort=newest|hottest&cursor=?&limit=?
+	g.GET("/list", h.List)
+	// /replies?rootId=?&cursor=?&limit=?
+	g.GET("/replies", h.Replies)
+	g.POST("/delete", h.Delete)
+	g.POST("/like", h.Like)
+}
+
+func (h *CommentHandler) Create(ctx *gin.Context) {
+	type Req struct {
+		// Biz 不传就是评论文章
+		Biz   string `json:"biz"`
+		BizId int64  `json:"bizId"`
+		// ParentId 回复哪一条评论，不传就是根评论
+		ParentId int64  `json:"parentId"`
+		Content  string `json:"content"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	if req.Biz == "" {
+		req.Biz = "article"
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	id, err := h.svc.Create(ctx, domain.Comment{
+		Commentator: domain.Author{Id: uc.Uid},
+		Biz:         req.Biz,
+		BizId:       req.BizId,
+		ParentId:    req.ParentId,
+		Content:     req.Content,
+	})
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Data: id,
+		})
+	case service.ErrInvalidComment, service.ErrCommentTargetNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("发表评论失败",
+			logger.Error(err),
+			logger.Int64("uid", uc.Uid),
+			logger.Int64("bizId", req.BizId),
+			logger.Int64("parentId", req.ParentId))
+	}
+}
+
+func (h *CommentHandler) List(ctx *gin.Context) {
+	biz := ctx.DefaultQuery("biz", "article")
+	bizId, err := strconv.ParseInt(ctx.Query("bizId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	limit, ok := h.limit(ctx)
+	if !ok {
+		return
+	}
+	likeCnt, maxId, err := decodeCursor(ctx.Query("cursor"))
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  err.Error(),
+		})
+		return
+	}
+	sort := ctx.DefaultQuery("sort", commentSortNewest)
+	var cmts []domain.Comment
+	switch sort {
+	case commentSortNewest:
+		cmts, err = h.svc.ListNewest(ctx, biz, bizId, maxId, limit)
+	case commentSortHottest:
+		cmts, err = h.svc.ListHottest(ctx, biz, bizId, likeCnt, maxId, limit)
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询评论失败",
+			logger.Error(err),
+			logger.String("biz", biz),
+			logger.Int64("bizId", bizId),
+			logger.String("sort", sort))
+		return
+	}
+	res := CommentListVo{}
+	if len(cmts) == limit {
+		last := cmts[len(cmts)-1]
+		if sort == commentSortHottest {
+			res.Cursor = encodeCursor(last.LikeCnt, last.Id)
+		} else {
+			res.Cursor = encodeCursor(0, last.Id)
+		}
+	}
+	h.respondComments(ctx, cmts, res)
+}
+
+func (h *CommentHandler) Replies(ctx *gin.Context) {
+	rootId, err := strconv.ParseInt(ctx.Query("rootId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	limit, ok := h.limit(ctx)
+	if !ok {
+		return
+	}
+	_, minId, err := decodeCursor(ctx.Query("cursor"))
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  err.Error(),
+		})
+		return
+	}
+	cmts, err := h.svc.ListReplies(ctx, rootId, minId, limit)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询回复失败",
+			logger.Error(err),
+			logger.Int64("rootId", rootId))
+		return
+	}
+	res := CommentListVo{}
+	if len(cmts) == limit {
+		res.Cursor = encodeCursor(0, cmts[len(cmts)-1].Id)
+	}
+	h.respondComments(ctx, cmts, res)
+}
+
+func (h *CommentHandler) Delete(ctx *gin.Context) {
+	type Req struct {
+		Id int64 `json:"id"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	err := h.svc.Delete(ctx, uc.Uid, req.Id)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "OK",
+		})
+	case service.ErrCommentTargetNotFound, service.ErrCommentPermissionDenied:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("删除评论失败",
+			logger.Error(err),
+			logger.Int64("uid", uc.Uid),
+			logger.Int64("id", req.Id))
+	}
+}
+
+// Like 评论的点赞和文章的点赞是同一套逻辑，只是 biz 不一样
+func (h *CommentHandler) Like(ctx *gin.Context) {
+	type Req struct {
+		Id int64 `json:"id"`
+		// true 是点赞，false 是取消点赞
+		Like bool `json:"like"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	// 评论不存在的话不能留下点赞记录
+	_, err := h.svc.FindById(ctx, req.Id)
+	if err == service.ErrCommentTargetNotFound {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4, Msg: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5, Msg: "系统错误",
+		})
+		h.l.Error("评论点赞的时候查询评论失败",
+			logger.Error(err),
+			logger.Int64("uid", uc.Uid),
+			logger.Int64("cid", req.Id))
+		return
+	}
+	if req.Like {
+		err = h.intrSvc.Like(ctx, h.biz, req.Id, uc.Uid)
+	} else {
+		err = h.intrSvc.CancelLike(ctx, h.biz, req.Id, uc.Uid)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5, Msg: "系统错误",
+		})
+		h.l.Error("评论点赞/取消点赞失败",
+			logger.Error(err),
+			logger.Int64("uid", uc.Uid),
+			logger.Int64("cid", req.Id))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "OK",
+	})
+}
+
+func (h *CommentHandler) limit(ctx *gin.Context) (int, bool) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return 0, false
+	}
+	return limit, true
+}
+
+// respondComments 补充当前用户是否点赞过，然后返回
+func (h *CommentHandler) respondComments(ctx *gin.Context, cmts []domain.Comment, res CommentListVo) {
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	ids := slice.Map[domain.Comment, int64](cmts, func(idx int, src domain.Comment) int64 {
+		return src.Id
+	})
+	intrs, err := h.intrSvc.GetByIds(ctx, h.biz, ids, uc.Uid)
+	if err != nil {
+		// 点赞状态查不到不影响评论的展示
+		h.l.Error("查询评论点赞状态失败",
+			logger.Error(err),
+			logger.Int64("uid", uc.Uid))
+	}
+	res.Comments = slice.Map[domain.Comment, CommentVo](cmts, func(idx int, src domain.Comment) CommentVo {
+		return CommentVo{
+			Id:              src.Id,
+			CommentatorId:   src.Commentator.Id,
+			CommentatorName: src.Commentator.Name,
+			RootId:          src.RootId,
+			ParentId:        src.ParentId,
+			Content:         src.Content,
+			LikeCnt:         src.LikeCnt,
+			Liked:           intrs[src.Id].Liked,
+			ReplyCnt:        src.ReplyCnt,
+			Ctime:           src.Ctime.Format(time.DateTime),
+		}
+	})
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
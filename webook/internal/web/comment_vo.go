@@ -0,0 +1,20 @@
+package web
+
+type CommentVo struct {
+	Id              int64  `json:"id"`
+	CommentatorId   int64  `json:"commentatorId"`
+	CommentatorName string `json:"commentatorName,omitempty"`
+	RootId          int64  `json:"rootId"`
+	ParentId        int64  `json:"parentId"`
+	Content         string `json:"content"`
+	LikeCnt         int64  `json:"likeCnt"`
+	Liked           bool   `json:"liked"`
+	ReplyCnt        int64  `json:"replyCnt"`
+	Ctime           string `json:"ctime"`
+}
+
+// CommentListVo 游标分页的结果，Cursor 为空代表没有下一页了
+type CommentListVo struct {
+	Comments []CommentVo `json:"comments"`
+	Cursor   string      `json:"cursor,omitempty"`
+}
@@ -0,0 +1,31 @@
+package web
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+var errInvalidCursor = errors.New("非法的游标")
+
+// encodeCursor 游标分页用的游标，由排序字段 score 和 id 组成，
+// 对前端来说是不透明的，原样带回来就可以
+func encodeCursor(score int64, id int64) string {
+	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d_%d", score, id))
+}
+
+// decodeCursor 空字符串代表第一页，返回的都是 0
+func decodeCursor(cursor string) (score int64, id int64, err error) {
+	if cursor == "" {
+		return 0, 0, nil
+	}
+	val, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, errInvalidCursor
+	}
+	_, err = fmt.Sscanf(string(val), "%d_%d", &score, &id)
+	if err != nil || id <= 0 {
+		return 0, 0, errInvalidCursor
+	}
+	return score, id, nil
+}
@@ -0,0 +1,107 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/service"
+	"basic-go/webook/internal/web/jwt"
+	"basic-go/webook/pkg/logger"
+
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+)
+
+type FeedHandler struct {
+	svc     service.FeedService
+	intrSvc service.InteractiveService
+	l       logger.LoggerV1
+	biz     string
+}
+
+func NewFeedHandler(svc service.FeedService, intrSvc service.InteractiveService, l logger.LoggerV1) *FeedHandler {
+	return &FeedHandler{
+		svc:     svc,
+		intrSvc: intrSvc,
+		l:       l,
+		biz:     "article",
+	}
+}
+
+func (h *FeedHandler) RegisterRoutes(server *gin.Engine) {
+	// /feed?cursor=?&limit=?
+	server.GET("/feed", h.Feed)
+}
+
+func (h *FeedHandler) Feed(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	ctime, maxAid, err := decodeCursor(ctx.Query("cursor"))
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  err.Error(),
+		})
+		return
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	items, next, err := h.svc.Feed(ctx, uc.Uid, ctime, maxAid, limit)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询时间线失败",
+			logger.Error(err),
+			logger.Int64("uid", uc.Uid))
+		return
+	}
+	ids := slice.Map[domain.FeedItem, int64](items, func(idx int, src domain.FeedItem) int64 {
+		return src.Article.Id
+	})
+	// 计数查不到不影响时间线的展示
+	intrs, err := h.intrSvc.GetByIds(ctx, h.biz, ids, uc.Uid)
+	if err != nil {
+		h.l.Error("批量查询文章计数失败",
+			logger.Error(err),
+			logger.Int64("uid", uc.Uid))
+	}
+	res := FeedVo{
+		Articles: slice.Map[domain.FeedItem, ArticleVo](items, func(idx int, src domain.FeedItem) ArticleVo {
+			intr := intrs[src.Article.Id]
+			return ArticleVo{
+				Id:         src.Article.Id,
+				Title:      src.Article.Title,
+				Abstract:   src.Article.Abstract(),
+				Category:   src.Article.Category,
+				Tags:       src.Article.Tags,
+				AuthorId:   src.Article.Author.Id,
+				AuthorName: src.Article.Author.Name,
+				// 时间线上的文章都是关注的人写的
+				Followed:   true,
+				Ctime:      src.Ctime.Format(time.DateTime),
+				Utime:      src.Article.Utime.Format(time.DateTime),
+				ReadCnt:    intr.ReadCnt,
+				LikeCnt:    intr.LikeCnt,
+				CollectCnt: intr.CollectCnt,
+				Liked:      intr.Liked,
+				Collected:  intr.Collected,
+			}
+		}),
+	}
+	// 被过滤掉的文章会让这一页不满 limit 条，是不是还有下一页要看 next
+	if next.Article.Id > 0 {
+		res.Cursor = encodeCursor(next.Ctime.UnixMilli(), next.Article.Id)
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
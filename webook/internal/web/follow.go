@@ -0,0 +1,171 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/service"
+	"basic-go/webook/internal/web/jwt"
+	"basic-go/webook/pkg/logger"
+
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+)
+
+type FollowHandler struct {
+	svc service.FollowService
+	l   logger.LoggerV1
+}
+
+func NewFollowHandler(svc service.FollowService, l logger.LoggerV1) *FollowHandler {
+	return &FollowHandler{
+		svc: svc,
+		l:   l,
+	}
+}
+
+func (h *FollowHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/follow")
+	g.POST("/follow", h.Follow)
+	g.POST("/cancel", h.CancelFollow)
+	// /followers?uid=?&cursor=?&limit=?，不传 uid 就是自己
+	g.GET("/followers", h.Followers)
+	// /followees?uid=?&cursor=?&limit=?，不传 uid 就是自己
+	g.GET("/followees", h.Followees)
+}
+
+func (h *FollowHandler) Follow(ctx *gin.Context) {
+	type Req struct {
+		Followee int64 `json:"followee"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	err := h.svc.Follow(ctx, uc.Uid, req.Followee)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "OK",
+		})
+	case service.ErrFollowSelf, service.ErrFolloweeNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("关注失败",
+			logger.Error(err),
+			logger.Int64("follower", uc.Uid),
+			logger.Int64("followee", req.Followee))
+	}
+}
+
+func (h *FollowHandler) CancelFollow(ctx *gin.Context) {
+	type Req struct {
+		Followee int64 `json:"followee"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	err := h.svc.CancelFollow(ctx, uc.Uid, req.Followee)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("取消关注失败",
+			logger.Error(err),
+			logger.Int64("follower", uc.Uid),
+			logger.Int64("followee", req.Followee))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "OK",
+	})
+}
+
+func (h *FollowHandler) Followers(ctx *gin.Context) {
+	h.list(ctx, h.svc.Followers, func(rel domain.FollowRelation) domain.Author {
+		return rel.Follower
+	})
+}
+
+func (h *FollowHandler) Followees(ctx *gin.Context) {
+	h.list(ctx, h.svc.Followees, func(rel domain.FollowRelation) domain.Author {
+		return rel.Followee
+	})
+}
+
+// list 粉丝列表和关注列表的处理逻辑是一样的，user 从关系里面取出要展示的那个人
+func (h *FollowHandler) list(ctx *gin.Context,
+	find func(ctx context.Context, uid int64, utime int64, maxId int64, limit int) ([]domain.FollowRelation, error),
+	user func(rel domain.FollowRelation) domain.Author) {
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	uid := uc.Uid
+	if uidStr := ctx.Query("uid"); uidStr != "" {
+		var err error
+		uid, err = strconv.ParseInt(uidStr, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusOK, Result{
+				Code: 4,
+				Msg:  "参数错误",
+			})
+			return
+		}
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	utime, maxId, err := decodeCursor(ctx.Query("cursor"))
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  err.Error(),
+		})
+		return
+	}
+	rels, err := find(ctx, uid, utime, maxId, limit)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询关注关系失败",
+			logger.Error(err),
+			logger.Int64("uid", uid))
+		return
+	}
+	res := FollowListVo{
+		Users: slice.Map[domain.FollowRelation, FollowUserVo](rels, func(idx int, src domain.FollowRelation) FollowUserVo {
+			u := user(src)
+			return FollowUserVo{
+				Uid:        u.Id,
+				Nickname:   u.Name,
+				FollowTime: src.Utime.Format(time.DateTime),
+			}
+		}),
+	}
+	if len(rels) == limit {
+		last := rels[len(rels)-1]
+		res.Cursor = encodeCursor(last.Utime.UnixMilli(), last.Id)
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
@@ -0,0 +1,26 @@
+package web
+
+type FollowUserVo struct {
+	Uid      int64  `json:"uid"`
+	Nickname string `json:"nickname,omitempty"`
+	// 关注的时间
+	FollowTime string `json:"followTime"`
+}
+
+// FollowListVo 游标分页的结果，Cursor 为空代表没有下一页了
+type FollowListVo struct {
+	Users  []FollowUserVo `json:"users"`
+	Cursor string         `json:"cursor,omitempty"`
+}
+
+// AuthorProfileVo 别人看到的作者主页信息，不包含邮箱之类的隐私
+type AuthorProfileVo struct {
+	Uid         int64  `json:"uid"`
+	Nickname    string `json:"nickname"`
+	Avatar      string `json:"avatar"`
+	AboutMe     string `json:"aboutMe"`
+	FollowerCnt int64  `json:"followerCnt"`
+	FolloweeCnt int64  `json:"followeeCnt"`
+	// Followed 当前用户是否关注了这个作者
+	Followed bool `json:"followed"`
+}
@@ -0,0 +1,15 @@
+package web
+
+type HistoryRecordVo struct {
+	Biz   string `json:"biz"`
+	BizId int64  `json:"bizId"`
+	// Title 内容已经删除或者撤回了就是空的
+	Title    string `json:"title"`
+	ReadTime string `json:"readTime"`
+}
+
+// HistoryListVo 游标分页的结果，Cursor 为空代表没有下一页了
+type HistoryListVo struct {
+	Records []HistoryRecordVo `json:"records"`
+	Cursor  string            `json:"cursor,omitempty"`
+}
@@ -0,0 +1,28 @@
+package web
+
+type LikerVo struct {
+	Uid      int64  `json:"uid"`
+	Nickname string `json:"nickname"`
+	Avatar   string `json:"avatar,omitempty"`
+	// 点赞的时间
+	LikeTime string `json:"likeTime"`
+}
+
+// LikerListVo 游标分页的结果，Cursor 为空代表没有下一页了
+type LikerListVo struct {
+	Likers []LikerVo `json:"likers"`
+	Cursor string    `json:"cursor,omitempty"`
+}
+
+type LikedItemVo struct {
+	Biz   string `json:"biz"`
+	BizId int64  `json:"bizId"`
+	// 点赞的时间
+	LikeTime string `json:"likeTime"`
+}
+
+// LikedListVo 游标分页的结果，Cursor 为空代表没有下一页了
+type LikedListVo struct {
+	Items  []LikedItemVo `json:"items"`
+	Cursor string        `json:"cursor,omitempty"`
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	ijwt "basic-go/webook/internal/web/jwt"
@@ -31,7 +32,14 @@ func (m *LoginJWTMiddlewareBuilder) CheckLogin() gin.HandlerFunc {
 			path == "/users/login_sms/code/send" ||
 			path == "/users/login_sms" ||
 			path == "/oauth2/wechat/authurl" ||
-			path == "/oauth2/wechat/callback" {
+			path == "/oauth2/wechat/callback" ||
+			path == "/tags/articles" ||
+			path == "/tags/cloud" ||
+			path == "/articles/search" ||
+			path == "/articles/hot" ||
+			path == "/articles/newest" ||
+			strings.HasPrefix(path, "/articles/author/") ||
+			strings.HasPrefix(path, "/files/") {
 			// 不需要登录校验
 			return
 		}
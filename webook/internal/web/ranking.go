@@ -0,0 +1,57 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/service"
+	"basic-go/webook/pkg/logger"
+
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+)
+
+type RankingHandler struct {
+	svc service.RankingService
+	l   logger.LoggerV1
+}
+
+func NewRankingHandler(svc service.RankingService, l logger.LoggerV1) *RankingHandler {
+	return &RankingHandler{
+		svc: svc,
+		l:   l,
+	}
+}
+
+func (h *RankingHandler) RegisterRoutes(server *gin.Engine) {
+	// 热榜不需要登录
+	server.GET("/articles/hot", h.TopN)
+}
+
+func (h *RankingHandler) TopN(ctx *gin.Context) {
+	arts, err := h.svc.GetTopN(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询热榜失败", logger.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: slice.Map[domain.Article, ArticleVo](arts, func(idx int, src domain.Article) ArticleVo {
+			return ArticleVo{
+				Id:         src.Id,
+				Title:      src.Title,
+				Abstract:   src.Content,
+				Category:   src.Category,
+				Tags:       src.Tags,
+				AuthorId:   src.Author.Id,
+				AuthorName: src.Author.Name,
+				Ctime:      src.Ctime.Format(time.DateTime),
+				Utime:      src.Utime.Format(time.DateTime),
+			}
+		}),
+	})
+}
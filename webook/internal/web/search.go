@@ -0,0 +1,83 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/service"
+	"basic-go/webook/pkg/logger"
+
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+)
+
+type SearchHandler struct {
+	svc service.SearchService
+	l   logger.LoggerV1
+}
+
+func NewSearchHandler(svc service.SearchService, l logger.LoggerV1) *SearchHandler {
+	return &SearchHandler{
+		svc: svc,
+		l:   l,
+	}
+}
+
+func (h *SearchHandler) RegisterRoutes(server *gin.Engine) {
+	// 搜索不需要登录
+	server.GET("/articles/search", h.SearchArticle)
+}
+
+// SearchArticle /articles/search?q=?&offset=?&limit=?
+func (h *SearchHandler) SearchArticle(ctx *gin.Context) {
+	q := ctx.Query("q")
+	offset, _ := strconv.Atoi(ctx.Query("offset"))
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if offset < 0 || err != nil || limit <= 0 || limit > 100 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	hits, total, err := h.svc.SearchArticle(ctx, q, offset, limit)
+	switch {
+	case err == nil:
+	case errors.Is(err, service.ErrInvalidSearchQuery):
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  err.Error(),
+		})
+		return
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("搜索文章失败",
+			logger.Error(err),
+			logger.String("q", q),
+			logger.Int("offset", offset),
+			logger.Int("limit", limit))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: SearchResultVo{
+			Total: total,
+			Articles: slice.Map[domain.ArticleSearchHit, ArticleSearchVo](hits,
+				func(idx int, src domain.ArticleSearchHit) ArticleSearchVo {
+					return ArticleSearchVo{
+						Id:       src.Id,
+						Title:    src.Title,
+						Snippet:  src.Snippet,
+						AuthorId: src.Author.Id,
+						Utime:    src.Utime.Format(time.DateTime),
+						Score:    src.Score,
+					}
+				}),
+		},
+	})
+}
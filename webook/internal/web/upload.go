@@ -0,0 +1,160 @@
+package web
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/service"
+	"basic-go/webook/internal/web/jwt"
+	"basic-go/webook/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead 请求体除了文件本身之外，还有 multipart 的边界和头部
+const multipartOverhead = 1 << 20
+
+type UploadHandler struct {
+	svc     service.UploadService
+	l       logger.LoggerV1
+	maxSize int64
+}
+
+func NewUploadHandler(svc service.UploadService, cfg service.UploadConfig, l logger.LoggerV1) *UploadHandler {
+	return &UploadHandler{
+		svc:     svc,
+		l:       l,
+		maxSize: cfg.MaxSize,
+	}
+}
+
+func (h *UploadHandler) RegisterRoutes(server *gin.Engine) {
+	// 编辑器上传图片和附件，表单字段是 file
+	server.POST("/articles/upload", h.Upload)
+	server.GET("/articles/upload/usage", h.Usage)
+	// 上传之后返回的 URL，不需要登录
+	server.GET("/files/:name", h.File)
+}
+
+func (h *UploadHandler) Upload(ctx *gin.Context) {
+	// 不限制的话，超大的请求体会一直读下去
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, h.maxSize+multipartOverhead)
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	fh, err := ctx.FormFile("file")
+	if err != nil {
+		msg := "没有上传文件"
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			msg = service.ErrFileTooLarge.Error()
+		}
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  msg,
+		})
+		return
+	}
+	if fh.Size > h.maxSize {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  service.ErrFileTooLarge.Error(),
+		})
+		return
+	}
+	data, err := h.readFile(fh)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("读取上传的文件失败",
+			logger.Error(err),
+			logger.Int64("uid", uc.Uid))
+		return
+	}
+	up, err := h.svc.Upload(ctx, uc.Uid, fh.Filename, data)
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, Result{
+			Data: h.toVo(up),
+		})
+	case errors.Is(err, service.ErrEmptyFile),
+		errors.Is(err, service.ErrFileTooLarge),
+		errors.Is(err, service.ErrUnsupportedFileType),
+		errors.Is(err, service.ErrUploadQuotaExceeded):
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("上传文件失败",
+			logger.Error(err),
+			logger.Int64("uid", uc.Uid))
+	}
+}
+
+func (h *UploadHandler) readFile(fh *multipart.FileHeader) ([]byte, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+func (h *UploadHandler) Usage(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	usage, err := h.svc.Usage(ctx, uc.Uid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询上传空间失败",
+			logger.Error(err),
+			logger.Int64("uid", uc.Uid))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: UploadUsageVo{
+			Used:  usage.Used,
+			Quota: usage.Quota,
+		},
+	})
+}
+
+func (h *UploadHandler) File(ctx *gin.Context) {
+	name := ctx.Param("name")
+	data, contentType, err := h.svc.File(ctx, name)
+	switch {
+	case err == nil:
+		// 文件名就是内容的哈希，内容永远不会变
+		ctx.Header("Cache-Control", "public, max-age=31536000, immutable")
+		// 不让浏览器猜测内容类型，免得伪装成图片的 HTML 被当成页面执行
+		ctx.Header("X-Content-Type-Options", "nosniff")
+		ctx.Data(http.StatusOK, contentType, data)
+	case errors.Is(err, service.ErrUploadNotFound):
+		ctx.Status(http.StatusNotFound)
+	default:
+		ctx.Status(http.StatusInternalServerError)
+		h.l.Error("读取上传的文件失败",
+			logger.Error(err),
+			logger.String("name", name))
+	}
+}
+
+func (h *UploadHandler) toVo(up domain.Upload) UploadVo {
+	return UploadVo{
+		Name:         up.Name,
+		ContentType:  up.ContentType,
+		Size:         up.Size,
+		URL:          up.URL,
+		ThumbnailURL: up.ThumbnailURL,
+	}
+}
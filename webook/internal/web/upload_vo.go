@@ -0,0 +1,15 @@
+package web
+
+type UploadVo struct {
+	Name        string `json:"name"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	URL         string `json:"url"`
+	// ThumbnailURL 只有图片才有
+	ThumbnailURL string `json:"thumbnailUrl,omitempty"`
+}
+
+type UploadUsageVo struct {
+	Used  int64 `json:"used"`
+	Quota int64 `json:"quota"`
+}
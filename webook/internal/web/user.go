@@ -3,6 +3,9 @@ package web
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"basic-go/webook/internal/domain"
@@ -15,6 +18,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 )
 
 var _ Handler = &UserHandler{}
@@ -26,6 +30,10 @@ type UserHandler struct {
 	svc            service.UserService
 	codeSvc        service.CodeService
 	codeLimiterSvc ratelimit.RateLimitSMSService
+	followSvc      service.FollowService
+	historySvc     service.HistoryService
+	// avatarBaseURL 头像只能用上传接口传上来的图片，也就是这个前缀下面的 URL
+	avatarBaseURL string
 }
 
 const (
@@ -33,12 +41,16 @@ const (
 	// 和上面比起来，用 ` 看起来就比较清爽
 	passwordRegexPattern = `^(?=.*[A-Za-z])(?=.*\d)(?=.*[$@$!%*#?&])[A-Za-z\d$@$!%*#?&]{8,}$`
 	bizLogin             = "login"
+	// maxAvatarLen 和 dao.User.Avatar 的列宽保持一致
+	maxAvatarLen = 1024
 )
 
 // func NewUserHandler(svc *service.UserService) *UserHandler {
 func NewUserHandler(svc service.UserService, codeSvc service.CodeService, codeLimiterSvc *ratelimit.RateLimitSMSService,
-
+	followSvc service.FollowService,
+	historySvc service.HistoryService,
 	hdl ijwt.Handler,
+	uploadCfg service.UploadConfig,
 ) *UserHandler {
 	return &UserHandler{
 		emailRexExp:    regexp.MustCompile(emailRegexPattern, regexp.None),
@@ -46,7 +58,10 @@ func NewUserHandler(svc service.UserService, codeSvc service.CodeService, codeLi
 		svc:            svc,
 		codeSvc:        codeSvc,
 		codeLimiterSvc: *codeLimiterSvc,
+		followSvc:      followSvc,
+		historySvc:     historySvc,
 		Handler:        hdl,
+		avatarBaseURL:  uploadCfg.BaseURL,
 	}
 }
 
@@ -70,7 +85,14 @@ func (h *UserHandler) RegisterRoutes(server *gin.Engine) {
 	ug.POST("/logout", h.LogoutJWT)
 
 	ug.POST("/edit", h.Edit)
+	ug.POST("/avatar", h.Avatar)
 	ug.GET("/profile", h.Profile)
+	// 别人的主页
+	ug.GET("/author/:id", h.AuthorProfile)
+	// 阅读历史 /history?cursor=?&limit=?
+	ug.GET("/history", h.History)
+	ug.POST("/history/delete", h.DeleteHistory)
+	ug.POST("/history/clear", h.ClearHistory)
 
 	ug.POST("/login_sms/code/send", h.SendSMSLoginCode)
 	ug.POST("/login_sms", h.LoginSMS)
@@ -358,6 +380,58 @@ func (h *UserHandler) Edit(ctx *gin.Context) {
 	}
 	ctx.String(http.StatusOK, "更新成功")
 }
+
+// Avatar 修改头像。头像单独一个接口，这样老的客户端调 Edit 不会把头像清掉
+func (h *UserHandler) Avatar(ctx *gin.Context) {
+	type Req struct {
+		// Avatar 头像的 URL，先通过上传接口上传图片。传空字符串就是清空头像
+		Avatar string `json:"avatar"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	if req.Avatar != "" && !h.validAvatar(req.Avatar) {
+		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "头像地址不对"})
+		return
+	}
+	err := h.svc.UpdateAvatar(ctx, uc.Uid, req.Avatar)
+	if err != nil {
+		zap.L().Error("更新头像失败",
+			zap.Int64("uid", uc.Uid),
+			zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{Msg: "OK"})
+}
+
+// validAvatar 头像必须是上传服务给出来的地址：在 BaseURL 下面，
+// 要么是 http(s) 的绝对地址，要么是站内的路径（BaseURL 默认就是 /files/）
+func (h *UserHandler) validAvatar(avatar string) bool {
+	if len(avatar) > maxAvatarLen || !strings.HasPrefix(avatar, h.avatarBaseURL) {
+		return false
+	}
+	u, err := url.Parse(avatar)
+	if err != nil || strings.Contains(u.Path, "..") {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return u.Host != ""
+	case "":
+		// 站内路径，不能是 //evil.com/x 这种
+		return u.Host == "" && strings.HasPrefix(u.Path, "/")
+	default:
+		return false
+	}
+}
+
 func (h *UserHandler) Profile(ctx *gin.Context) {
 	//us := ctx.MustGet("user").(UserClaims)
 	//ctx.String(http.StatusOK, "这是 profile")
@@ -380,9 +454,11 @@ func (h *UserHandler) Profile(ctx *gin.Context) {
 		Email    string `json:"email"`
 		AboutMe  string `json:"aboutMe"`
 		Birthday string `json:"birthday"`
+		Avatar   string `json:"avatar"`
 	}
 	ctx.JSON(http.StatusOK, User{
 		Nickname: u.Nickname,
+		Avatar:   u.Avatar,
 		Email:    u.Email,
 		AboutMe:  u.AboutMe,
 		Birthday: u.Birthday.Format(time.DateOnly),
@@ -396,3 +472,67 @@ func (h *UserHandler) Profile(ctx *gin.Context) {
 // 	Uid       int64
 // 	UserAgent string
 // }
+
+// AuthorProfile 查看别人的主页，带上粉丝数、关注数以及当前用户有没有关注他
+func (h *UserHandler) AuthorProfile(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "id 参数错误",
+		})
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	var (
+		eg       errgroup.Group
+		u        domain.User
+		stat     domain.FollowStatistics
+		followed bool
+	)
+	eg.Go(func() error {
+		var er error
+		u, er = h.svc.FindById(ctx, id)
+		return er
+	})
+	eg.Go(func() error {
+		var er error
+		stat, er = h.followSvc.GetStatistics(ctx, id)
+		return er
+	})
+	eg.Go(func() error {
+		var er error
+		followed, er = h.followSvc.Followed(ctx, uc.Uid, id)
+		return er
+	})
+	err = eg.Wait()
+	if err == service.ErrUserNotFound {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "用户不存在",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		zap.L().Error("查询作者主页失败",
+			zap.Int64("uid", uc.Uid),
+			zap.Int64("author", id),
+			zap.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: AuthorProfileVo{
+			Uid:         u.Id,
+			Nickname:    u.Nickname,
+			Avatar:      u.Avatar,
+			AboutMe:     u.AboutMe,
+			FollowerCnt: stat.Followers,
+			FolloweeCnt: stat.Followees,
+			Followed:    followed,
+		},
+	})
+}
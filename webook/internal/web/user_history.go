@@ -0,0 +1,111 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	ijwt "basic-go/webook/internal/web/jwt"
+
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (h *UserHandler) History(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	readTime, maxId, err := decodeCursor(ctx.Query("cursor"))
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  err.Error(),
+		})
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	records, err := h.historySvc.List(ctx, uc.Uid, time.UnixMilli(readTime), maxId, limit)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		zap.L().Error("查询阅读历史失败",
+			zap.Int64("uid", uc.Uid),
+			zap.Error(err))
+		return
+	}
+	res := HistoryListVo{
+		Records: slice.Map[domain.HistoryRecord, HistoryRecordVo](records, func(idx int, src domain.HistoryRecord) HistoryRecordVo {
+			return HistoryRecordVo{
+				Biz:      src.Biz,
+				BizId:    src.BizId,
+				Title:    src.Title,
+				ReadTime: src.LastReadTime.Format(time.DateTime),
+			}
+		}),
+	}
+	if len(records) == limit {
+		last := records[len(records)-1]
+		res.Cursor = encodeCursor(last.LastReadTime.UnixMilli(), last.Id)
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
+
+func (h *UserHandler) DeleteHistory(ctx *gin.Context) {
+	type Req struct {
+		Biz   string `json:"biz"`
+		BizId int64  `json:"bizId"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	if req.Biz == "" {
+		req.Biz = "article"
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	err := h.historySvc.Delete(ctx, uc.Uid, req.Biz, req.BizId)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		zap.L().Error("删除阅读历史失败",
+			zap.Int64("uid", uc.Uid),
+			zap.String("biz", req.Biz),
+			zap.Int64("bizId", req.BizId),
+			zap.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "OK",
+	})
+}
+
+func (h *UserHandler) ClearHistory(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	err := h.historySvc.Clear(ctx, uc.Uid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		zap.L().Error("清空阅读历史失败",
+			zap.Int64("uid", uc.Uid),
+			zap.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "OK",
+	})
+}
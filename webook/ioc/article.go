@@ -0,0 +1,54 @@
+package ioc
+
+import (
+	"fmt"
+
+	"basic-go/webook/internal/repository/dao"
+	"basic-go/webook/pkg/objectstore"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// ArticleStorage 文章的制作库、线上库和历史版本放在哪里。
+// 标签和文章的关系总是在 MySQL 里面，但是按照标签列出文章要查线上库，所以 TagDAO 也跟着存储变
+type ArticleStorage struct {
+	ArticleDAO  dao.ArticleDAO
+	RevisionDAO dao.ArticleRevisionDAO
+	TagDAO      dao.ArticleTagDAO
+}
+
+// InitArticleStorage 根据 article.storage 选择存储，可以是 mysql、mongodb 或者 oss，默认是 mysql。
+// oss 的时候线上库的内容放在对象存储里面，key 的前缀是 article.ossPrefix
+func InitArticleStorage(db *gorm.DB, store objectstore.ObjectStore) ArticleStorage {
+	storage := viper.GetString("article.storage")
+	switch storage {
+	case "", "mysql":
+		return ArticleStorage{
+			ArticleDAO:  dao.NewArticleGORMDAO(db),
+			RevisionDAO: dao.NewArticleRevisionGORMDAO(db),
+			TagDAO:      dao.NewArticleTagGORMDAO(db),
+		}
+	case "mongodb":
+		mdb := InitMongoDB()
+		node := InitSnowflakeNode()
+		return ArticleStorage{
+			ArticleDAO:  dao.NewMongoDBArticleDAO(mdb, node),
+			RevisionDAO: dao.NewMongoDBArticleRevisionDAO(mdb, node),
+			TagDAO:      dao.NewArticleTagMongoDBDAO(db, mdb),
+		}
+	case "oss":
+		prefix := "articles/"
+		if viper.IsSet("article.ossPrefix") {
+			prefix = viper.GetString("article.ossPrefix")
+		}
+		return ArticleStorage{
+			ArticleDAO: dao.NewArticleS3DAO(db, store, prefix),
+			// 历史版本只有作者自己看，还是放在 MySQL
+			RevisionDAO: dao.NewArticleRevisionGORMDAO(db),
+			TagDAO:      dao.NewArticleTagS3DAO(db),
+		}
+	default:
+		panic(fmt.Sprintf("未知的文章存储 %s，只支持 mysql、mongodb 和 oss", storage))
+	}
+}
@@ -0,0 +1,27 @@
+package ioc
+
+import (
+	"basic-go/webook/internal/repository"
+	"basic-go/webook/internal/service"
+	"basic-go/webook/pkg/logger"
+
+	"github.com/spf13/viper"
+)
+
+func InitFeedService(repo repository.FeedRepository,
+	followRepo repository.FollowRepository,
+	artRepo repository.ArticleRepository,
+	l logger.LoggerV1) service.FeedService {
+	type Config struct {
+		// PullThreshold 粉丝数超过这个值的作者，文章不推送给粉丝，读的时候再拉
+		PullThreshold int64 `yaml:"pullThreshold"`
+	}
+	cfg := Config{
+		PullThreshold: 1000,
+	}
+	err := viper.UnmarshalKey("feed", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return service.NewFeedService(repo, followRepo, artRepo, cfg.PullThreshold, l)
+}
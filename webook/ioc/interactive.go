@@ -0,0 +1,60 @@
+package ioc
+
+import (
+	"time"
+
+	"basic-go/webook/internal/repository"
+	"basic-go/webook/internal/repository/cache"
+	"basic-go/webook/internal/repository/dao"
+	"basic-go/webook/internal/service"
+	"basic-go/webook/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+)
+
+func InitReadCache(client redis.Cmdable) cache.ReadCache {
+	type Config struct {
+		// ReadDedupWindow 同一个读者在这段时间里面重复打开同一篇文章只算一次阅读
+		ReadDedupWindow time.Duration `yaml:"readDedupWindow"`
+	}
+	cfg := Config{
+		ReadDedupWindow: time.Minute * 30,
+	}
+	err := viper.UnmarshalKey("interactive", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return cache.NewReadRedisCache(client, cfg.ReadDedupWindow)
+}
+
+func InitInteractiveRepository(d dao.InteractiveDAO, l logger.LoggerV1,
+	c cache.InteractiveCache, readCache cache.ReadCache,
+	deltaCache cache.InteractiveDeltaCache,
+	stateCache cache.InteractiveStateCache) repository.InteractiveRepository {
+	cfg := loadWriteBehindConfig()
+	return repository.NewCachedInteractiveRepository(d, l, c, readCache, deltaCache, stateCache, cfg.Bizs)
+}
+
+func InitInteractiveFlusher(repo repository.InteractiveRepository, l logger.LoggerV1) *service.InteractiveFlusher {
+	cfg := loadWriteBehindConfig()
+	return service.NewInteractiveFlusher(repo, cfg.Bizs, cfg.FlushInterval, l)
+}
+
+type writeBehindConfig struct {
+	// Bizs 这些 biz 的点赞数和阅读数用写回模式，不在里面的每次都直接写数据库
+	Bizs []string `yaml:"bizs"`
+	// FlushInterval 多久把增量写入数据库一次
+	FlushInterval time.Duration `yaml:"flushInterval"`
+}
+
+func loadWriteBehindConfig() writeBehindConfig {
+	cfg := writeBehindConfig{
+		FlushInterval: time.Millisecond * 500,
+	}
+	err := viper.UnmarshalKey("interactive.writeBehind", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return cfg
+}
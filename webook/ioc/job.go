@@ -0,0 +1,146 @@
+package ioc
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"basic-go/webook/internal/domain"
+	"basic-go/webook/internal/job"
+	"basic-go/webook/internal/repository"
+	"basic-go/webook/internal/service"
+	"basic-go/webook/pkg/logger"
+	"basic-go/webook/pkg/rlock"
+
+	"github.com/spf13/viper"
+)
+
+func InitRankingJob(svc service.RankingService, lock *rlock.Client, l logger.LoggerV1) *job.RankingJob {
+	type Config struct {
+		// Interval 多久重新计算一次热榜
+		Interval time.Duration `yaml:"interval"`
+		// Timeout 一次计算最多允许的时间
+		Timeout time.Duration `yaml:"timeout"`
+	}
+	cfg := Config{
+		Interval: time.Minute,
+		Timeout:  time.Second * 30,
+	}
+	err := viper.UnmarshalKey("ranking", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return job.NewRankingJob(svc, lock, l, cfg.Interval, cfg.Timeout)
+}
+
+func InitCronJobService(repo repository.CronJobRepository, l logger.LoggerV1) service.CronJobService {
+	type Config struct {
+		// ReclaimAfter 心跳超过这么久没有更新，任务就可以被别的节点抢走
+		ReclaimAfter time.Duration `yaml:"reclaimAfter"`
+	}
+	cfg := Config{
+		ReclaimAfter: time.Minute,
+	}
+	err := viper.UnmarshalKey("job", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return service.NewCronJobService(repo, l, cfg.ReclaimAfter)
+}
+
+func InitScheduler(svc service.CronJobService, local *job.LocalFuncExecutor, l logger.LoggerV1) *job.Scheduler {
+	type Config struct {
+		// MaxConcurrency 一个节点同时执行的任务数量
+		MaxConcurrency int64 `yaml:"maxConcurrency"`
+		// HeartbeatInterval 要明显小于 ReclaimAfter
+		HeartbeatInterval time.Duration `yaml:"heartbeatInterval"`
+		// HttpTimeout HTTP 回调的超时时间
+		HttpTimeout time.Duration `yaml:"httpTimeout"`
+	}
+	cfg := Config{
+		MaxConcurrency:    10,
+		HeartbeatInterval: time.Second * 10,
+		HttpTimeout:       time.Second * 30,
+	}
+	err := viper.UnmarshalKey("job", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	s := job.NewScheduler(svc, l, cfg.MaxConcurrency, cfg.HeartbeatInterval)
+	s.RegisterExecutor(local)
+	s.RegisterExecutor(job.NewHttpExecutor(&http.Client{Timeout: cfg.HttpTimeout}))
+	return s
+}
+
+// InitLocalFuncExecutor 本地执行的定时任务都在这里注册
+func InitLocalFuncExecutor(svc service.CronJobService,
+	uploadSvc service.UploadService,
+	purger *service.ArticlePurger,
+	reconciler *service.InteractiveReconciler,
+	l logger.LoggerV1) *job.LocalFuncExecutor {
+	type UploadConfig struct {
+		// CleanupCron 多久清理一次没有引用的上传文件
+		CleanupCron string        `yaml:"cleanupCron"`
+		OrphanAfter time.Duration `yaml:"orphanAfter"`
+	}
+	uploadCfg := UploadConfig{
+		CleanupCron: "0 4 * * *",
+		OrphanAfter: time.Hour * 24,
+	}
+	err := viper.UnmarshalKey("upload", &uploadCfg)
+	if err != nil {
+		panic(err)
+	}
+	type ArticleConfig struct {
+		// PurgeCron 多久清理一次回收站
+		PurgeCron string `yaml:"purgeCron"`
+		// TrashRetention 文章在回收站里面保留多久
+		TrashRetention time.Duration `yaml:"trashRetention"`
+	}
+	articleCfg := ArticleConfig{
+		PurgeCron:      "30 3 * * *",
+		TrashRetention: time.Hour * 24 * 30,
+	}
+	err = viper.UnmarshalKey("article", &articleCfg)
+	if err != nil {
+		panic(err)
+	}
+	type InteractiveConfig struct {
+		// ReconcileCron 多久检查一次计数缓存和数据库是不是一致
+		ReconcileCron string `yaml:"reconcileCron"`
+		// ReconcileMaxKeys 一次最多检查多少个缓存，剩下的下次继续
+		ReconcileMaxKeys int `yaml:"reconcileMaxKeys"`
+	}
+	intrCfg := InteractiveConfig{
+		ReconcileCron:    "*/10 * * * *",
+		ReconcileMaxKeys: 10000,
+	}
+	err = viper.UnmarshalKey("interactive", &intrCfg)
+	if err != nil {
+		panic(err)
+	}
+
+	local := job.NewLocalFuncExecutor()
+	local.RegisterFunc(job.UploadCleanupJobName,
+		job.NewUploadCleanupJob(uploadSvc, l, uploadCfg.OrphanAfter).Run)
+	local.RegisterFunc(job.ArticlePurgeJobName,
+		job.NewArticlePurgeJob(purger, l, articleCfg.TrashRetention).Run)
+	local.RegisterFunc(job.InteractiveReconcileJobName,
+		job.NewInteractiveReconcileJob(reconciler, l, intrCfg.ReconcileMaxKeys).Run)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	jobs := []domain.Job{
+		{Name: job.UploadCleanupJobName, Expression: uploadCfg.CleanupCron},
+		{Name: job.ArticlePurgeJobName, Expression: articleCfg.PurgeCron},
+		{Name: job.InteractiveReconcileJobName, Expression: intrCfg.ReconcileCron},
+	}
+	for _, j := range jobs {
+		j.Executor = local.Name()
+		err = svc.AddJob(ctx, j)
+		if err != nil {
+			panic(err)
+		}
+	}
+	return local
+}
@@ -3,12 +3,31 @@ package ioc
 import (
 	"basic-go/webook/internal/events"
 	"basic-go/webook/internal/events/article"
+	"basic-go/webook/internal/events/feed"
+	"basic-go/webook/internal/repository"
+	"basic-go/webook/pkg/logger"
 
 	"github.com/IBM/sarama"
 	"github.com/spf13/viper"
 )
 
 func InitSaramaClient() sarama.Client {
+	scfg := sarama.NewConfig()
+	scfg.Producer.Return.Successes = true
+	return newSaramaClient(scfg)
+}
+
+// InitSearchIndexConsumer 搜索索引的消费者用单独的 client。
+// 新的消费者组要从头开始消费，新实例的搜索索引要靠重放发表事件来建立，
+// 别的消费者组还是从最新的消息开始，所以不能设置在共享的 client 上
+func InitSearchIndexConsumer(repo repository.SearchRepository,
+	l logger.LoggerV1) *article.SearchIndexConsumer {
+	scfg := sarama.NewConfig()
+	scfg.Consumer.Offsets.Initial = sarama.OffsetOldest
+	return article.NewSearchIndexConsumer(repo, newSaramaClient(scfg), l)
+}
+
+func newSaramaClient(scfg *sarama.Config) sarama.Client {
 	type Config struct {
 		Addr []string `yaml:"addr"`
 	}
@@ -17,8 +36,6 @@ func InitSaramaClient() sarama.Client {
 	if err != nil {
 		panic(err)
 	}
-	scfg := sarama.NewConfig()
-	scfg.Producer.Return.Successes = true
 	client, err := sarama.NewClient(cfg.Addr, scfg)
 	if err != nil {
 		panic(err)
@@ -34,6 +51,9 @@ func InitSyncProducer(c sarama.Client) sarama.SyncProducer {
 	return p
 }
 
-func InitConsumers(c1 *article.InteractiveReadEventConsumer) []events.Consumer {
-	return []events.Consumer{c1}
+func InitConsumers(c1 *article.InteractiveReadEventConsumer,
+	c2 *article.SearchIndexConsumer,
+	c3 *feed.ArticlePublishConsumer,
+	c4 *article.HistoryRecordConsumer) []events.Consumer {
+	return []events.Consumer{c1, c2, c3, c4}
 }
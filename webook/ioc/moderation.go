@@ -0,0 +1,64 @@
+package ioc
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"basic-go/webook/internal/service"
+	"basic-go/webook/internal/service/moderation"
+	"basic-go/webook/internal/web"
+	"basic-go/webook/pkg/logger"
+	"basic-go/webook/pkg/sensitive"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+)
+
+func InitModerator(client redis.Cmdable, l logger.LoggerV1) *moderation.Moderator {
+	type Config struct {
+		// WordsFile 敏感词库，修改之后 ReloadInterval 之内生效
+		WordsFile      string        `yaml:"wordsFile"`
+		ReloadInterval time.Duration `yaml:"reloadInterval"`
+		// 一篇文章至少有 MinLinks 个链接，并且每千字超过 MaxLinksPerThousand 个链接才算违规
+		MinLinks            int     `yaml:"minLinks"`
+		MaxLinksPerThousand float64 `yaml:"maxLinksPerThousand"`
+		// 少于 DuplicateMinRunes 个字的文章不做重复检测
+		DuplicateMinRunes   int           `yaml:"duplicateMinRunes"`
+		DuplicateExpiration time.Duration `yaml:"duplicateExpiration"`
+	}
+	cfg := Config{
+		WordsFile:           "config/sensitive_words.txt",
+		ReloadInterval:      time.Minute,
+		MinLinks:            5,
+		MaxLinksPerThousand: 10,
+		DuplicateMinRunes:   100,
+		DuplicateExpiration: time.Hour * 24 * 30,
+	}
+	err := viper.UnmarshalKey("moderation", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	words, err := moderation.LoadWords(cfg.WordsFile)
+	// 词库文件不存在就先用空的词库，文件创建之后会自动加载
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		panic(err)
+	}
+	dict := sensitive.NewDictionary(words)
+	go moderation.WatchWords(context.Background(), dict, cfg.WordsFile, cfg.ReloadInterval, l)
+	return moderation.NewModerator(l,
+		moderation.NewSensitiveWordRule(dict),
+		moderation.NewLinkDensityRule(cfg.MinLinks, cfg.MaxLinksPerThousand),
+		moderation.NewDuplicateContentRule(client, cfg.DuplicateMinRunes, cfg.DuplicateExpiration),
+	)
+}
+
+func InitArticleReviewHandler(svc service.ArticleReviewService, l logger.LoggerV1) *web.ArticleReviewHandler {
+	var reviewers []int64
+	err := viper.UnmarshalKey("moderation.reviewers", &reviewers)
+	if err != nil {
+		panic(err)
+	}
+	return web.NewArticleReviewHandler(svc, reviewers, l)
+}
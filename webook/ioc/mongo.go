@@ -0,0 +1,62 @@
+package ioc
+
+import (
+	"context"
+	"time"
+
+	"basic-go/webook/internal/repository/dao"
+
+	"github.com/bwmarrin/snowflake"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func InitMongoDB() *mongo.Database {
+	type Config struct {
+		URI      string `yaml:"uri"`
+		Database string `yaml:"database"`
+	}
+	cfg := Config{
+		URI:      "mongodb://localhost:27017",
+		Database: "webook",
+	}
+	err := viper.UnmarshalKey("mongo", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.URI))
+	if err != nil {
+		panic(err)
+	}
+	// Connect 不会真的去连，这里 Ping 一下，配置不对的话启动就失败
+	err = client.Ping(ctx, nil)
+	if err != nil {
+		panic(err)
+	}
+	mdb := client.Database(cfg.Database)
+	err = dao.InitCollections(ctx, mdb)
+	if err != nil {
+		panic(err)
+	}
+	return mdb
+}
+
+// InitSnowflakeNode 每个实例的 node 必须不一样，否则生成的 ID 会重复
+func InitSnowflakeNode() *snowflake.Node {
+	type Config struct {
+		Node int64 `yaml:"node"`
+	}
+	var cfg Config
+	err := viper.UnmarshalKey("snowflake", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	node, err := snowflake.NewNode(cfg.Node)
+	if err != nil {
+		panic(err)
+	}
+	return node
+}
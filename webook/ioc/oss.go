@@ -0,0 +1,64 @@
+package ioc
+
+import (
+	"fmt"
+
+	"basic-go/webook/pkg/objectstore"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/spf13/viper"
+)
+
+// InitObjectStore 根据 oss.type 选择对象存储，可以是 local 或者 s3，默认是 local
+func InitObjectStore() objectstore.ObjectStore {
+	type Config struct {
+		Type string `yaml:"type"`
+		// Dir local 的时候文件放在哪个目录
+		Dir string `yaml:"dir"`
+
+		Endpoint  string `yaml:"endpoint"`
+		Region    string `yaml:"region"`
+		Bucket    string `yaml:"bucket"`
+		AccessKey string `yaml:"accessKey"`
+		SecretKey string `yaml:"secretKey"`
+	}
+	cfg := Config{
+		Type: "local",
+		Dir:  "./data/oss",
+	}
+	err := viper.UnmarshalKey("oss", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	switch cfg.Type {
+	case "local":
+		store, err := objectstore.NewLocalStore(cfg.Dir)
+		if err != nil {
+			panic(err)
+		}
+		return store
+	case "s3":
+		awsCfg := &aws.Config{
+			Region: aws.String(cfg.Region),
+			// 腾讯云 COS 之类的只支持这种风格
+			S3ForcePathStyle: aws.Bool(true),
+		}
+		if cfg.Endpoint != "" {
+			awsCfg.Endpoint = aws.String(cfg.Endpoint)
+		}
+		// 没有配置的话就用环境变量之类的默认方式
+		if cfg.AccessKey != "" {
+			awsCfg.Credentials = credentials.NewStaticCredentials(cfg.AccessKey, cfg.SecretKey, "")
+		}
+		sess, err := session.NewSession(awsCfg)
+		if err != nil {
+			panic(err)
+		}
+		return objectstore.NewS3Store(s3.New(sess), cfg.Bucket)
+	default:
+		panic(fmt.Sprintf("未知的对象存储 %s，只支持 local 和 s3", cfg.Type))
+	}
+}
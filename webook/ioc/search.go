@@ -0,0 +1,26 @@
+package ioc
+
+import (
+	"basic-go/webook/pkg/fulltext"
+
+	"github.com/spf13/viper"
+)
+
+func InitSearchIndex() *fulltext.Index {
+	type Config struct {
+		// Dir 索引文件放在哪个目录，每个实例要用自己的目录
+		Dir string `yaml:"dir"`
+	}
+	cfg := Config{
+		Dir: "./data/search",
+	}
+	err := viper.UnmarshalKey("search", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	idx, err := fulltext.Open(cfg.Dir, fulltext.NewBigramTokenizer())
+	if err != nil {
+		panic(err)
+	}
+	return idx
+}
@@ -0,0 +1,32 @@
+package ioc
+
+import (
+	"basic-go/webook/internal/service"
+
+	"github.com/spf13/viper"
+)
+
+func InitUploadConfig() service.UploadConfig {
+	type Config struct {
+		MaxSize       int64  `yaml:"maxSize"`
+		Quota         int64  `yaml:"quota"`
+		BaseURL       string `yaml:"baseURL"`
+		ThumbnailSize int    `yaml:"thumbnailSize"`
+	}
+	cfg := Config{
+		MaxSize:       10 << 20,
+		Quota:         1 << 30,
+		BaseURL:       "/files/",
+		ThumbnailSize: 320,
+	}
+	err := viper.UnmarshalKey("upload", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return service.UploadConfig{
+		MaxSize:       cfg.MaxSize,
+		Quota:         cfg.Quota,
+		BaseURL:       cfg.BaseURL,
+		ThumbnailSize: cfg.ThumbnailSize,
+	}
+}
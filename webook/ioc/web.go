@@ -32,6 +32,14 @@ import (
 func InitWebServer(mdls []gin.HandlerFunc,
 	userHdl *web.UserHandler,
 	artHdl *web.ArticleHandler,
+	searchHdl *web.SearchHandler,
+	commentHdl *web.CommentHandler,
+	followHdl *web.FollowHandler,
+	feedHdl *web.FeedHandler,
+	rankingHdl *web.RankingHandler,
+	collectionHdl *web.CollectionHandler,
+	uploadHdl *web.UploadHandler,
+	reviewHdl *web.ArticleReviewHandler,
 	wechatHdl *web.OAuth2WechatHandler) *gin.Engine {
 
 	server := gin.Default()
@@ -39,6 +47,14 @@ func InitWebServer(mdls []gin.HandlerFunc,
 	userHdl.RegisterRoutes(server)
 	wechatHdl.RegisterRoutes(server)
 	artHdl.RegisterRoutes(server)
+	searchHdl.RegisterRoutes(server)
+	commentHdl.RegisterRoutes(server)
+	followHdl.RegisterRoutes(server)
+	feedHdl.RegisterRoutes(server)
+	rankingHdl.RegisterRoutes(server)
+	collectionHdl.RegisterRoutes(server)
+	uploadHdl.RegisterRoutes(server)
+	reviewHdl.RegisterRoutes(server)
 	return server
 }
 
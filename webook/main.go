@@ -1,9 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
+	_ "expvar"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/pflag"
@@ -14,6 +21,13 @@ import (
 func main() {
 	initViperV1()
 	initLogger()
+	// 带了子命令的话只执行子命令
+	if pflag.NArg() > 0 {
+		if err := runCommand(pflag.Args()); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
 	// server := InitWebServer()
 	app := InitWebServer()
 	fmt.Println("len(app.consumers) ", len(app.consumers))
@@ -23,6 +37,10 @@ func main() {
 			panic(err)
 		}
 	}
+	app.publisher.Start()
+	app.rankingJob.Start()
+	app.flusher.Start()
+	app.scheduler.Start()
 	server := app.server
 	server.GET("/hello", func(ctx *gin.Context) {
 		ctx.String(http.StatusOK, "hello，启动成功了！")
@@ -30,7 +48,44 @@ func main() {
 	//addr := viper.Get("addr")
 	//server.Run(":8081")
 	//server.Run(addr)
-	server.Run(":8080")
+	srv := &http.Server{Addr: ":8080", Handler: server}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			panic(err)
+		}
+	}()
+	// 监控指标用单独的端口，expvar 的 /debug/vars 注册在 DefaultServeMux 上
+	go func() {
+		if err := http.ListenAndServe(":8081", nil); err != nil {
+			log.Println("启动监控指标服务失败", err)
+		}
+	}()
+
+	// 收到退出信号之后，先停止调度器和后台循环，让正在执行的任务有机会释放，
+	// 关闭 HTTP 服务之后不会再有新的增量，最后把计数的增量写一次，关掉全文索引
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	if err := app.scheduler.Stop(ctx); err != nil {
+		log.Println("停止任务调度器超时", err)
+	}
+	if err := app.publisher.Stop(ctx); err != nil {
+		log.Println("停止定时发表超时", err)
+	}
+	if err := app.rankingJob.Stop(ctx); err != nil {
+		log.Println("停止热榜计算超时", err)
+	}
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Println("关闭 HTTP 服务失败", err)
+	}
+	if err := app.flusher.Stop(ctx); err != nil {
+		log.Println("停止计数增量的写入超时", err)
+	}
+	if err := app.index.Close(); err != nil {
+		log.Println("关闭全文索引失败", err)
+	}
 }
 func initLogger() {
 	logger, err := zap.NewDevelopment()
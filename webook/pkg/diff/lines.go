@@ -0,0 +1,142 @@
+package diff
+
+import "strings"
+
+type Op uint8
+
+const (
+	// OpEqual 两边都有的行
+	OpEqual Op = iota
+	// OpInsert 新版本里面新增的行
+	OpInsert
+	// OpDelete 旧版本里面被删掉的行
+	OpDelete
+)
+
+func (o Op) String() string {
+	switch o {
+	case OpInsert:
+		return "insert"
+	case OpDelete:
+		return "delete"
+	default:
+		return "equal"
+	}
+}
+
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// Lines 按行比较 a 和 b，返回把 a 变成 b 的最短编辑脚本
+// 用的是 Myers 差分算法，复杂度是 O((N+M)D)，D 是差异的行数
+// 文章修改一般都是小改动，所以 D 不会很大
+func Lines(a, b string) []Line {
+	return diff(splitLines(a), splitLines(b))
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func diff(a, b []string) []Line {
+	// 先把公共前缀和后缀剥掉，大多数情况下能省掉绝大部分计算
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	res := make([]Line, 0, len(a)+len(b))
+	for _, l := range a[:prefix] {
+		res = append(res, Line{Op: OpEqual, Text: l})
+	}
+	res = append(res, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, l := range a[len(a)-suffix:] {
+		res = append(res, Line{Op: OpEqual, Text: l})
+	}
+	return res
+}
+
+func myers(a, b []string) []Line {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+2)
+	// trace 记录每一步 d 的 v，回溯的时候要用
+	trace := make([][]int, 0, 8)
+	var found bool
+	for d := 0; d <= max && !found; d++ {
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				// 往下走，也就是插入
+				x = v[offset+k+1]
+			} else {
+				// 往右走，也就是删除
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	return backtrack(trace, a, b, offset)
+}
+
+func backtrack(trace [][]int, a, b []string, offset int) []Line {
+	x, y := len(a), len(b)
+	res := make([]Line, 0, len(a)+len(b))
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			res = append(res, Line{Op: OpEqual, Text: a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				res = append(res, Line{Op: OpInsert, Text: b[prevY]})
+			} else {
+				res = append(res, Line{Op: OpDelete, Text: a[prevX]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	// 回溯出来的是倒序的
+	for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+		res[i], res[j] = res[j], res[i]
+	}
+	return res
+}
@@ -0,0 +1,81 @@
+package fulltext
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Highlight 从 text 里面截取一段包含 terms 的摘要，最多 maxRunes 个字符，
+// 命中的部分用 pre 和 post 包起来。text 的其余部分会做 HTML 转义，
+// 所以返回值可以直接交给前端渲染。terms 要求是小写的，和 Tokenizer 的输出一致。
+func Highlight(text string, terms []string, maxRunes int, pre, post string) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		tr := []rune(term)
+		if len(tr) == 0 {
+			continue
+		}
+		for i := 0; i+len(tr) <= len(lower); i++ {
+			if !hasPrefix(lower[i:], tr) {
+				continue
+			}
+			for j := i; j < i+len(tr); j++ {
+				marked[j] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+
+	start := 0
+	if first > 0 {
+		// 命中的地方前面留一点上下文
+		start = max(0, first-maxRunes/4)
+	}
+	end := min(len(runes), start+maxRunes)
+	// 后面不够长的话，往前多留一点，尽量把 maxRunes 用满
+	start = max(0, min(start, end-maxRunes))
+
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString("...")
+	}
+	inMark := false
+	for i := start; i < end; i++ {
+		if marked[i] && !inMark {
+			sb.WriteString(pre)
+			inMark = true
+		} else if !marked[i] && inMark {
+			sb.WriteString(post)
+			inMark = false
+		}
+		sb.WriteString(html.EscapeString(string(runes[i])))
+	}
+	if inMark {
+		sb.WriteString(post)
+	}
+	if end < len(runes) {
+		sb.WriteString("...")
+	}
+	return sb.String()
+}
+
+func hasPrefix(s, prefix []rune) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if s[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
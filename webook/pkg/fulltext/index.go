@@ -0,0 +1,331 @@
+package fulltext
+
+import (
+	"bufio"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const (
+	// BM25 的两个参数，用的是最常见的取值
+	bm25K1 = 1.2
+	bm25B  = 0.75
+	// titleWeight 标题里的词比正文里的词重要，相当于出现了 titleWeight 次
+	titleWeight = 3
+	// defaultCompactThreshold 日志里累计了这么多条操作之后，就做一次快照
+	defaultCompactThreshold = 1000
+
+	snapshotFile = "snapshot.gob"
+	walFile      = "wal.log"
+
+	opUpsert = "upsert"
+	opDelete = "delete"
+)
+
+type Document struct {
+	Id       int64
+	Title    string
+	Content  string
+	AuthorId int64
+	Utime    int64
+}
+
+type Hit struct {
+	Doc   Document
+	Score float64
+}
+
+// Index 进程内的倒排索引，用 BM25 打分。
+// 如果指定了目录，那么每一次修改都会先追加到日志（WAL）里面，
+// 日志足够长了就把全部文档写成一个快照，然后清空日志。
+// 启动的时候先加载快照，再重放日志，倒排表在内存里重建。
+type Index struct {
+	mu        sync.RWMutex
+	tokenizer Tokenizer
+
+	docs map[int64]Document
+	// 每个文档的长度（词的数量），BM25 要用
+	docLens  map[int64]int
+	totalLen int
+	// 词 -> 文档 ID -> 词频
+	postings map[string]map[int64]int
+
+	dir              string
+	wal              *os.File
+	walOps           int
+	compactThreshold int
+}
+
+// Open 打开 dir 下面的索引，dir 为空就是一个纯内存的索引
+func Open(dir string, tokenizer Tokenizer) (*Index, error) {
+	idx := &Index{
+		tokenizer:        tokenizer,
+		docs:             make(map[int64]Document),
+		docLens:          make(map[int64]int),
+		postings:         make(map[string]map[int64]int),
+		dir:              dir,
+		compactThreshold: defaultCompactThreshold,
+	}
+	if dir == "" {
+		return idx, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := idx.load(); err != nil {
+		return nil, err
+	}
+	for _, doc := range idx.docs {
+		idx.add(doc)
+	}
+	wal, err := os.OpenFile(filepath.Join(dir, walFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	idx.wal = wal
+	// 日志里面可能有只写了一半的记录，启动的时候直接做一次快照，把日志清空
+	if stat, err := wal.Stat(); err == nil && stat.Size() > 0 {
+		if err = idx.compact(); err != nil {
+			_ = wal.Close()
+			return nil, err
+		}
+	}
+	return idx, nil
+}
+
+func (i *Index) Upsert(doc Document) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if err := i.appendLog(walRecord{Op: opUpsert, Doc: doc}); err != nil {
+		return err
+	}
+	i.remove(doc.Id)
+	i.docs[doc.Id] = doc
+	i.add(doc)
+	return i.maybeCompact()
+}
+
+func (i *Index) Delete(id int64) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if _, ok := i.docs[id]; !ok {
+		return nil
+	}
+	if err := i.appendLog(walRecord{Op: opDelete, Doc: Document{Id: id}}); err != nil {
+		return err
+	}
+	i.remove(id)
+	delete(i.docs, id)
+	return i.maybeCompact()
+}
+
+// Search 返回 [offset, offset+limit) 的结果，以及命中的总数
+func (i *Index) Search(query string, offset int, limit int) ([]Hit, int) {
+	terms := i.Terms(query)
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if len(terms) == 0 || len(i.docs) == 0 {
+		return nil, 0
+	}
+	n := float64(len(i.docs))
+	avgLen := float64(i.totalLen) / n
+	scores := make(map[int64]float64)
+	for _, term := range terms {
+		posting := i.postings[term]
+		if len(posting) == 0 {
+			continue
+		}
+		df := float64(len(posting))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, tf := range posting {
+			f := float64(tf)
+			norm := 1 - bm25B + bm25B*float64(i.docLens[id])/avgLen
+			scores[id] += idf * f * (bm25K1 + 1) / (f + bm25K1*norm)
+		}
+	}
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{Doc: i.docs[id], Score: score})
+	}
+	sort.Slice(hits, func(a, b int) bool {
+		if hits[a].Score != hits[b].Score {
+			return hits[a].Score > hits[b].Score
+		}
+		// 分数一样，新的文章排前面
+		if hits[a].Doc.Utime != hits[b].Doc.Utime {
+			return hits[a].Doc.Utime > hits[b].Doc.Utime
+		}
+		return hits[a].Doc.Id > hits[b].Doc.Id
+	})
+	total := len(hits)
+	if offset >= total {
+		return nil, total
+	}
+	end := min(offset+limit, total)
+	return hits[offset:end], total
+}
+
+// Terms 把查询切成去重之后的词，高亮的时候也要用
+func (i *Index) Terms(query string) []string {
+	tokens := i.tokenizer.Tokenize(query)
+	res := make([]string, 0, len(tokens))
+	seen := make(map[string]struct{}, len(tokens))
+	for _, t := range tokens {
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		res = append(res, t)
+	}
+	return res
+}
+
+// Close 关闭之前做一次快照，下次启动就不需要重放日志了
+func (i *Index) Close() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.wal == nil {
+		return nil
+	}
+	err := i.compact()
+	return errors.Join(err, i.wal.Close())
+}
+
+func (i *Index) add(doc Document) {
+	tfs := make(map[string]int)
+	length := 0
+	for _, t := range i.tokenizer.Tokenize(doc.Title) {
+		tfs[t] += titleWeight
+		length += titleWeight
+	}
+	for _, t := range i.tokenizer.Tokenize(doc.Content) {
+		tfs[t]++
+		length++
+	}
+	for t, tf := range tfs {
+		posting, ok := i.postings[t]
+		if !ok {
+			posting = make(map[int64]int)
+			i.postings[t] = posting
+		}
+		posting[doc.Id] = tf
+	}
+	i.docLens[doc.Id] = length
+	i.totalLen += length
+}
+
+func (i *Index) remove(id int64) {
+	doc, ok := i.docs[id]
+	if !ok {
+		return
+	}
+	for _, t := range i.tokenizer.Tokenize(doc.Title + " " + doc.Content) {
+		posting := i.postings[t]
+		delete(posting, id)
+		if len(posting) == 0 {
+			delete(i.postings, t)
+		}
+	}
+	i.totalLen -= i.docLens[id]
+	delete(i.docLens, id)
+}
+
+type walRecord struct {
+	Op  string   `json:"op"`
+	Doc Document `json:"doc"`
+}
+
+func (i *Index) appendLog(rec walRecord) error {
+	if i.wal == nil {
+		return nil
+	}
+	val, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err = i.wal.Write(append(val, '\n')); err != nil {
+		return err
+	}
+	i.walOps++
+	return i.wal.Sync()
+}
+
+func (i *Index) maybeCompact() error {
+	if i.wal == nil || i.walOps < i.compactThreshold {
+		return nil
+	}
+	return i.compact()
+}
+
+// compact 先写临时文件再改名，保证任何时候磁盘上都有一个完整的快照
+func (i *Index) compact() error {
+	tmp := filepath.Join(i.dir, snapshotFile+".tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = gob.NewEncoder(f).Encode(i.docs)
+	if err == nil {
+		err = f.Sync()
+	}
+	if er := f.Close(); err == nil {
+		err = er
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, filepath.Join(i.dir, snapshotFile)); err != nil {
+		return err
+	}
+	if err = i.wal.Truncate(0); err != nil {
+		return err
+	}
+	i.walOps = 0
+	return nil
+}
+
+func (i *Index) load() error {
+	f, err := os.Open(filepath.Join(i.dir, snapshotFile))
+	switch {
+	case err == nil:
+		err = gob.NewDecoder(f).Decode(&i.docs)
+		_ = f.Close()
+		if err != nil {
+			return err
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	wal, err := os.Open(filepath.Join(i.dir, walFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer wal.Close()
+	scanner := bufio.NewScanner(wal)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var rec walRecord
+		if err = json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// 最后一条可能只写了一半，直接丢弃
+			break
+		}
+		switch rec.Op {
+		case opUpsert:
+			i.docs[rec.Doc.Id] = rec.Doc
+		case opDelete:
+			delete(i.docs, rec.Doc.Id)
+		}
+		i.walOps++
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,101 @@
+package fulltext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBigramTokenizer(t *testing.T) {
+	testCases := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "中文二元切分",
+			text: "分布式锁",
+			want: []string{"分布", "布式", "式锁"},
+		},
+		{
+			name: "中英文混合",
+			text: "用 Redis 实现分布式锁",
+			want: []string{"用", "redis", "实现", "现分", "分布", "布式", "式锁"},
+		},
+		{
+			name: "标点是分隔符",
+			text: "Go,语言！v1.22",
+			want: []string{"go", "语言", "v1", "22"},
+		},
+	}
+	tokenizer := NewBigramTokenizer()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tokenizer.Tokenize(tc.text))
+		})
+	}
+}
+
+func TestIndex_Search(t *testing.T) {
+	idx, err := Open("", NewBigramTokenizer())
+	require.NoError(t, err)
+	require.NoError(t, idx.Upsert(Document{Id: 1, Title: "Go 语言入门", Content: "介绍 Go 的基础语法", Utime: 1}))
+	require.NoError(t, idx.Upsert(Document{Id: 2, Title: "分布式锁", Content: "基于 Redis 的分布式锁实现", Utime: 2}))
+	require.NoError(t, idx.Upsert(Document{Id: 3, Title: "Redis 入门", Content: "Redis 的数据结构", Utime: 3}))
+
+	hits, total := idx.Search("分布式锁", 0, 10)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, int64(2), hits[0].Doc.Id)
+
+	// 标题命中的排前面
+	hits, total = idx.Search("redis", 0, 10)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, int64(3), hits[0].Doc.Id)
+
+	// 更新之后旧的内容就搜不到了
+	require.NoError(t, idx.Upsert(Document{Id: 2, Title: "限流", Content: "滑动窗口", Utime: 4}))
+	_, total = idx.Search("分布式锁", 0, 10)
+	assert.Equal(t, 0, total)
+
+	require.NoError(t, idx.Delete(3))
+	hits, total = idx.Search("入门", 0, 10)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, int64(1), hits[0].Doc.Id)
+}
+
+func TestIndex_Persist(t *testing.T) {
+	dir := t.TempDir()
+	idx, err := Open(dir, NewBigramTokenizer())
+	require.NoError(t, err)
+	idx.compactThreshold = 2
+	require.NoError(t, idx.Upsert(Document{Id: 1, Title: "Go 语言入门"}))
+	require.NoError(t, idx.Upsert(Document{Id: 2, Title: "分布式锁"}))
+	// 这一条只在日志里面，没有进快照
+	require.NoError(t, idx.Upsert(Document{Id: 3, Title: "Redis 入门"}))
+	require.NoError(t, idx.wal.Close())
+
+	// 模拟崩溃重启：不调用 Close
+	idx, err = Open(dir, NewBigramTokenizer())
+	require.NoError(t, err)
+	_, total := idx.Search("入门", 0, 10)
+	assert.Equal(t, 2, total)
+	require.NoError(t, idx.Delete(1))
+	require.NoError(t, idx.Close())
+
+	idx, err = Open(dir, NewBigramTokenizer())
+	require.NoError(t, err)
+	hits, total := idx.Search("入门", 0, 10)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, int64(3), hits[0].Doc.Id)
+	require.NoError(t, idx.Close())
+}
+
+func TestHighlight(t *testing.T) {
+	tokenizer := NewBigramTokenizer()
+	res := Highlight("基于 Redis 的分布式锁<实现>", tokenizer.Tokenize("redis 分布式"), 100, "<em>", "</em>")
+	assert.Equal(t, "基于 <em>Redis</em> 的<em>分布式</em>锁&lt;实现&gt;", res)
+
+	res = Highlight("一二三四五六七八九十分布式", tokenizer.Tokenize("分布式"), 8, "<em>", "</em>")
+	assert.Equal(t, "...六七八九十<em>分布式</em>", res)
+}
@@ -0,0 +1,70 @@
+package fulltext
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokenizer 把一段文本切分成词
+type Tokenizer interface {
+	Tokenize(text string) []string
+}
+
+// BigramTokenizer 中日韩文字按照二元切分，比如 "分布式锁" 切成 "分布" "布式" "式锁"，
+// 英文和数字按照单词切分并且转成小写，其余字符都当成分隔符。
+// 二元切分不需要词典，召回率高，代价是索引会大一些。
+type BigramTokenizer struct{}
+
+func NewBigramTokenizer() *BigramTokenizer {
+	return &BigramTokenizer{}
+}
+
+func (b *BigramTokenizer) Tokenize(text string) []string {
+	var (
+		res  []string
+		word strings.Builder
+		cjk  []rune
+	)
+	flushWord := func() {
+		if word.Len() > 0 {
+			res = append(res, strings.ToLower(word.String()))
+			word.Reset()
+		}
+	}
+	flushCJK := func() {
+		switch len(cjk) {
+		case 0:
+		case 1:
+			// 只有一个字的时候没法二元切分，就保留单字
+			res = append(res, string(cjk))
+		default:
+			for i := 0; i+1 < len(cjk); i++ {
+				res = append(res, string(cjk[i:i+2]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word.WriteRune(r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return res
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
@@ -0,0 +1,154 @@
+// Package markdown 把作者写的 Markdown 渲染成可以直接交给前端的 HTML。
+// 作者可以在 Markdown 里面混写 HTML，所以渲染的时候不转义 HTML，
+// 而是在渲染之后统一用白名单过滤一遍，去掉脚本、事件属性和 javascript: 链接之类的东西。
+package markdown
+
+import (
+	"bytes"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+const (
+	// 中文每分钟大概读 300 个字，英文大概 200 个单词
+	cjkPerMinute  = 300
+	wordPerMinute = 200
+)
+
+var (
+	md = goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		// 标题带上 id，目录里面的锚点才能跳过去
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+	policy = newPolicy()
+)
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	// 代码高亮要用到 language-go 这样的 class
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	// GFM 的任务列表
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^$|^checked$|^disabled$`)).OnElements("input")
+	return p
+}
+
+// Heading 目录里面的一项
+type Heading struct {
+	Level int
+	Text  string
+	// Id 渲染出来的 HTML 里面标题的 id
+	Id string
+}
+
+type Document struct {
+	// HTML 已经过滤过，可以直接渲染
+	HTML     string
+	Headings []Heading
+	// Text 去掉了所有标记的纯文本，不包含代码块
+	Text string
+}
+
+// Render 渲染 Markdown，解析一次同时拿到 HTML、目录和纯文本
+func Render(src string) (Document, error) {
+	source := []byte(src)
+	doc := md.Parser().Parse(text.NewReader(source))
+	var buf bytes.Buffer
+	if err := md.Renderer().Render(&buf, source, doc); err != nil {
+		return Document{}, err
+	}
+	var headings []Heading
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		h, ok := n.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		var id string
+		if val, ok := h.AttributeString("id"); ok {
+			if bs, ok := val.([]byte); ok {
+				id = string(bs)
+			}
+		}
+		headings = append(headings, Heading{
+			Level: h.Level,
+			Text:  plainText(h, source),
+			Id:    id,
+		})
+		return ast.WalkSkipChildren, nil
+	})
+	return Document{
+		HTML:     policy.Sanitize(buf.String()),
+		Headings: headings,
+		Text:     plainText(doc, source),
+	}, nil
+}
+
+// PlainText 去掉 Markdown 和 HTML 标记之后的纯文本，连续的空白会合并成一个空格
+func PlainText(src string) string {
+	source := []byte(src)
+	return plainText(md.Parser().Parse(text.NewReader(source)), source)
+}
+
+func plainText(root ast.Node, source []byte) string {
+	var sb strings.Builder
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			// 块和块之间要有分隔，不然上一段的结尾和下一段的开头就连在一起了
+			if n.Type() == ast.TypeBlock {
+				sb.WriteByte(' ')
+			}
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock, *ast.RawHTML, *ast.Image:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			sb.Write(node.Segment.Value(source))
+			if node.SoftLineBreak() || node.HardLineBreak() {
+				sb.WriteByte(' ')
+			}
+		case *ast.String:
+			sb.Write(node.Value)
+		case *ast.AutoLink:
+			sb.Write(node.Label(source))
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// ReadingTime 根据纯文本估计阅读时间，按分钟向上取整，最少一分钟
+func ReadingTime(plain string) time.Duration {
+	var cjk, words int
+	inWord := false
+	for _, r := range plain {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+			unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r):
+			cjk++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if !inWord {
+				words++
+				inWord = true
+			}
+		default:
+			inWord = false
+		}
+	}
+	minutes := float64(cjk)/cjkPerMinute + float64(words)/wordPerMinute
+	return time.Duration(max(1, math.Ceil(minutes))) * time.Minute
+}
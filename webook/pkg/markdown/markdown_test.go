@@ -0,0 +1,62 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	src := "# 分布式锁\n\n用 **Redis** 实现，见 [文档](https://redis.io)。\n\n" +
+		"## Setup Guide\n\n```go\nfmt.Println(\"hi\")\n```\n\n" +
+		"<script>alert(1)</script>\n\n" +
+		"<a href=\"javascript:alert(1)\" onclick=\"x()\">点我</a>\n\n" +
+		"- [x] 完成\n"
+	doc, err := Render(src)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Heading{
+		{Level: 1, Text: "分布式锁", Id: "heading"},
+		{Level: 2, Text: "Setup Guide", Id: "setup-guide"},
+	}, doc.Headings)
+	assert.Contains(t, doc.HTML, `<h2 id="setup-guide">Setup Guide</h2>`)
+	assert.Contains(t, doc.HTML, `<strong>Redis</strong>`)
+	assert.Contains(t, doc.HTML, `<code class="language-go">`)
+	assert.Contains(t, doc.HTML, `<input checked="" disabled="" type="checkbox">`)
+	// 脚本、事件和 javascript: 链接都被去掉了
+	assert.NotContains(t, doc.HTML, "<script")
+	assert.NotContains(t, doc.HTML, "onclick")
+	assert.NotContains(t, doc.HTML, "javascript:")
+	assert.Contains(t, doc.HTML, "点我")
+
+	assert.Equal(t, "分布式锁 用 Redis 实现，见 文档。 Setup Guide 点我 完成", doc.Text)
+}
+
+func TestPlainText(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{name: "强调和链接", src: "这是 *一段* [链接](http://x.com) `code`", want: "这是 一段 链接 code"},
+		{name: "图片和 HTML 被去掉", src: "![图](a.png)前面<br/>后面\n\n<div>块</div>", want: "前面后面"},
+		{name: "换行合并成空格", src: "第一行\n第二行\n\n> 引用", want: "第一行 第二行 引用"},
+		{name: "纯文本不变", src: "就是一句话", want: "就是一句话"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, PlainText(tc.src))
+		})
+	}
+}
+
+func TestReadingTime(t *testing.T) {
+	assert.Equal(t, time.Minute, ReadingTime(""))
+	assert.Equal(t, time.Minute, ReadingTime(strings.Repeat("字", 300)))
+	assert.Equal(t, 2*time.Minute, ReadingTime(strings.Repeat("字", 301)))
+	// 300 个字加 200 个单词
+	assert.Equal(t, 2*time.Minute, ReadingTime(strings.Repeat("字", 300)+strings.Repeat(" word", 200)))
+}
@@ -0,0 +1,40 @@
+package objectstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStore(filepath.Join(dir, "oss"))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = store.Get(ctx, "articles/1")
+	assert.Equal(t, ErrObjectNotFound, err)
+
+	require.NoError(t, store.Put(ctx, "articles/1", []byte("第一版"), "text/plain"))
+	require.NoError(t, store.Put(ctx, "articles/1", []byte("第二版"), "text/plain"))
+	data, err := store.Get(ctx, "articles/1")
+	require.NoError(t, err)
+	assert.Equal(t, "第二版", string(data))
+
+	require.NoError(t, store.Delete(ctx, "articles/1"))
+	_, err = store.Get(ctx, "articles/1")
+	assert.Equal(t, ErrObjectNotFound, err)
+	require.NoError(t, store.Delete(ctx, "articles/1"))
+
+	// key 跑不出 dir
+	require.NoError(t, store.Put(ctx, "../../escape", []byte("x"), "text/plain"))
+	_, err = os.Stat(filepath.Join(dir, "escape"))
+	assert.True(t, os.IsNotExist(err))
+	data, err = store.Get(ctx, "escape")
+	require.NoError(t, err)
+	assert.Equal(t, "x", string(data))
+}
@@ -0,0 +1,17 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrObjectNotFound = errors.New("对象不存在")
+
+// ObjectStore 对象存储，key 是形如 articles/123 的路径
+type ObjectStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get 对象不存在的时候返回 ErrObjectNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete 对象不存在的时候也返回 nil
+	Delete(ctx context.Context, key string) error
+}
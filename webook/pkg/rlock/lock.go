@@ -0,0 +1,182 @@
+package rlock
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	//go:embed lua/lock.lua
+	luaLock string
+	//go:embed lua/refresh.lua
+	luaRefresh string
+	//go:embed lua/unlock.lua
+	luaUnlock string
+)
+
+var (
+	// ErrFailedToPreemptLock 锁被别人持有
+	ErrFailedToPreemptLock = errors.New("rlock: 抢锁失败")
+	// ErrLockNotHold 锁已经过期了，或者被别人拿走了
+	ErrLockNotHold = errors.New("rlock: 未持有锁")
+)
+
+// Client 基于 Redis 的分布式锁。
+// 每一把锁都有一个随机的 token，续约和释放的时候都要先比较 token，
+// 所以不会误操作别人的锁
+type Client struct {
+	client redis.Cmdable
+	// valuer 生成 token，测试的时候可以替换
+	valuer func() string
+}
+
+func NewClient(client redis.Cmdable) *Client {
+	return &Client{
+		client: client,
+		valuer: func() string {
+			return uuid.New().String()
+		},
+	}
+}
+
+// TryLock 只尝试一次，锁被别人持有就返回 ErrFailedToPreemptLock
+func (c *Client) TryLock(ctx context.Context, key string, expiration time.Duration) (*Lock, error) {
+	val := c.valuer()
+	ok, err := c.client.SetNX(ctx, key, val, expiration).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrFailedToPreemptLock
+	}
+	return newLock(c.client, key, val, expiration), nil
+}
+
+// Lock 加锁，失败了按照 retry 重试。
+// timeout 是每一次加锁请求的超时时间，超时了也会重试：
+// 同一次 Lock 调用的所有请求用的是同一个 token，
+// 所以即便上一次请求其实已经成功了，重试也能拿到锁
+func (c *Client) Lock(ctx context.Context, key string, expiration time.Duration,
+	timeout time.Duration, retry RetryStrategy) (*Lock, error) {
+	val := c.valuer()
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	for {
+		lctx, cancel := context.WithTimeout(ctx, timeout)
+		res, err := c.client.Eval(lctx, luaLock, []string{key}, val, expiration.Milliseconds()).Result()
+		cancel()
+		if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		if res == "OK" {
+			return newLock(c.client, key, val, expiration), nil
+		}
+		interval, ok := retry.Next()
+		if !ok {
+			if err != nil {
+				return nil, err
+			}
+			return nil, ErrFailedToPreemptLock
+		}
+		if timer == nil {
+			timer = time.NewTimer(interval)
+		} else {
+			timer.Reset(interval)
+		}
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+type Lock struct {
+	client     redis.Cmdable
+	key        string
+	value      string
+	expiration time.Duration
+
+	unlockOnce sync.Once
+	unlocked   chan struct{}
+}
+
+func newLock(client redis.Cmdable, key string, value string, expiration time.Duration) *Lock {
+	return &Lock{
+		client:     client,
+		key:        key,
+		value:      value,
+		expiration: expiration,
+		unlocked:   make(chan struct{}),
+	}
+}
+
+// Refresh 续约一次，把过期时间重新设置为 expiration
+func (l *Lock) Refresh(ctx context.Context) error {
+	res, err := l.client.Eval(ctx, luaRefresh, []string{l.key}, l.value, l.expiration.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+	if res != 1 {
+		return ErrLockNotHold
+	}
+	return nil
+}
+
+// AutoRefresh 每隔 interval 续约一次，每次续约的超时时间是 timeout。
+// 续约超时会立刻重试，其余错误直接返回，调用者应该认为锁已经丢了。
+// 这个方法会一直阻塞，直到 Unlock 被调用，所以一般在单独的 goroutine 里面调用
+func (l *Lock) AutoRefresh(interval time.Duration, timeout time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	retry := make(chan struct{}, 1)
+	refresh := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		err := l.Refresh(ctx)
+		if errors.Is(err, context.DeadlineExceeded) {
+			retry <- struct{}{}
+			return nil
+		}
+		return err
+	}
+	for {
+		select {
+		case <-ticker.C:
+			if err := refresh(); err != nil {
+				return err
+			}
+		case <-retry:
+			if err := refresh(); err != nil {
+				return err
+			}
+		case <-l.unlocked:
+			return nil
+		}
+	}
+}
+
+// Unlock 释放锁，同时停止自动续约。锁已经不是自己的了就返回 ErrLockNotHold
+func (l *Lock) Unlock(ctx context.Context) error {
+	l.unlockOnce.Do(func() {
+		close(l.unlocked)
+	})
+	res, err := l.client.Eval(ctx, luaUnlock, []string{l.key}, l.value).Int64()
+	if err != nil {
+		return err
+	}
+	if res != 1 {
+		return ErrLockNotHold
+	}
+	return nil
+}
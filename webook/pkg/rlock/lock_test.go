@@ -0,0 +1,173 @@
+package rlock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T) (*Client, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	return NewClient(redis.NewClient(&redis.Options{Addr: mr.Addr()})), mr
+}
+
+func TestClient_TryLock(t *testing.T) {
+	c, mr := newTestClient(t)
+	ctx := context.Background()
+
+	l, err := c.TryLock(ctx, "key1", time.Minute)
+	require.NoError(t, err)
+	val, err := mr.Get("key1")
+	require.NoError(t, err)
+	assert.Equal(t, l.value, val)
+	assert.Equal(t, time.Minute, mr.TTL("key1"))
+
+	_, err = c.TryLock(ctx, "key1", time.Minute)
+	assert.Equal(t, ErrFailedToPreemptLock, err)
+}
+
+func TestClient_Lock(t *testing.T) {
+	testCases := []struct {
+		name   string
+		before func(mr *miniredis.Miniredis)
+		// 加锁过程中别人释放了锁
+		release bool
+		wantErr error
+	}{
+		{
+			name:   "直接加锁成功",
+			before: func(mr *miniredis.Miniredis) {},
+		},
+		{
+			name: "上一次请求其实已经成功了",
+			before: func(mr *miniredis.Miniredis) {
+				require.NoError(t, mr.Set("key1", "token"))
+			},
+		},
+		{
+			name: "重试之后加锁成功",
+			before: func(mr *miniredis.Miniredis) {
+				require.NoError(t, mr.Set("key1", "other"))
+			},
+			release: true,
+		},
+		{
+			name: "重试次数用完",
+			before: func(mr *miniredis.Miniredis) {
+				require.NoError(t, mr.Set("key1", "other"))
+			},
+			wantErr: ErrFailedToPreemptLock,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, mr := newTestClient(t)
+			c.valuer = func() string {
+				return "token"
+			}
+			tc.before(mr)
+			if tc.release {
+				go func() {
+					time.Sleep(time.Millisecond * 30)
+					mr.Del("key1")
+				}()
+			}
+			l, err := c.Lock(context.Background(), "key1", time.Minute, time.Second,
+				&FixIntervalRetry{Interval: time.Millisecond * 20, Max: 5})
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, "token", l.value)
+			val, err := mr.Get("key1")
+			require.NoError(t, err)
+			assert.Equal(t, "token", val)
+			assert.Equal(t, time.Minute, mr.TTL("key1"))
+		})
+	}
+}
+
+func TestLock_Unlock(t *testing.T) {
+	c, mr := newTestClient(t)
+	ctx := context.Background()
+
+	l, err := c.TryLock(ctx, "key1", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, l.Unlock(ctx))
+	assert.False(t, mr.Exists("key1"))
+
+	// 锁过期之后被别人拿走了，不能释放别人的锁
+	l, err = c.TryLock(ctx, "key1", time.Minute)
+	require.NoError(t, err)
+	mr.FastForward(time.Minute)
+	require.NoError(t, mr.Set("key1", "other"))
+	assert.Equal(t, ErrLockNotHold, l.Unlock(ctx))
+	val, err := mr.Get("key1")
+	require.NoError(t, err)
+	assert.Equal(t, "other", val)
+}
+
+func TestLock_Refresh(t *testing.T) {
+	c, mr := newTestClient(t)
+	ctx := context.Background()
+
+	l, err := c.TryLock(ctx, "key1", time.Minute)
+	require.NoError(t, err)
+	mr.FastForward(time.Second * 50)
+	require.NoError(t, l.Refresh(ctx))
+	assert.Equal(t, time.Minute, mr.TTL("key1"))
+
+	mr.FastForward(time.Minute)
+	assert.Equal(t, ErrLockNotHold, l.Refresh(ctx))
+}
+
+func TestLock_AutoRefresh(t *testing.T) {
+	c, mr := newTestClient(t)
+	ctx := context.Background()
+
+	l, err := c.TryLock(ctx, "key1", time.Minute)
+	require.NoError(t, err)
+	done := make(chan error, 1)
+	go func() {
+		done <- l.AutoRefresh(time.Millisecond*10, time.Second)
+	}()
+	mr.FastForward(time.Second * 50)
+	assert.Eventually(t, func() bool {
+		return mr.TTL("key1") == time.Minute
+	}, time.Second, time.Millisecond*10)
+
+	require.NoError(t, l.Unlock(ctx))
+	select {
+	case err = <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Unlock 之后自动续约没有退出")
+	}
+}
+
+func TestLock_AutoRefreshLost(t *testing.T) {
+	c, mr := newTestClient(t)
+	l, err := c.TryLock(context.Background(), "key1", time.Minute)
+	require.NoError(t, err)
+	// 锁被别人拿走了，自动续约返回错误
+	require.NoError(t, mr.Set("key1", "other"))
+	assert.Equal(t, ErrLockNotHold, l.AutoRefresh(time.Millisecond*10, time.Second))
+}
+
+func TestExponentialBackoffRetry(t *testing.T) {
+	r := &ExponentialBackoffRetry{Initial: time.Millisecond, MaxInterval: time.Millisecond * 3, Max: 3}
+	var intervals []time.Duration
+	for {
+		interval, ok := r.Next()
+		if !ok {
+			break
+		}
+		intervals = append(intervals, interval)
+	}
+	assert.Equal(t, []time.Duration{time.Millisecond, time.Millisecond * 2, time.Millisecond * 3}, intervals)
+}
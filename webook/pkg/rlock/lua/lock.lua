@@ -0,0 +1,10 @@
+-- 加锁。如果锁已经是自己的了（比如上一次加锁超时，但其实成功了），就刷新过期时间
+local val = redis.call('get', KEYS[1])
+if val == false then
+    return redis.call('set', KEYS[1], ARGV[1], 'PX', ARGV[2])
+elseif val == ARGV[1] then
+    redis.call('pexpire', KEYS[1], ARGV[2])
+    return "OK"
+else
+    return ""
+end
@@ -0,0 +1,6 @@
+-- 锁还是自己的才删除
+if redis.call('get', KEYS[1]) == ARGV[1] then
+    return redis.call('del', KEYS[1])
+else
+    return 0
+end
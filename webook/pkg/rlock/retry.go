@@ -0,0 +1,40 @@
+package rlock
+
+import "time"
+
+// RetryStrategy 加锁失败之后的重试策略
+type RetryStrategy interface {
+	// Next 返回下一次重试之前要等待的时间，以及要不要继续重试
+	Next() (time.Duration, bool)
+}
+
+// FixIntervalRetry 固定间隔重试，最多重试 Max 次
+type FixIntervalRetry struct {
+	Interval time.Duration
+	Max      int
+	cnt      int
+}
+
+func (f *FixIntervalRetry) Next() (time.Duration, bool) {
+	f.cnt++
+	return f.Interval, f.cnt <= f.Max
+}
+
+// ExponentialBackoffRetry 指数退避重试，间隔从 Initial 开始翻倍，最大不超过 MaxInterval
+type ExponentialBackoffRetry struct {
+	Initial     time.Duration
+	MaxInterval time.Duration
+	Max         int
+	cnt         int
+	interval    time.Duration
+}
+
+func (e *ExponentialBackoffRetry) Next() (time.Duration, bool) {
+	e.cnt++
+	if e.interval == 0 {
+		e.interval = e.Initial
+	} else {
+		e.interval = min(e.interval*2, e.MaxInterval)
+	}
+	return e.interval, e.cnt <= e.Max
+}
@@ -0,0 +1,149 @@
+package sensitive
+
+import (
+	"sync/atomic"
+	"unicode"
+)
+
+// Matcher 基于 Aho-Corasick 自动机的多模式匹配，一次扫描就能找出文本里面所有的敏感词。
+// 匹配不区分大小写。构建好之后是只读的，可以并发使用。
+type Matcher struct {
+	nodes []node
+}
+
+type node struct {
+	children map[rune]int
+	// fail 失配的时候跳转的节点
+	fail int
+	// word 以这个节点结尾的敏感词，不是结尾就是空字符串
+	word string
+	// output 沿着 fail 链能找到的下一个结尾节点，省得每次都走完整条 fail 链
+	output int
+}
+
+// NewMatcher 用 words 构建自动机，空字符串会被忽略
+func NewMatcher(words []string) *Matcher {
+	m := &Matcher{nodes: []node{{children: map[rune]int{}, output: -1}}}
+	for _, w := range words {
+		m.insert(w)
+	}
+	m.build()
+	return m
+}
+
+func (m *Matcher) insert(word string) {
+	cur := 0
+	for _, r := range word {
+		r = unicode.ToLower(r)
+		next, ok := m.nodes[cur].children[r]
+		if !ok {
+			next = len(m.nodes)
+			m.nodes = append(m.nodes, node{children: map[rune]int{}, output: -1})
+			m.nodes[cur].children[r] = next
+		}
+		cur = next
+	}
+	if cur != 0 {
+		m.nodes[cur].word = word
+	}
+}
+
+// build 按层遍历，计算每个节点的 fail 和 output
+func (m *Matcher) build() {
+	queue := make([]int, 0, len(m.nodes))
+	for _, child := range m.nodes[0].children {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[cur].children {
+			f := m.nodes[cur].fail
+			for f != 0 {
+				if _, ok := m.nodes[f].children[r]; ok {
+					break
+				}
+				f = m.nodes[f].fail
+			}
+			if next, ok := m.nodes[f].children[r]; ok && next != child {
+				m.nodes[child].fail = next
+			}
+			fail := m.nodes[child].fail
+			if m.nodes[fail].word != "" {
+				m.nodes[child].output = fail
+			} else {
+				m.nodes[child].output = m.nodes[fail].output
+			}
+			queue = append(queue, child)
+		}
+	}
+}
+
+// FindAll 返回 text 里面出现过的敏感词，按照第一次出现的顺序，每个词只返回一次
+func (m *Matcher) FindAll(text string) []string {
+	var res []string
+	seen := make(map[int]struct{})
+	m.scan(text, func(n int) bool {
+		if _, ok := seen[n]; !ok {
+			seen[n] = struct{}{}
+			res = append(res, m.nodes[n].word)
+		}
+		return true
+	})
+	return res
+}
+
+// Contains 只关心有没有敏感词的时候用它，找到第一个就返回
+func (m *Matcher) Contains(text string) bool {
+	found := false
+	m.scan(text, func(n int) bool {
+		found = true
+		return false
+	})
+	return found
+}
+
+// scan 每匹配到一个敏感词就调用一次 fn，fn 返回 false 就停止
+func (m *Matcher) scan(text string, fn func(n int) bool) {
+	cur := 0
+	for _, r := range text {
+		r = unicode.ToLower(r)
+		for cur != 0 {
+			if _, ok := m.nodes[cur].children[r]; ok {
+				break
+			}
+			cur = m.nodes[cur].fail
+		}
+		// 根节点也没有这个字符的话，回到根节点
+		cur = m.nodes[cur].children[r]
+		out := cur
+		if m.nodes[out].word == "" {
+			out = m.nodes[out].output
+		}
+		for ; out > 0; out = m.nodes[out].output {
+			if !fn(out) {
+				return
+			}
+		}
+	}
+}
+
+// Dictionary 可以在运行期间整体替换的敏感词库。
+// 替换的时候构建一个新的 Matcher 再原子地换上去，正在进行的匹配不受影响。
+type Dictionary struct {
+	matcher atomic.Pointer[Matcher]
+}
+
+func NewDictionary(words []string) *Dictionary {
+	d := &Dictionary{}
+	d.Reload(words)
+	return d
+}
+
+func (d *Dictionary) Reload(words []string) {
+	d.matcher.Store(NewMatcher(words))
+}
+
+func (d *Dictionary) FindAll(text string) []string {
+	return d.matcher.Load().FindAll(text)
+}
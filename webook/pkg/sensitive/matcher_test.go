@@ -0,0 +1,62 @@
+package sensitive
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatcher_FindAll(t *testing.T) {
+	testCases := []struct {
+		name  string
+		words []string
+		text  string
+		want  []string
+	}{
+		{
+			name:  "没有敏感词",
+			words: []string{"赌博", "代开发票"},
+			text:  "今天聊聊分布式锁",
+		},
+		{
+			name:  "重叠的词",
+			words: []string{"he", "she", "his", "hers"},
+			text:  "ushers",
+			want:  []string{"she", "he", "hers"},
+		},
+		{
+			name:  "重复出现只返回一次",
+			words: []string{"赌博", "代开发票"},
+			text:  "赌博网站，代开发票，赌博",
+			want:  []string{"赌博", "代开发票"},
+		},
+		{
+			name:  "不区分大小写",
+			words: []string{"Casino"},
+			text:  "online CASINO here",
+			want:  []string{"Casino"},
+		},
+		{
+			name:  "失配之后跳转",
+			words: []string{"abcd", "bc"},
+			text:  "abce",
+			want:  []string{"bc"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewMatcher(tc.words)
+			assert.Equal(t, tc.want, m.FindAll(tc.text))
+			assert.Equal(t, len(tc.want) > 0, m.Contains(tc.text))
+		})
+	}
+}
+
+func TestDictionary_Reload(t *testing.T) {
+	d := NewDictionary([]string{"赌博"})
+	assert.Equal(t, []string{"赌博"}, d.FindAll("赌博和彩票"))
+	d.Reload([]string{"彩票"})
+	assert.Equal(t, []string{"彩票"}, d.FindAll("赌博和彩票"))
+	d.Reload(nil)
+	assert.Empty(t, d.FindAll("赌博和彩票"))
+}
@@ -0,0 +1,85 @@
+// Package thumbnail 只依赖标准库的缩略图生成，支持 JPEG、PNG 和 GIF（第一帧）
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+)
+
+// maxPixels 解码之前先检查尺寸，防止很小的文件解码出很大的图片把内存撑爆
+const maxPixels = 50_000_000
+
+var ErrImageTooLarge = errors.New("图片尺寸太大")
+
+// Generate 把图片等比例缩小到长边不超过 maxSize，输出 JPEG。
+// 图片本来就不大的时候不需要缩略图，ok 返回 false。
+// 透明的部分会变成白色，因为 JPEG 不支持透明。
+func Generate(data []byte, maxSize int) (res []byte, ok bool, err error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, false, err
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, false, ErrImageTooLarge
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, false, err
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxSize && h <= maxSize {
+		return nil, false, nil
+	}
+	dw, dh := maxSize, h*maxSize/w
+	if h > w {
+		dw, dh = w*maxSize/h, maxSize
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+	dst := scale(src, dw, dh)
+	var buf bytes.Buffer
+	err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80})
+	if err != nil {
+		return nil, false, err
+	}
+	return buf.Bytes(), true, nil
+}
+
+// scale 用区域平均的方式缩小，每个目标像素是对应的那一块源像素的平均值，
+// 缩小的时候效果比最近邻好很多，也不需要引入 golang.org/x/image
+func scale(src image.Image, dw, dh int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := b.Min.Y+y*sh/dh, b.Min.Y+(y+1)*sh/dh
+		y1 = max(y1, y0+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := b.Min.X+x*sw/dw, b.Min.X+(x+1)*sw/dw
+			x1 = max(x1, x0+1)
+			var r, g, bl, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					// 返回的是预乘过 alpha 的值，和白色背景混合只需要加上 (1-alpha) 的白色
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr + 0xffff - ca)
+					g += uint64(cg + 0xffff - ca)
+					bl += uint64(cb + 0xffff - ca)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(bl / n >> 8),
+				A: 0xff,
+			})
+		}
+	}
+	return dst
+}
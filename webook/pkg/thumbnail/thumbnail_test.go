@@ -0,0 +1,71 @@
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		w, h   int
+		wantOk bool
+		wantW  int
+		wantH  int
+	}{
+		{name: "宽图", w: 800, h: 400, wantOk: true, wantW: 200, wantH: 100},
+		{name: "长图", w: 300, h: 900, wantOk: true, wantW: 66, wantH: 200},
+		{name: "小图不需要缩略图", w: 200, h: 150},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			img := image.NewNRGBA(image.Rect(0, 0, tc.w, tc.h))
+			for x := 0; x < tc.w; x++ {
+				for y := 0; y < tc.h; y++ {
+					img.Set(x, y, color.NRGBA{R: 0xff, A: 0xff})
+				}
+			}
+			var buf bytes.Buffer
+			require.NoError(t, png.Encode(&buf, img))
+			res, ok, err := Generate(buf.Bytes(), 200)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantOk, ok)
+			if !ok {
+				return
+			}
+			thumb, err := jpeg.Decode(bytes.NewReader(res))
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantW, thumb.Bounds().Dx())
+			assert.Equal(t, tc.wantH, thumb.Bounds().Dy())
+			r, g, _, _ := thumb.At(tc.wantW/2, tc.wantH/2).RGBA()
+			assert.True(t, r>>8 > 0xf0)
+			assert.True(t, g>>8 < 0x10)
+		})
+	}
+}
+
+func TestGenerate_Transparent(t *testing.T) {
+	// 透明的图片背景是白色
+	img := image.NewNRGBA(image.Rect(0, 0, 400, 400))
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	res, ok, err := Generate(buf.Bytes(), 100)
+	require.NoError(t, err)
+	require.True(t, ok)
+	thumb, err := jpeg.Decode(bytes.NewReader(res))
+	require.NoError(t, err)
+	r, g, b, _ := thumb.At(50, 50).RGBA()
+	assert.True(t, r>>8 > 0xf0 && g>>8 > 0xf0 && b>>8 > 0xf0)
+}
+
+func TestGenerate_NotImage(t *testing.T) {
+	_, _, err := Generate([]byte("hello"), 100)
+	assert.Error(t, err)
+}
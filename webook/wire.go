@@ -4,6 +4,7 @@ package main
 
 import (
 	"basic-go/webook/internal/events/article"
+	"basic-go/webook/internal/events/feed"
 	"basic-go/webook/internal/repository"
 	"basic-go/webook/internal/repository/cache"
 	"basic-go/webook/internal/repository/dao"
@@ -12,13 +13,22 @@ import (
 	"basic-go/webook/internal/web"
 	ijwt "basic-go/webook/internal/web/jwt"
 	"basic-go/webook/ioc"
+	"basic-go/webook/pkg/rlock"
 
 	"github.com/google/wire"
 )
 
-var interactiveSvcSet = wire.NewSet(dao.NewGORMInteractiveDAO,
+// interactiveRepoSet 计数相关的依赖，命令行工具不需要 InteractiveService，所以单独拆出来
+var interactiveRepoSet = wire.NewSet(dao.NewGORMInteractiveDAO,
 	cache.NewInteractiveRedisCache,
-	repository.NewCachedInteractiveRepository,
+	ioc.InitSnowflakeNode,
+	cache.NewInteractiveDeltaRedisCache,
+	cache.NewInteractiveStateRedisCache,
+	ioc.InitReadCache,
+	ioc.InitInteractiveRepository,
+)
+
+var interactiveSvcSet = wire.NewSet(interactiveRepoSet,
 	service.NewInteractiveService,
 )
 
@@ -30,24 +40,61 @@ func InitWebServer() *App {
 		ioc.InitSaramaClient,
 		ioc.InitSyncProducer,
 		ioc.InitConsumers,
+		ioc.InitSearchIndex,
+		ioc.InitObjectStore,
+		ioc.InitArticleStorage,
+		wire.FieldsOf(new(ioc.ArticleStorage), "ArticleDAO", "RevisionDAO", "TagDAO"),
+		ioc.InitRankingJob,
+		ioc.InitInteractiveFlusher,
+		ioc.InitCronJobService,
+		ioc.InitScheduler,
+		ioc.InitLocalFuncExecutor,
+		ioc.InitUploadConfig,
+		ioc.InitModerator,
+		rlock.NewClient,
 
 		// DAO 部分
 		dao.NewUserDAO,
-		dao.NewArticleGORMDAO,
+		dao.NewCommentGORMDAO,
+		dao.NewFollowGORMDAO,
+		dao.NewFeedGORMDAO,
+		dao.NewGORMJobDAO,
+		dao.NewCollectionGORMDAO,
+		dao.NewHistoryRecordGORMDAO,
+		dao.NewUploadGORMDAO,
+		dao.NewArticleReviewGORMDAO,
 
 		interactiveSvcSet,
 
 		article.NewSaramaSyncProducer,
 		article.NewInteractiveReadEventConsumer,
+		ioc.InitSearchIndexConsumer,
+		article.NewHistoryRecordConsumer,
+		feed.NewArticlePublishConsumer,
 
 		// cache 部分
 		cache.NewCodeCache, cache.NewUserCache,
 		cache.NewArticleRedisCache,
+		cache.NewFollowRedisCache,
+		cache.NewRankingRedisCache,
+		cache.NewRankingLocalCache,
+		cache.NewHistoryRecordRedisCache,
 
 		// repository 部分
 		repository.NewCachedUserRepository,
 		repository.NewCodeRepository,
 		repository.NewCachedArticleRepository,
+		repository.NewArticleRevisionRepository,
+		repository.NewLocalSearchRepository,
+		repository.NewCommentRepository,
+		repository.NewCachedFollowRepository,
+		repository.NewFeedRepository,
+		repository.NewCachedRankingRepository,
+		repository.NewPreemptCronJobRepository,
+		repository.NewCollectionRepository,
+		repository.NewCachedHistoryRecordRepository,
+		repository.NewUploadRepository,
+		repository.NewArticleReviewRepository,
 
 		// Service 部分
 		ioc.InitSMSService,
@@ -55,6 +102,18 @@ func InitWebServer() *App {
 		service.NewUserService,
 		service.NewCodeService,
 		service.NewArticleService,
+		service.NewArticleReviewService,
+		service.NewScheduledPublisher,
+		service.NewSearchService,
+		service.NewCommentService,
+		service.NewFollowService,
+		ioc.InitFeedService,
+		service.NewBatchRankingService,
+		service.NewCollectionService,
+		service.NewHistoryService,
+		service.NewUploadService,
+		service.NewArticlePurger,
+		service.NewInteractiveReconciler,
 
 		// ratelimit.NewSMSLimiter,
 		ratelimit.NewRateLimitSMSService,
@@ -62,6 +121,14 @@ func InitWebServer() *App {
 		// handler 部分
 		web.NewUserHandler,
 		web.NewArticleHandler,
+		web.NewSearchHandler,
+		web.NewCommentHandler,
+		web.NewFollowHandler,
+		web.NewFeedHandler,
+		web.NewRankingHandler,
+		web.NewCollectionHandler,
+		web.NewUploadHandler,
+		ioc.InitArticleReviewHandler,
 		ijwt.NewRedisJWTHandler,
 		web.NewOAuth2WechatHandler,
 		ioc.InitGinMiddlewares,
@@ -71,3 +138,14 @@ func InitWebServer() *App {
 	)
 	return new(App)
 }
+
+// InitInteractiveReconciler 命令行工具用，只需要计数相关的依赖
+func InitInteractiveReconciler() *service.InteractiveReconciler {
+	wire.Build(
+		ioc.InitRedis, ioc.InitDB,
+		ioc.InitLogger,
+		interactiveRepoSet,
+		service.NewInteractiveReconciler,
+	)
+	return new(service.InteractiveReconciler)
+}
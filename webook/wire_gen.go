@@ -8,6 +8,7 @@ package main
 
 import (
 	"basic-go/webook/internal/events/article"
+	"basic-go/webook/internal/events/feed"
 	"basic-go/webook/internal/repository"
 	"basic-go/webook/internal/repository/cache"
 	"basic-go/webook/internal/repository/dao"
@@ -16,6 +17,7 @@ import (
 	"basic-go/webook/internal/web"
 	"basic-go/webook/internal/web/jwt"
 	"basic-go/webook/ioc"
+	"basic-go/webook/pkg/rlock"
 	"github.com/google/wire"
 )
 
@@ -36,34 +38,119 @@ func InitWebServer() *App {
 	smsService := ioc.InitSMSService(cmdable)
 	codeService := service.NewCodeService(codeRepository, smsService)
 	rateLimitSMSService := ratelimit.NewRateLimitSMSService(codeService, cmdable)
-	userHandler := web.NewUserHandler(userService, codeService, rateLimitSMSService, handler)
-	articleDAO := dao.NewArticleGORMDAO(db)
+	followDAO := dao.NewFollowGORMDAO(db)
+	followCache := cache.NewFollowRedisCache(cmdable)
+	followRepository := repository.NewCachedFollowRepository(followDAO, followCache, userRepository, loggerV1)
+	followService := service.NewFollowService(followRepository, userRepository)
+	historyRecordDAO := dao.NewHistoryRecordGORMDAO(db)
+	historyRecordCache := cache.NewHistoryRecordRedisCache(cmdable)
+	historyRecordRepository := repository.NewCachedHistoryRecordRepository(historyRecordDAO, historyRecordCache, loggerV1)
+	objectStore := ioc.InitObjectStore()
+	articleStorage := ioc.InitArticleStorage(db, objectStore)
+	articleDAO := articleStorage.ArticleDAO
 	articleCache := cache.NewArticleRedisCache(cmdable)
-	articleRepository := repository.NewCachedArticleRepository(articleDAO, userRepository, articleCache)
-	
+	articleTagDAO := articleStorage.TagDAO
+	articleRepository := repository.NewCachedArticleRepository(articleDAO, articleTagDAO, userRepository, articleCache)
+	historyService := service.NewHistoryService(historyRecordRepository, articleRepository)
+	uploadConfig := ioc.InitUploadConfig()
+	userHandler := web.NewUserHandler(userService, codeService, rateLimitSMSService, followService, historyService, handler, uploadConfig)
+	articleRevisionDAO := articleStorage.RevisionDAO
+	articleRevisionRepository := repository.NewArticleRevisionRepository(articleRevisionDAO)
 	client := ioc.InitSaramaClient()
 	syncProducer := ioc.InitSyncProducer(client)
 	producer := article.NewSaramaSyncProducer(syncProducer)
-	articleService := service.NewArticleService(articleRepository, producer)
+	moderator := ioc.InitModerator(cmdable, loggerV1)
+	articleReviewDAO := dao.NewArticleReviewGORMDAO(db)
+	articleReviewRepository := repository.NewArticleReviewRepository(articleReviewDAO)
+	articleService := service.NewArticleService(articleRepository, articleRevisionRepository, producer, moderator, articleReviewRepository, loggerV1)
 
 	interactiveDAO := dao.NewGORMInteractiveDAO(db)
 	interactiveCache := cache.NewInteractiveRedisCache(cmdable)
-	interactiveRepository := repository.NewCachedInteractiveRepository(interactiveDAO, loggerV1, interactiveCache)
-	interactiveService := service.NewInteractiveService(interactiveRepository)
-	articleHandler := web.NewArticleHandler(loggerV1, articleService, interactiveService)
+	readCache := ioc.InitReadCache(cmdable)
+	node := ioc.InitSnowflakeNode()
+	interactiveDeltaCache := cache.NewInteractiveDeltaRedisCache(cmdable, node)
+	interactiveStateCache := cache.NewInteractiveStateRedisCache(cmdable)
+	interactiveRepository := ioc.InitInteractiveRepository(interactiveDAO, loggerV1, interactiveCache, readCache, interactiveDeltaCache, interactiveStateCache)
+	collectionDAO := dao.NewCollectionGORMDAO(db)
+	collectionRepository := repository.NewCollectionRepository(collectionDAO, interactiveCache, interactiveStateCache, loggerV1)
+	interactiveService := service.NewInteractiveService(interactiveRepository, collectionRepository, userRepository)
+	articleHandler := web.NewArticleHandler(loggerV1, articleService, interactiveService, followService)
 	wechatService := ioc.InitWechatService(loggerV1)
 	oAuth2WechatHandler := web.NewOAuth2WechatHandler(wechatService, handler, userService)
-	engine := ioc.InitWebServer(v, userHandler, articleHandler, oAuth2WechatHandler)
+	index := ioc.InitSearchIndex()
+	searchRepository := repository.NewLocalSearchRepository(index)
+	searchService := service.NewSearchService(searchRepository)
+	searchHandler := web.NewSearchHandler(searchService, loggerV1)
+	commentDAO := dao.NewCommentGORMDAO(db)
+	commentRepository := repository.NewCommentRepository(commentDAO, userRepository)
+	commentService := service.NewCommentService(commentRepository, articleRepository, interactiveRepository)
+	commentHandler := web.NewCommentHandler(commentService, interactiveService, loggerV1)
+	followHandler := web.NewFollowHandler(followService, loggerV1)
+	feedDAO := dao.NewFeedGORMDAO(db)
+	feedRepository := repository.NewFeedRepository(feedDAO)
+	feedService := ioc.InitFeedService(feedRepository, followRepository, articleRepository, loggerV1)
+	feedHandler := web.NewFeedHandler(feedService, interactiveService, loggerV1)
+	rankingRedisCache := cache.NewRankingRedisCache(cmdable)
+	rankingLocalCache := cache.NewRankingLocalCache()
+	rankingRepository := repository.NewCachedRankingRepository(rankingRedisCache, rankingLocalCache)
+	rankingService := service.NewBatchRankingService(articleRepository, interactiveRepository, userRepository, rankingRepository)
+	rankingHandler := web.NewRankingHandler(rankingService, loggerV1)
+	collectionService := service.NewCollectionService(collectionRepository)
+	collectionHandler := web.NewCollectionHandler(collectionService, loggerV1)
+	uploadDAO := dao.NewUploadGORMDAO(db)
+	uploadRepository := repository.NewUploadRepository(uploadDAO)
+	rlockClient := rlock.NewClient(cmdable)
+	uploadService := service.NewUploadService(uploadRepository, articleRepository, objectStore, rlockClient, loggerV1, uploadConfig)
+	uploadHandler := web.NewUploadHandler(uploadService, uploadConfig, loggerV1)
+	articleReviewService := service.NewArticleReviewService(articleRepository, articleReviewRepository, moderator, producer, loggerV1)
+	articleReviewHandler := ioc.InitArticleReviewHandler(articleReviewService, loggerV1)
+	engine := ioc.InitWebServer(v, userHandler, articleHandler, searchHandler, commentHandler, followHandler, feedHandler, rankingHandler, collectionHandler, uploadHandler, articleReviewHandler, oAuth2WechatHandler)
 	
 	interactiveReadEventConsumer := article.NewInteractiveReadEventConsumer(interactiveRepository, client, loggerV1)
-	v2 := ioc.InitConsumers(interactiveReadEventConsumer)
+	searchIndexConsumer := ioc.InitSearchIndexConsumer(searchRepository, loggerV1)
+	articlePublishConsumer := feed.NewArticlePublishConsumer(feedService, client, loggerV1)
+	historyRecordConsumer := article.NewHistoryRecordConsumer(historyRecordRepository, client, loggerV1)
+	v2 := ioc.InitConsumers(interactiveReadEventConsumer, searchIndexConsumer, articlePublishConsumer, historyRecordConsumer)
+	scheduledPublisher := service.NewScheduledPublisher(articleRepository, producer, moderator, loggerV1)
+	rankingJob := ioc.InitRankingJob(rankingService, rlockClient, loggerV1)
+	interactiveFlusher := ioc.InitInteractiveFlusher(interactiveRepository, loggerV1)
+	jobDAO := dao.NewGORMJobDAO(db)
+	cronJobRepository := repository.NewPreemptCronJobRepository(jobDAO)
+	cronJobService := ioc.InitCronJobService(cronJobRepository, loggerV1)
+	articlePurger := service.NewArticlePurger(articleRepository, articleRevisionRepository, interactiveRepository, loggerV1)
+	interactiveReconciler := service.NewInteractiveReconciler(interactiveRepository)
+	localFuncExecutor := ioc.InitLocalFuncExecutor(cronJobService, uploadService, articlePurger, interactiveReconciler, loggerV1)
+	scheduler := ioc.InitScheduler(cronJobService, localFuncExecutor, loggerV1)
 	app := &App{
-		server:    engine,
-		consumers: v2,
+		server:     engine,
+		consumers:  v2,
+		publisher:  scheduledPublisher,
+		rankingJob: rankingJob,
+		flusher:    interactiveFlusher,
+		scheduler:  scheduler,
+		index:      index,
 	}
 	return app
 }
 
 // wire.go:
 
-var interactiveSvcSet = wire.NewSet(dao.NewGORMInteractiveDAO, cache.NewInteractiveRedisCache, repository.NewCachedInteractiveRepository, service.NewInteractiveService)
+// interactiveRepoSet 计数相关的依赖，命令行工具不需要 InteractiveService，所以单独拆出来
+var interactiveRepoSet = wire.NewSet(dao.NewGORMInteractiveDAO, cache.NewInteractiveRedisCache, ioc.InitSnowflakeNode, cache.NewInteractiveDeltaRedisCache, cache.NewInteractiveStateRedisCache, ioc.InitReadCache, ioc.InitInteractiveRepository)
+
+var interactiveSvcSet = wire.NewSet(interactiveRepoSet, service.NewInteractiveService)
+
+func InitInteractiveReconciler() *service.InteractiveReconciler {
+	loggerV1 := ioc.InitLogger()
+	db := ioc.InitDB(loggerV1)
+	interactiveDAO := dao.NewGORMInteractiveDAO(db)
+	cmdable := ioc.InitRedis()
+	interactiveCache := cache.NewInteractiveRedisCache(cmdable)
+	readCache := ioc.InitReadCache(cmdable)
+	node := ioc.InitSnowflakeNode()
+	interactiveDeltaCache := cache.NewInteractiveDeltaRedisCache(cmdable, node)
+	interactiveStateCache := cache.NewInteractiveStateRedisCache(cmdable)
+	interactiveRepository := ioc.InitInteractiveRepository(interactiveDAO, loggerV1, interactiveCache, readCache, interactiveDeltaCache, interactiveStateCache)
+	interactiveReconciler := service.NewInteractiveReconciler(interactiveRepository)
+	return interactiveReconciler
+}